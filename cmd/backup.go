@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"SongLibrary/pkg/backup"
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/service"
+)
+
+const (
+	backupUsage  = "usage: backup [-output text|json] FILE|-"
+	restoreUsage = "usage: restore [-on-conflict skip|overwrite|fail] [-verify-only] [-output text|json] FILE"
+)
+
+// runBackup пишет резервную копию каталога в файл (- - стандартный вывод)
+func runBackup(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf(backupUsage)
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	summary := backup.Summary{}
+	err = writeFile(path, func(w io.Writer) error {
+		summary, err = backup.Write(ctx, repo, w)
+		return err
+	})
+	if err != nil || path == "-" {
+		// копия в stdout, итог не печатается
+		return err
+	}
+
+	return out.print(summary, func(w io.Writer) { printSummary(w, summary) })
+}
+
+func printSummary(w io.Writer, summary backup.Summary) {
+	c := summary.Counts
+	fmt.Fprintf(w, "version %d, created at %s\nartists: %d, albums: %d, songs: %d, playlists: %d\n",
+		summary.Version, summary.CreatedAt.Format("2006-01-02 15:04:05Z07:00"),
+		c[backup.TypeArtist], c[backup.TypeAlbum], c[backup.TypeSong], c[backup.TypePlaylist])
+}
+
+// runRestore восстанавливает каталог из резервной копии. С -verify-only
+// только проверяет копию и печатает ее содержимое
+func runRestore(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	strategy := fs.String("on-conflict", backup.ConflictSkip, "records that already exist: skip, overwrite or fail")
+	verifyOnly := fs.Bool("verify-only", false, "check the archive without restoring it")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf(restoreUsage)
+	}
+	switch *strategy {
+	case backup.ConflictSkip, backup.ConflictOverwrite, backup.ConflictFail:
+	default:
+		return fmt.Errorf("-on-conflict must be skip, overwrite or fail: %s", restoreUsage)
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	open := func() (io.ReadCloser, error) { return os.Open(path) }
+
+	if *verifyOnly {
+		file, err := open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		summary, err := backup.Verify(file)
+		if err != nil {
+			return err
+		}
+		return out.print(summary, func(w io.Writer) { printSummary(w, summary) })
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	report, err := backup.Restore(ctx, repo, open, *strategy)
+	if err != nil && report == (backup.Report{}) {
+		// копия не прошла проверку или конфликтует с хранилищем, ничего не изменено
+		return err
+	}
+	// при ошибке на середине печатается, что успело восстановиться
+	printErr := out.print(report, func(w io.Writer) {
+		rows := [][]string{{"TYPE", "CREATED", "UPDATED", "SKIPPED"}}
+		for _, line := range []struct {
+			name   string
+			counts backup.Counts
+		}{
+			{"artists", report.Artists},
+			{"albums", report.Albums},
+			{"songs", report.Songs},
+			{"playlists", report.Playlists},
+		} {
+			c := line.counts
+			rows = append(rows, []string{line.name, fmt.Sprint(c.Created), fmt.Sprint(c.Updated), fmt.Sprint(c.Skipped)})
+		}
+		table(w, rows)
+	})
+	if err != nil {
+		return err
+	}
+	return printErr
+}
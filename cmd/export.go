@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/service"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/songexport"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+)
+
+const exportUsage = "usage: export [-format jsonl|csv|json] [-group G]... [-tag T]... [-tag-match any|all] [-sort FIELDS] [-output text|json] FILE|-"
+
+// runExport выгружает песни в файл JSON Lines, CSV или JSON, который снова принимает import.
+// Файл .gz сжимается
+func runExport(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fileFormatFlag := fs.String("format", "", "file format: jsonl, csv or json, by default from the file extension")
+	var groups, tags stringsFlag
+	fs.Var(&groups, "group", "songs of the group, repeat for several groups")
+	fs.Var(&tags, "tag", "songs with the tag, repeat for several tags")
+	tagMatch := fs.String("tag-match", storage.TagMatchAny, "songs with any or all of the tags")
+	sortFlag := fs.String("sort", "", "sort fields, e.g. group,-release_date")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf(exportUsage)
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	fileFormat := *fileFormatFlag
+	if fileFormat == "" {
+		fileFormat = exportFileFormat(path)
+	}
+	switch fileFormat {
+	case songimport.FormatJSONL, songimport.FormatCSV, songimport.FormatJSON:
+	default:
+		return fmt.Errorf("-format must be jsonl, csv or json: %s", exportUsage)
+	}
+	if *tagMatch != storage.TagMatchAny && *tagMatch != storage.TagMatchAll {
+		return fmt.Errorf("-tag-match must be any or all: %s", exportUsage)
+	}
+
+	params := storage.ListParams{Filter: storage.SongFilter{Groups: groups, TagMatch: *tagMatch}}
+	if params.Filter.Tags, err = storage.NormalizeTags(tags); err != nil {
+		return err
+	}
+	if params.Sort, err = storage.ParseSort(*sortFlag); err != nil {
+		return fmt.Errorf("%w: %s", err, exportUsage)
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	count := 0
+	compress := strings.EqualFold(filepath.Ext(path), ".gz")
+	err = writeFile(path, func(w io.Writer) error {
+		return writeTo(w, compress, func(w io.Writer) error {
+			writer, err := songexport.NewWriter(w, fileFormat)
+			if err != nil {
+				return err
+			}
+			err = repo.ExportSongs(ctx, params, func(s song.Song) error {
+				count++
+				return writer.WriteSong(s)
+			})
+			if err != nil {
+				return err
+			}
+			return writer.Close()
+		})
+	})
+	if err != nil || path == "-" {
+		// песни в stdout, итог не печатается
+		return err
+	}
+
+	result := struct {
+		Songs int `json:"songs"`
+	}{count}
+	return out.print(result, func(w io.Writer) { fmt.Fprintf(w, "exported %d songs to %s\n", count, path) })
+}
+
+// exportFileFormat - формат файла по расширению, для - - JSON Lines
+func exportFileFormat(path string) string {
+	if path == "-" {
+		return songimport.FormatJSONL
+	}
+	return fileFormat(path)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/service"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+)
+
+const importUsage = "usage: import [-format jsonl|csv|json] [-on-conflict skip|upsert] [-batch N] [-errors-only] [-output text|json] FILE|-"
+
+// runImport добавляет песни из файла JSON Lines, CSV или JSON (- - стандартный ввод)
+// и печатает результат каждой строки. Файл .gz распаковывается.
+// В режиме -output json строки копятся в памяти и печатаются в конце вместе с итогом
+func runImport(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "file format: jsonl, csv or json, by default from the file extension")
+	policy := fs.String("on-conflict", storage.ImportSkip, "songs that already exist: skip or upsert")
+	batch := fs.Int("batch", songimport.DefaultBatchSize, "songs saved in one transaction")
+	errorsOnly := fs.Bool("errors-only", false, "print only failed rows")
+	outputFormat := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf(importUsage)
+	}
+	if *policy != storage.ImportSkip && *policy != storage.ImportUpsert {
+		return fmt.Errorf("-on-conflict must be skip or upsert: %s", importUsage)
+	}
+	if *batch <= 0 {
+		return fmt.Errorf("-batch must be positive: %s", importUsage)
+	}
+
+	out, err := newOutput(*outputFormat)
+	if err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = fileFormat(path)
+	}
+	switch *format {
+	case songimport.FormatJSONL, songimport.FormatCSV, songimport.FormatJSON:
+	default:
+		return fmt.Errorf("-format must be jsonl, csv or json: %s", importUsage)
+	}
+
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	if strings.EqualFold(filepath.Ext(path), ".gz") {
+		gz, err := gzip.NewReader(input)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		input = gz
+	}
+
+	reader, err := songimport.NewReader(input, *format)
+	if err != nil {
+		return err
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	importer := songimport.NewImporter(repo)
+	importer.BatchSize = *batch
+
+	report := song.ImportReport{Rows: []song.ImportRow{}}
+	err = importer.Import(ctx, reader, *policy, func(row song.ImportRow) error {
+		switch {
+		case *errorsOnly && row.Status != song.ImportFailed:
+			report.Count(row)
+		case out.json():
+			report.Add(row)
+		default:
+			// в текстовом режиме строки не копятся в памяти: отчет печатается по мере сохранения пачек
+			report.Count(row)
+			if row.Status == song.ImportFailed {
+				fmt.Fprintf(out.w, "line %d: error: %s\n", row.Line, row.Error)
+			} else {
+				fmt.Fprintf(out.w, "line %d: %s song %d (%s - %s)\n", row.Line, row.Status, row.SongID, row.Group, row.Song)
+			}
+		}
+		return nil
+	})
+
+	printErr := out.print(report, func(w io.Writer) {
+		fmt.Fprintf(w, "created: %d, updated: %d, skipped: %d, errors: %d\n", report.Created, report.Updated, report.Skipped, report.Failed)
+	})
+	if err != nil {
+		return err
+	}
+	if printErr != nil {
+		return printErr
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("failed to import %d rows", report.Failed)
+	}
+
+	return nil
+}
+
+// fileFormat - формат файла по расширению, для сжатого файла - по расширению перед .gz
+func fileFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".gz") {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return songimport.FormatJSONL
+	case ".csv":
+		return songimport.FormatCSV
+	case ".json":
+		return songimport.FormatJSON
+	}
+	return ""
+}
@@ -2,22 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 
 	_ "SongLibrary/docs"
+	"SongLibrary/pkg/config"
 	"SongLibrary/pkg/service"
 
-	"github.com/joho/godotenv"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-const (
-	envDebug = "debug"
-	envProd  = "prod"
-)
-
 // @title Library of songs
 // @version 1.0
 // @description API Server for Library of Songs
@@ -26,57 +26,112 @@ const (
 // @BasePath /api
 
 func main() {
-	err := godotenv.Load(".env")
+	cfg, args, err := config.Load(os.Args[1:])
 	if err != nil {
-		slog.Error("error load env file",
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		slog.Error("error load config",
 			"ERROR", err,
 		)
 		os.Exit(1)
 	}
-	slog.Info("parse .env file success")
-
-	levelLog := os.Getenv("LOG_LEVEL")
-	if levelLog != "info" && levelLog != "debug" {
-		levelLog = "info"
+	slog.Info("load config success")
+
+	cmd := commands[0]
+	if len(args) > 0 {
+		if cmd, err = findCommand(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		args = args[1:]
 	}
 
-	logger := setupLogger(levelLog)
-	logger.Info("setup slog level", "level", levelLog)
+	// вывод команд идет в stdout, поэтому их логи пишутся в stderr
+	logOutput := os.Stderr
+	if cmd.name == "serve" {
+		logOutput = os.Stdout
+	}
+	logger := setupLogger(cfg.LogLevel, logOutput)
+	logger.Info("setup slog level", "level", cfg.LogLevel)
 	logger.Debug("debug messages are enabled")
 
-	s, err := service.NewService(logger)
-	if err != nil {
-		logger.Error("error create service:",
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err = cmd.run(ctx, cfg, logger, args); err != nil {
+		logger.Error("error run command:",
+			"command", cmd.name,
 			"ERROR", err,
 		)
 		os.Exit(1)
 	}
+}
+
+// command - подкоманда бинарника. Без команды запускается сервер
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error
+}
+
+var commands = []command{
+	{"serve", serveUsage, runServe},
+	{"migrate", migrateUsage, runMigrate},
+	{"import", importUsage, runImport},
+	{"export", exportUsage, runExport},
+	{"add", addUsage, runAdd},
+	{"delete", deleteUsage, runDelete},
+	{"search", searchUsage, runSearch},
+	{"reindex", reindexUsage, runReindex},
+	{"enrich", enrichUsage, runEnrich},
+	{"refresh", refreshUsage, runRefresh},
+	{"backup", backupUsage, runBackup},
+	{"restore", restoreUsage, runRestore},
+}
+
+func findCommand(name string) (command, error) {
+	usages := make([]string, 0, len(commands))
+	for _, c := range commands {
+		if c.name == name {
+			return c, nil
+		}
+		usages = append(usages, "  "+strings.TrimPrefix(c.usage, "usage: "))
+	}
+	return command{}, fmt.Errorf("unknown command %q, commands:\n%s", name, strings.Join(usages, "\n"))
+}
+
+const serveUsage = "usage: serve"
+
+// runServe запускает HTTP сервер и воркеров заполнения до SIGINT или SIGTERM
+func runServe(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf(serveUsage)
+	}
+	if err := cfg.External.Validate(); err != nil {
+		return err
+	}
+
+	s, err := service.NewService(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
 	logger.Info("service create success")
 
 	http.Handle("/swagger/", httpSwagger.WrapHandler)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	if err = s.Start(ctx, logger); err != nil {
-		logger.Error("error start service:",
-			"ERROR", err,
-		)
-		os.Exit(1)
+		return err
 	}
 
 	logger.Info("service gracefull shutdown")
+	return nil
 }
 
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger
-
-	switch env {
-	case envDebug:
-		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	case envProd:
-		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
-	}
+func setupLogger(levelLog string, w io.Writer) *slog.Logger {
+	var level slog.Level
+	// уровень уже проверен при загрузке конфигурации
+	_ = level.UnmarshalText([]byte(levelLog))
 
-	return log
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/service"
+	"SongLibrary/pkg/storage"
+)
+
+const (
+	reindexUsage = "usage: reindex [-output text|json]"
+	enrichUsage  = "usage: enrich [-limit N] [-output text|json]"
+)
+
+// runReindex перестраивает индексы хранилища, например после массового импорта
+func runReindex(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf(reindexUsage)
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	reindexer, ok := repo.(storage.Reindexer)
+	if !ok {
+		return fmt.Errorf("reindex is not supported by %s storage", cfg.Storage.Driver)
+	}
+
+	tables, err := reindexer.Reindex(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Tables []string `json:"tables"`
+	}{tables}
+	return out.print(result, func(w io.Writer) { fmt.Fprintf(w, "reindexed: %s\n", strings.Join(tables, ", ")) })
+}
+
+// runEnrich один раз разбирает очередь задач заполнения песен и завершается.
+// Подходит для cron, если сервер запущен без воркеров (ENRICHMENT_WORKERS=0)
+func runEnrich(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("enrich", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "maximum number of jobs, 0 - all ready jobs")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *limit < 0 {
+		return fmt.Errorf(enrichUsage)
+	}
+	if err := cfg.External.Validate(); err != nil {
+		return err
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	worker := enrichment.NewWorker(enrichment.NewHTTPClient(cfg.External), repo, cfg.Enrichment, logger)
+	report, err := worker.Drain(ctx, *limit)
+	if err != nil {
+		return err
+	}
+
+	return out.print(report, func(w io.Writer) {
+		fmt.Fprintf(w, "done: %d, retried: %d, failed: %d\n", report.Done, report.Retried, report.Failed)
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"SongLibrary/migrations"
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/migrate"
+	"SongLibrary/pkg/storage/repository/postgres"
+)
+
+const migrateUsage = "usage: migrate up | down [-output text|json] [N] | status [-output text|json]"
+
+// migrationJSON - миграция в выводе -output json
+type migrationJSON struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// runMigrate выполняет команду "migrate up|down [N]|status" для базы PostgreSQL
+func runMigrate(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	format := outputFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+	args = append(args[:1], fs.Args()...)
+
+	if cfg.Storage.Driver != config.DriverPostgres {
+		return fmt.Errorf("migrations are supported only for %s storage, got %s", config.DriverPostgres, cfg.Storage.Driver)
+	}
+
+	pool, err := postgres.NewConnPostgres(ctx, cfg.Postgres, logger)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	m, err := migrate.NewMigrator(pool, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrate up success", "applied", len(applied))
+		return printMigrations(out, "applied", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("bad number of steps %q: %s", args[1], migrateUsage)
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrate down success", "reverted", len(reverted))
+		return printMigrations(out, "reverted", reverted)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(out, statuses)
+	default:
+		return fmt.Errorf("unknown migrate command %q: %s", args[0], migrateUsage)
+	}
+}
+
+// printMigrations печатает примененные или откаченные миграции
+func printMigrations(out *output, action string, list []migrate.Migration) error {
+	result := make([]migrationJSON, 0, len(list))
+	for _, m := range list {
+		result = append(result, migrationJSON{Version: m.Version, Name: m.Name, Applied: action == "applied"})
+	}
+	return out.print(result, func(w io.Writer) {
+		for _, m := range list {
+			fmt.Fprintf(w, "%s %04d %s\n", action, m.Version, m.Name)
+		}
+		if len(list) == 0 {
+			fmt.Fprintf(w, "no migrations %s\n", action)
+		}
+	})
+}
+
+func printMigrationStatus(out *output, statuses []migrate.Status) error {
+	result := make([]migrationJSON, 0, len(statuses))
+	for _, s := range statuses {
+		m := migrationJSON{Version: s.Version, Name: s.Name, Applied: s.Applied}
+		if s.Applied {
+			m.AppliedAt = &s.AppliedAt
+		}
+		result = append(result, m)
+	}
+	return out.print(result, func(w io.Writer) {
+		rows := [][]string{{"VERSION", "NAME", "STATUS", "APPLIED AT"}}
+		for _, s := range statuses {
+			status, appliedAt := "pending", ""
+			if s.Applied {
+				status, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			rows = append(rows, []string{fmt.Sprintf("%04d", s.Version), s.Name, status, appliedAt})
+		}
+		table(w, rows)
+	})
+}
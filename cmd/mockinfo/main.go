@@ -0,0 +1,71 @@
+// mockinfo - локальная заглушка внешнего сервиса с информацией о песнях
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"SongLibrary/pkg/mockinfo"
+)
+
+func main() {
+	addr := flag.String("addr", ":8088", "listen address")
+	fixturesPath := flag.String("fixtures", "", "json file with songs, embedded fixtures by default")
+	latency := flag.Duration("latency", 0, "delay before every response")
+	failure := flag.String("failure", mockinfo.FailureNone, `simulated failure: "500" or "malformed"`)
+	failureRate := flag.Float64("failure-rate", 0, "share of failed requests in (0, 1], 0 fails every request")
+	flag.Parse()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	fixtures := mockinfo.DefaultFixtures()
+	if *fixturesPath != "" {
+		var err error
+		fixtures, err = mockinfo.LoadFixtures(*fixturesPath)
+		if err != nil {
+			logger.Error("error load fixtures", "ERROR", err)
+			os.Exit(1)
+		}
+	}
+
+	mock := mockinfo.NewServer(fixtures)
+	mock.Logger = logger
+	err := mock.SetMode(mockinfo.Mode{
+		Latency:     *latency,
+		Failure:     *failure,
+		FailureRate: *failureRate,
+	})
+	if err != nil {
+		logger.Error("error set mode", "ERROR", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: mock}
+	go func() {
+		logger.Info("mock info service start", "addr", *addr, "songs", len(fixtures))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("error listen server", "ERROR", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("error shutdown server", "ERROR", err)
+		os.Exit(1)
+	}
+	logger.Info("mock info service stopped")
+}
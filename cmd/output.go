@@ -0,0 +1,113 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// форматы вывода команд: для человека и для скриптов
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// output - вывод результата команды. Логи идут в stderr, поэтому stdout можно
+// разбирать скриптом: в режиме json команда печатает один JSON документ
+type output struct {
+	format string
+	w      io.Writer
+}
+
+// outputFlag добавляет команде флаг -output
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", outputText, "output format: text or json")
+}
+
+func newOutput(format string) (*output, error) {
+	if format != outputText && format != outputJSON {
+		return nil, fmt.Errorf("-output must be text or json, got %q", format)
+	}
+	return &output{format: format, w: os.Stdout}, nil
+}
+
+func (o *output) json() bool {
+	return o.format == outputJSON
+}
+
+// print печатает v в режиме json и text() в режиме text
+func (o *output) print(v any, text func(w io.Writer)) error {
+	if o.json() {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text(o.w)
+	return nil
+}
+
+// table печатает строки колонками, первая строка - заголовок
+func table(w io.Writer, rows [][]string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// stringsFlag - флаг, который можно повторить
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// writeFile пишет файл path функцией write (- - стандартный вывод). Файл пишется во временный
+// рядом и переименовывается в конце, поэтому прерванная запись не портит прежний файл
+func writeFile(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err = write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// writeTo пишет функцией write в w, со сжатием gzip, если compress
+func writeTo(w io.Writer, compress bool, write func(io.Writer) error) error {
+	if !compress {
+		return write(w)
+	}
+	gz := gzip.NewWriter(w)
+	if err := write(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
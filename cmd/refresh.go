@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/service"
+	"SongLibrary/pkg/song"
+)
+
+const refreshUsage = "usage: refresh [-yes | -dry-run] -all | SONG_ID..."
+
+// сколько символов значения поля показывать в diff
+const diffValueWidth = 60
+
+// runRefresh заново запрашивает данные песен во внешнем сервисе, печатает отличия
+// и сохраняет их: сразу (-yes), после подтверждения для каждой песни или никогда (-dry-run)
+func runRefresh(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("refresh", flag.ContinueOnError)
+	all := fs.Bool("all", false, "refresh all songs")
+	yes := fs.Bool("yes", false, "apply changes without confirmation")
+	dryRun := fs.Bool("dry-run", false, "only show changes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *yes && *dryRun {
+		return fmt.Errorf("-yes and -dry-run are mutually exclusive: %s", refreshUsage)
+	}
+
+	ids := make([]int, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("bad song id %q: %s", arg, refreshUsage)
+		}
+		ids = append(ids, id)
+	}
+	if *all == (len(ids) > 0) {
+		return fmt.Errorf(refreshUsage)
+	}
+	if err := cfg.External.Validate(); err != nil {
+		return err
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	refresher := enrichment.NewRefresher(enrichment.NewHTTPClient(cfg.External), repo, repo)
+	r := &refreshRun{
+		refresher: refresher,
+		confirm:   !*yes && !*dryRun,
+		input:     bufio.NewReader(os.Stdin),
+		out:       os.Stdout,
+	}
+
+	if *all {
+		err = refresher.RefreshAll(ctx, *yes, func(result song.RefreshResult, err error) error {
+			return r.handle(ctx, result, err)
+		})
+	} else {
+		for _, id := range ids {
+			result, refreshErr := refresher.Refresh(ctx, id, *yes)
+			if refreshErr != nil {
+				result.SongID = int64(id)
+			}
+			if err = r.handle(ctx, result, refreshErr); err != nil {
+				break
+			}
+		}
+	}
+	if errors.Is(err, errRefreshQuit) {
+		err = nil
+	}
+
+	fmt.Fprintf(r.out, "checked: %d, changed: %d, applied: %d, errors: %d\n", r.checked, r.changed, r.applied, r.failed)
+	if err != nil {
+		return err
+	}
+	if r.failed > 0 {
+		return fmt.Errorf("failed to refresh %d songs", r.failed)
+	}
+
+	return nil
+}
+
+var errRefreshQuit = errors.New("refresh stopped by user")
+
+type refreshRun struct {
+	refresher *enrichment.Refresher
+	confirm   bool
+	applyAll  bool
+	input     *bufio.Reader
+	out       io.Writer
+
+	checked, changed, applied, failed int
+}
+
+func (r *refreshRun) handle(ctx context.Context, result song.RefreshResult, err error) error {
+	r.checked++
+	if err != nil {
+		r.failed++
+		fmt.Fprintf(r.out, "%s: error: %v\n", songTitle(result), err)
+		return nil
+	}
+	if len(result.Changes) == 0 {
+		return nil
+	}
+	r.changed++
+
+	fmt.Fprintf(r.out, "%s:\n", songTitle(result))
+	for _, change := range result.Changes {
+		fmt.Fprintf(r.out, "  %s:\n    - %s\n    + %s\n", change.Field, shorten(change.Old), shorten(change.New))
+	}
+
+	if r.confirm && !r.applyAll {
+		apply, err := r.ask()
+		if err != nil || !apply {
+			return err
+		}
+	}
+	if r.confirm {
+		if err = r.refresher.Apply(ctx, &result); err != nil {
+			r.failed++
+			fmt.Fprintf(r.out, "  error: %v\n", err)
+			return nil
+		}
+	}
+	if result.Applied {
+		r.applied++
+	}
+
+	return nil
+}
+
+// ask спрашивает, применить ли изменения: y - да, n - нет, a - да для всех оставшихся, q - закончить
+func (r *refreshRun) ask() (bool, error) {
+	for {
+		fmt.Fprint(r.out, "apply changes? [y/n/a/q] ")
+		answer, err := r.input.ReadString('\n')
+		if err != nil && answer == "" {
+			if errors.Is(err, io.EOF) {
+				return false, errRefreshQuit
+			}
+			return false, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no", "":
+			return false, nil
+		case "a", "all":
+			r.applyAll = true
+			return true, nil
+		case "q", "quit":
+			return false, errRefreshQuit
+		}
+	}
+}
+
+func songTitle(result song.RefreshResult) string {
+	if result.Group == "" && result.Song == "" {
+		return fmt.Sprintf("song %d", result.SongID)
+	}
+	return fmt.Sprintf("song %d (%s - %s)", result.SongID, result.Group, result.Song)
+}
+
+func shorten(value string) string {
+	runes := []rune(value)
+	if len(runes) > diffValueWidth {
+		return strconv.Quote(string(runes[:diffValueWidth])) + "..."
+	}
+	return strconv.Quote(value)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/service"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+)
+
+const (
+	addUsage    = "usage: add [-date DATE] [-text TEXT] [-link URL] [-album-id N] [-tag T]... [-output text|json] GROUP SONG"
+	deleteUsage = "usage: delete [-output text|json] SONG_ID..."
+	searchUsage = "usage: search [-limit N] [-offset N] [-output text|json] QUERY..."
+)
+
+// runAdd добавляет песню. Песня с датой выпуска, текстом и ссылкой сохраняется как есть,
+// недостающие данные запрашиваются во внешнем сервисе: сразу в режиме sync,
+// фоновой задачей в режиме async (ее выполнит воркер сервера или команда enrich)
+func runAdd(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	rec := songimport.Record{}
+	fs.StringVar(&rec.ReleaseDate, "date", "", "release date: YYYY-MM-DD, YYYY-MM or YYYY")
+	fs.StringVar(&rec.Text, "text", "", "text of the song, verses are separated by an empty line")
+	fs.StringVar(&rec.Link, "link", "", "link to the song")
+	fs.Int64Var(&rec.AlbumID, "album-id", 0, "album of the song artist")
+	var tags stringsFlag
+	fs.Var(&tags, "tag", "tag of the song, repeat for several tags")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf(addUsage)
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	rec.Group, rec.Song, rec.Tags = fs.Arg(0), fs.Arg(1), tags
+	s, err := rec.ToSong()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, addUsage)
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	if s.EnrichmentStatus == song.EnrichmentPending && cfg.Enrichment.Mode == config.EnrichmentSync {
+		if err = cfg.External.Validate(); err != nil {
+			return err
+		}
+		info, err := enrichment.NewHTTPClient(cfg.External).GetSongInfo(ctx, s.Group, s.Song)
+		if err != nil {
+			return err
+		}
+		// данные, переданные флагами, важнее данных внешнего сервиса
+		if s.ReleaseDate.IsZero() {
+			s.ReleaseDate = info.ReleaseDate
+		}
+		if s.Text == "" {
+			s.Text = info.Text
+		}
+		if s.Link == "" {
+			s.Link = info.Link
+		}
+		s.EnrichmentStatus = song.EnrichmentDone
+	}
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	created, err := repo.AddSongToDB(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	return out.print(created, func(w io.Writer) {
+		fmt.Fprintf(w, "added song %d: %s - %s (%s)\n", created.SongID, created.Group, created.Song, created.EnrichmentStatus)
+	})
+}
+
+// deleteResult - итог удаления одной песни
+type deleteResult struct {
+	SongID  int    `json:"song_id"`
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runDelete удаляет песни по id. Песни, которых нет, не мешают удалить остальные
+func runDelete(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf(deleteUsage)
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("bad song id %q: %s", arg, deleteUsage)
+		}
+		ids = append(ids, id)
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	results := make([]deleteResult, 0, len(ids))
+	failed := 0
+	for _, id := range ids {
+		result := deleteResult{SongID: id}
+		if _, err = repo.DeleteSongByIDFromDB(ctx, id); err != nil {
+			if !errors.Is(err, storage.ErrorSongNotExist) {
+				return err
+			}
+			result.Error = err.Error()
+			failed++
+		} else {
+			result.Deleted = true
+		}
+		results = append(results, result)
+	}
+
+	err = out.print(results, func(w io.Writer) {
+		for _, r := range results {
+			if r.Deleted {
+				fmt.Fprintf(w, "deleted song %d\n", r.SongID)
+			} else {
+				fmt.Fprintf(w, "song %d: %s\n", r.SongID, r.Error)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d songs", failed)
+	}
+	return nil
+}
+
+// runSearch ищет песни полнотекстовым поиском, как GET /api/songs/search
+func runSearch(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := fs.Int("limit", 10, "number of songs")
+	offset := fs.Int("offset", 0, "number of songs to skip")
+	format := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf(searchUsage)
+	}
+	out, err := newOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	repo, err := service.NewSongRepo(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	searchRepo, ok := repo.(storage.SearchRepo)
+	if !ok {
+		return fmt.Errorf("search is not supported by %s storage", cfg.Storage.Driver)
+	}
+
+	results, err := searchRepo.SearchSongs(ctx, strings.Join(fs.Args(), " "), *limit, *offset)
+	if err != nil {
+		return err
+	}
+
+	return out.print(results, func(w io.Writer) {
+		rows := [][]string{{"ID", "GROUP", "SONG", "RANK"}}
+		for _, r := range results {
+			rows = append(rows, []string{strconv.FormatInt(r.SongID, 10), r.Group, r.Song.Song, strconv.FormatFloat(r.Rank, 'f', 4, 64)})
+		}
+		table(w, rows)
+	})
+}
@@ -0,0 +1,16 @@
+ALTER TABLE songs ADD COLUMN enrichment_status varchar(20) NOT NULL DEFAULT 'done';
+
+CREATE TABLE enrichment_jobs (
+    job_id BIGSERIAL PRIMARY KEY,
+    song_id integer NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT '',
+    run_at timestamptz NOT NULL DEFAULT now(),
+    locked_until timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX enrichment_jobs_queue_idx ON enrichment_jobs (run_at) WHERE status IN ('pending', 'running');
+CREATE INDEX enrichment_jobs_status_idx ON enrichment_jobs (status, job_id);
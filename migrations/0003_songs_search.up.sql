@@ -0,0 +1,12 @@
+-- полнотекстовый поиск по названию, группе и тексту песни.
+-- Каталог смешанный, поэтому вектор строится сразу в русской и английской конфигурациях.
+ALTER TABLE songs ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(song_name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(song_name, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(group_name, '')), 'B') ||
+    setweight(to_tsvector('russian', coalesce(text_of_song, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(text_of_song, '')), 'C')
+) STORED;
+
+CREATE INDEX songs_search_vector_idx ON songs USING GIN (search_vector);
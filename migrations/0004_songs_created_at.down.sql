@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS songs_song_name_lower_idx;
+DROP INDEX IF EXISTS songs_group_name_lower_idx;
+DROP INDEX IF EXISTS songs_created_at_idx;
+
+ALTER TABLE songs DROP COLUMN created_at;
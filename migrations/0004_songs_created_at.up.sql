@@ -0,0 +1,5 @@
+ALTER TABLE songs ADD COLUMN created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX songs_created_at_idx ON songs (created_at, song_id);
+CREATE INDEX songs_group_name_lower_idx ON songs (lower(group_name), song_id);
+CREATE INDEX songs_song_name_lower_idx ON songs (lower(song_name), song_id);
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS songs_release_date_idx;
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_release_date_precision_check;
+
+ALTER TABLE songs ADD COLUMN release_date_text varchar(50) NOT NULL DEFAULT '';
+UPDATE songs SET release_date_text = CASE release_date_precision
+    WHEN 'year' THEN to_char(release_date, 'YYYY')
+    WHEN 'month' THEN to_char(release_date, 'MM.YYYY')
+    WHEN 'day' THEN to_char(release_date, 'DD.MM.YYYY')
+    ELSE ''
+END;
+
+ALTER TABLE songs DROP COLUMN release_date;
+ALTER TABLE songs DROP COLUMN release_date_precision;
+ALTER TABLE songs RENAME COLUMN release_date_text TO release_date;
+ALTER TABLE songs ALTER COLUMN release_date DROP DEFAULT;
@@ -0,0 +1,42 @@
+-- дата выпуска хранится датой с точностью (day, month, year) вместо строки внешнего сервиса.
+-- Старые значения DD.MM.YYYY, MM.YYYY, YYYY и ISO 8601 переводятся в дату,
+-- нераспознанные становятся неизвестной датой (NULL).
+CREATE FUNCTION pg_temp.parse_release_date(value text, OUT day date, OUT precision text) AS $$
+BEGIN
+    value := btrim(value);
+    CASE
+        WHEN value ~ '^\d{1,2}\.\d{1,2}\.\d{4}$' THEN
+            day := to_date(value, 'DD.MM.YYYY'); precision := 'day';
+        WHEN value ~ '^\d{4}-\d{2}-\d{2}$' THEN
+            day := value::date; precision := 'day';
+        WHEN value ~ '^\d{1,2}\.\d{4}$' THEN
+            day := to_date(value, 'MM.YYYY'); precision := 'month';
+        WHEN value ~ '^\d{4}-\d{2}$' THEN
+            day := to_date(value, 'YYYY-MM'); precision := 'month';
+        WHEN value ~ '^\d{4}$' THEN
+            day := to_date(value, 'YYYY'); precision := 'year';
+        ELSE
+            day := NULL; precision := '';
+    END CASE;
+EXCEPTION WHEN others THEN
+    day := NULL; precision := '';
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE songs RENAME COLUMN release_date TO release_date_text;
+ALTER TABLE songs ADD COLUMN release_date date;
+ALTER TABLE songs ADD COLUMN release_date_precision varchar(5) NOT NULL DEFAULT '';
+
+UPDATE songs SET (release_date, release_date_precision) =
+    (SELECT day, precision FROM pg_temp.parse_release_date(release_date_text));
+
+ALTER TABLE songs DROP COLUMN release_date_text;
+ALTER TABLE songs ADD CONSTRAINT songs_release_date_precision_check CHECK (
+    (release_date IS NULL AND release_date_precision = '') OR
+    (release_date IS NOT NULL AND release_date_precision IN ('day', 'month', 'year'))
+);
+
+CREATE INDEX songs_release_date_idx ON songs ((coalesce(release_date, '-infinity'::date)), song_id);
+
+-- функция нужна только этой миграции, иначе повторный up в той же сессии на ней упадет
+DROP FUNCTION pg_temp.parse_release_date(text);
@@ -0,0 +1,9 @@
+-- group_name уже приведена к имени исполнителя, исходное написание групп не восстанавливается
+DROP INDEX IF EXISTS songs_album_id_idx;
+DROP INDEX IF EXISTS songs_artist_id_idx;
+
+ALTER TABLE songs DROP COLUMN album_id;
+ALTER TABLE songs DROP COLUMN artist_id;
+
+DROP TABLE albums;
+DROP TABLE artists;
@@ -0,0 +1,46 @@
+-- исполнители и альбомы. Песня ссылается на исполнителя, group_name остается копией его имени:
+-- по ней строятся полнотекстовый индекс и сортировка списка.
+CREATE TABLE artists (
+    artist_id BIGSERIAL PRIMARY KEY,
+    name varchar(100) NOT NULL CHECK (name = btrim(name, E' \t\r\n') AND name <> ''),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX artists_name_key ON artists (lower(name));
+
+CREATE TABLE albums (
+    album_id BIGSERIAL PRIMARY KEY,
+    artist_id bigint NOT NULL REFERENCES artists (artist_id) ON DELETE CASCADE,
+    title varchar(200) NOT NULL CHECK (title = btrim(title, E' \t\r\n') AND title <> ''),
+    release_date date,
+    release_date_precision varchar(5) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX albums_title_key ON albums (artist_id, lower(title));
+
+-- группы, которые отличаются только регистром и пробелами по краям, становятся одним исполнителем.
+-- Имя исполнителя берется из самой ранней песни группы.
+INSERT INTO artists (name)
+SELECT name FROM (
+    SELECT DISTINCT ON (lower(btrim(group_name, E' \t\r\n'))) btrim(group_name, E' \t\r\n') AS name, song_id
+    FROM songs
+    WHERE btrim(group_name, E' \t\r\n') <> ''
+    ORDER BY lower(btrim(group_name, E' \t\r\n')), song_id
+) first_songs
+ORDER BY song_id;
+
+-- у песен без группы исполнитель тоже должен быть
+INSERT INTO artists (name)
+SELECT 'Unknown' WHERE EXISTS (SELECT 1 FROM songs WHERE btrim(group_name, E' \t\r\n') = '')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE songs ADD COLUMN artist_id bigint REFERENCES artists (artist_id);
+ALTER TABLE songs ADD COLUMN album_id bigint REFERENCES albums (album_id) ON DELETE SET NULL;
+
+UPDATE songs SET artist_id = a.artist_id, group_name = a.name
+FROM artists a
+WHERE lower(a.name) = coalesce(nullif(lower(btrim(songs.group_name, E' \t\r\n')), ''), 'unknown');
+
+ALTER TABLE songs ALTER COLUMN artist_id SET NOT NULL;
+
+CREATE INDEX songs_artist_id_idx ON songs (artist_id, song_id);
+CREATE INDEX songs_album_id_idx ON songs (album_id) WHERE album_id IS NOT NULL;
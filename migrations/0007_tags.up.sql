@@ -0,0 +1,14 @@
+-- теги песен. Имя тега хранится приведенным к нижнему регистру без пробелов по краям
+-- (storage.NormalizeTag), поэтому уникально как есть.
+CREATE TABLE tags (
+    tag_id BIGSERIAL PRIMARY KEY,
+    name varchar(50) NOT NULL UNIQUE CHECK (name = btrim(name, E' \t\r\n') AND name <> '')
+);
+
+CREATE TABLE song_tags (
+    song_id integer NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    tag_id bigint NOT NULL REFERENCES tags (tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+-- отбор песен по тегу и подсчет песен тега
+CREATE INDEX song_tags_tag_id_idx ON song_tags (tag_id, song_id);
@@ -0,0 +1,20 @@
+-- плейлисты. Записи упорядочены по (position, entry_id), position - не номер записи,
+-- а ключ сортировки: после удаления записей в нем остаются пропуски, номер считается при чтении.
+CREATE TABLE playlists (
+    playlist_id BIGSERIAL PRIMARY KEY,
+    name varchar(100) NOT NULL CHECK (name = btrim(name, E' \t\r\n') AND name <> ''),
+    description text NOT NULL DEFAULT '',
+    allow_duplicates boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE playlist_entries (
+    entry_id BIGSERIAL PRIMARY KEY,
+    playlist_id bigint NOT NULL REFERENCES playlists (playlist_id) ON DELETE CASCADE,
+    song_id integer NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    added_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX playlist_entries_order_idx ON playlist_entries (playlist_id, position, entry_id);
+CREATE INDEX playlist_entries_song_id_idx ON playlist_entries (song_id);
@@ -0,0 +1 @@
+DROP INDEX songs_artist_name_idx;
@@ -0,0 +1,2 @@
+-- поиск песни исполнителя по названию: проверка повторов при добавлении и массовом импорте
+CREATE INDEX songs_artist_name_idx ON songs (artist_id, song_name);
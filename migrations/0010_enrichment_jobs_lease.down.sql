@@ -0,0 +1 @@
+ALTER TABLE enrichment_jobs DROP COLUMN leased_by;
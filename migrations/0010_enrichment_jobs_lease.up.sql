@@ -0,0 +1,2 @@
+-- токен воркера, который забрал задачу: результат сохраняется, только пока он держит lease
+ALTER TABLE enrichment_jobs ADD COLUMN leased_by text NOT NULL DEFAULT '';
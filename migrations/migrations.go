@@ -0,0 +1,8 @@
+// Package migrations содержит версионированные миграции схемы PostgreSQL.
+// Файлы именуются NNNN_name.up.sql и NNNN_name.down.sql и встраиваются в бинарник.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
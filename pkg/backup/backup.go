@@ -0,0 +1,228 @@
+// Package backup - резервная копия каталога: исполнители, альбомы, песни с тегами и плейлисты.
+// Копия читается и восстанавливается только через интерфейсы storage, поэтому подходит
+// для любого хранилища и переносит каталог между ними.
+//
+// Файл копии - JSON Lines, сжатый gzip. Первая строка - заголовок с Kind и версией формата,
+// затем записи по типам в порядке artist, album, song, playlist, последняя строка - итог
+// с числом записей каждого типа и SHA-256 всех строк до нее.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"time"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// Kind отличает файл копии от других файлов JSON Lines
+const Kind = "songlibrary-backup"
+
+// Version - версия формата, которую пишет Write. Restore читает версии до Version включительно
+const Version = 1
+
+// типы записей копии
+const (
+	TypeArtist   = "artist"
+	TypeAlbum    = "album"
+	TypeSong     = "song"
+	TypePlaylist = "playlist"
+	typeEnd      = "end"
+)
+
+// pageSize - сколько исполнителей, альбомов, плейлистов и записей читается из хранилища за раз
+const pageSize = 100
+
+var (
+	ErrBadArchive         = errors.New("bad backup archive")
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+	ErrChecksum           = errors.New("backup checksum mismatch")
+)
+
+// Header - первая строка копии
+type Header struct {
+	Kind      string    `json:"kind"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Playlist - плейлист копии, SongIDs - id песен копии в порядке записей
+type Playlist struct {
+	song.Playlist
+	SongIDs []int64 `json:"song_ids"`
+}
+
+// record - строка копии после заголовка. Заполнено поле ее типа, у итога - Counts и SHA256
+type record struct {
+	Type     string       `json:"type"`
+	Artist   *song.Artist `json:"artist,omitempty"`
+	Album    *song.Album  `json:"album,omitempty"`
+	Song     *song.Song   `json:"song,omitempty"`
+	Playlist *Playlist    `json:"playlist,omitempty"`
+
+	Counts map[string]int `json:"counts,omitempty"`
+	SHA256 string         `json:"sha256,omitempty"`
+}
+
+// Summary - содержимое копии: заголовок и число записей каждого типа
+type Summary struct {
+	Header
+	Counts map[string]int `json:"counts"`
+}
+
+// archiveWriter пишет строки копии и считает их хеш
+type archiveWriter struct {
+	gz     *gzip.Writer
+	w      *bufio.Writer
+	hash   hash.Hash
+	counts map[string]int
+}
+
+func (a *archiveWriter) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	a.hash.Write(data)
+	_, err = a.w.Write(data)
+	return err
+}
+
+func (a *archiveWriter) add(rec record) error {
+	a.counts[rec.Type]++
+	return a.write(rec)
+}
+
+// Write пишет копию каталога repo в w. Хранилище читается по частям, поэтому
+// изменения каталога во время копирования могут попасть в нее частично.
+func Write(ctx context.Context, repo storage.Repository, w io.Writer) (Summary, error) {
+	gz := gzip.NewWriter(w)
+	a := &archiveWriter{gz: gz, w: bufio.NewWriter(gz), hash: sha256.New(), counts: make(map[string]int)}
+
+	header := Header{Kind: Kind, Version: Version, CreatedAt: time.Now().UTC()}
+	if err := a.write(header); err != nil {
+		return Summary{}, err
+	}
+
+	artists, err := writeArtists(ctx, repo, a)
+	if err != nil {
+		return Summary{}, err
+	}
+	for _, artistID := range artists {
+		if err = writeAlbums(ctx, repo, a, artistID); err != nil {
+			return Summary{}, err
+		}
+	}
+
+	err = repo.ExportSongs(ctx, storage.ListParams{}, func(s song.Song) error {
+		return a.add(record{Type: TypeSong, Song: &s})
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+
+	if err = writePlaylists(ctx, repo, a); err != nil {
+		return Summary{}, err
+	}
+
+	// итог не входит в хеш
+	end, err := json.Marshal(record{Type: typeEnd, Counts: a.counts, SHA256: hex.EncodeToString(a.hash.Sum(nil))})
+	if err != nil {
+		return Summary{}, err
+	}
+	if _, err = a.w.Write(append(end, '\n')); err != nil {
+		return Summary{}, err
+	}
+	if err = a.w.Flush(); err != nil {
+		return Summary{}, err
+	}
+	if err = gz.Close(); err != nil {
+		return Summary{}, err
+	}
+
+	return Summary{Header: header, Counts: a.counts}, nil
+}
+
+// writeArtists пишет исполнителей и возвращает их id
+func writeArtists(ctx context.Context, repo storage.ArtistRepo, a *archiveWriter) ([]int64, error) {
+	ids := []int64{}
+	for offset := 0; ; offset += pageSize {
+		artists, err := repo.ListArtists(ctx, "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, artist := range artists {
+			if err = a.add(record{Type: TypeArtist, Artist: &artist}); err != nil {
+				return nil, err
+			}
+			ids = append(ids, artist.ArtistID)
+		}
+		if len(artists) < pageSize {
+			return ids, nil
+		}
+	}
+}
+
+func writeAlbums(ctx context.Context, repo storage.ArtistRepo, a *archiveWriter, artistID int64) error {
+	for offset := 0; ; offset += pageSize {
+		albums, err := repo.ListAlbums(ctx, artistID, pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, album := range albums {
+			if err = a.add(record{Type: TypeAlbum, Album: &album}); err != nil {
+				return err
+			}
+		}
+		if len(albums) < pageSize {
+			return nil
+		}
+	}
+}
+
+func writePlaylists(ctx context.Context, repo storage.PlaylistRepo, a *archiveWriter) error {
+	for offset := 0; ; offset += pageSize {
+		playlists, err := repo.ListPlaylists(ctx, "", pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, p := range playlists {
+			songIDs, err := playlistSongs(ctx, repo, p.PlaylistID)
+			if err != nil {
+				return err
+			}
+			if err = a.add(record{Type: TypePlaylist, Playlist: &Playlist{Playlist: p, SongIDs: songIDs}}); err != nil {
+				return err
+			}
+		}
+		if len(playlists) < pageSize {
+			return nil
+		}
+	}
+}
+
+// playlistSongs - id песен плейлиста в порядке записей
+func playlistSongs(ctx context.Context, repo storage.PlaylistRepo, playlistID int64) ([]int64, error) {
+	ids := []int64{}
+	for offset := 0; ; offset += pageSize {
+		entries, err := repo.ListPlaylistEntries(ctx, playlistID, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			ids = append(ids, e.Song.SongID)
+		}
+		if len(entries) < pageSize {
+			return ids, nil
+		}
+	}
+}
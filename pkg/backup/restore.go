@@ -0,0 +1,550 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// стратегии восстановления записей, которые уже есть в хранилище.
+// Исполнитель, альбом, песня и плейлист совпадают с записью копии по имени
+// (альбом - в пределах исполнителя, песня - по группе и названию)
+const (
+	// ConflictSkip оставляет запись хранилища как есть
+	ConflictSkip = "skip"
+	// ConflictOverwrite заменяет поля записи хранилища данными копии
+	ConflictOverwrite = "overwrite"
+	// ConflictFail отменяет восстановление до первого изменения, если совпала хоть одна запись
+	ConflictFail = "fail"
+)
+
+var ErrConflict = errors.New("backup conflicts with existing data")
+
+// Counts - итог восстановления записей одного типа
+type Counts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// Report - итог восстановления по типам записей
+type Report struct {
+	Artists   Counts `json:"artists"`
+	Albums    Counts `json:"albums"`
+	Songs     Counts `json:"songs"`
+	Playlists Counts `json:"playlists"`
+}
+
+// Open открывает копию с начала. Restore читает копию несколько раз,
+// чтобы не держать ее в памяти
+type Open func() (io.ReadCloser, error)
+
+// archiveReader читает строки копии и проверяет их по итогу
+type archiveReader struct {
+	r      *bufio.Reader
+	hash   hash.Hash
+	header Header
+	counts map[string]int
+	line   int
+}
+
+func newArchiveReader(r io.Reader) (*archiveReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadArchive, err)
+	}
+	a := &archiveReader{r: bufio.NewReader(gz), hash: sha256.New(), counts: make(map[string]int)}
+
+	line, err := a.readLine()
+	if err != nil {
+		return nil, err
+	}
+	a.hash.Write(line)
+	if err = json.Unmarshal(line, &a.header); err != nil || a.header.Kind != Kind {
+		return nil, fmt.Errorf("%w: not a backup", ErrBadArchive)
+	}
+	if a.header.Version < 1 || a.header.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, a.header.Version)
+	}
+	return a, nil
+}
+
+func (a *archiveReader) readLine() ([]byte, error) {
+	line, err := a.r.ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: archive is truncated", ErrBadArchive)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadArchive, err)
+	}
+	a.line++
+	return line, nil
+}
+
+// next возвращает следующую запись. После итога, если он сошелся, возвращает io.EOF
+func (a *archiveReader) next() (record, error) {
+	line, err := a.readLine()
+	if err != nil {
+		return record{}, err
+	}
+
+	rec := record{}
+	if err = json.Unmarshal(line, &rec); err != nil {
+		return record{}, fmt.Errorf("%w: line %d: %v", ErrBadArchive, a.line, err)
+	}
+
+	if rec.Type == typeEnd {
+		if rec.SHA256 != hex.EncodeToString(a.hash.Sum(nil)) || !maps.Equal(rec.Counts, a.counts) {
+			return record{}, ErrChecksum
+		}
+		if _, err = a.r.ReadByte(); !errors.Is(err, io.EOF) {
+			return record{}, fmt.Errorf("%w: data after end of archive", ErrBadArchive)
+		}
+		return record{}, io.EOF
+	}
+
+	a.hash.Write(line)
+	a.counts[rec.Type]++
+	ok := false
+	switch rec.Type {
+	case TypeArtist:
+		ok = rec.Artist != nil
+	case TypeAlbum:
+		ok = rec.Album != nil
+	case TypeSong:
+		ok = rec.Song != nil
+	case TypePlaylist:
+		ok = rec.Playlist != nil
+	}
+	if !ok {
+		return record{}, fmt.Errorf("%w: line %d: bad record of type %q", ErrBadArchive, a.line, rec.Type)
+	}
+	return rec, nil
+}
+
+// Verify читает копию целиком и проверяет заголовок, версию и контрольную сумму
+func Verify(r io.Reader) (Summary, error) {
+	return readAll(r, func(record) error { return nil })
+}
+
+func readAll(r io.Reader, fn func(record) error) (Summary, error) {
+	a, err := newArchiveReader(r)
+	if err != nil {
+		return Summary{}, err
+	}
+	for {
+		rec, err := a.next()
+		if errors.Is(err, io.EOF) {
+			return Summary{Header: a.header, Counts: a.counts}, nil
+		}
+		if err != nil {
+			return Summary{}, err
+		}
+		if err = fn(rec); err != nil {
+			return Summary{}, err
+		}
+	}
+}
+
+func readArchive(open Open, fn func(record) error) error {
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = readAll(rc, fn)
+	return err
+}
+
+// Restore восстанавливает копию в repo. Сначала копия проверяется целиком, поэтому
+// поврежденная копия ничего не меняет. Id записей хранилище выдает заново, связи между
+// записями копии сохраняются. Задачи заполнения не переносятся: песни в статусе
+// song.EnrichmentPending получают новые задачи.
+func Restore(ctx context.Context, repo storage.Repository, open Open, strategy string) (Report, error) {
+	switch strategy {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return Report{}, fmt.Errorf("unknown conflict strategy %q", strategy)
+	}
+
+	if err := readArchive(open, func(record) error { return ctx.Err() }); err != nil {
+		return Report{}, err
+	}
+
+	if strategy == ConflictFail {
+		c := &conflicts{repo: repo, artists: make(map[int64]string)}
+		if err := readArchive(open, func(rec record) error { return c.check(ctx, rec) }); err != nil {
+			return Report{}, err
+		}
+		if c.found > 0 {
+			return Report{}, fmt.Errorf("%w: %d records exist", ErrConflict, c.found)
+		}
+	}
+
+	r := &restorer{
+		repo:     repo,
+		strategy: strategy,
+		artists:  make(map[int64]int64),
+		albums:   make(map[int64]int64),
+		songs:    make(map[int64]int64),
+	}
+	err := readArchive(open, func(rec record) error { return r.apply(ctx, rec) })
+	return r.report, err
+}
+
+// conflicts ищет в хранилище записи копии
+type conflicts struct {
+	repo storage.Repository
+	// artists - имена исполнителей копии по id копии
+	artists map[int64]string
+	found   int
+}
+
+func (c *conflicts) check(ctx context.Context, rec record) error {
+	var err error
+	exists := false
+	switch rec.Type {
+	case TypeArtist:
+		c.artists[rec.Artist.ArtistID] = rec.Artist.Name
+		// исполнитель без альбомов и песен ничего не перезаписывает, поэтому конфликтом не считается
+	case TypeAlbum:
+		var artist song.Artist
+		artist, exists, err = findArtist(ctx, c.repo, c.artists[rec.Album.ArtistID])
+		if err == nil && exists {
+			_, exists, err = findAlbum(ctx, c.repo, artist.ArtistID, rec.Album.Title)
+		}
+	case TypeSong:
+		_, exists, err = findSong(ctx, c.repo, rec.Song.Group, rec.Song.Song)
+	case TypePlaylist:
+		_, exists, err = findPlaylist(ctx, c.repo, rec.Playlist.Name)
+	}
+	if exists {
+		c.found++
+	}
+	return err
+}
+
+// restorer применяет записи копии к хранилищу. Карты переводят id копии в id хранилища
+type restorer struct {
+	repo     storage.Repository
+	strategy string
+	report   Report
+
+	artists map[int64]int64
+	albums  map[int64]int64
+	songs   map[int64]int64
+}
+
+func (r *restorer) apply(ctx context.Context, rec record) error {
+	var err error
+	switch rec.Type {
+	case TypeArtist:
+		err = r.artist(ctx, *rec.Artist)
+	case TypeAlbum:
+		err = r.album(ctx, *rec.Album)
+	case TypeSong:
+		err = r.song(ctx, *rec.Song)
+	case TypePlaylist:
+		err = r.playlist(ctx, *rec.Playlist)
+	}
+	return err
+}
+
+func (r *restorer) artist(ctx context.Context, a song.Artist) error {
+	artist, err := r.repo.AddArtist(ctx, a.Name)
+	switch {
+	case err == nil:
+		r.report.Artists.Created++
+	case errors.Is(err, storage.ErrorArtistExist):
+		var found bool
+		artist, found, err = findArtist(ctx, r.repo, a.Name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("artist %q: %w", a.Name, storage.ErrorArtistNotExist)
+		}
+		r.report.Artists.Skipped++
+	default:
+		return fmt.Errorf("artist %q: %w", a.Name, err)
+	}
+	r.artists[a.ArtistID] = artist.ArtistID
+	return nil
+}
+
+func (r *restorer) album(ctx context.Context, al song.Album) error {
+	artistID, ok := r.artists[al.ArtistID]
+	if !ok {
+		return fmt.Errorf("%w: album %q of unknown artist %d", ErrBadArchive, al.Title, al.ArtistID)
+	}
+
+	album, err := r.repo.AddAlbum(ctx, song.Album{ArtistID: artistID, Title: al.Title, ReleaseDate: al.ReleaseDate})
+	switch {
+	case err == nil:
+		r.report.Albums.Created++
+	case errors.Is(err, storage.ErrorAlbumExist):
+		var found bool
+		album, found, err = findAlbum(ctx, r.repo, artistID, al.Title)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("album %q: %w", al.Title, storage.ErrorAlbumNotExist)
+		}
+		if r.strategy == ConflictOverwrite {
+			if _, err = r.repo.UpdateAlbum(ctx, album.AlbumID, song.AlbumForUpdate{ReleaseDate: &al.ReleaseDate}); err != nil {
+				return fmt.Errorf("album %q: %w", al.Title, err)
+			}
+			r.report.Albums.Updated++
+		} else {
+			r.report.Albums.Skipped++
+		}
+	default:
+		return fmt.Errorf("album %q: %w", al.Title, err)
+	}
+	r.albums[al.AlbumID] = album.AlbumID
+	return nil
+}
+
+func (r *restorer) song(ctx context.Context, s song.Song) error {
+	oldID := s.SongID
+	if s.AlbumID != 0 {
+		albumID, ok := r.albums[s.AlbumID]
+		if !ok {
+			return fmt.Errorf("%w: song %d of unknown album %d", ErrBadArchive, oldID, s.AlbumID)
+		}
+		s.AlbumID = albumID
+	}
+	s.SongID, s.ArtistID, s.CreatedAt = 0, 0, time.Time{}
+
+	created, err := r.repo.AddSongToDB(ctx, s)
+	switch {
+	case err == nil:
+		r.report.Songs.Created++
+	case errors.Is(err, storage.ErrorSongExist):
+		var found bool
+		created, found, err = findSong(ctx, r.repo, s.Group, s.Song)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("song %d: %w", oldID, storage.ErrorSongNotExist)
+		}
+		if r.strategy == ConflictOverwrite {
+			if err = r.overwriteSong(ctx, created, s); err != nil {
+				return fmt.Errorf("song %d: %w", oldID, err)
+			}
+			r.report.Songs.Updated++
+		} else {
+			r.report.Songs.Skipped++
+		}
+	default:
+		return fmt.Errorf("song %d: %w", oldID, err)
+	}
+	r.songs[oldID] = created.SongID
+	return nil
+}
+
+// overwriteSong заменяет поля и теги песни existing данными песни копии s
+func (r *restorer) overwriteSong(ctx context.Context, existing, s song.Song) error {
+	status := s.EnrichmentStatus
+	if status == "" {
+		status = song.EnrichmentDone
+	}
+	update := song.SongForUpdate{
+		ReleaseDate:      &s.ReleaseDate,
+		Text:             &s.Text,
+		Link:             &s.Link,
+		AlbumID:          &s.AlbumID,
+		EnrichmentStatus: &status,
+	}
+	id := int(existing.SongID)
+	if _, err := r.repo.UpdateSongByID(ctx, update, id); err != nil {
+		return err
+	}
+
+	add, remove := []string{}, []string{}
+	for _, tag := range s.Tags {
+		if !slices.Contains(existing.Tags, tag) {
+			add = append(add, tag)
+		}
+	}
+	for _, tag := range existing.Tags {
+		if !slices.Contains(s.Tags, tag) {
+			remove = append(remove, tag)
+		}
+	}
+	if len(add) > 0 {
+		if _, err := r.repo.AddSongTags(ctx, id, add); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		if _, err := r.repo.RemoveSongTags(ctx, id, remove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) playlist(ctx context.Context, p Playlist) error {
+	songIDs := make([]int64, 0, len(p.SongIDs))
+	for _, oldID := range p.SongIDs {
+		id, ok := r.songs[oldID]
+		if !ok {
+			return fmt.Errorf("%w: playlist %q has unknown song %d", ErrBadArchive, p.Name, oldID)
+		}
+		songIDs = append(songIDs, id)
+	}
+
+	existing, found, err := findPlaylist(ctx, r.repo, p.Name)
+	if err != nil {
+		return err
+	}
+
+	var id int64
+	switch {
+	case !found:
+		created, err := r.repo.AddPlaylist(ctx, song.Playlist{Name: p.Name, Description: p.Description, AllowDuplicates: p.AllowDuplicates})
+		if err != nil {
+			return fmt.Errorf("playlist %q: %w", p.Name, err)
+		}
+		id = created.PlaylistID
+		r.report.Playlists.Created++
+	case r.strategy == ConflictOverwrite:
+		id = existing.PlaylistID
+		// записи удаляются до смены AllowDuplicates, иначе нельзя запретить повторы
+		if err = clearPlaylist(ctx, r.repo, id); err != nil {
+			return fmt.Errorf("playlist %q: %w", p.Name, err)
+		}
+		update := song.PlaylistForUpdate{Description: &p.Description, AllowDuplicates: &p.AllowDuplicates}
+		if _, err = r.repo.UpdatePlaylist(ctx, id, update); err != nil {
+			return fmt.Errorf("playlist %q: %w", p.Name, err)
+		}
+		r.report.Playlists.Updated++
+	default:
+		r.report.Playlists.Skipped++
+		return nil
+	}
+
+	for _, songID := range songIDs {
+		if _, err = r.repo.AddPlaylistEntry(ctx, id, songID, 0); err != nil {
+			return fmt.Errorf("playlist %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func clearPlaylist(ctx context.Context, repo storage.PlaylistRepo, id int64) error {
+	for {
+		entries, err := repo.ListPlaylistEntries(ctx, id, pageSize, 0)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		for _, e := range entries {
+			if err = repo.DeletePlaylistEntry(ctx, id, e.EntryID); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// findArtist ищет исполнителя с таким же именем без учета регистра
+func findArtist(ctx context.Context, repo storage.ArtistRepo, name string) (song.Artist, bool, error) {
+	key := storage.NameKey(name)
+	for offset := 0; ; offset += pageSize {
+		artists, err := repo.ListArtists(ctx, storage.EscapeLike(storage.NormalizeName(name)), pageSize, offset)
+		if err != nil {
+			return song.Artist{}, false, err
+		}
+		for _, a := range artists {
+			if storage.NameKey(a.Name) == key {
+				return a, true, nil
+			}
+		}
+		if len(artists) < pageSize {
+			return song.Artist{}, false, nil
+		}
+	}
+}
+
+// findAlbum ищет альбом исполнителя с таким же названием без учета регистра
+func findAlbum(ctx context.Context, repo storage.ArtistRepo, artistID int64, title string) (song.Album, bool, error) {
+	key := storage.NameKey(title)
+	for offset := 0; ; offset += pageSize {
+		albums, err := repo.ListAlbums(ctx, artistID, pageSize, offset)
+		if err != nil {
+			return song.Album{}, false, err
+		}
+		for _, al := range albums {
+			if storage.NameKey(al.Title) == key {
+				return al, true, nil
+			}
+		}
+		if len(albums) < pageSize {
+			return song.Album{}, false, nil
+		}
+	}
+}
+
+// findSong ищет песню группы group с названием name
+func findSong(ctx context.Context, repo storage.SongRepo, group, name string) (song.Song, bool, error) {
+	params := storage.ListParams{
+		Filter: storage.SongFilter{
+			Name:   storage.EscapeLike(name),
+			Groups: []string{storage.EscapeLike(storage.NormalizeName(group))},
+			Match:  storage.MatchExact,
+		},
+		Limit: pageSize,
+	}
+	key := storage.NameKey(group)
+	for {
+		page, err := repo.ListSongs(ctx, params)
+		if err != nil {
+			return song.Song{}, false, err
+		}
+		for _, s := range page.Items {
+			if s.Song == name && storage.NameKey(s.Group) == key {
+				return s, true, nil
+			}
+		}
+		if page.Next == nil {
+			return song.Song{}, false, nil
+		}
+		params.After = page.Next
+	}
+}
+
+// findPlaylist ищет плейлист с точно таким же названием
+func findPlaylist(ctx context.Context, repo storage.PlaylistRepo, name string) (song.Playlist, bool, error) {
+	for offset := 0; ; offset += pageSize {
+		playlists, err := repo.ListPlaylists(ctx, storage.EscapeLike(name), pageSize, offset)
+		if err != nil {
+			return song.Playlist{}, false, err
+		}
+		for _, p := range playlists {
+			if p.Name == name {
+				return p, true, nil
+			}
+		}
+		if len(playlists) < pageSize {
+			return song.Playlist{}, false, nil
+		}
+	}
+}
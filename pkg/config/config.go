@@ -0,0 +1,218 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"strconv"
+	"time"
+
+	"SongLibrary/pkg/backoff"
+	"SongLibrary/pkg/storage"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+)
+
+type Config struct {
+	LogLevel   string
+	Server     Server
+	Storage    Storage
+	Postgres   Postgres
+	SQLite     SQLite
+	External   External
+	Enrichment Enrichment
+}
+
+type Server struct {
+	Host            string
+	Port            int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+	// MaxPageSize - наибольший limit в постраничных списках
+	MaxPageSize int
+}
+
+func (s Server) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+type Storage struct {
+	Driver   string
+	Timeouts storage.Timeouts
+}
+
+type Postgres struct {
+	User           string
+	Password       string
+	Host           string
+	Port           int
+	DB             string
+	MigrateOnStart bool
+
+	// параметры пула, нулевые значения означают значения по умолчанию pgxpool
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// Retry - задержки между попытками подключения, ConnectMaxWait - общее время ожидания базы
+	Retry          backoff.Backoff
+	ConnectMaxWait time.Duration
+}
+
+func (p Postgres) ConnString() string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s",
+		p.User,
+		p.Password,
+		net.JoinHostPort(p.Host, strconv.Itoa(p.Port)),
+		p.DB,
+	)
+}
+
+type SQLite struct {
+	Path string
+}
+
+// External - внешний сервис с информацией о песнях
+type External struct {
+	Host    string
+	Port    int
+	Timeout time.Duration
+
+	// MaxRetries - число повторов запроса при ошибках сети и ответах 5xx и 429
+	MaxRetries int
+	Retry      backoff.Backoff
+
+	// BreakerThreshold запросов подряд с ошибкой сети, 5xx или 429 размыкают circuit breaker на BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+func (e External) Addr() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+const (
+	// EnrichmentSync - POST /api/songs ждет ответа внешнего сервиса
+	EnrichmentSync = "sync"
+	// EnrichmentAsync - песня сохраняется сразу, данные заполняет фоновый воркер
+	EnrichmentAsync = "async"
+)
+
+// Enrichment - режим заполнения песен данными внешнего сервиса и настройки фоновых воркеров
+type Enrichment struct {
+	Mode         string
+	Workers      int
+	PollInterval time.Duration
+	// Lease - время, на которое воркер забирает задачу; если он не успел, задачу заберет другой
+	Lease       time.Duration
+	MaxAttempts int
+	Retry       backoff.Backoff
+}
+
+func Default() Config {
+	return Config{
+		LogLevel: "info",
+		Server: Server{
+			Host:            "localhost",
+			Port:            8080,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+			MaxPageSize:     100,
+		},
+		Storage: Storage{
+			Driver:   DriverPostgres,
+			Timeouts: storage.DefaultTimeouts(),
+		},
+		Postgres: Postgres{
+			Port:           5432,
+			MigrateOnStart: true,
+			Retry:          backoff.Default(),
+			ConnectMaxWait: time.Minute,
+		},
+		SQLite: SQLite{
+			Path: "songs.db",
+		},
+		External: External{
+			Timeout:    5 * time.Second,
+			MaxRetries: 2,
+			Retry: backoff.Backoff{
+				Initial:    200 * time.Millisecond,
+				Max:        2 * time.Second,
+				Multiplier: 2,
+				Jitter:     0.2,
+			},
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		Enrichment: Enrichment{
+			Mode:         EnrichmentSync,
+			Workers:      2,
+			PollInterval: time.Second,
+			Lease:        time.Minute,
+			MaxAttempts:  5,
+			Retry: backoff.Backoff{
+				Initial:    5 * time.Second,
+				Max:        5 * time.Minute,
+				Multiplier: 2,
+				Jitter:     0.2,
+			},
+		},
+	}
+}
+
+// Load собирает конфигурацию: значения по умолчанию, затем переменные окружения
+// (в том числе из необязательного .env файла), затем флаги командной строки.
+// Возвращает аргументы, оставшиеся после флагов (подкоманду и ее аргументы).
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	vars := cfg.variables()
+
+	flags := flag.NewFlagSet("songlibrary", flag.ContinueOnError)
+	envFile := flags.String("env-file", ".env", "path to .env file, it is optional")
+	flagValues := make(map[string]*string, len(vars))
+	for _, v := range vars {
+		flagValues[v.flag()] = flags.String(v.flag(), "", fmt.Sprintf("%s (env %s)", v.usage, v.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := loadEnvFile(*envFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("load env file %s: %w", *envFile, err)
+	}
+
+	setFlags := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	var errs []error
+	for _, v := range vars {
+		value, source := lookupEnv(v.env), v.env
+		if setFlags[v.flag()] {
+			value, source = *flagValues[v.flag()], "-"+v.flag()
+		}
+		if value == "" {
+			continue
+		}
+		if err := v.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+
+	if err := errors.Join(append(errs, cfg.Validate())...); err != nil {
+		return nil, nil, err
+	}
+
+	return &cfg, flags.Args(), nil
+}
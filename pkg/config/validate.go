@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки сразу.
+// Внешний сервис нужен не всем командам, его проверяет External.Validate
+func (cfg *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(cfg.LogLevel)) == nil, "LOG_LEVEL: unknown level %q", cfg.LogLevel)
+
+	check(cfg.Server.Host != "", "SERVER_HOST: must not be empty")
+	check(validPort(cfg.Server.Port), "SERVER_PORT: bad port %d", cfg.Server.Port)
+	check(cfg.Server.ReadTimeout >= 0, "SERVER_READ_TIMEOUT: must not be negative")
+	check(cfg.Server.WriteTimeout >= 0, "SERVER_WRITE_TIMEOUT: must not be negative")
+	check(cfg.Server.ShutdownTimeout > 0, "SERVER_SHUTDOWN_TIMEOUT: must be positive")
+	check(cfg.Server.MaxPageSize > 0, "SERVER_MAX_PAGE_SIZE: must be positive")
+
+	switch cfg.Storage.Driver {
+	case DriverPostgres:
+		errs = append(errs, cfg.Postgres.validate()...)
+	case DriverSQLite:
+		check(cfg.SQLite.Path != "", "SQLITE_PATH: must not be empty")
+	case DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_DRIVER: unknown driver %q", cfg.Storage.Driver))
+	}
+
+	switch cfg.Enrichment.Mode {
+	case EnrichmentSync, EnrichmentAsync:
+	default:
+		errs = append(errs, fmt.Errorf("ENRICHMENT_MODE: unknown mode %q", cfg.Enrichment.Mode))
+	}
+	check(cfg.Enrichment.Workers >= 0, "ENRICHMENT_WORKERS: must not be negative")
+	check(cfg.Enrichment.Mode != EnrichmentAsync || cfg.Enrichment.Workers > 0, "ENRICHMENT_WORKERS: must be positive in async mode")
+	check(cfg.Enrichment.PollInterval > 0, "ENRICHMENT_POLL_INTERVAL: must be positive")
+	check(cfg.Enrichment.Lease > 0, "ENRICHMENT_LEASE: must be positive")
+	check(cfg.Enrichment.MaxAttempts > 0, "ENRICHMENT_MAX_ATTEMPTS: must be positive")
+	check(cfg.Enrichment.Retry.Initial > 0, "ENRICHMENT_INITIAL_BACKOFF: must be positive")
+	check(cfg.Enrichment.Retry.Max >= cfg.Enrichment.Retry.Initial, "ENRICHMENT_MAX_BACKOFF: must not be less than ENRICHMENT_INITIAL_BACKOFF")
+
+	return errors.Join(errs...)
+}
+
+// Validate проверяет настройки внешнего сервиса, их проверяют команды, которые к нему обращаются
+func (e External) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(e.Host != "", "EXTERNAL_SERVICE_HOST: must not be empty")
+	check(validPort(e.Port), "EXTERNAL_SERVICE_PORT: bad port %d", e.Port)
+	check(e.Timeout > 0, "EXTERNAL_SERVICE_TIMEOUT: must be positive")
+	check(e.MaxRetries >= 0, "EXTERNAL_SERVICE_MAX_RETRIES: must not be negative")
+	check(e.Retry.Initial > 0, "EXTERNAL_SERVICE_INITIAL_BACKOFF: must be positive")
+	check(e.Retry.Max >= e.Retry.Initial, "EXTERNAL_SERVICE_MAX_BACKOFF: must not be less than EXTERNAL_SERVICE_INITIAL_BACKOFF")
+	check(e.BreakerThreshold >= 0, "EXTERNAL_SERVICE_BREAKER_THRESHOLD: must not be negative")
+	check(e.BreakerCooldown > 0, "EXTERNAL_SERVICE_BREAKER_COOLDOWN: must be positive")
+
+	return errors.Join(errs...)
+}
+
+func (p Postgres) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(p.User != "", "POSTGRES_USER: must not be empty")
+	check(p.Host != "", "POSTGRES_HOST: must not be empty")
+	check(validPort(p.Port), "POSTGRES_PORT: bad port %d", p.Port)
+	check(p.DB != "", "POSTGRES_DB: must not be empty")
+	check(p.MaxConns >= 0, "POSTGRES_MAX_CONNS: must not be negative")
+	check(p.MinConns >= 0, "POSTGRES_MIN_CONNS: must not be negative")
+	check(p.MaxConns == 0 || p.MinConns <= p.MaxConns, "POSTGRES_MIN_CONNS: must not be greater than POSTGRES_MAX_CONNS")
+	check(p.Retry.Initial > 0, "POSTGRES_CONNECT_INITIAL_BACKOFF: must be positive")
+	check(p.Retry.Max >= p.Retry.Initial, "POSTGRES_CONNECT_MAX_BACKOFF: must not be less than POSTGRES_CONNECT_INITIAL_BACKOFF")
+	check(p.Retry.Jitter >= 0 && p.Retry.Jitter <= 1, "POSTGRES_CONNECT_JITTER: must be from 0 to 1")
+	check(p.ConnectMaxWait > 0, "POSTGRES_CONNECT_MAX_WAIT: must be positive")
+
+	return errs
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// variable - параметр конфигурации, который задается переменной окружения env
+// или флагом с тем же именем в нижнем регистре через дефис (POSTGRES_HOST -> -postgres-host)
+type variable struct {
+	env   string
+	usage string
+	ptr   any
+}
+
+func (v variable) flag() string {
+	return strings.ReplaceAll(strings.ToLower(v.env), "_", "-")
+}
+
+func (v variable) set(value string) error {
+	switch ptr := v.ptr.(type) {
+	case *string:
+		*ptr = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("bad integer %q", value)
+		}
+		*ptr = n
+	case *int32:
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("bad integer %q", value)
+		}
+		*ptr = int32(n)
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("bad number %q", value)
+		}
+		*ptr = f
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("bad boolean %q", value)
+		}
+		*ptr = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("bad duration %q", value)
+		}
+		*ptr = d
+	default:
+		panic(fmt.Sprintf("config: unsupported type %T of %s", v.ptr, v.env))
+	}
+
+	return nil
+}
+
+func (cfg *Config) variables() []variable {
+	return []variable{
+		{"LOG_LEVEL", "log level: debug, info, warn or error", &cfg.LogLevel},
+
+		{"SERVER_HOST", "http server host", &cfg.Server.Host},
+		{"SERVER_PORT", "http server port", &cfg.Server.Port},
+		{"SERVER_READ_TIMEOUT", "http server read timeout", &cfg.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", "http server write timeout", &cfg.Server.WriteTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", "time to finish requests on shutdown", &cfg.Server.ShutdownTimeout},
+		{"SERVER_MAX_PAGE_SIZE", "max limit of paginated lists", &cfg.Server.MaxPageSize},
+
+		{"STORAGE_DRIVER", "storage: postgres, sqlite or memory", &cfg.Storage.Driver},
+		{"DB_TIMEOUT_ADD", "timeout of adding a song", &cfg.Storage.Timeouts.Add},
+		{"DB_TIMEOUT_LIST", "timeout of listing songs", &cfg.Storage.Timeouts.List},
+		{"DB_TIMEOUT_GET", "timeout of getting a song", &cfg.Storage.Timeouts.Get},
+		{"DB_TIMEOUT_UPDATE", "timeout of updating a song", &cfg.Storage.Timeouts.Update},
+		{"DB_TIMEOUT_DELETE", "timeout of deleting a song", &cfg.Storage.Timeouts.Delete},
+
+		{"POSTGRES_USER", "postgres user", &cfg.Postgres.User},
+		{"POSTGRES_PASSWORD", "postgres password", &cfg.Postgres.Password},
+		{"POSTGRES_HOST", "postgres host", &cfg.Postgres.Host},
+		{"POSTGRES_PORT", "postgres port", &cfg.Postgres.Port},
+		{"POSTGRES_DB", "postgres database", &cfg.Postgres.DB},
+		{"MIGRATE_ON_START", "apply postgres migrations on start", &cfg.Postgres.MigrateOnStart},
+		{"POSTGRES_MAX_CONNS", "max connections in pool", &cfg.Postgres.MaxConns},
+		{"POSTGRES_MIN_CONNS", "min connections in pool", &cfg.Postgres.MinConns},
+		{"POSTGRES_MAX_CONN_LIFETIME", "max lifetime of connection", &cfg.Postgres.MaxConnLifetime},
+		{"POSTGRES_MAX_CONN_IDLE_TIME", "max idle time of connection", &cfg.Postgres.MaxConnIdleTime},
+		{"POSTGRES_HEALTH_CHECK_PERIOD", "period of pool health check", &cfg.Postgres.HealthCheckPeriod},
+		{"POSTGRES_CONNECT_INITIAL_BACKOFF", "first delay between connection attempts", &cfg.Postgres.Retry.Initial},
+		{"POSTGRES_CONNECT_MAX_BACKOFF", "max delay between connection attempts", &cfg.Postgres.Retry.Max},
+		{"POSTGRES_CONNECT_JITTER", "random part of delay between connection attempts, from 0 to 1", &cfg.Postgres.Retry.Jitter},
+		{"POSTGRES_CONNECT_MAX_WAIT", "max time to wait for postgres on start", &cfg.Postgres.ConnectMaxWait},
+
+		{"SQLITE_PATH", "path to sqlite db file", &cfg.SQLite.Path},
+
+		{"EXTERNAL_SERVICE_HOST", "host of external song info service", &cfg.External.Host},
+		{"EXTERNAL_SERVICE_PORT", "port of external song info service", &cfg.External.Port},
+		{"EXTERNAL_SERVICE_TIMEOUT", "timeout of one request to external service", &cfg.External.Timeout},
+		{"EXTERNAL_SERVICE_MAX_RETRIES", "retries of failed request to external service", &cfg.External.MaxRetries},
+		{"EXTERNAL_SERVICE_INITIAL_BACKOFF", "first delay between retries to external service", &cfg.External.Retry.Initial},
+		{"EXTERNAL_SERVICE_MAX_BACKOFF", "max delay between retries to external service", &cfg.External.Retry.Max},
+		{"EXTERNAL_SERVICE_BREAKER_THRESHOLD", "failures in a row that open circuit breaker, 0 disables it", &cfg.External.BreakerThreshold},
+		{"EXTERNAL_SERVICE_BREAKER_COOLDOWN", "time circuit breaker stays open", &cfg.External.BreakerCooldown},
+
+		{"ENRICHMENT_MODE", "sync: POST /api/songs waits for external service, async: song is enriched in background", &cfg.Enrichment.Mode},
+		{"ENRICHMENT_WORKERS", "background enrichment workers, 0 disables them", &cfg.Enrichment.Workers},
+		{"ENRICHMENT_POLL_INTERVAL", "interval of polling enrichment queue", &cfg.Enrichment.PollInterval},
+		{"ENRICHMENT_LEASE", "time a worker holds an enrichment job", &cfg.Enrichment.Lease},
+		{"ENRICHMENT_MAX_ATTEMPTS", "attempts before enrichment job goes to dead letter", &cfg.Enrichment.MaxAttempts},
+		{"ENRICHMENT_INITIAL_BACKOFF", "first delay before retry of enrichment job", &cfg.Enrichment.Retry.Initial},
+		{"ENRICHMENT_MAX_BACKOFF", "max delay before retry of enrichment job", &cfg.Enrichment.Retry.Max},
+	}
+}
+
+func lookupEnv(env string) string {
+	return os.Getenv(env)
+}
+
+// loadEnvFile дополняет окружение значениями из файла, не перезаписывая уже заданные
+func loadEnvFile(path string) error {
+	return godotenv.Load(path)
+}
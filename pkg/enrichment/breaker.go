@@ -0,0 +1,82 @@
+package enrichment
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker размыкается после Threshold неудачных запросов подряд и на время Cooldown
+// сразу отклоняет новые запросы. После Cooldown пропускает один пробный запрос:
+// успех замыкает его, неудача снова размыкает.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow сообщает, можно ли выполнить запрос
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == stateClosed {
+		return true
+	}
+	// в полуоткрытом состоянии пробный запрос уже выполняется,
+	// новый пропускаем, только если тот не завершился за Cooldown
+	if b.now().Sub(b.openedAt) < b.Cooldown {
+		return false
+	}
+	b.state = stateHalfOpen
+	b.openedAt = b.now()
+
+	return true
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = stateClosed
+	b.failures = 0
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == stateHalfOpen || (b.Threshold > 0 && b.failures >= b.Threshold) {
+		b.state = stateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Open сообщает, разомкнут ли breaker сейчас
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state == stateOpen && b.now().Sub(b.openedAt) < b.Cooldown
+}
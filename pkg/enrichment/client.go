@@ -0,0 +1,149 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"SongLibrary/pkg/backoff"
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+)
+
+// maxBodySize - ограничение на размер ответа внешнего сервиса
+const maxBodySize = 1 << 20
+
+// HTTPClient ходит в GET /info внешнего сервиса. Повторяет запрос с задержкой при ошибках сети,
+// таймаутах и ответах 5xx и 429, а при длительной недоступности сервиса сразу отвечает ErrorCircuitOpen.
+// Breaker считает только такие ошибки: остальные ответы, в том числе 404 и 4xx, значат, что сервис доступен.
+type HTTPClient struct {
+	BaseURL    url.URL
+	HTTP       *http.Client
+	MaxRetries int
+	Retry      backoff.Backoff
+	Breaker    *Breaker
+}
+
+func NewHTTPClient(cfg config.External) *HTTPClient {
+	return &HTTPClient{
+		BaseURL: url.URL{
+			Scheme: "http",
+			Host:   cfg.Addr(),
+			Path:   "info",
+		},
+		HTTP: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		MaxRetries: cfg.MaxRetries,
+		Retry:      cfg.Retry,
+		Breaker:    NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}
+}
+
+func (c *HTTPClient) GetSongInfo(ctx context.Context, group, name string) (song.ResponseFromExternalAPI, error) {
+	logger := reqctx.Logger(ctx)
+
+	params := url.Values{}
+	params.Add("song", name)
+	params.Add("group", group)
+
+	reqURL := c.BaseURL
+	reqURL.RawQuery = params.Encode()
+	logger.Debug("Create new url:", "url", reqURL.String())
+
+	var err error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := c.Retry.Duration(attempt - 1)
+			logger.Warn("retry request to external service",
+				"attempt", attempt+1,
+				"delay", delay,
+				"ERROR", err,
+			)
+			if sleepErr := backoff.Sleep(ctx, delay); sleepErr != nil {
+				return song.ResponseFromExternalAPI{}, sleepErr
+			}
+		}
+
+		if !c.Breaker.Allow() {
+			logger.Error("circuit breaker is open")
+			return song.ResponseFromExternalAPI{}, ErrorCircuitOpen
+		}
+
+		var info song.ResponseFromExternalAPI
+		var unavailable bool
+		info, unavailable, err = c.do(ctx, reqURL.String())
+		if !unavailable {
+			c.Breaker.Success()
+			return info, err
+		}
+		if ctx.Err() != nil {
+			return song.ResponseFromExternalAPI{}, err
+		}
+		c.Breaker.Failure()
+	}
+
+	return song.ResponseFromExternalAPI{}, err
+}
+
+// do выполняет один запрос и сообщает, недоступен ли сервис: ошибка сети, 5xx или 429.
+// Такой запрос имеет смысл повторить
+func (c *HTTPClient) do(ctx context.Context, reqURL string) (song.ResponseFromExternalAPI, bool, error) {
+	info := song.ResponseFromExternalAPI{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return info, false, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return info, true, fmt.Errorf("%w: %w", ErrorUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return info, false, ErrorSongNotFound
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return info, true, fmt.Errorf("%w: status %d", ErrorUpstream, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return info, false, fmt.Errorf("%w: status %d", ErrorUpstream, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return info, true, fmt.Errorf("%w: read body: %w", ErrorUpstream, err)
+	}
+
+	if err = json.Unmarshal(body, &info); err != nil {
+		return info, false, fmt.Errorf("%w: %w", ErrorBadResponse, err)
+	}
+	if err = validate(info); err != nil {
+		return info, false, err
+	}
+
+	return info, false, nil
+}
+
+func validate(info song.ResponseFromExternalAPI) error {
+	var missing []string
+	if info.ReleaseDate.IsZero() {
+		missing = append(missing, "releaseDate")
+	}
+	if info.Text == "" {
+		missing = append(missing, "text")
+	}
+	if info.Link == "" {
+		missing = append(missing, "link")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: empty fields %v", ErrorBadResponse, missing)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"SongLibrary/pkg/backoff"
+)
+
+func newTestClient(t *testing.T, status int) *HTTPClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	base, err := url.Parse(server.URL + "/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &HTTPClient{
+		BaseURL:    *base,
+		HTTP:       server.Client(),
+		MaxRetries: 0,
+		Retry:      backoff.Backoff{Initial: time.Millisecond, Max: time.Millisecond},
+		Breaker:    NewBreaker(2, time.Minute),
+	}
+}
+
+func TestBreakerCountsOnlyUnavailability(t *testing.T) {
+	cases := []struct {
+		status int
+		open   bool
+	}{
+		{http.StatusNotFound, false},
+		{http.StatusBadRequest, false},
+		{http.StatusForbidden, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	for _, c := range cases {
+		t.Run(http.StatusText(c.status), func(t *testing.T) {
+			client := newTestClient(t, c.status)
+			for i := 0; i < 3; i++ {
+				if _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising"); err == nil {
+					t.Fatalf("status %d: got no error", c.status)
+				}
+			}
+
+			if client.Breaker.Open() != c.open {
+				t.Errorf("status %d: breaker open = %v, want %v", c.status, client.Breaker.Open(), c.open)
+			}
+			_, err := client.GetSongInfo(context.Background(), "Muse", "Uprising")
+			if errors.Is(err, ErrorCircuitOpen) != c.open {
+				t.Errorf("status %d: got %v after failures", c.status, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+
+	"SongLibrary/pkg/song"
+)
+
+// Client получает дополнительную информацию о песне из внешнего сервиса
+type Client interface {
+	GetSongInfo(ctx context.Context, group, name string) (song.ResponseFromExternalAPI, error)
+}
+
+var (
+	ErrorSongNotFound = fmt.Errorf("song not found in external service")
+	ErrorCircuitOpen  = fmt.Errorf("external service is unavailable, circuit breaker is open")
+	ErrorBadResponse  = fmt.Errorf("bad response from external service")
+	ErrorUpstream     = fmt.Errorf("external service error")
+	// ErrorRefreshChanged - данные внешнего сервиса изменились после того, как пользователь увидел отличия
+	ErrorRefreshChanged = fmt.Errorf("external service data changed since the diff was shown")
+)
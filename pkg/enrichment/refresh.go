@@ -0,0 +1,169 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// размер страницы, которой Refresher.RefreshAll читает песни из хранилища
+const refreshBatchSize = 100
+
+// Refresher заново запрашивает данные сохраненных песен во внешнем сервисе
+// и показывает, чем они отличаются от данных в хранилище
+type Refresher struct {
+	Client Client
+	Songs  storage.SongRepo
+	// Jobs сохраняет изменения и закрывает задачи заполнения обновленной песни
+	Jobs storage.JobRepo
+}
+
+func NewRefresher(client Client, songs storage.SongRepo, jobs storage.JobRepo) *Refresher {
+	return &Refresher{
+		Client: client,
+		Songs:  songs,
+		Jobs:   jobs,
+	}
+}
+
+// Refresh сравнивает песню с ответом внешнего сервиса и, если apply, сразу сохраняет изменения
+func (r *Refresher) Refresh(ctx context.Context, id int, apply bool) (song.RefreshResult, error) {
+	current, err := r.Songs.GetSongByID(ctx, id)
+	if err != nil {
+		return song.RefreshResult{}, err
+	}
+
+	return r.RefreshSong(ctx, current, apply)
+}
+
+// Confirm сохраняет изменения песни, только если внешний сервис вернул те же отличия,
+// что пользователь видел и подтвердил. Иначе возвращает новые отличия и ErrorRefreshChanged
+func (r *Refresher) Confirm(ctx context.Context, id int, confirmed []song.FieldChange) (song.RefreshResult, error) {
+	current, err := r.Songs.GetSongByID(ctx, id)
+	if err != nil {
+		return song.RefreshResult{}, err
+	}
+
+	result, err := r.RefreshSong(ctx, current, false)
+	if err != nil {
+		return result, err
+	}
+	if !slices.Equal(result.Changes, confirmed) {
+		reqctx.Logger(ctx).Warn("refresh diff changed", "id", id)
+		return result, ErrorRefreshChanged
+	}
+
+	err = r.Apply(ctx, &result)
+	return result, err
+}
+
+// RefreshSong работает как Refresh, но для уже прочитанной из хранилища песни
+func (r *Refresher) RefreshSong(ctx context.Context, current song.Song, apply bool) (song.RefreshResult, error) {
+	info, err := r.Client.GetSongInfo(ctx, current.Group, current.Song)
+	if err != nil {
+		return song.RefreshResult{}, err
+	}
+
+	result := song.RefreshResult{
+		SongID:  current.SongID,
+		Song:    current.Song,
+		Group:   current.Group,
+		Changes: Diff(current, info),
+	}
+	if apply {
+		if err = r.Apply(ctx, &result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Apply сохраняет изменения из result, например после подтверждения пользователем.
+// Задачи заполнения песни закрываются в той же транзакции, чтобы воркер не записал поверх свой ответ
+func (r *Refresher) Apply(ctx context.Context, result *song.RefreshResult) error {
+	if len(result.Changes) == 0 {
+		return nil
+	}
+
+	update := song.SongForUpdate{}
+	for _, change := range result.Changes {
+		value := change.New
+		switch change.Field {
+		case "releaseDate":
+			date, err := song.ParseReleaseDate(value)
+			if err != nil {
+				return err
+			}
+			update.ReleaseDate = &date
+		case "text":
+			update.Text = &value
+		case "link":
+			update.Link = &value
+		case "enrichment_status":
+			update.EnrichmentStatus = &value
+		default:
+			return fmt.Errorf("unknown field %q", change.Field)
+		}
+	}
+
+	if _, err := r.Jobs.RefreshSongByID(ctx, update, int(result.SongID)); err != nil {
+		return err
+	}
+	result.Applied = true
+
+	reqctx.Logger(ctx).Info("song refreshed", "id", result.SongID, "changes", len(result.Changes))
+	return nil
+}
+
+// RefreshAll вызывает RefreshSong для всех песен хранилища по порядку song_id.
+// Ошибки по отдельным песням передаются в fn и не прерывают обход;
+// обход прекращается, если fn вернул ошибку.
+func (r *Refresher) RefreshAll(ctx context.Context, apply bool, fn func(song.RefreshResult, error) error) error {
+	for offset := 0; ; offset += refreshBatchSize {
+		songs, err := r.Songs.GetSongsFromDB(ctx, song.Song{}, refreshBatchSize, offset)
+		if errors.Is(err, storage.ErrorListOfSongsEmpty) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, s := range songs {
+			result, err := r.RefreshSong(ctx, s, apply)
+			if err != nil {
+				result.SongID, result.Song, result.Group = s.SongID, s.Song, s.Group
+			}
+			if err = fn(result, err); err != nil {
+				return err
+			}
+		}
+
+		if len(songs) < refreshBatchSize {
+			return nil
+		}
+	}
+}
+
+// Diff возвращает поля песни, которые отличаются от ответа внешнего сервиса.
+// Песня, заполнение которой не завершилось, получает статус song.EnrichmentDone.
+func Diff(current song.Song, info song.ResponseFromExternalAPI) []song.FieldChange {
+	changes := []song.FieldChange{}
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, song.FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	add("releaseDate", current.ReleaseDate.String(), info.ReleaseDate.String())
+	add("text", current.Text, info.Text)
+	add("link", current.Link, info.Link)
+	add("enrichment_status", current.EnrichmentStatus, song.EnrichmentDone)
+
+	return changes
+}
@@ -0,0 +1,180 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"SongLibrary/pkg/backoff"
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// Worker - пул воркеров, который разбирает очередь задач на заполнение песен из JobRepo.
+// Состояние задач хранится в базе, поэтому после перезапуска работа продолжается с того же места.
+type Worker struct {
+	Client       Client
+	Jobs         storage.JobRepo
+	Logger       *slog.Logger
+	Workers      int
+	PollInterval time.Duration
+	Lease        time.Duration
+	MaxAttempts  int
+	Retry        backoff.Backoff
+
+	wake chan struct{}
+}
+
+func NewWorker(client Client, jobs storage.JobRepo, cfg config.Enrichment, logger *slog.Logger) *Worker {
+	return &Worker{
+		Client:       client,
+		Jobs:         jobs,
+		Logger:       logger,
+		Workers:      cfg.Workers,
+		PollInterval: cfg.PollInterval,
+		Lease:        cfg.Lease,
+		MaxAttempts:  cfg.MaxAttempts,
+		Retry:        cfg.Retry,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Notify будит воркеров, не дожидаясь следующего опроса очереди
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run запускает воркеров и блокируется до отмены ctx
+func (w *Worker) Run(ctx context.Context) {
+	w.Logger.Info("enrichment workers start", "workers", w.Workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.Workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			w.loop(ctx, w.Logger.With("worker", id))
+		}(i)
+	}
+	wg.Wait()
+
+	w.Logger.Info("enrichment workers stopped")
+}
+
+func (w *Worker) loop(ctx context.Context, logger *slog.Logger) {
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	for ctx.Err() == nil {
+		jobs, err := w.Jobs.ClaimEnrichmentJobs(ctx, 1, w.Lease)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("error claim enrichment jobs", "ERROR", err)
+		}
+		if len(jobs) == 0 {
+			w.wait(ctx)
+			continue
+		}
+
+		for _, job := range jobs {
+			w.process(ctx, job)
+		}
+	}
+}
+
+// DrainReport - итог Drain: число задач по статусу, в котором они остались
+type DrainReport struct {
+	Done    int `json:"done"`
+	Retried int `json:"retried"`
+	Failed  int `json:"failed"`
+}
+
+// Drain разбирает готовые задачи очереди в одном воркере, пока они не кончатся или не будет
+// обработано limit задач (0 - без ограничения), и возвращает итог. Задачи, отложенные на
+// повтор, в этом запуске больше не берутся. Нужен для запуска из cron без постоянных воркеров
+func (w *Worker) Drain(ctx context.Context, limit int) (DrainReport, error) {
+	ctx = reqctx.WithLogger(ctx, w.Logger)
+
+	report := DrainReport{}
+	for n := 0; limit == 0 || n < limit; n++ {
+		jobs, err := w.Jobs.ClaimEnrichmentJobs(ctx, 1, w.Lease)
+		if err != nil {
+			return report, err
+		}
+		if len(jobs) == 0 {
+			break
+		}
+
+		switch w.process(ctx, jobs[0]) {
+		case song.JobDone:
+			report.Done++
+		case song.JobPending:
+			report.Retried++
+		case song.JobDead:
+			report.Failed++
+		}
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (w *Worker) wait(ctx context.Context) {
+	timer := time.NewTimer(w.PollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-w.wake:
+	case <-timer.C:
+	}
+}
+
+// process выполняет задачу и возвращает ее новый статус, пустой - если результат не сохранен
+func (w *Worker) process(ctx context.Context, job song.EnrichmentJob) string {
+	logger := reqctx.Logger(ctx).With(
+		"job_id", job.JobID,
+		"song_id", job.SongID,
+		"attempt", job.Attempts,
+	)
+	ctx = reqctx.WithLogger(ctx, logger)
+
+	info, err := w.Client.GetSongInfo(ctx, job.Group, job.Song)
+	if ctx.Err() != nil {
+		// задачу заберет другой воркер, когда истечет lease
+		logger.Info("enrichment job interrupted")
+		return ""
+	}
+
+	var status string
+	switch {
+	case err == nil:
+		status = song.JobDone
+		err = w.Jobs.CompleteEnrichmentJob(ctx, job.JobID, job.Lease, info)
+	case errors.Is(err, ErrorSongNotFound) || errors.Is(err, ErrorBadResponse) || job.Attempts >= w.MaxAttempts:
+		logger.Error("enrichment job failed", "ERROR", err)
+		status = song.JobDead
+		err = w.Jobs.FailEnrichmentJob(ctx, job.JobID, job.Lease, err.Error())
+	default:
+		runAt := time.Now().Add(w.Retry.Duration(job.Attempts - 1))
+		logger.Warn("enrichment job will be retried", "run_at", runAt, "ERROR", err)
+		status = song.JobPending
+		err = w.Jobs.RetryEnrichmentJob(ctx, job.JobID, job.Lease, err.Error(), runAt)
+	}
+	if errors.Is(err, storage.ErrorLeaseLost) {
+		// lease истек и задачу забрал другой воркер, результат сохранит он
+		logger.Warn("enrichment job result dropped", "ERROR", err)
+		return ""
+	}
+	if err != nil {
+		logger.Error("error save enrichment job result", "ERROR", err)
+		return ""
+	}
+	return status
+}
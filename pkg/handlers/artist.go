@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+type ArtistHandler struct {
+	Logger     *slog.Logger
+	ArtistRepo storage.ArtistRepo
+	SongRepo   storage.SongRepo
+	// MaxPageSize - наибольший limit в списках, 0 - DefaultMaxPageSize
+	MaxPageSize int
+}
+
+// requestLogger - логгер запроса и контекст с ним
+func (h *ArtistHandler) requestLogger(r *http.Request) (*slog.Logger, *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	return logger, r.WithContext(reqctx.WithLogger(r.Context(), logger))
+}
+
+// @Summary Get a list of artists
+// @Description Возвращает исполнителей по имени без учета регистра
+// @Tags artists
+// @Produce json
+// @Param name query string false "Substring of artist name"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} song.ArtistList "Page of artists"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/artists [get]
+func (h *ArtistHandler) GetListOfArtists(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	query := r.URL.Query()
+	page, limit, err := pagination(query, 10, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse pagination",
+			"ERROR", err,
+		)
+		return
+	}
+
+	artists, err := h.ArtistRepo.ListArtists(r.Context(), query.Get("name"), limit, (page-1)*limit)
+	if err != nil {
+		logger.Error("Error get artists from db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, song.ArtistList{Items: artists})
+	logger.Info("Get list of artists success", "count", len(artists))
+}
+
+// @Summary Add new artist
+// @Description Добавляет исполнителя. Имя уникально без учета регистра и пробелов по краям.
+// @Tags artists
+// @Accept json
+// @Produce json
+// @Param artist body song.PayloadArtist true "Artist"
+// @Success 201 {object} song.Artist "Created artist"
+// @Header 201 {string} Location "/api/artist/{ARTIST_ID}"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 409 {object} problem.Problem "Artist exist"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/artists [post]
+func (h *ArtistHandler) AddArtist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	payload := song.PayloadArtist{}
+	if err := decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+
+	artist, err := h.ArtistRepo.AddArtist(r.Context(), payload.Name)
+	if err != nil {
+		logger.Error("Error add artist to db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/artist/%d", artist.ArtistID))
+	writeJSON(w, r, http.StatusCreated, artist)
+	logger.Info("add artist success", "id", artist.ArtistID)
+}
+
+// @Summary Get an artist by ID
+// @Tags artists
+// @Produce json
+// @Param ARTIST_ID path int true "Artist ID"
+// @Success 200 {object} song.Artist "Artist"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Artist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/artist/{ARTIST_ID} [get]
+func (h *ArtistHandler) GetArtistByID(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ARTIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	artist, err := h.ArtistRepo.GetArtistByID(r.Context(), int64(id))
+	if err != nil {
+		logger.Error("Error get artist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, artist)
+	logger.Info("get artist by id success", "id", id)
+}
+
+// @Summary Rename an artist
+// @Description Меняет имя исполнителя, группа всех его песен меняется вместе с ним
+// @Tags artists
+// @Accept json
+// @Produce json
+// @Param ARTIST_ID path int true "Artist ID"
+// @Param artist body song.PayloadArtist true "New name"
+// @Success 200 {object} song.Artist "Updated artist"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Artist not found"
+// @Failure 409 {object} problem.Problem "Artist with this name exist"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/artist/{ARTIST_ID} [put]
+func (h *ArtistHandler) UpdateArtist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ARTIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	payload := song.PayloadArtist{}
+	if err = decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+
+	artist, err := h.ArtistRepo.RenameArtist(r.Context(), int64(id), payload.Name)
+	if err != nil {
+		logger.Error("Error update artist in db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, artist)
+	logger.Info("update artist success", "id", id)
+}
+
+// @Summary Delete an artist
+// @Description Удаляет исполнителя вместе с его альбомами. Исполнителя, у которого есть песни, удалить нельзя.
+// @Tags artists
+// @Param ARTIST_ID path int true "Artist ID"
+// @Success 204 "Artist deleted"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Artist not found"
+// @Failure 409 {object} problem.Problem "Artist has songs"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/artist/{ARTIST_ID} [delete]
+func (h *ArtistHandler) DeleteArtist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ARTIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	if err = h.ArtistRepo.DeleteArtist(r.Context(), int64(id)); err != nil {
+		logger.Error("Error delete artist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info("delete artist success", "id", id)
+}
+
+// @Summary Get songs of an artist
+// @Description Возвращает страницу песен исполнителя. Фильтры, сортировка и курсор - как у GET /api/songs.
+// @Tags artists
+// @Produce json
+// @Param ARTIST_ID path int true "Artist ID"
+// @Param sort query string false "Sort fields, e.g. -release_date"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Number of items per page" default(10)
+// @Param include_total query bool false "Count songs of the artist" default(false)
+// @Success 200 {object} song.SongList "Page of songs"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Artist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/artist/{ARTIST_ID}/songs [get]
+func (h *ArtistHandler) GetSongsOfArtist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ARTIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	params, err := listParams(r.URL.Query(), 10, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse query params",
+			"ERROR", err,
+		)
+		return
+	}
+	params.Filter.ArtistID = int64(id)
+
+	if _, err = h.ArtistRepo.GetArtistByID(r.Context(), int64(id)); err != nil {
+		logger.Error("Error get artist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := h.SongRepo.ListSongs(r.Context(), params)
+	if err != nil {
+		logger.Error("Error get songs from db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newSongList(page))
+	logger.Info("Get songs of artist success", "id", id, "count", len(page.Items))
+}
+
+// @Summary Get albums of an artist
+// @Description Возвращает альбомы исполнителя по дате выпуска, альбомы без даты идут первыми
+// @Tags artists
+// @Produce json
+// @Param ARTIST_ID path int true "Artist ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} song.AlbumList "Page of albums"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Artist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/artist/{ARTIST_ID}/albums [get]
+func (h *ArtistHandler) GetAlbumsOfArtist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ARTIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	page, limit, err := pagination(r.URL.Query(), 10, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse pagination",
+			"ERROR", err,
+		)
+		return
+	}
+
+	if _, err = h.ArtistRepo.GetArtistByID(r.Context(), int64(id)); err != nil {
+		logger.Error("Error get artist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	albums, err := h.ArtistRepo.ListAlbums(r.Context(), int64(id), limit, (page-1)*limit)
+	if err != nil {
+		logger.Error("Error get albums from db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, song.AlbumList{Items: albums})
+	logger.Info("Get albums of artist success", "id", id, "count", len(albums))
+}
+
+// @Summary Add new album
+// @Description Добавляет альбом исполнителю. Название уникально у исполнителя без учета регистра.
+// @Tags albums
+// @Accept json
+// @Produce json
+// @Param album body song.PayloadAlbum true "Album"
+// @Success 201 {object} song.Album "Created album"
+// @Header 201 {string} Location "/api/album/{ALBUM_ID}"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Artist not found"
+// @Failure 409 {object} problem.Problem "Album exist"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/albums [post]
+func (h *ArtistHandler) AddAlbum(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	payload := song.PayloadAlbum{}
+	if err := decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+	if payload.ArtistID <= 0 {
+		verr := &ValidationError{}
+		verr.Add("artist_id", "must be a positive integer")
+		writeError(w, r, verr)
+		logger.Error("Error field of struct",
+			"ERROR", verr,
+			"fields", verr.Fields,
+		)
+		return
+	}
+
+	album, err := h.ArtistRepo.AddAlbum(r.Context(), song.Album{
+		ArtistID:    payload.ArtistID,
+		Title:       payload.Title,
+		ReleaseDate: payload.ReleaseDate,
+	})
+	if err != nil {
+		logger.Error("Error add album to db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/album/%d", album.AlbumID))
+	writeJSON(w, r, http.StatusCreated, album)
+	logger.Info("add album success", "id", album.AlbumID)
+}
+
+// @Summary Get an album by ID
+// @Tags albums
+// @Produce json
+// @Param ALBUM_ID path int true "Album ID"
+// @Success 200 {object} song.Album "Album"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Album not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/album/{ALBUM_ID} [get]
+func (h *ArtistHandler) GetAlbumByID(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ALBUM_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	album, err := h.ArtistRepo.GetAlbumByID(r.Context(), int64(id))
+	if err != nil {
+		logger.Error("Error get album from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, album)
+	logger.Info("get album by id success", "id", id)
+}
+
+// @Summary Update an album
+// @Description Меняет название и дату выпуска альбома
+// @Tags albums
+// @Accept json
+// @Produce json
+// @Param ALBUM_ID path int true "Album ID"
+// @Param album body song.AlbumForUpdate true "Data for update"
+// @Success 200 {object} song.Album "Updated album"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Album not found"
+// @Failure 409 {object} problem.Problem "Album with this title exist"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/album/{ALBUM_ID} [put]
+func (h *ArtistHandler) UpdateAlbum(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ALBUM_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	payload := song.AlbumForUpdate{}
+	if err = decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+
+	album, err := h.ArtistRepo.UpdateAlbum(r.Context(), int64(id), payload)
+	if err != nil {
+		logger.Error("Error update album in db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, album)
+	logger.Info("update album success", "id", id)
+}
+
+// @Summary Delete an album
+// @Description Удаляет альбом, его песни остаются у исполнителя без альбома
+// @Tags albums
+// @Param ALBUM_ID path int true "Album ID"
+// @Success 204 "Album deleted"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Album not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/album/{ALBUM_ID} [delete]
+func (h *ArtistHandler) DeleteAlbum(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "ALBUM_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	if err = h.ArtistRepo.DeleteAlbum(r.Context(), int64(id)); err != nil {
+		logger.Error("Error delete album from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info("delete album success", "id", id)
+}
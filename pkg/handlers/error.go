@@ -1,14 +1,167 @@
 package handlers
 
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/playlistfmt"
+	"SongLibrary/pkg/problem"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+)
+
+// APIError - вид ошибки api. Клиенту он отдается как problem details,
+// Code становится последней частью URI типа ошибки
+type APIError struct {
+	Status int
+	Code   string
+	Title  string
+}
+
+func (e *APIError) Error() string {
+	return e.Title
+}
+
 var (
-	ErrContentType      = "bad Content-Type"
-	ErrParseBody        = "cant read body request"
-	ErrUnmarshal        = "cant decode body"
-	ErrFieldEmpty       = "field of struct payload empty"
-	ErrInternal         = "internal error"
-	ErrExternalService  = "error from external service"
-	ErrParseQuery       = "error parse value of query param"
-	ErrSongsNotFound    = "songs not found"
-	ErrSongByIDNotFound = "song by id not found"
-	ErrSongExist        = "song exist"
+	ErrContentType          = &APIError{http.StatusBadRequest, "bad-content-type", "bad Content-Type"}
+	ErrParseBody            = &APIError{http.StatusBadRequest, "bad-body", "cant read body request"}
+	ErrUnmarshal            = &APIError{http.StatusBadRequest, "bad-json", "cant decode body"}
+	ErrValidation           = &APIError{http.StatusBadRequest, "validation-error", "request validation failed"}
+	ErrFieldEmpty           = &APIError{http.StatusBadRequest, "no-fields", "field of struct payload empty"}
+	ErrInternal             = &APIError{http.StatusInternalServerError, "internal", "internal error"}
+	ErrTimeout              = &APIError{http.StatusGatewayTimeout, "timeout", "request timeout"}
+	ErrExternalService      = &APIError{http.StatusBadGateway, "external-service-error", "error from external service"}
+	ErrExternalUnavailable  = &APIError{http.StatusServiceUnavailable, "external-service-unavailable", "external service is unavailable"}
+	ErrSongNotFoundExternal = &APIError{http.StatusNotFound, "external-song-not-found", "song not found in external service"}
+	ErrParseQuery           = &APIError{http.StatusBadRequest, "bad-query", "error parse value of query param"}
+	ErrSongsNotFound        = &APIError{http.StatusNotFound, "songs-not-found", "songs not found"}
+	ErrSongByIDNotFound     = &APIError{http.StatusNotFound, "song-not-found", "song by id not found"}
+	ErrVersesNotFound       = &APIError{http.StatusNotFound, "verses-not-found", "error amount of verses"}
+	ErrSongExist            = &APIError{http.StatusBadRequest, "song-exist", "song exist"}
+	ErrJobNotFound          = &APIError{http.StatusNotFound, "job-not-found", "enrichment job not found"}
+	ErrJobNotDead           = &APIError{http.StatusConflict, "job-not-dead", "enrichment job is not in dead letter"}
+	ErrRefreshChanged       = &APIError{http.StatusConflict, "refresh-changed", "external service data changed since the diff was shown"}
+	ErrRouteNotFound        = &APIError{http.StatusNotFound, "route-not-found", "route not found"}
+	ErrMethodNotAllowed     = &APIError{http.StatusMethodNotAllowed, "method-not-allowed", "method not allowed"}
+	ErrSearchNotSupported   = &APIError{http.StatusNotImplemented, "search-not-supported", "search is not supported by storage"}
+	ErrEmptyName            = &APIError{http.StatusBadRequest, "empty-name", "name or title is empty"}
+	ErrArtistExist          = &APIError{http.StatusConflict, "artist-exist", "artist with this name exist"}
+	ErrArtistNotFound       = &APIError{http.StatusNotFound, "artist-not-found", "artist not found"}
+	ErrArtistHasSongs       = &APIError{http.StatusConflict, "artist-has-songs", "artist has songs"}
+	ErrAlbumExist           = &APIError{http.StatusConflict, "album-exist", "artist has album with this title"}
+	ErrAlbumNotFound        = &APIError{http.StatusNotFound, "album-not-found", "album not found"}
+	ErrAlbumOfOtherArtist   = &APIError{http.StatusBadRequest, "album-of-other-artist", "album belongs to another artist"}
+	ErrInvalidTag           = &APIError{http.StatusBadRequest, "invalid-tag", "tag must be from 1 to 50 characters"}
+	ErrPlaylistNotFound     = &APIError{http.StatusNotFound, "playlist-not-found", "playlist not found"}
+	ErrEntryNotFound        = &APIError{http.StatusNotFound, "playlist-entry-not-found", "playlist entry not found"}
+	ErrSongInPlaylist       = &APIError{http.StatusConflict, "song-in-playlist", "song is already in playlist"}
+	ErrPlaylistHasRepeats   = &APIError{http.StatusConflict, "playlist-has-repeats", "playlist has repeated songs"}
+	ErrBadReorder           = &APIError{http.StatusBadRequest, "bad-reorder", "entry ids must list every entry of the playlist once"}
+	ErrBadPlaylistFile      = &APIError{http.StatusBadRequest, "bad-playlist-file", "cant read playlist file"}
+	ErrBodyTooLarge         = &APIError{http.StatusRequestEntityTooLarge, "body-too-large", "request body is too large"}
+	ErrBadImportFile        = &APIError{http.StatusBadRequest, "bad-import-file", "cant read import file"}
 )
+
+// ValidationError - ошибки валидации отдельных полей запроса
+type ValidationError struct {
+	Fields []problem.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return ErrValidation.Title
+}
+
+// Add добавляет ошибку поля
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, problem.FieldError{Field: field, Message: message})
+}
+
+// Err возвращает nil, если ошибок полей нет
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// errorMapping - соответствие ошибок хранилища и внешнего сервиса видам ошибок api
+var errorMapping = []struct {
+	err    error
+	apiErr *APIError
+}{
+	{storage.ErrorSongExist, ErrSongExist},
+	{storage.ErrorSongNotExist, ErrSongByIDNotFound},
+	{storage.ErrorListOfSongsEmpty, ErrSongsNotFound},
+	{storage.ErrorNoFieldsToUpdate, ErrFieldEmpty},
+	{storage.ErrorNegativePaginator, ErrParseQuery},
+	{storage.ErrorJobNotExist, ErrJobNotFound},
+	{storage.ErrorJobNotDead, ErrJobNotDead},
+	{storage.ErrorEmptySearchQuery, ErrParseQuery},
+	{storage.ErrorBadCursor, ErrParseQuery},
+	{storage.ErrorBadSort, ErrParseQuery},
+	{storage.ErrorEmptyName, ErrEmptyName},
+	{storage.ErrorArtistExist, ErrArtistExist},
+	{storage.ErrorArtistNotExist, ErrArtistNotFound},
+	{storage.ErrorArtistHasSongs, ErrArtistHasSongs},
+	{storage.ErrorAlbumExist, ErrAlbumExist},
+	{storage.ErrorAlbumNotExist, ErrAlbumNotFound},
+	{storage.ErrorAlbumOfOtherArtist, ErrAlbumOfOtherArtist},
+	{storage.ErrorInvalidTag, ErrInvalidTag},
+	{storage.ErrorPlaylistNotExist, ErrPlaylistNotFound},
+	{storage.ErrorEntryNotExist, ErrEntryNotFound},
+	{storage.ErrorSongInPlaylist, ErrSongInPlaylist},
+	{storage.ErrorPlaylistHasRepeats, ErrPlaylistHasRepeats},
+	{storage.ErrorBadReorder, ErrBadReorder},
+	{playlistfmt.ErrBadFile, ErrBadPlaylistFile},
+	{songimport.ErrBadFile, ErrBadImportFile},
+	{enrichment.ErrorSongNotFound, ErrSongNotFoundExternal},
+	{enrichment.ErrorCircuitOpen, ErrExternalUnavailable},
+	{enrichment.ErrorBadResponse, ErrExternalService},
+	{enrichment.ErrorUpstream, ErrExternalService},
+	{enrichment.ErrorRefreshChanged, ErrRefreshChanged},
+	{context.DeadlineExceeded, ErrTimeout},
+}
+
+// toProblem переводит ошибку в problem details. Детали неизвестных ошибок клиенту не отдаются.
+func toProblem(err error) problem.Problem {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		p := newProblem(ErrValidation)
+		p.Errors = validationErr.Fields
+		return p
+	}
+
+	apiErr := ErrInternal
+	var target *APIError
+	if errors.As(err, &target) {
+		apiErr = target
+	} else {
+		for _, m := range errorMapping {
+			if errors.Is(err, m.err) {
+				apiErr = m.apiErr
+				break
+			}
+		}
+	}
+
+	p := newProblem(apiErr)
+	if apiErr != ErrInternal && err.Error() != apiErr.Title {
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+func newProblem(apiErr *APIError) problem.Problem {
+	return problem.Problem{
+		Type:   problem.TypePrefix + apiErr.Code,
+		Title:  apiErr.Title,
+		Status: apiErr.Status,
+	}
+}
+
+// writeError - единственное место, где ошибки превращаются в ответ клиенту
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem.Write(w, r, toProblem(err))
+}
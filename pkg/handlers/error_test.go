@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/playlistfmt"
+	"SongLibrary/pkg/problem"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+)
+
+func TestToProblemSentinels(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{storage.ErrorSongExist, http.StatusBadRequest, "song-exist"},
+		{storage.ErrorSongNotExist, http.StatusNotFound, "song-not-found"},
+		{storage.ErrorListOfSongsEmpty, http.StatusNotFound, "songs-not-found"},
+		{storage.ErrorNoFieldsToUpdate, http.StatusBadRequest, "no-fields"},
+		{storage.ErrorNegativePaginator, http.StatusBadRequest, "bad-query"},
+		{storage.ErrorJobNotExist, http.StatusNotFound, "job-not-found"},
+		{storage.ErrorJobNotDead, http.StatusConflict, "job-not-dead"},
+		{storage.ErrorEmptySearchQuery, http.StatusBadRequest, "bad-query"},
+		{storage.ErrorBadCursor, http.StatusBadRequest, "bad-query"},
+		{storage.ErrorBadSort, http.StatusBadRequest, "bad-query"},
+		{storage.ErrorEmptyName, http.StatusBadRequest, "empty-name"},
+		{storage.ErrorArtistExist, http.StatusConflict, "artist-exist"},
+		{storage.ErrorArtistNotExist, http.StatusNotFound, "artist-not-found"},
+		{storage.ErrorArtistHasSongs, http.StatusConflict, "artist-has-songs"},
+		{storage.ErrorAlbumExist, http.StatusConflict, "album-exist"},
+		{storage.ErrorAlbumNotExist, http.StatusNotFound, "album-not-found"},
+		{storage.ErrorAlbumOfOtherArtist, http.StatusBadRequest, "album-of-other-artist"},
+		{storage.ErrorInvalidTag, http.StatusBadRequest, "invalid-tag"},
+		{storage.ErrorPlaylistNotExist, http.StatusNotFound, "playlist-not-found"},
+		{storage.ErrorEntryNotExist, http.StatusNotFound, "playlist-entry-not-found"},
+		{storage.ErrorSongInPlaylist, http.StatusConflict, "song-in-playlist"},
+		{storage.ErrorPlaylistHasRepeats, http.StatusConflict, "playlist-has-repeats"},
+		{storage.ErrorBadReorder, http.StatusBadRequest, "bad-reorder"},
+		{playlistfmt.ErrBadFile, http.StatusBadRequest, "bad-playlist-file"},
+		{songimport.ErrBadFile, http.StatusBadRequest, "bad-import-file"},
+		{enrichment.ErrorSongNotFound, http.StatusNotFound, "external-song-not-found"},
+		{enrichment.ErrorCircuitOpen, http.StatusServiceUnavailable, "external-service-unavailable"},
+		{enrichment.ErrorBadResponse, http.StatusBadGateway, "external-service-error"},
+		{enrichment.ErrorUpstream, http.StatusBadGateway, "external-service-error"},
+		{enrichment.ErrorRefreshChanged, http.StatusConflict, "refresh-changed"},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+	}
+
+	tested := make(map[error]bool, len(tests))
+	for _, tt := range tests {
+		tested[tt.err] = true
+		t.Run(tt.code+"/"+tt.err.Error(), func(t *testing.T) {
+			for _, err := range []error{tt.err, fmt.Errorf("context: %w", tt.err)} {
+				p := toProblem(err)
+				if p.Status != tt.status || p.Type != problem.TypePrefix+tt.code {
+					t.Errorf("toProblem(%q) = %d %s, want %d %s", err, p.Status, p.Type, tt.status, problem.TypePrefix+tt.code)
+				}
+				if p.Title == "" {
+					t.Errorf("toProblem(%q): empty title", err)
+				}
+			}
+		})
+	}
+
+	// каждая ошибка из errorMapping проверена выше
+	for _, m := range errorMapping {
+		if !tested[m.err] {
+			t.Errorf("no test for mapping of %q", m.err)
+		}
+	}
+}
+
+func TestToProblemDetail(t *testing.T) {
+	// детали известной ошибки отдаются клиенту, если отличаются от заголовка
+	p := toProblem(fmt.Errorf("%w: status 500", enrichment.ErrorUpstream))
+	if p.Detail != "external service error: status 500" {
+		t.Errorf("detail = %q", p.Detail)
+	}
+	if p := toProblem(ErrContentType); p.Detail != "" {
+		t.Errorf("detail of bare api error = %q, want empty", p.Detail)
+	}
+
+	// APIError в цепочке важнее ошибок из errorMapping
+	p = toProblem(fmt.Errorf("%w: %w", songimport.ErrBadFile, ErrBodyTooLarge))
+	if p.Status != http.StatusRequestEntityTooLarge || p.Type != problem.TypePrefix+ErrBodyTooLarge.Code {
+		t.Errorf("toProblem of wrapped api error = %d %s", p.Status, p.Type)
+	}
+}
+
+func TestToProblemUnknownError(t *testing.T) {
+	for _, err := range []error{
+		errors.New("pq: password authentication failed for user songs"),
+		fmt.Errorf("scan row: %w", errors.New("secret")),
+		context.Canceled,
+	} {
+		p := toProblem(err)
+		if p.Status != http.StatusInternalServerError || p.Type != problem.TypePrefix+ErrInternal.Code || p.Title != ErrInternal.Title {
+			t.Errorf("toProblem(%q) = %+v, want internal error", err, p)
+		}
+		if p.Detail != "" {
+			t.Errorf("toProblem(%q): detail %q is shown to client", err, p.Detail)
+		}
+	}
+}
+
+func TestToProblemValidation(t *testing.T) {
+	verr := &ValidationError{}
+	if verr.Err() != nil {
+		t.Fatal("empty ValidationError is an error")
+	}
+	verr.Add("group", "must not be empty")
+	verr.Add("limit", "must be a positive integer")
+
+	p := toProblem(fmt.Errorf("decode: %w", verr.Err()))
+	if p.Status != http.StatusBadRequest || p.Type != problem.TypePrefix+ErrValidation.Code || p.Title != ErrValidation.Title {
+		t.Errorf("unexpected problem %+v", p)
+	}
+	want := []problem.FieldError{
+		{Field: "group", Message: "must not be empty"},
+		{Field: "limit", Message: "must be a positive integer"},
+	}
+	if len(p.Errors) != len(want) {
+		t.Fatalf("errors = %+v, want %+v", p.Errors, want)
+	}
+	for i := range want {
+		if p.Errors[i] != want[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, p.Errors[i], want[i])
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/song/7", nil)
+	req = req.WithContext(reqctx.WithRequestID(req.Context(), "req-1"))
+	rec := httptest.NewRecorder()
+
+	writeError(rec, req, fmt.Errorf("get song: %w", storage.ErrorSongNotExist))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+	}
+	got := map[string]any{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	want := map[string]any{
+		"type":       problem.TypePrefix + ErrSongByIDNotFound.Code,
+		"title":      ErrSongByIDNotFound.Title,
+		"status":     float64(http.StatusNotFound),
+		"detail":     "get song: song not exist",
+		"instance":   "/api/song/7",
+		"request_id": "req-1",
+	}
+	if len(got) != len(want) {
+		t.Errorf("body = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/playlistfmt"
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/songexport"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+)
+
+// MaxImportSize - наибольший размер файла плейлиста при импорте
+const MaxImportSize = 1 << 20
+
+// @Summary Export songs
+// @Description Выгружает все песни под фильтрами списка (как в GET /api/songs) в порядке sort файлом, не собирая его в памяти.
+// @Description jsonl, csv и json - каталог для POST /api/songs/import: песни в том виде, в котором их отдает api
+// @Description (в CSV теги через ";"). m3u8 - расширенный M3U в UTF-8, песни без ссылки в него не попадают; xspf - XSPF версии 1,
+// @Description адрес трека - ссылка песни, исполнитель и название - группа и название песни.
+// @Description С заголовком Accept-Encoding: gzip файл сжимается.
+// @Tags songs
+// @Produce application/x-ndjson
+// @Produce text/csv
+// @Produce json
+// @Produce application/vnd.apple.mpegurl
+// @Produce application/xspf+xml
+// @Param format query string true "File format" Enums(jsonl, csv, json, m3u8, xspf)
+// @Param name query string false "Filter by song name"
+// @Param group query []string false "Filter by group, repeat the param for several groups" collectionFormat(multi)
+// @Param tag query []string false "Filter by tag, repeat the param for several tags" collectionFormat(multi)
+// @Param sort query string false "Sort fields, e.g. group,-release_date"
+// @Success 200 {file} file "Songs file"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/songs/export [get]
+func (h *SongHandler) ExportSongs(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
+
+	query := r.URL.Query()
+	verr := &ValidationError{}
+	format := songsExportFormat(query, verr)
+	params := storage.ListParams{
+		Filter: songFilter(query, verr),
+		Sort:   sortParam(query, verr),
+	}
+	if err := verr.Err(); err != nil {
+		logger.Error("Error parse query params",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	// ответ начинается с первой песней, чтобы об ошибке до нее можно было ответить problem details
+	var resp *fileResponse
+	var file songexport.Writer
+	start := func() (err error) {
+		resp = startFile(w, r, exportMediaType(format), "songs."+format)
+		file, err = newSongWriter(resp, format)
+		return err
+	}
+
+	count := 0
+	err := h.ExportRepo.ExportSongs(ctx, params, func(s song.Song) error {
+		if file == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		count++
+		return file.WriteSong(s)
+	})
+	if err == nil && file == nil {
+		err = start()
+	}
+	if err != nil {
+		logger.Error("Error export songs",
+			"ERROR", err,
+			"count", count,
+		)
+		if resp == nil {
+			writeError(w, r, err)
+		}
+		// иначе ответ уже начат, поэтому файл просто обрывается
+		return
+	}
+
+	if err = file.Close(); err == nil {
+		err = resp.Close()
+	}
+	if err != nil {
+		logger.Error("Error write export file",
+			"ERROR", err,
+		)
+		return
+	}
+	logger.Info("export songs success", "format", format, "count", count)
+}
+
+// @Summary Export a playlist
+// @Description Выгружает песни плейлиста по порядку файлом с названием плейлиста.
+// @Description m3u8 - расширенный M3U в UTF-8, песни без ссылки в него не попадают; xspf - XSPF версии 1.
+// @Description С заголовком Accept-Encoding: gzip файл сжимается.
+// @Tags playlists
+// @Produce application/vnd.apple.mpegurl
+// @Produce application/xspf+xml
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param format query string true "File format" Enums(m3u8, xspf)
+// @Success 200 {file} file "Playlist file"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID}/export [get]
+func (h *PlaylistHandler) ExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	verr := &ValidationError{}
+	format := exportFormat(r.URL.Query(), verr)
+	if err = verr.Err(); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse query params",
+			"ERROR", err,
+		)
+		return
+	}
+
+	playlist, err := h.PlaylistRepo.GetPlaylistByID(r.Context(), int64(id))
+	if err != nil {
+		logger.Error("Error get playlist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	resp := startFile(w, r, playlistfmt.MediaType(format), playlist.Name+"."+format)
+	file, err := playlistfmt.NewWriter(resp, format, playlist.Name)
+	if err != nil {
+		logger.Error("Error write playlist file",
+			"ERROR", err,
+		)
+		return
+	}
+
+	count := 0
+	for {
+		entries, err := h.PlaylistRepo.ListPlaylistEntries(r.Context(), int64(id), DefaultMaxPageSize, count)
+		if err != nil {
+			// ответ уже начат, поэтому файл просто обрывается
+			logger.Error("Error get playlist entries from db",
+				"ERROR", err,
+				"id", id,
+			)
+			return
+		}
+		for _, e := range entries {
+			if err = file.WriteTrack(songTrack(e.Song)); err != nil {
+				logger.Error("Error write playlist file",
+					"ERROR", err,
+				)
+				return
+			}
+		}
+		count += len(entries)
+		if len(entries) < DefaultMaxPageSize {
+			break
+		}
+	}
+
+	if err = file.Close(); err == nil {
+		err = resp.Close()
+	}
+	if err != nil {
+		logger.Error("Error write playlist file",
+			"ERROR", err,
+		)
+		return
+	}
+	logger.Info("export playlist success", "id", id, "format", format, "count", count)
+}
+
+// @Summary Import a playlist file
+// @Description Добавляет в конец плейлиста песни из файла M3U/M3U8 или XSPF. Песня ищется по группе и названию без учета регистра,
+// @Description в M3U они берутся из строки #EXTINF вида "группа - название". Формат задается параметром format,
+// @Description иначе определяется по Content-Type и содержимому файла. Треки без подходящей песни возвращаются в unmatched,
+// @Description песни, которые уже есть в плейлисте без повторов, - в skipped.
+// @Tags playlists
+// @Accept application/vnd.apple.mpegurl
+// @Accept application/xspf+xml
+// @Produce json
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param format query string false "File format" Enums(m3u, m3u8, xspf)
+// @Param file body string true "Playlist file"
+// @Success 200 {object} song.PlaylistImport "Import report"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist not found"
+// @Failure 413 {object} problem.Problem "File is too large"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID}/import [post]
+func (h *PlaylistHandler) ImportPlaylist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	format := ""
+	if value := r.URL.Query().Get("format"); value != "" {
+		verr := &ValidationError{}
+		format = importFormat(value, verr)
+		if err = verr.Err(); err != nil {
+			writeError(w, r, err)
+			logger.Error("Error parse query params",
+				"ERROR", err,
+			)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = ErrBodyTooLarge
+		} else {
+			err = ErrParseBody
+		}
+		writeError(w, r, err)
+		logger.Error("Error read body",
+			"ERROR", err,
+		)
+		return
+	}
+	if format == "" {
+		format = playlistfmt.Detect(r.Header.Get("Content-Type"), data)
+	}
+
+	tracks, err := playlistfmt.Read(bytes.NewReader(data), format)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error read playlist file",
+			"ERROR", err,
+			"format", format,
+		)
+		return
+	}
+
+	// плейлист проверяется до поиска песен, чтобы не искать их зря
+	if _, err = h.PlaylistRepo.GetPlaylistByID(r.Context(), int64(id)); err != nil {
+		logger.Error("Error get playlist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	report := song.PlaylistImport{Skipped: []song.Track{}, Unmatched: []song.Track{}}
+	for _, t := range tracks {
+		s, found, err := findTrackSong(r.Context(), h.SongRepo, t)
+		if err != nil {
+			logger.Error("Error find song of track",
+				"ERROR", err,
+			)
+			writeError(w, r, err)
+			return
+		}
+		if !found {
+			report.Unmatched = append(report.Unmatched, t)
+			continue
+		}
+
+		_, err = h.PlaylistRepo.AddPlaylistEntry(r.Context(), int64(id), s.SongID, 0)
+		switch {
+		case errors.Is(err, storage.ErrorSongInPlaylist):
+			report.Skipped = append(report.Skipped, t)
+		case err != nil:
+			logger.Error("Error add playlist entry to db",
+				"ERROR", err,
+				"id", id,
+			)
+			writeError(w, r, err)
+			return
+		default:
+			report.Added++
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, report)
+	logger.Info("import playlist success", "id", id, "format", format,
+		"added", report.Added, "skipped", len(report.Skipped), "unmatched", len(report.Unmatched))
+}
+
+// exportFormat читает обязательный формат файла плейлиста format
+func exportFormat(query url.Values, verr *ValidationError) string {
+	format := query.Get("format")
+	switch format {
+	case playlistfmt.FormatM3U8, playlistfmt.FormatXSPF:
+	default:
+		verr.Add("format", "must be m3u8 or xspf")
+	}
+	return format
+}
+
+// importFormat - формат файла импорта, m3u читается как m3u8
+func importFormat(value string, verr *ValidationError) string {
+	switch value {
+	case "m3u", playlistfmt.FormatM3U8:
+		return playlistfmt.FormatM3U8
+	case playlistfmt.FormatXSPF:
+		return playlistfmt.FormatXSPF
+	}
+	verr.Add("format", "must be m3u, m3u8 or xspf")
+	return ""
+}
+
+// songsExportFormat читает обязательный формат выгрузки песен: файл каталога или плейлиста
+func songsExportFormat(query url.Values, verr *ValidationError) string {
+	format := query.Get("format")
+	switch format {
+	case songimport.FormatJSONL, songimport.FormatCSV, songimport.FormatJSON, playlistfmt.FormatM3U8, playlistfmt.FormatXSPF:
+	default:
+		verr.Add("format", "must be jsonl, csv, json, m3u8 or xspf")
+	}
+	return format
+}
+
+// exportMediaType - тип содержимого формата выгрузки песен
+func exportMediaType(format string) string {
+	switch format {
+	case playlistfmt.FormatM3U8, playlistfmt.FormatXSPF:
+		return playlistfmt.MediaType(format)
+	default:
+		return songexport.MediaType(format)
+	}
+}
+
+// newSongWriter начинает файл песен, файлы плейлистов пишутся треками песен
+func newSongWriter(w io.Writer, format string) (songexport.Writer, error) {
+	switch format {
+	case playlistfmt.FormatM3U8, playlistfmt.FormatXSPF:
+		file, err := playlistfmt.NewWriter(w, format, "")
+		return trackWriter{file}, err
+	default:
+		return songexport.NewWriter(w, format)
+	}
+}
+
+// trackWriter пишет песни в файл плейлиста
+type trackWriter struct {
+	playlistfmt.Writer
+}
+
+func (t trackWriter) WriteSong(s song.Song) error {
+	return t.WriteTrack(songTrack(s))
+}
+
+// fileResponse - тело ответа-файла, сжатое gzip, если клиент его принимает
+type fileResponse struct {
+	io.Writer
+	gz *gzip.Writer
+}
+
+// startFile отвечает заголовками файла filename. Close дописывает сжатые данные.
+// Файл пишется дольше SERVER_WRITE_TIMEOUT, поэтому срок записи ответа снимается
+func startFile(w http.ResponseWriter, r *http.Request, mediaType, filename string) *fileResponse {
+	// ErrNotSupported означает, что срока записи у такого ответа нет
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	header := w.Header()
+	header.Set("Content-Type", mediaType)
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	header.Add("Vary", "Accept-Encoding")
+
+	resp := &fileResponse{Writer: w}
+	if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		header.Set("Content-Encoding", "gzip")
+		resp.gz = gzip.NewWriter(w)
+		resp.Writer = resp.gz
+	}
+	w.WriteHeader(http.StatusOK)
+	return resp
+}
+
+func (f *fileResponse) Close() error {
+	if f.gz != nil {
+		return f.gz.Close()
+	}
+	return nil
+}
+
+// acceptsGzip - в Accept-Encoding есть gzip без q=0
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		_, q, ok := strings.Cut(strings.ReplaceAll(params, " ", ""), "q=")
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
+}
+
+// songTrack - трек файла плейлиста для песни
+func songTrack(s song.Song) song.Track {
+	return song.Track{Group: s.Group, Song: s.Song, Location: s.Link}
+}
+
+// findTrackSong ищет песню с группой и названием трека без учета регистра.
+// Если таких песен несколько, берется первая добавленная.
+func findTrackSong(ctx context.Context, repo storage.SongRepo, t song.Track) (song.Song, bool, error) {
+	group, name := storage.NormalizeName(t.Group), storage.NormalizeName(t.Song)
+	if group == "" || name == "" {
+		return song.Song{}, false, nil
+	}
+
+	page, err := repo.ListSongs(ctx, storage.ListParams{
+		Filter: storage.SongFilter{
+			Name:   storage.EscapeLike(name),
+			Groups: []string{storage.EscapeLike(group)},
+			Match:  storage.MatchExact,
+		},
+		Limit: 1,
+	})
+	if err != nil || len(page.Items) == 0 {
+		return song.Song{}, false, err
+	}
+	return page.Items[0], true, nil
+}
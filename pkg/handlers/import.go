@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+)
+
+const (
+	// MaxSongsImportSize - наибольший размер файла песен при импорте, у сжатого тела - и до, и после распаковки
+	MaxSongsImportSize = 256 << 20
+	// MaxImportReportRows - сколько строк с ошибкой попадает в отчет импорта, остальные только считаются
+	MaxImportReportRows = 1000
+)
+
+// @Summary Import songs
+// @Description Добавляет песни из файла JSON Lines (объект на строку), CSV с заголовком или массива JSON,
+// @Description например выгрузки GET /api/songs/export. Обязательны group и song,
+// @Description releaseDate, text, link, tags (в CSV через ";"), album_id и enrichment_status - нет, лишние поля не учитываются.
+// @Description Песня без enrichment_status с датой выпуска, текстом и ссылкой сохраняется заполненной, остальным данные дозаполняет
+// @Description внешний сервис в фоне. Песня с альбомом другого исполнителя или несуществующим альбомом не сохраняется.
+// @Description Если у исполнителя уже есть песня с тем же названием, она пропускается (on_conflict=skip)
+// @Description или получает непустые поля и теги из файла (on_conflict=upsert). Файл читается потоком и сохраняется
+// @Description пачками, при ошибке файла или хранилища уже сохраненные пачки остаются.
+// @Description Формат задается параметром format, иначе определяется по Content-Type. Тело можно сжать gzip (Content-Encoding: gzip),
+// @Description файл не больше 256 МиБ и до, и после распаковки. В rows отчета попадают только строки с ошибкой, не больше 1000.
+// @Tags songs
+// @Accept application/x-ndjson
+// @Accept text/csv
+// @Accept json
+// @Produce json
+// @Param format query string false "File format" Enums(jsonl, csv, json)
+// @Param on_conflict query string false "What to do with songs that already exist" Enums(skip, upsert) default(skip)
+// @Param file body string true "Songs file"
+// @Success 200 {object} song.ImportReport "Import report"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 413 {object} problem.Problem "File is too large"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/songs/import [post]
+func (h *SongHandler) ImportSongs(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
+
+	query := r.URL.Query()
+	verr := &ValidationError{}
+	format := songsFileFormat(query, r.Header.Get("Content-Type"), verr)
+	policy := conflictPolicy(query, verr)
+	if err := verr.Err(); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse query params",
+			"ERROR", err,
+		)
+		return
+	}
+	defer r.Body.Close()
+
+	// большой файл читается и сохраняется дольше SERVER_READ_TIMEOUT и SERVER_WRITE_TIMEOUT,
+	// ErrNotSupported означает, что сроков у такого запроса нет
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxSongsImportSize)
+	body, err := requestBody(r)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error read body",
+			"ERROR", err,
+		)
+		return
+	}
+	defer body.Close()
+
+	reader, err := songimport.NewReader(body, format)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error read import file",
+			"ERROR", err,
+			"format", format,
+		)
+		return
+	}
+
+	report := song.ImportReport{Rows: []song.ImportRow{}}
+	err = songimport.NewImporter(h.ImportRepo).Import(ctx, reader, policy, func(row song.ImportRow) error {
+		if row.Status == song.ImportFailed && len(report.Rows) < MaxImportReportRows {
+			report.Add(row)
+		} else {
+			report.Count(row)
+		}
+		return nil
+	})
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = ErrBodyTooLarge
+		}
+		writeError(w, r, err)
+		logger.Error("Error import songs",
+			"ERROR", err,
+			"format", format,
+			"saved", report.Created+report.Updated,
+		)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, report)
+	logger.Info("import songs success", "format", format, "policy", policy,
+		"created", report.Created, "updated", report.Updated, "skipped", report.Skipped, "failed", report.Failed)
+}
+
+// songsFileFormat - формат файла песен из параметра format, а без него - из типа содержимого
+func songsFileFormat(query url.Values, contentType string, verr *ValidationError) string {
+	format := query.Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch mediaType {
+		case "text/csv":
+			format = songimport.FormatCSV
+		case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+			format = songimport.FormatJSONL
+		case ApplicationJSON:
+			format = songimport.FormatJSON
+		}
+	}
+
+	switch format {
+	case songimport.FormatJSONL, songimport.FormatCSV, songimport.FormatJSON:
+	default:
+		verr.Add("format", "must be jsonl, csv or json")
+	}
+	return format
+}
+
+// requestBody - тело запроса, распакованное, если оно сжато gzip.
+// Распакованное тело больше MaxSongsImportSize читается с ошибкой ErrBodyTooLarge
+func requestBody(r *http.Request) (io.ReadCloser, error) {
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return r.Body, nil
+	case "gzip":
+		body, err := gzip.NewReader(r.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, ErrBodyTooLarge
+			}
+			return nil, fmt.Errorf("%w: %w", ErrParseBody, err)
+		}
+		return &limitedBody{ReadCloser: body, left: MaxSongsImportSize}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported Content-Encoding", ErrParseBody)
+	}
+}
+
+// limitedBody отдает не больше left байт, дальше - ошибку ErrBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		// тело могло закончиться ровно на пределе
+		var b [1]byte
+		if n, err := l.ReadCloser.Read(b[:]); n == 0 {
+			return 0, err
+		}
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+// conflictPolicy читает политику on_conflict, по умолчанию песни, которые уже есть, пропускаются
+func conflictPolicy(query url.Values, verr *ValidationError) string {
+	policy := query.Get("on_conflict")
+	switch policy {
+	case "":
+		return storage.ImportSkip
+	case storage.ImportSkip, storage.ImportUpsert:
+	default:
+		verr.Add("on_conflict", "must be skip or upsert")
+	}
+	return policy
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+type JobHandler struct {
+	Logger  *slog.Logger
+	JobRepo storage.JobRepo
+	// MaxPageSize - наибольший limit списка задач, 0 - DefaultMaxPageSize
+	MaxPageSize int
+}
+
+// @Summary Get a list of enrichment jobs
+// @Description Возвращает задачи на заполнение песен данными внешнего сервиса. Задачи со статусом dead исчерпали попытки (dead letter).
+// @Tags enrichment
+// @Produce json
+// @Param status query string false "Job status" Enums(pending, running, done, dead)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {array} song.EnrichmentJob "List of jobs"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/enrichment/jobs [get]
+func (h *JobHandler) GetListOfJobs(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
+
+	query := r.URL.Query()
+	verr := &ValidationError{}
+	status := query.Get("status")
+	switch status {
+	case "", song.JobPending, song.JobRunning, song.JobDone, song.JobDead:
+	default:
+		verr.Add("status", "must be one of pending, running, done, dead")
+	}
+	page := positiveInt(query, "page", 1, verr)
+	limit := limitParam(query, 10, h.MaxPageSize, verr)
+	if err := verr.Err(); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse query",
+			"ERROR", err,
+			"fields", verr.Fields,
+		)
+		return
+	}
+
+	jobs, err := h.JobRepo.GetEnrichmentJobs(ctx, status, limit, (page-1)*limit)
+	if err != nil {
+		logger.Error("Error get jobs from db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(jobs)
+	if err != nil {
+		logger.Error("Error marshal list jobs",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	logger.Info("Get list of jobs success")
+}
+
+// @Summary Requeue a dead enrichment job
+// @Description Возвращает задачу из dead letter в очередь, счетчик попыток сбрасывается
+// @Tags enrichment
+// @Produce json
+// @Param JOB_ID path int true "Job ID"
+// @Success 204 "Job requeued"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Job not found"
+// @Failure 409 {object} problem.Problem "Job is not in dead letter"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/enrichment/job/{JOB_ID}/requeue [post]
+func (h *JobHandler) RequeueJob(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
+
+	id, err := pathID(r, "JOB_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	err = h.JobRepo.RequeueEnrichmentJob(ctx, int64(id))
+	if err != nil {
+		logger.Error("Error requeue job",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info("requeue job success", "id", id)
+}
@@ -9,17 +9,62 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func NewMuxServer(songHandler *SongHandler, logger *slog.Logger) http.Handler {
+func NewMuxServer(songHandler *SongHandler, jobHandler *JobHandler, artistHandler *ArtistHandler, tagHandler *TagHandler, playlistHandler *PlaylistHandler, logger *slog.Logger) http.Handler {
 	r := mux.NewRouter()
 
 	r.HandleFunc("/api/songs", songHandler.GetListOfSongs).Methods(http.MethodGet)
 	r.HandleFunc("/api/songs", songHandler.AddNewSong).Methods(http.MethodPost)
+	r.HandleFunc("/api/songs/search", songHandler.SearchSongs).Methods(http.MethodGet)
+	r.HandleFunc("/api/songs/export", songHandler.ExportSongs).Methods(http.MethodGet)
+	r.HandleFunc("/api/songs/import", songHandler.ImportSongs).Methods(http.MethodPost)
 	r.HandleFunc("/api/song/{SONG_ID}", songHandler.DeleteSongByID).Methods(http.MethodDelete)
 	r.HandleFunc("/api/song/{SONG_ID}", songHandler.UpdateSong).Methods(http.MethodPut)
-	r.HandleFunc("/api/song/{SONG_ID}", songHandler.GetTextOfSong).Methods(http.MethodGet)
+	r.HandleFunc("/api/song/{SONG_ID}", songHandler.GetSongByID).Methods(http.MethodGet)
+	r.HandleFunc("/api/song/{SONG_ID}/text", songHandler.GetTextOfSong).Methods(http.MethodGet)
+	r.HandleFunc("/api/song/{SONG_ID}/refresh", songHandler.RefreshSong).Methods(http.MethodPost)
 
-	mux := middleware.AccessLog(songHandler.Logger, r)
-	mux = middleware.Panic(mux)
+	r.HandleFunc("/api/song/{SONG_ID}/tags", tagHandler.AddSongTags).Methods(http.MethodPost)
+	r.HandleFunc("/api/song/{SONG_ID}/tags", tagHandler.RemoveSongTags).Methods(http.MethodDelete)
+	r.HandleFunc("/api/tags", tagHandler.GetListOfTags).Methods(http.MethodGet)
+
+	r.HandleFunc("/api/artists", artistHandler.GetListOfArtists).Methods(http.MethodGet)
+	r.HandleFunc("/api/artists", artistHandler.AddArtist).Methods(http.MethodPost)
+	r.HandleFunc("/api/artist/{ARTIST_ID}", artistHandler.GetArtistByID).Methods(http.MethodGet)
+	r.HandleFunc("/api/artist/{ARTIST_ID}", artistHandler.UpdateArtist).Methods(http.MethodPut)
+	r.HandleFunc("/api/artist/{ARTIST_ID}", artistHandler.DeleteArtist).Methods(http.MethodDelete)
+	r.HandleFunc("/api/artist/{ARTIST_ID}/songs", artistHandler.GetSongsOfArtist).Methods(http.MethodGet)
+	r.HandleFunc("/api/artist/{ARTIST_ID}/albums", artistHandler.GetAlbumsOfArtist).Methods(http.MethodGet)
+	r.HandleFunc("/api/albums", artistHandler.AddAlbum).Methods(http.MethodPost)
+	r.HandleFunc("/api/album/{ALBUM_ID}", artistHandler.GetAlbumByID).Methods(http.MethodGet)
+	r.HandleFunc("/api/album/{ALBUM_ID}", artistHandler.UpdateAlbum).Methods(http.MethodPut)
+	r.HandleFunc("/api/album/{ALBUM_ID}", artistHandler.DeleteAlbum).Methods(http.MethodDelete)
+
+	r.HandleFunc("/api/playlists", playlistHandler.GetListOfPlaylists).Methods(http.MethodGet)
+	r.HandleFunc("/api/playlists", playlistHandler.AddPlaylist).Methods(http.MethodPost)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}", playlistHandler.GetPlaylistByID).Methods(http.MethodGet)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}", playlistHandler.UpdatePlaylist).Methods(http.MethodPut)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}", playlistHandler.DeletePlaylist).Methods(http.MethodDelete)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}/entries", playlistHandler.GetPlaylistEntries).Methods(http.MethodGet)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}/entries", playlistHandler.AddPlaylistEntry).Methods(http.MethodPost)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}/entries", playlistHandler.ReorderPlaylist).Methods(http.MethodPut)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}/export", playlistHandler.ExportPlaylist).Methods(http.MethodGet)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}/import", playlistHandler.ImportPlaylist).Methods(http.MethodPost)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}/entry/{ENTRY_ID}", playlistHandler.MovePlaylistEntry).Methods(http.MethodPut)
+	r.HandleFunc("/api/playlist/{PLAYLIST_ID}/entry/{ENTRY_ID}", playlistHandler.DeletePlaylistEntry).Methods(http.MethodDelete)
+
+	r.HandleFunc("/api/enrichment/jobs", jobHandler.GetListOfJobs).Methods(http.MethodGet)
+	r.HandleFunc("/api/enrichment/job/{JOB_ID}/requeue", jobHandler.RequeueJob).Methods(http.MethodPost)
+
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, ErrRouteNotFound)
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, ErrMethodNotAllowed)
+	})
+
+	// Panic внутри AccessLog, чтобы в ответе о панике был id запроса
+	mux := middleware.Panic(r)
+	mux = middleware.AccessLog(songHandler.Logger, mux)
 
 	return mux
 }
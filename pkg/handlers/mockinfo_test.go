@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"SongLibrary/pkg/backoff"
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/mockinfo"
+	"SongLibrary/pkg/problem"
+)
+
+// newMockInfoHandler поднимает заглушку внешнего сервиса в режиме mode
+// и возвращает handler с настоящим клиентом к ней
+func newMockInfoHandler(t *testing.T, mode mockinfo.Mode) *SongHandler {
+	t.Helper()
+
+	server := mockinfo.NewServer(mockinfo.DefaultFixtures())
+	if err := server.SetMode(mode); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newTestSongHandler(enrichment.NewHTTPClient(config.External{
+		Host:             host,
+		Port:             portNumber,
+		Timeout:          100 * time.Millisecond,
+		MaxRetries:       0,
+		Retry:            backoff.Backoff{Initial: time.Millisecond, Max: time.Millisecond},
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	}))
+}
+
+func checkProblem(t *testing.T, rec *httptest.ResponseRecorder, apiErr *APIError) {
+	t.Helper()
+
+	if rec.Code != apiErr.Status {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, apiErr.Status, rec.Body)
+	}
+	if p := decodeProblem(t, rec); p.Type != problem.TypePrefix+apiErr.Code {
+		t.Errorf("type = %q, want %q", p.Type, problem.TypePrefix+apiErr.Code)
+	}
+}
+
+func TestAddNewSongMockInfo(t *testing.T) {
+	h := newMockInfoHandler(t, mockinfo.Mode{})
+
+	rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Uprising"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+}
+
+func TestAddNewSongMockInfoLatency(t *testing.T) {
+	h := newMockInfoHandler(t, mockinfo.Mode{Latency: time.Second})
+
+	start := time.Now()
+	rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Uprising"}`)
+	checkProblem(t, rec, ErrExternalService)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("response in %v, client timeout is 100ms", elapsed)
+	}
+}
+
+func TestAddNewSongMockInfoServerError(t *testing.T) {
+	h := newMockInfoHandler(t, mockinfo.Mode{Failure: mockinfo.FailureServerError})
+
+	// BreakerThreshold ответов 500 подряд размыкают breaker
+	for i := 0; i < 2; i++ {
+		rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Uprising"}`)
+		checkProblem(t, rec, ErrExternalService)
+	}
+	rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Uprising"}`)
+	checkProblem(t, rec, ErrExternalUnavailable)
+}
+
+func TestAddNewSongMockInfoMalformed(t *testing.T) {
+	h := newMockInfoHandler(t, mockinfo.Mode{Failure: mockinfo.FailureMalformed})
+
+	rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Uprising"}`)
+	checkProblem(t, rec, ErrExternalService)
+}
+
+func TestAddNewSongMockInfoUnknownSong(t *testing.T) {
+	h := newMockInfoHandler(t, mockinfo.Mode{})
+
+	rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Unknown"}`)
+	checkProblem(t, rec, ErrSongNotFoundExternal)
+}
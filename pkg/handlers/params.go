@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultMaxPageSize - наибольший limit, если в обработчике не задан MaxPageSize
+const DefaultMaxPageSize = 100
+
+// pathID читает целочисленный параметр пути name
+func pathID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		verr := &ValidationError{}
+		verr.Add(name, "must be an integer")
+		return 0, verr
+	}
+	return id, nil
+}
+
+// pagination читает query параметры page (по умолчанию 1) и limit (по умолчанию defaultLimit, не больше maxLimit)
+func pagination(query url.Values, defaultLimit, maxLimit int) (int, int, error) {
+	verr := &ValidationError{}
+	page := positiveInt(query, "page", 1, verr)
+	limit := limitParam(query, defaultLimit, maxLimit, verr)
+
+	return page, limit, verr.Err()
+}
+
+// listParams читает параметры списка песен: фильтры, sort, cursor или page, limit и include_total.
+// Курсор и номер страницы вместе не принимаются.
+func listParams(query url.Values, defaultLimit, maxLimit int) (storage.ListParams, error) {
+	verr := &ValidationError{}
+	params := storage.ListParams{
+		Filter: songFilter(query, verr),
+		Limit:  limitParam(query, defaultLimit, maxLimit, verr),
+	}
+
+	params.Sort = sortParam(query, verr)
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := storage.DecodeCursor(value)
+		if err == nil {
+			params.After = &cursor
+			err = params.CheckCursor()
+		}
+		if err != nil {
+			verr.Add("cursor", "must be a value of next_cursor from the previous page with the same sort")
+			params.After = nil
+		}
+		if query.Has("page") {
+			verr.Add("page", "must not be used with cursor")
+		}
+	} else {
+		page := positiveInt(query, "page", 1, verr)
+		params.Offset = (page - 1) * params.Limit
+	}
+
+	if value := query.Get("include_total"); value != "" {
+		withTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			verr.Add("include_total", "must be a boolean")
+		}
+		params.WithTotal = withTotal
+	}
+
+	return params, verr.Err()
+}
+
+// sortParam читает сортировку списка песен sort
+func sortParam(query url.Values, verr *ValidationError) []storage.SortField {
+	sort, err := storage.ParseSort(query.Get("sort"))
+	if err != nil {
+		verr.Add("sort", "must be a comma separated list of name, group, release_date, created_at, - before a field for descending order")
+	}
+	return sort
+}
+
+// songFilter читает фильтры списка песен: name, group (можно несколько - любая из групп),
+// text, link, match (exact, prefix или substring), artist_id, album_id, tag (можно несколько)
+// с tag_match (any или all), date_from и date_to.
+// date - период выпуска (YYYY-MM-DD, YYYY-MM или YYYY), заменяет date_from и date_to.
+func songFilter(query url.Values, verr *ValidationError) storage.SongFilter {
+	f := storage.SongFilter{
+		Name:  query.Get("name"),
+		Text:  query.Get("text"),
+		Link:  query.Get("link"),
+		Match: query.Get("match"),
+
+		ArtistID: int64(positiveInt(query, "artist_id", 0, verr)),
+		AlbumID:  int64(positiveInt(query, "album_id", 0, verr)),
+	}
+	for _, g := range query["group"] {
+		if g != "" {
+			f.Groups = append(f.Groups, g)
+		}
+	}
+
+	if tags := query["tag"]; len(tags) > 0 {
+		var err error
+		if f.Tags, err = storage.NormalizeTags(tags); err != nil {
+			verr.Add("tag", tagMessage)
+		}
+	}
+	f.TagMatch = query.Get("tag_match")
+	switch f.TagMatch {
+	case "", storage.TagMatchAny, storage.TagMatchAll:
+	default:
+		verr.Add("tag_match", "must be one of any, all")
+	}
+
+	switch f.Match {
+	case "", storage.MatchSubstring, storage.MatchExact, storage.MatchPrefix:
+	default:
+		verr.Add("match", "must be one of substring, exact, prefix")
+	}
+
+	if value := query.Get("date"); value != "" {
+		if query.Has("date_from") || query.Has("date_to") {
+			verr.Add("date", "must not be used with date_from or date_to")
+		}
+		date, err := song.ParseReleaseDate(value)
+		if err != nil {
+			verr.Add("date", "must be a date YYYY-MM-DD, YYYY-MM or YYYY")
+			return f
+		}
+		from, to := date.Date, date.End()
+		f.DateFrom, f.DateTo = &from, &to
+		return f
+	}
+
+	f.DateFrom = dateParam(query, "date_from", verr)
+	f.DateTo = dateParam(query, "date_to", verr)
+	if f.DateFrom != nil && f.DateTo != nil && f.DateFrom.After(*f.DateTo) {
+		verr.Add("date_to", "must not be before date_from")
+	}
+
+	return f
+}
+
+// tagMessage - ошибка валидации тегов
+var tagMessage = fmt.Sprintf("each tag must be from 1 to %d characters", storage.MaxTagLength)
+
+// dateFormats - форматы дат в query параметрах
+var dateFormats = []string{"2006-01-02", "02.01.2006"}
+
+func dateParam(query url.Values, name string, verr *ValidationError) *time.Time {
+	value := query.Get(name)
+	if value == "" {
+		return nil
+	}
+
+	for _, layout := range dateFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	verr.Add(name, "must be a date YYYY-MM-DD or DD.MM.YYYY")
+	return nil
+}
+
+// limitParam читает limit (по умолчанию def), который не может быть больше maxLimit
+func limitParam(query url.Values, def, maxLimit int, verr *ValidationError) int {
+	if maxLimit <= 0 {
+		maxLimit = DefaultMaxPageSize
+	}
+
+	limit := positiveInt(query, "limit", def, verr)
+	if limit > maxLimit {
+		verr.Add("limit", fmt.Sprintf("must not be greater than %d", maxLimit))
+		return def
+	}
+	return limit
+}
+
+func positiveInt(query url.Values, name string, def int, verr *ValidationError) int {
+	value := query.Get(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		verr.Add(name, "must be a positive integer")
+		return def
+	}
+	return n
+}
+
+// decodeBody читает json тела запроса в v
+func decodeBody(r *http.Request, v any) error {
+	if r.Header.Get("Content-Type") != ApplicationJSON {
+		return ErrContentType
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return ErrParseBody
+	}
+	defer r.Body.Close()
+
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnmarshal, err)
+	}
+	return nil
+}
+
+// writeJSON отвечает v в json со статусом status
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ApplicationJSON)
+	w.WriteHeader(status)
+	w.Write(body)
+}
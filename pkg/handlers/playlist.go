@@ -0,0 +1,511 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"unicode/utf8"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+type PlaylistHandler struct {
+	Logger       *slog.Logger
+	PlaylistRepo storage.PlaylistRepo
+	// SongRepo ищет песни файлов при импорте
+	SongRepo storage.SongRepo
+	// MaxPageSize - наибольший limit в списках, 0 - DefaultMaxPageSize
+	MaxPageSize int
+}
+
+// requestLogger - логгер запроса и контекст с ним
+func (h *PlaylistHandler) requestLogger(r *http.Request) (*slog.Logger, *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	return logger, r.WithContext(reqctx.WithLogger(r.Context(), logger))
+}
+
+// @Summary Get a list of playlists
+// @Description Возвращает плейлисты в порядке создания
+// @Tags playlists
+// @Produce json
+// @Param name query string false "Substring of playlist name"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} song.PlaylistList "Page of playlists"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlists [get]
+func (h *PlaylistHandler) GetListOfPlaylists(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	query := r.URL.Query()
+	page, limit, err := pagination(query, 10, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse pagination",
+			"ERROR", err,
+		)
+		return
+	}
+
+	playlists, err := h.PlaylistRepo.ListPlaylists(r.Context(), query.Get("name"), limit, (page-1)*limit)
+	if err != nil {
+		logger.Error("Error get playlists from db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, song.PlaylistList{Items: playlists})
+	logger.Info("Get list of playlists success", "count", len(playlists))
+}
+
+// @Summary Add a playlist
+// @Description Создает пустой плейлист. Если allow_duplicates false, песню можно добавить в плейлист только один раз.
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param playlist body song.PayloadPlaylist true "Playlist"
+// @Success 201 {object} song.Playlist "Created playlist"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlists [post]
+func (h *PlaylistHandler) AddPlaylist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	payload := song.PayloadPlaylist{}
+	if err := decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+	if err := validatePlaylistName(payload.Name); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error validate playlist name",
+			"ERROR", err,
+		)
+		return
+	}
+
+	playlist, err := h.PlaylistRepo.AddPlaylist(r.Context(), song.Playlist{
+		Name:            payload.Name,
+		Description:     payload.Description,
+		AllowDuplicates: payload.AllowDuplicates,
+	})
+	if err != nil {
+		logger.Error("Error add playlist to db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/playlist/%d", playlist.PlaylistID))
+	writeJSON(w, r, http.StatusCreated, playlist)
+	logger.Info("add playlist success", "id", playlist.PlaylistID)
+}
+
+// @Summary Get a playlist by ID
+// @Tags playlists
+// @Produce json
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Success 200 {object} song.Playlist "Playlist"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID} [get]
+func (h *PlaylistHandler) GetPlaylistByID(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	playlist, err := h.PlaylistRepo.GetPlaylistByID(r.Context(), int64(id))
+	if err != nil {
+		logger.Error("Error get playlist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, playlist)
+	logger.Info("get playlist success", "id", id)
+}
+
+// @Summary Update a playlist
+// @Description Меняет переданные поля плейлиста. Запретить повторы нельзя, пока в плейлисте есть повторяющиеся песни.
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param playlist body song.PlaylistForUpdate true "Fields to update"
+// @Success 200 {object} song.Playlist "Updated playlist"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist not found"
+// @Failure 409 {object} problem.Problem "Playlist has repeated songs"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID} [put]
+func (h *PlaylistHandler) UpdatePlaylist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	payload := song.PlaylistForUpdate{}
+	if err = decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+	if payload.Name != nil {
+		if err = validatePlaylistName(*payload.Name); err != nil {
+			writeError(w, r, err)
+			logger.Error("Error validate playlist name",
+				"ERROR", err,
+			)
+			return
+		}
+	}
+
+	playlist, err := h.PlaylistRepo.UpdatePlaylist(r.Context(), int64(id), payload)
+	if err != nil {
+		logger.Error("Error update playlist in db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, playlist)
+	logger.Info("update playlist success", "id", id)
+}
+
+// @Summary Delete a playlist
+// @Description Удаляет плейлист вместе с записями, песни остаются
+// @Tags playlists
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Success 204 "Playlist deleted"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID} [delete]
+func (h *PlaylistHandler) DeletePlaylist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	if err = h.PlaylistRepo.DeletePlaylist(r.Context(), int64(id)); err != nil {
+		logger.Error("Error delete playlist from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info("delete playlist success", "id", id)
+}
+
+// @Summary Get entries of a playlist
+// @Description Возвращает записи плейлиста по порядку вместе с песнями. Позиции идут с 1 без пропусков.
+// @Tags playlists
+// @Produce json
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} song.PlaylistEntryList "Page of entries"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID}/entries [get]
+func (h *PlaylistHandler) GetPlaylistEntries(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	page, limit, err := pagination(r.URL.Query(), 10, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse pagination",
+			"ERROR", err,
+		)
+		return
+	}
+
+	entries, err := h.PlaylistRepo.ListPlaylistEntries(r.Context(), int64(id), limit, (page-1)*limit)
+	if err != nil {
+		logger.Error("Error get playlist entries from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, song.PlaylistEntryList{Items: entries})
+	logger.Info("Get playlist entries success", "id", id, "count", len(entries))
+}
+
+// @Summary Add a song to a playlist
+// @Description Добавляет песню на позицию position, следующие записи сдвигаются. Без position или с позицией за концом - в конец.
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param entry body song.PayloadPlaylistEntry true "Song and position"
+// @Success 201 {object} song.PlaylistEntry "Created entry"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist or song not found"
+// @Failure 409 {object} problem.Problem "Song is already in playlist"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID}/entries [post]
+func (h *PlaylistHandler) AddPlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	payload := song.PayloadPlaylistEntry{}
+	if err = decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+	verr := &ValidationError{}
+	if payload.SongID <= 0 {
+		verr.Add("song_id", "must be a positive integer")
+	}
+	if payload.Position < 0 {
+		verr.Add("position", "must not be negative")
+	}
+	if err = verr.Err(); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error field of struct",
+			"ERROR", err,
+			"fields", verr.Fields,
+		)
+		return
+	}
+
+	entry, err := h.PlaylistRepo.AddPlaylistEntry(r.Context(), int64(id), payload.SongID, payload.Position)
+	if err != nil {
+		logger.Error("Error add playlist entry to db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, entry)
+	logger.Info("add playlist entry success", "id", id, "entry_id", entry.EntryID)
+}
+
+// @Summary Reorder a playlist
+// @Description Задает новый порядок записей плейлиста. entry_ids должен содержать все записи плейлиста по одному разу.
+// @Tags playlists
+// @Accept json
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param order body song.PayloadReorder true "Entry IDs in new order"
+// @Success 204 "Playlist reordered"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID}/entries [put]
+func (h *PlaylistHandler) ReorderPlaylist(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	payload := song.PayloadReorder{}
+	if err = decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+
+	if err = h.PlaylistRepo.ReorderPlaylist(r.Context(), int64(id), payload.EntryIDs); err != nil {
+		logger.Error("Error reorder playlist in db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info("reorder playlist success", "id", id)
+}
+
+// @Summary Move a playlist entry
+// @Description Переносит запись на позицию position, остальные записи сдвигаются. Позиция за концом - в конец.
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param ENTRY_ID path int true "Entry ID"
+// @Param position body song.PayloadMoveEntry true "New position"
+// @Success 200 {object} song.PlaylistEntry "Moved entry"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist or entry not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID}/entry/{ENTRY_ID} [put]
+func (h *PlaylistHandler) MovePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, entryID, err := entryPath(r)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	payload := song.PayloadMoveEntry{}
+	if err = decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+	if payload.Position < 1 {
+		verr := &ValidationError{}
+		verr.Add("position", "must be a positive integer")
+		writeError(w, r, verr)
+		logger.Error("Error field of struct",
+			"ERROR", verr,
+			"fields", verr.Fields,
+		)
+		return
+	}
+
+	entry, err := h.PlaylistRepo.MovePlaylistEntry(r.Context(), int64(id), int64(entryID), payload.Position)
+	if err != nil {
+		logger.Error("Error move playlist entry in db",
+			"ERROR", err,
+			"id", id,
+			"entry_id", entryID,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, entry)
+	logger.Info("move playlist entry success", "id", id, "entry_id", entryID)
+}
+
+// @Summary Remove an entry from a playlist
+// @Description Удаляет запись из плейлиста, следующие записи сдвигаются
+// @Tags playlists
+// @Param PLAYLIST_ID path int true "Playlist ID"
+// @Param ENTRY_ID path int true "Entry ID"
+// @Success 204 "Entry deleted"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Playlist or entry not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/playlist/{PLAYLIST_ID}/entry/{ENTRY_ID} [delete]
+func (h *PlaylistHandler) DeletePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, entryID, err := entryPath(r)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	if err = h.PlaylistRepo.DeletePlaylistEntry(r.Context(), int64(id), int64(entryID)); err != nil {
+		logger.Error("Error delete playlist entry from db",
+			"ERROR", err,
+			"id", id,
+			"entry_id", entryID,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info("delete playlist entry success", "id", id, "entry_id", entryID)
+}
+
+// entryPath читает id плейлиста и записи из пути
+func entryPath(r *http.Request) (int, int, error) {
+	id, err := pathID(r, "PLAYLIST_ID")
+	if err != nil {
+		return 0, 0, err
+	}
+	entryID, err := pathID(r, "ENTRY_ID")
+	return id, entryID, err
+}
+
+// validatePlaylistName проверяет длину названия плейлиста, пустое название отклонит хранилище
+func validatePlaylistName(name string) error {
+	verr := &ValidationError{}
+	if utf8.RuneCountInString(storage.NormalizeName(name)) > storage.MaxPlaylistNameLength {
+		verr.Add("name", fmt.Sprintf("must be at most %d characters", storage.MaxPlaylistNameLength))
+	}
+	return verr.Err()
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/storage"
+)
+
+// @Summary Search songs
+// @Description Полнотекстовый поиск по названию, группе и тексту песни, результаты отсортированы по релевантности.
+// @Description Слова запроса ищутся вместе, "фраза в кавычках" - подряд, слово* - по префиксу.
+// @Description snippet - первый подходящий куплет, совпадения выделены тегом <b>.
+// @Tags songs
+// @Produce  json
+// @Param q query string true "Search query"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {array} song.SearchResult "Found songs"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Failure 501 {object} problem.Problem "Search is not supported by storage"
+// @Router /api/songs/search [get]
+func (h *SongHandler) SearchSongs(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
+
+	searchRepo, ok := h.SongRepo.(storage.SearchRepo)
+	if !ok {
+		logger.Error("Storage does not support search")
+		writeError(w, r, ErrSearchNotSupported)
+		return
+	}
+
+	query := r.URL.Query()
+	verr := &ValidationError{}
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		verr.Add("q", "must not be empty")
+	}
+	page := positiveInt(query, "page", 1, verr)
+	limit := limitParam(query, 10, h.MaxPageSize, verr)
+	if err := verr.Err(); err != nil {
+		logger.Error("Error parse query params",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	results, err := searchRepo.SearchSongs(ctx, q, limit, (page-1)*limit)
+	if err != nil {
+		logger.Error("Error search songs in db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(results)
+	if err != nil {
+		logger.Error("Error marshal search results",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	logger.Info("Search songs success", "found", len(results))
+}
@@ -1,21 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
-	"os"
 	"strconv"
 	"strings"
 
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/reqctx"
 	"SongLibrary/pkg/song"
 	"SongLibrary/pkg/storage"
-
-	"github.com/gorilla/mux"
 )
 
 const ApplicationJSON = "application/json"
@@ -23,28 +21,51 @@ const ApplicationJSON = "application/json"
 type SongHandler struct {
 	Logger   *slog.Logger
 	SongRepo storage.SongRepo
+	Enricher enrichment.Client
+	// JobRepo - очередь заполнения, refresh закрывает задачу обновленной песни
+	JobRepo storage.JobRepo
+
+	// ImportRepo и ExportRepo - массовые загрузка и выгрузка песен
+	ImportRepo storage.ImportRepo
+	ExportRepo storage.ExportRepo
+
+	// AsyncEnrichment - песня сохраняется сразу со статусом pending,
+	// а данные из внешнего сервиса заполняет Worker
+	AsyncEnrichment bool
+	Worker          *enrichment.Worker
+
+	// MaxPageSize - наибольший limit в списках, 0 - DefaultMaxPageSize
+	MaxPageSize int
 }
 
 // @Summary Add New Song
-// @Description Добавляет песню в базу. Принимает json с именем группы и песни
+// @Description Добавляет песню в базу. Принимает json с именем группы и песни.
+// @Description Группа сопоставляется с исполнителем без учета регистра и пробелов по краям, нового исполнителя сервис создает сам. album_id - необязательный альбом этого исполнителя.
+// @Description В асинхронном режиме (ENRICHMENT_MODE=async) песня сохраняется сразу со статусом pending, данные из внешнего сервиса заполняются в фоне.
 // @Tags songs
 // @Accept json
 // @Produce json
 // @Param song body song.PayloadSong true "Song Information"
-// @Success 200 {string} string "Add new song success"
-// @Failure 400 {string} string "Bad request"
-// @Failure 500 {string} string "Internal server error"
+// @Success 201 {object} song.Song "Created song"
+// @Success 202 {object} song.Song "Song accepted, enrichment is pending"
+// @Header 201,202 {string} Location "/api/song/{SONG_ID}"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Song not found in external service or album not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Failure 502 {object} problem.Problem "Error from external service"
+// @Failure 503 {object} problem.Problem "External service is unavailable"
 // @Router /api/songs [post]
 func (h *SongHandler) AddNewSong(w http.ResponseWriter, r *http.Request) {
 	logger := h.Logger.With(
-		"request_id", r.Context().Value("requestID"),
+		"request_id", reqctx.RequestID(r.Context()),
 		"url", r.URL.Path,
 		"method", r.Method,
 		"remote_addr", r.RemoteAddr,
 	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
 
 	if r.Header.Get("Content-Type") != ApplicationJSON {
-		http.Error(w, ErrContentType, http.StatusBadRequest)
+		writeError(w, r, ErrContentType)
 		logger.Error("Error of Content-Type",
 			"ERROR", ErrContentType,
 		)
@@ -53,7 +74,7 @@ func (h *SongHandler) AddNewSong(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, ErrParseBody, http.StatusBadRequest)
+		writeError(w, r, ErrParseBody)
 		logger.Error("Error from ReadAll",
 			"ERROR", err,
 		)
@@ -64,199 +85,196 @@ func (h *SongHandler) AddNewSong(w http.ResponseWriter, r *http.Request) {
 	payload := song.PayloadSong{}
 	err = json.Unmarshal(body, &payload)
 	if err != nil {
-		http.Error(w, ErrUnmarshal, http.StatusBadRequest)
+		writeError(w, r, fmt.Errorf("%w: %w", ErrUnmarshal, err))
 		logger.Error("Error of decode json",
 			"ERROR", err,
 		)
 		return
 	}
-	if payload.Group == "" || payload.Song == "" {
-		http.Error(w, ErrFieldEmpty, http.StatusBadRequest)
+	verr := &ValidationError{}
+	if payload.Group == "" {
+		verr.Add("group", "must not be empty")
+	}
+	if payload.Song == "" {
+		verr.Add("song", "must not be empty")
+	}
+	if _, err = storage.NormalizeTags(payload.Tags); err != nil {
+		verr.Add("tags", tagMessage)
+	}
+	if err = verr.Err(); err != nil {
+		writeError(w, r, err)
 		logger.Error("Error field of struct",
-			"ERROR", ErrFieldEmpty,
-			"url", r.URL.Path,
-			"method", r.Method,
-			"remote_addr", r.RemoteAddr,
+			"ERROR", err,
+			"fields", verr.Fields,
 		)
 		return
 	}
 
-	client := http.Client{}
-	host := os.Getenv("EXTERNAL_SERVICE_HOST")
-	port := os.Getenv("EXTERNAL_SERVICE_PORT")
-	addr := host + ":" + port
-
-	path := "info"
-
-	params := url.Values{}
-	params.Add("song", payload.Song)
-	params.Add("group", payload.Group)
-
-	baseURL := url.URL{
-		Scheme: "http",
-		Host:   addr,
-		Path:   path,
+	if h.AsyncEnrichment {
+		h.addPendingSong(ctx, w, r, payload)
+		return
 	}
-	baseURL.RawQuery = params.Encode()
-	logger.Debug("Create new url:", "url", baseURL.String())
 
-	req, err := http.NewRequest("GET", baseURL.String(), nil)
+	respAPI, err := h.Enricher.GetSongInfo(ctx, payload.Group, payload.Song)
 	if err != nil {
-		http.Error(w, ErrInternal, http.StatusInternalServerError)
-		logger.Error("Error create request",
+		logger.Error("Error get song info from external service",
 			"ERROR", err,
 		)
+		writeError(w, r, err)
 		return
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		http.Error(w, ErrExternalService, http.StatusInternalServerError)
-		logger.Error("Error exec request",
-			"ERROR", err,
-		)
-		return
+	resultSong := song.Song{
+		Song:             payload.Song,
+		Group:            payload.Group,
+		AlbumID:          payload.AlbumID,
+		Tags:             payload.Tags,
+		ReleaseDate:      respAPI.ReleaseDate,
+		Text:             respAPI.Text,
+		Link:             respAPI.Link,
+		EnrichmentStatus: song.EnrichmentDone,
 	}
+	logger.Debug("get result song", "song", fmt.Sprintf("%#v", resultSong))
 
-	body, err = io.ReadAll(resp.Body)
+	created, err := h.SongRepo.AddSongToDB(ctx, resultSong)
 	if err != nil {
-		http.Error(w, ErrExternalService, http.StatusInternalServerError)
-		logger.Error("Error read body from external service",
+		writeError(w, r, err)
+		logger.Error("Error add song to db",
 			"ERROR", err,
 		)
 		return
 	}
-	defer resp.Body.Close()
 
-	respAPI := song.ResponseFromExternalAPI{}
-	err = json.Unmarshal(body, &respAPI)
+	writeCreatedSong(w, r, http.StatusCreated, created)
+	logger.Info("add new song", "id", created.SongID)
+}
+
+// addPendingSong сохраняет песню без данных внешнего сервиса и ставит задачу на их заполнение
+func (h *SongHandler) addPendingSong(ctx context.Context, w http.ResponseWriter, r *http.Request, payload song.PayloadSong) {
+	logger := reqctx.Logger(ctx)
+
+	pendingSong := song.Song{
+		Song:             payload.Song,
+		Group:            payload.Group,
+		AlbumID:          payload.AlbumID,
+		Tags:             payload.Tags,
+		EnrichmentStatus: song.EnrichmentPending,
+	}
+
+	created, err := h.SongRepo.AddSongToDB(ctx, pendingSong)
 	if err != nil {
-		http.Error(w, ErrExternalService, http.StatusInternalServerError)
-		logger.Error("Error decode json from external service",
+		logger.Error("Error add song to db",
 			"ERROR", err,
 		)
+		writeError(w, r, err)
 		return
 	}
-
-	resultSong := song.Song{
-		Song:        payload.Song,
-		Group:       payload.Group,
-		ReleaseDate: respAPI.ReleaseDate,
-		Text:        respAPI.Text,
-		Link:        respAPI.Link,
+	if h.Worker != nil {
+		h.Worker.Notify()
 	}
-	logger.Debug("get result song", "song", fmt.Sprintf("%#v", resultSong))
 
-	err = h.SongRepo.AddSongToDB(logger, resultSong)
+	writeCreatedSong(w, r, http.StatusAccepted, created)
+	logger.Info("add new song, enrichment is pending", "id", created.SongID)
+}
+
+// writeCreatedSong отвечает созданной песней со ссылкой на нее в заголовке Location
+func writeCreatedSong(w http.ResponseWriter, r *http.Request, status int, created song.Song) {
+	body, err := json.Marshal(created)
 	if err != nil {
-		if errors.Is(err, storage.ErrorSongExist) {
-			http.Error(w, ErrSongExist, http.StatusBadRequest)
-			logger.Error("Error add song to db",
-				"ERROR", err,
-			)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		logger.Error("Error add song to db",
-			"ERROR", err,
-		)
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("Location", fmt.Sprintf("/api/song/%d", created.SongID))
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Add new song success"))
-	logger.Info("add new song")
+	w.WriteHeader(status)
+	w.Write(body)
 }
 
 // @Summary Get a list of songs
-// @Description Возвращает список песен с пагинацией и фильтрацией. Принимает query параметры.
+// @Description Возвращает страницу списка песен с фильтрацией и сортировкой. Принимает query параметры.
+// @Description Текстовые фильтры сравниваются без учета регистра способом match, песня подходит, если совпала любая из переданных групп.
+// @Description sort - поля через запятую (name, group, release_date, created_at), минус перед полем - по убыванию; по умолчанию песни идут по id.
+// @Description Следующая страница запрашивается параметром cursor со значением next_cursor из ответа, на последней странице next_cursor равен null.
+// @Description Параметр page оставлен для совместимости и не сочетается с cursor.
 // @Tags songs
 // @Produce  json
 // @Param name query string false "Song Name"
-// @Param group query string false "Group Name"
-// @Param date query string false "Release Date"
+// @Param group query []string false "Group Name, repeat the param for any of several groups" collectionFormat(multi)
+// @Param date query string false "Release period: YYYY-MM-DD, YYYY-MM or YYYY, not used with date_from and date_to"
 // @Param text query string false "Text"
 // @Param link query string false "Link"
+// @Param match query string false "Matching of name, group, text and link" Enums(substring, exact, prefix) default(substring)
+// @Param artist_id query int false "Artist ID"
+// @Param album_id query int false "Album ID"
+// @Param tag query []string false "Tag, repeat the param for several tags" collectionFormat(multi)
+// @Param tag_match query string false "Songs with any or all of the tags" Enums(any, all) default(any)
+// @Param date_from query string false "Release date from, inclusive: YYYY-MM-DD or DD.MM.YYYY"
+// @Param date_to query string false "Release date to, inclusive: YYYY-MM-DD or DD.MM.YYYY"
+// @Param sort query string false "Sort fields, e.g. group,-release_date"
+// @Param cursor query string false "Cursor of the next page"
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Number of items per page" default(10)
-// @Success 200 {array} song.Song "List of songs"
-// @Failure 400 {string} string "Bad request"
-// @Failure 404 {string} string "No songs found"
-// @Failure 500 {string} string "Internal server error"
+// @Param limit query int false "Number of items per page, not greater than SERVER_MAX_PAGE_SIZE" default(10)
+// @Param include_total query bool false "Count songs matching the filter" default(false)
+// @Success 200 {object} song.SongList "Page of songs"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/songs [get]
 func (h *SongHandler) GetListOfSongs(w http.ResponseWriter, r *http.Request) {
 	logger := h.Logger.With(
-		"request_id", r.Context().Value("requestID"),
+		"request_id", reqctx.RequestID(r.Context()),
 		"url", r.URL.Path,
 		"method", r.Method,
 		"remote_addr", r.RemoteAddr,
 	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
 
 	query := r.URL.Query()
-	s := song.Song{
-		Song:        query.Get("name"),
-		Group:       query.Get("group"),
-		ReleaseDate: query.Get("date"),
-		Text:        query.Get("text"),
-		Link:        query.Get("link"),
-	}
-
-	pageStr := query.Get("page")
-	limitStr := query.Get("limit")
-
-	page := 1
-	limit := 10
-	if pageStr != "" {
-		p, err := strconv.Atoi(pageStr)
-		if err != nil {
-			http.Error(w, ErrParseQuery, http.StatusBadRequest)
-			logger.Error("Error in Atoi",
-				"ERROR", err,
-			)
-			return
-		}
-		page = p
-	}
-	if limitStr != "" {
-		l, err := strconv.Atoi(limitStr)
-		if err != nil {
-			http.Error(w, ErrParseQuery, http.StatusBadRequest)
-			logger.Error("Error in Atoi",
-				"ERROR", err,
-			)
-			return
-		}
-		limit = l
+	params, err := listParams(query, 10, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse query params",
+			"ERROR", err,
+		)
+		return
 	}
 
-	offset := (page - 1) * limit
-
-	songs, err := h.SongRepo.GetSongsFromDB(logger, s, limit, offset)
+	page, err := h.SongRepo.ListSongs(ctx, params)
 	if err != nil {
 		logger.Error("Error get songs from db",
 			"ERROR", err,
 		)
-		if errors.Is(err, storage.ErrorListOfSongsEmpty) {
-			http.Error(w, ErrSongsNotFound, http.StatusNotFound)
-			return
-		}
-		http.Error(w, ErrInternal, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	body, err := json.Marshal(songs)
+	list := newSongList(page)
+	body, err := json.Marshal(list)
 	if err != nil {
 		logger.Error("Error marshal list songs",
 			"ERROR", err,
 		)
-		http.Error(w, ErrInternal, http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(body)
-	logger.Info("Get list of songs success")
+	logger.Info("Get list of songs success", "count", len(list.Items))
+}
+
+// newSongList - ответ со страницей списка песен
+func newSongList(page storage.SongPage) song.SongList {
+	list := song.SongList{
+		Items: page.Items,
+		Total: page.Total,
+	}
+	if page.Next != nil {
+		next := page.Next.Encode()
+		list.NextCursor = &next
+	}
+	return list
 }
 
 // @Summary Delete a song by ID from the library
@@ -264,47 +282,40 @@ func (h *SongHandler) GetListOfSongs(w http.ResponseWriter, r *http.Request) {
 // @Tags song
 // @Produce  json
 // @Param SONG_ID path int true "Song ID"
-// @Success 200 {string} string "Song deleted successfully"
-// @Failure 400 {string} string "Bad request"
-// @Failure 404 {string} string "Song not found"
-// @Failure 500 {string} string "Internal server error"
+// @Success 204 "Song deleted successfully"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Song not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/song/{SONG_ID} [delete]
 func (h *SongHandler) DeleteSongByID(w http.ResponseWriter, r *http.Request) {
 	logger := h.Logger.With(
-		"request_id", r.Context().Value("requestID"),
+		"request_id", reqctx.RequestID(r.Context()),
 		"url", r.URL.Path,
 		"method", r.Method,
 		"remote_addr", r.RemoteAddr,
 	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
 
-	vars := mux.Vars(r)
-	idStr := vars["SONG_ID"]
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "SONG_ID")
 	if err != nil {
-		http.Error(w, ErrParseQuery, http.StatusBadRequest)
+		writeError(w, r, err)
 		logger.Error("Error in Atoi",
 			"ERROR", err,
 		)
 		return
 	}
 
-	id, err = h.SongRepo.DeleteSongByIDFromDB(logger, id)
+	id, err = h.SongRepo.DeleteSongByIDFromDB(ctx, id)
 	if err != nil {
 		logger.Error("Error delete song from db",
 			"ERROR", err,
 			"id", id,
 		)
-		if errors.Is(err, storage.ErrorSongNotExist) {
-			http.Error(w, ErrSongByIDNotFound, http.StatusNotFound)
-			return
-		}
-		http.Error(w, ErrInternal, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf("delete song by id success, id: %v", id)))
+	w.WriteHeader(http.StatusNoContent)
 	logger.Info("delete song success", "id", id)
 }
 
@@ -316,23 +327,22 @@ func (h *SongHandler) DeleteSongByID(w http.ResponseWriter, r *http.Request) {
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Limit per page" default(2)
 // @Success 200 {array} string "Array of song verses"
-// @Failure 400 {string} string "Bad request"
-// @Failure 404 {string} string "Song not found or invalid verse range"
-// @Failure 500 {string} string "Internal server error"
-// @Router /api/song/{SONG_ID} [get]
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Song not found or invalid verse range"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/song/{SONG_ID}/text [get]
 func (h *SongHandler) GetTextOfSong(w http.ResponseWriter, r *http.Request) {
 	logger := h.Logger.With(
-		"request_id", r.Context().Value("requestID"),
+		"request_id", reqctx.RequestID(r.Context()),
 		"url", r.URL.Path,
 		"method", r.Method,
 		"remote_addr", r.RemoteAddr,
 	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
 
-	vars := mux.Vars(r)
-	idStr := vars["SONG_ID"]
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "SONG_ID")
 	if err != nil {
-		http.Error(w, ErrParseQuery, http.StatusBadRequest)
+		writeError(w, r, err)
 		logger.Error("Error in Atoi",
 			"ERROR", err,
 		)
@@ -340,46 +350,23 @@ func (h *SongHandler) GetTextOfSong(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := r.URL.Query()
-	pageStr := query.Get("page")
-	limitStr := query.Get("limit")
-
-	page := 1
-	limit := 2
-	if pageStr != "" {
-		p, err := strconv.Atoi(pageStr)
-		if err != nil {
-			http.Error(w, ErrParseQuery, http.StatusBadRequest)
-			logger.Error("Error in Atoi",
-				"ERROR", err,
-			)
-			return
-		}
-		page = p
-	}
-	if limitStr != "" {
-		l, err := strconv.Atoi(limitStr)
-		if err != nil {
-			http.Error(w, ErrParseQuery, http.StatusBadRequest)
-			logger.Error("Error in Atoi",
-				"ERROR", err,
-			)
-			return
-		}
-		limit = l
+	page, limit, err := pagination(query, 2, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse pagination",
+			"ERROR", err,
+		)
+		return
 	}
 
 	offset := (page - 1) * limit
-	text, err := h.SongRepo.GetTextOfSongFromDB(logger, id)
+	text, err := h.SongRepo.GetTextOfSongFromDB(ctx, id)
 	if err != nil {
-		logger.Error("Error delete song from db",
+		logger.Error("Error get text of song from db",
 			"ERROR", err,
 			"id", id,
 		)
-		if errors.Is(err, storage.ErrorSongNotExist) {
-			http.Error(w, ErrSongByIDNotFound, http.StatusNotFound)
-			return
-		}
-		http.Error(w, ErrInternal, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -389,7 +376,7 @@ func (h *SongHandler) GetTextOfSong(w http.ResponseWriter, r *http.Request) {
 	start := offset
 	end := offset + limit
 	if start >= totalVerses {
-		http.Error(w, "Error amount of verses", http.StatusNotFound)
+		writeError(w, r, ErrVersesNotFound)
 		logger.Error("Error amount of verses",
 			"ERROR", err,
 			"id", id,
@@ -405,7 +392,8 @@ func (h *SongHandler) GetTextOfSong(w http.ResponseWriter, r *http.Request) {
 		logger.Error("Error marshal text of songs",
 			"ERROR", err,
 		)
-		http.Error(w, ErrInternal, http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -414,31 +402,84 @@ func (h *SongHandler) GetTextOfSong(w http.ResponseWriter, r *http.Request) {
 	logger.Info("get text of song by id success", "id", id)
 }
 
+// @Summary Get a song by ID
+// @Description Возвращает все данные песни по id
+// @Tags song
+// @Produce  json
+// @Param SONG_ID path int true "Song ID"
+// @Success 200 {object} song.Song "Song"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Song not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/song/{SONG_ID} [get]
+func (h *SongHandler) GetSongByID(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
+
+	id, err := pathID(r, "SONG_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	s, err := h.SongRepo.GetSongByID(ctx, id)
+	if err != nil {
+		logger.Error("Error get song from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		logger.Error("Error marshal song",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	logger.Info("get song by id success", "id", id)
+}
+
 // @Summary Update to song by id
 // @Description Обновляет поля по id. Принимает json, который содержит поля для обновления.
+// @Description group меняет исполнителя песни, при этом песня отвязывается от альбома прежнего исполнителя. album_id привязывает песню к альбому ее исполнителя, 0 - отвязывает.
 // @Tags song
 // @Accept json
 // @Produce json
 // @Param SONG_ID path int true "ID of song"
 // @Param song body song.SongForUpdate true "Data for update"
-// @Success 200 {string} string "update song by id success"
-// @Failure 400 {string} string "Invalid request"
-// @Failure 404 {string} string "Song not found"
-// @Failure 500 {string} string "Internal server error"
+// @Success 200 {object} song.Song "Updated song"
+// @Failure 400 {object} problem.Problem "Invalid request"
+// @Failure 404 {object} problem.Problem "Song or album not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/song/{SONG_ID} [put]
 func (h *SongHandler) UpdateSong(w http.ResponseWriter, r *http.Request) {
 	logger := h.Logger.With(
-		"request_id", r.Context().Value("requestID"),
+		"request_id", reqctx.RequestID(r.Context()),
 		"url", r.URL.Path,
 		"method", r.Method,
 		"remote_addr", r.RemoteAddr,
 	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
 
-	vars := mux.Vars(r)
-	idStr := vars["SONG_ID"]
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "SONG_ID")
 	if err != nil {
-		http.Error(w, ErrParseQuery, http.StatusBadRequest)
+		writeError(w, r, err)
 		logger.Error("Error in Atoi",
 			"ERROR", err,
 		)
@@ -447,7 +488,7 @@ func (h *SongHandler) UpdateSong(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, ErrParseBody, http.StatusBadRequest)
+		writeError(w, r, ErrParseBody)
 		logger.Error("Error from ReadAll",
 			"ERROR", err,
 		)
@@ -458,29 +499,134 @@ func (h *SongHandler) UpdateSong(w http.ResponseWriter, r *http.Request) {
 	payload := song.SongForUpdate{}
 	err = json.Unmarshal(body, &payload)
 	if err != nil {
-		http.Error(w, ErrUnmarshal, http.StatusBadRequest)
+		writeError(w, r, fmt.Errorf("%w: %w", ErrUnmarshal, err))
 		logger.Error("Error of decode json",
 			"ERROR", err,
 		)
 		return
 	}
 
-	id, err = h.SongRepo.UpdateSongByID(logger, payload, id)
+	updated, err := h.SongRepo.UpdateSongByID(ctx, payload, id)
 	if err != nil {
 		logger.Error("Error update song in db",
 			"ERROR", err,
 			"id", id,
 		)
-		if errors.Is(err, storage.ErrorSongNotExist) {
-			http.Error(w, ErrSongByIDNotFound, http.StatusNotFound)
+		writeError(w, r, err)
+		return
+	}
+
+	body, err = json.Marshal(updated)
+	if err != nil {
+		logger.Error("Error marshal song",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	logger.Info("update song success", "id", id)
+}
+
+// @Summary Refresh song from external service
+// @Description Заново запрашивает дату выхода, текст и ссылку песни во внешнем сервисе и возвращает отличия от сохраненных данных.
+// @Description Без apply=true изменения только показываются. Чтобы сохранить их, повторите запрос с apply=true
+// @Description и показанными отличиями в теле: если внешний сервис вернул другие данные, ответ 409 и ничего не сохраняется.
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param SONG_ID path int true "Song ID"
+// @Param apply query bool false "Save changes" default(false)
+// @Param input body song.PayloadRefresh false "Confirmed changes, required with apply=true"
+// @Success 200 {object} song.RefreshResult "Per-field diff"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Song not found"
+// @Failure 409 {object} problem.Problem "External service data changed since the diff was shown"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Failure 502 {object} problem.Problem "Error from external service"
+// @Failure 503 {object} problem.Problem "External service is unavailable"
+// @Router /api/song/{SONG_ID}/refresh [post]
+func (h *SongHandler) RefreshSong(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	ctx := reqctx.WithLogger(r.Context(), logger)
+
+	id, err := pathID(r, "SONG_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	apply := false
+	if value := r.URL.Query().Get("apply"); value != "" {
+		apply, err = strconv.ParseBool(value)
+		if err != nil {
+			verr := &ValidationError{}
+			verr.Add("apply", "must be a boolean")
+			writeError(w, r, verr)
+			logger.Error("Error in ParseBool",
+				"ERROR", err,
+			)
 			return
 		}
-		http.Error(w, ErrInternal, http.StatusInternalServerError)
+	}
+
+	refresher := enrichment.NewRefresher(h.Enricher, h.SongRepo, h.JobRepo)
+	var result song.RefreshResult
+	if apply {
+		payload := song.PayloadRefresh{}
+		if err = decodeBody(r, &payload); err != nil {
+			writeError(w, r, err)
+			logger.Error("Error of decode json",
+				"ERROR", err,
+			)
+			return
+		}
+		if payload.Changes == nil {
+			verr := &ValidationError{}
+			verr.Add("changes", "must list the confirmed changes")
+			writeError(w, r, verr)
+			logger.Error("Error refresh without confirmed changes")
+			return
+		}
+		result, err = refresher.Confirm(ctx, id, payload.Changes)
+	} else {
+		result, err = refresher.Refresh(ctx, id, false)
+	}
+	if err != nil {
+		logger.Error("Error refresh song",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Error marshal refresh result",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf("update song by id success, id: %v", id)))
-	logger.Info("delete song success", "id", id)
+	w.Write(body)
+	logger.Info("refresh song success",
+		"id", id,
+		"changes", len(result.Changes),
+		"applied", result.Applied,
+	)
 }
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"SongLibrary/pkg/enrichment"
+	"SongLibrary/pkg/problem"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage/repository/memory"
+)
+
+// fakeEnricher отвечает info или err, а при block ждет окончания контекста запроса
+type fakeEnricher struct {
+	info  song.ResponseFromExternalAPI
+	err   error
+	block bool
+	calls int
+}
+
+func (f *fakeEnricher) GetSongInfo(ctx context.Context, group, name string) (song.ResponseFromExternalAPI, error) {
+	f.calls++
+	if f.block {
+		<-ctx.Done()
+		return song.ResponseFromExternalAPI{}, ctx.Err()
+	}
+	return f.info, f.err
+}
+
+func newTestSongHandler(enricher enrichment.Client) *SongHandler {
+	repo := memory.NewSongMemoryRepository()
+	return &SongHandler{
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		SongRepo: repo,
+		JobRepo:  repo,
+		Enricher: enricher,
+	}
+}
+
+func postSong(t *testing.T, h *SongHandler, ctx context.Context, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/songs", strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", ApplicationJSON)
+	rec := httptest.NewRecorder()
+	h.AddNewSong(rec, req)
+	return rec
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem.Problem {
+	t.Helper()
+
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Fatalf("Content-Type = %q, want %q", ct, problem.ContentType)
+	}
+	p := problem.Problem{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body.String(), err)
+	}
+	return p
+}
+
+func TestAddNewSongEnrichmentErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		enricher *fakeEnricher
+		status   int
+		apiErr   *APIError
+		detail   string
+	}{
+		{"SongNotFound", &fakeEnricher{err: enrichment.ErrorSongNotFound}, http.StatusNotFound, ErrSongNotFoundExternal, ""},
+		{"CircuitOpen", &fakeEnricher{err: enrichment.ErrorCircuitOpen}, http.StatusServiceUnavailable, ErrExternalUnavailable, enrichment.ErrorCircuitOpen.Error()},
+		{"BadResponse", &fakeEnricher{err: fmt.Errorf("%w: unexpected EOF", enrichment.ErrorBadResponse)}, http.StatusBadGateway, ErrExternalService, "bad response from external service: unexpected EOF"},
+		{"Upstream", &fakeEnricher{err: fmt.Errorf("%w: status 500", enrichment.ErrorUpstream)}, http.StatusBadGateway, ErrExternalService, "external service error: status 500"},
+		{"Timeout", &fakeEnricher{block: true}, http.StatusGatewayTimeout, ErrTimeout, context.DeadlineExceeded.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestSongHandler(tt.enricher)
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			rec := postSong(t, h, ctx, `{"group": "Muse", "song": "Uprising"}`)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.status, rec.Body)
+			}
+			p := decodeProblem(t, rec)
+			if p.Type != problem.TypePrefix+tt.apiErr.Code || p.Title != tt.apiErr.Title || p.Status != tt.status {
+				t.Errorf("unexpected problem %+v", p)
+			}
+			if p.Detail != tt.detail {
+				t.Errorf("detail = %q, want %q", p.Detail, tt.detail)
+			}
+			if p.Instance != "/api/songs" {
+				t.Errorf("instance = %q, want /api/songs", p.Instance)
+			}
+			if tt.enricher.calls != 1 {
+				t.Errorf("external service called %d times, want 1", tt.enricher.calls)
+			}
+
+			// песня с ошибкой внешнего сервиса не сохраняется
+			if _, err := h.SongRepo.GetSongByID(context.Background(), 1); err == nil {
+				t.Error("song was saved")
+			}
+		})
+	}
+}
+
+func TestAddNewSongCreated(t *testing.T) {
+	date, err := song.ParseReleaseDate("2009-09-07")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := song.ResponseFromExternalAPI{
+		ReleaseDate: date,
+		Text:        "Paranoia is in bloom",
+		Link:        "https://www.youtube.com/watch?v=w8KQmps-Sog",
+	}
+	h := newTestSongHandler(&fakeEnricher{info: info})
+
+	rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Uprising", "tags": ["rock"]}`)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ApplicationJSON {
+		t.Errorf("Content-Type = %q, want %q", ct, ApplicationJSON)
+	}
+	got := song.Song{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode song %q: %v", rec.Body.String(), err)
+	}
+	if got.SongID == 0 || got.Song != "Uprising" || got.Group != "Muse" || got.ReleaseDate.String() != info.ReleaseDate.String() ||
+		got.Text != info.Text || got.Link != info.Link || got.EnrichmentStatus != song.EnrichmentDone ||
+		len(got.Tags) != 1 || got.Tags[0] != "rock" {
+		t.Errorf("unexpected song %+v", got)
+	}
+	if location := rec.Header().Get("Location"); location != fmt.Sprintf("/api/song/%d", got.SongID) {
+		t.Errorf("Location = %q, want /api/song/%d", location, got.SongID)
+	}
+}
+
+func TestAddNewSongAsync(t *testing.T) {
+	enricher := &fakeEnricher{err: enrichment.ErrorCircuitOpen}
+	h := newTestSongHandler(enricher)
+	h.AsyncEnrichment = true
+
+	rec := postSong(t, h, context.Background(), `{"group": "Muse", "song": "Uprising"}`)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	got := song.Song{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode song %q: %v", rec.Body.String(), err)
+	}
+	if got.SongID == 0 || got.EnrichmentStatus != song.EnrichmentPending || got.Text != "" {
+		t.Errorf("unexpected song %+v", got)
+	}
+	if location := rec.Header().Get("Location"); location != fmt.Sprintf("/api/song/%d", got.SongID) {
+		t.Errorf("Location = %q, want /api/song/%d", location, got.SongID)
+	}
+	// данные заполняет воркер, handler во внешний сервис не ходит
+	if enricher.calls != 0 {
+		t.Errorf("external service called %d times, want 0", enricher.calls)
+	}
+
+	jobs, err := h.JobRepo.GetEnrichmentJobs(context.Background(), song.JobPending, 10, 0)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].SongID != got.SongID {
+		t.Errorf("unexpected jobs %+v", jobs)
+	}
+}
+
+func TestAddNewSongValidation(t *testing.T) {
+	enricher := &fakeEnricher{}
+	h := newTestSongHandler(enricher)
+
+	rec := postSong(t, h, context.Background(), `{"group": "", "song": ""}`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	p := decodeProblem(t, rec)
+	if p.Type != problem.TypePrefix+ErrValidation.Code || len(p.Errors) != 2 ||
+		p.Errors[0].Field != "group" || p.Errors[1].Field != "song" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+	if enricher.calls != 0 {
+		t.Errorf("external service called %d times, want 0", enricher.calls)
+	}
+}
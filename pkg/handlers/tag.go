@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+type TagHandler struct {
+	Logger  *slog.Logger
+	TagRepo storage.TagRepo
+	// MaxPageSize - наибольший limit в списках, 0 - DefaultMaxPageSize
+	MaxPageSize int
+}
+
+// requestLogger - логгер запроса и контекст с ним
+func (h *TagHandler) requestLogger(r *http.Request) (*slog.Logger, *http.Request) {
+	logger := h.Logger.With(
+		"request_id", reqctx.RequestID(r.Context()),
+		"url", r.URL.Path,
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+	)
+	return logger, r.WithContext(reqctx.WithLogger(r.Context(), logger))
+}
+
+// @Summary Get a list of tags
+// @Description Возвращает теги, которые есть хотя бы у одной песни, с числом песен, по убыванию числа песен
+// @Tags tags
+// @Produce json
+// @Param name query string false "Substring of tag"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page" default(10)
+// @Success 200 {object} song.TagList "Page of tags"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/tags [get]
+func (h *TagHandler) GetListOfTags(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	query := r.URL.Query()
+	page, limit, err := pagination(query, 10, h.MaxPageSize)
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error parse pagination",
+			"ERROR", err,
+		)
+		return
+	}
+
+	tags, err := h.TagRepo.ListTags(r.Context(), query.Get("name"), limit, (page-1)*limit)
+	if err != nil {
+		logger.Error("Error get tags from db",
+			"ERROR", err,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, song.TagList{Items: tags})
+	logger.Info("Get list of tags success", "count", len(tags))
+}
+
+// @Summary Add tags to a song
+// @Description Добавляет песне теги. Теги приводятся к нижнему регистру без пробелов по краям, уже добавленные пропускаются.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param SONG_ID path int true "Song ID"
+// @Param tags body song.PayloadTags true "Tags"
+// @Success 200 {object} song.Song "Song with tags"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Song not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/song/{SONG_ID}/tags [post]
+func (h *TagHandler) AddSongTags(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "SONG_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	payload := song.PayloadTags{}
+	if err = decodeBody(r, &payload); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error decode body",
+			"ERROR", err,
+		)
+		return
+	}
+	if err = validateTags("tags", payload.Tags); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error validate tags",
+			"ERROR", err,
+		)
+		return
+	}
+
+	s, err := h.TagRepo.AddSongTags(r.Context(), id, payload.Tags)
+	if err != nil {
+		logger.Error("Error add tags to db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, s)
+	logger.Info("add song tags success", "id", id)
+}
+
+// @Summary Remove tags from a song
+// @Description Убирает у песни теги из параметров tag, теги, которых у песни нет, пропускаются
+// @Tags tags
+// @Produce json
+// @Param SONG_ID path int true "Song ID"
+// @Param tag query []string true "Tag, repeat the param for several tags" collectionFormat(multi)
+// @Success 200 {object} song.Song "Song with tags"
+// @Failure 400 {object} problem.Problem "Bad request"
+// @Failure 404 {object} problem.Problem "Song not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/song/{SONG_ID}/tags [delete]
+func (h *TagHandler) RemoveSongTags(w http.ResponseWriter, r *http.Request) {
+	logger, r := h.requestLogger(r)
+
+	id, err := pathID(r, "SONG_ID")
+	if err != nil {
+		writeError(w, r, err)
+		logger.Error("Error in Atoi",
+			"ERROR", err,
+		)
+		return
+	}
+
+	tags := r.URL.Query()["tag"]
+	if err = validateTags("tag", tags); err != nil {
+		writeError(w, r, err)
+		logger.Error("Error validate tags",
+			"ERROR", err,
+		)
+		return
+	}
+
+	s, err := h.TagRepo.RemoveSongTags(r.Context(), id, tags)
+	if err != nil {
+		logger.Error("Error remove tags from db",
+			"ERROR", err,
+			"id", id,
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, s)
+	logger.Info("remove song tags success", "id", id)
+}
+
+// validateTags проверяет, что тегов не меньше одного и каждый из них допустим
+func validateTags(field string, tags []string) error {
+	verr := &ValidationError{}
+	if len(tags) == 0 {
+		verr.Add(field, "must not be empty")
+	} else if _, err := storage.NormalizeTags(tags); err != nil {
+		verr.Add(field, tagMessage)
+	}
+	return verr.Err()
+}
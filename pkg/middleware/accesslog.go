@@ -1,19 +1,22 @@
 package middleware
 
 import (
-	"context"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"SongLibrary/pkg/reqctx"
+
 	"github.com/google/uuid"
 )
 
 func AccessLog(logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestID := uuid.New().String()
-		ctx := context.WithValue(r.Context(), "requestID", requestID)
+		ctx := reqctx.WithRequestID(r.Context(), requestID)
+		ctx = reqctx.WithLogger(ctx, logger.With("request_id", requestID))
 		r = r.WithContext(ctx)
+		w.Header().Set("X-Request-ID", requestID)
 
 		logger.Info("Get new request",
 			"request_id", requestID,
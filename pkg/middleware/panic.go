@@ -3,14 +3,21 @@ package middleware
 import (
 	"log/slog"
 	"net/http"
+
+	"SongLibrary/pkg/problem"
+	"SongLibrary/pkg/reqctx"
 )
 
 func Panic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				slog.Info("recovered", "request_id", r.Context().Value("requestID"), "error", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				slog.Info("recovered", "request_id", reqctx.RequestID(r.Context()), "error", err)
+				problem.Write(w, r, problem.Problem{
+					Type:   problem.TypePrefix + "internal",
+					Title:  "internal error",
+					Status: http.StatusInternalServerError,
+				})
 			}
 		}()
 
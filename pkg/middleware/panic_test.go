@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"SongLibrary/pkg/problem"
+	"SongLibrary/pkg/reqctx"
+)
+
+func TestPanic(t *testing.T) {
+	handler := Panic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/songs", nil)
+	req = req.WithContext(reqctx.WithRequestID(req.Context(), "req-1"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+	}
+	p := problem.Problem{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	want := problem.Problem{
+		Type:      problem.TypePrefix + "internal",
+		Title:     "internal error",
+		Status:    http.StatusInternalServerError,
+		Instance:  "/api/songs",
+		RequestID: "req-1",
+	}
+	// значение паники клиенту не отдается
+	if p.Type != want.Type || p.Title != want.Title || p.Status != want.Status ||
+		p.Instance != want.Instance || p.RequestID != want.RequestID || p.Detail != "" {
+		t.Errorf("problem = %+v, want %+v", p, want)
+	}
+}
+
+func TestPanicPassesResponse(t *testing.T) {
+	handler := Panic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/song/1", nil))
+
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Errorf("status = %d, body %q, want %d and empty body", rec.Code, rec.Body, http.StatusNoContent)
+	}
+}
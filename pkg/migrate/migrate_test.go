@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"SongLibrary/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dsnEnv - строка подключения к тестовой базе, миграции применяются в отдельной схеме
+const dsnEnv = "POSTGRES_TEST_DSN"
+
+func file(body string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(body)}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"10_tags.up.sql":             file("CREATE TABLE tags ()"),
+		"10_tags.down.sql":           file("DROP TABLE tags"),
+		"0002_artists.up.sql":        file("CREATE TABLE artists ()"),
+		"0001_create_songs.up.sql":   file("CREATE TABLE songs ()"),
+		"0001_create_songs.down.sql": file("DROP TABLE songs"),
+		"migrations.go":              file("package migrations"),
+		"README.md":                  file("# migrations"),
+		"0003_Bad_Name.up.sql":       file("SELECT 1"),
+	}
+
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "create_songs", Up: "CREATE TABLE songs ()", Down: "DROP TABLE songs"},
+		{Version: 2, Name: "artists", Up: "CREATE TABLE artists ()"},
+		{Version: 10, Name: "tags", Up: "CREATE TABLE tags ()", Down: "DROP TABLE tags"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d migrations %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			"NameMismatch",
+			fstest.MapFS{
+				"0001_create_songs.up.sql":   file("CREATE TABLE songs ()"),
+				"0001_create_track.down.sql": file("DROP TABLE songs"),
+			},
+			"different names",
+		},
+		{
+			"MissingUp",
+			fstest.MapFS{
+				"0001_create_songs.up.sql": file("CREATE TABLE songs ()"),
+				"0002_tags.down.sql":       file("DROP TABLE tags"),
+			},
+			"has no up file",
+		},
+		{
+			"DuplicateVersion",
+			fstest.MapFS{
+				"0001_create_songs.up.sql": file("CREATE TABLE songs ()"),
+				"1_create_songs.up.sql":    file("CREATE TABLE songs (id int)"),
+			},
+			"several up files",
+		},
+		{
+			"DuplicateVersionOtherName",
+			fstest.MapFS{
+				"0001_create_songs.up.sql": file("CREATE TABLE songs ()"),
+				"0001_tags.up.sql":         file("CREATE TABLE tags ()"),
+			},
+			"different names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load: got %v, want error with %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err := Load(fstest.MapFS{"README.md": file("# migrations")})
+	if !errors.Is(err, ErrorNoMigrations) {
+		t.Errorf("Load without migrations: got %v, want %v", err, ErrorNoMigrations)
+	}
+}
+
+// TestUpDownUp применяет миграции проекта, откатывает их все и применяет снова
+// на одном соединении, чтобы временные объекты сессии не мешали повторному up
+func TestUpDownUp(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skip(dsnEnv + " is not set")
+	}
+	ctx := context.Background()
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	if _, err = admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	cfg.MaxConns = 1
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	m, err := NewMigrator(pool, migrations.FS, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(m.Migrations)
+
+	checkApplied := func(want int) {
+		t.Helper()
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		applied := 0
+		for _, s := range statuses {
+			if s.Applied {
+				applied++
+			}
+		}
+		if len(statuses) != total || applied != want {
+			t.Fatalf("got %d statuses with %d applied, want %d with %d applied", len(statuses), applied, total, want)
+		}
+	}
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != total {
+		t.Fatalf("Up: applied %d of %d: %v", len(applied), total, err)
+	}
+	checkApplied(total)
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("repeated Up: applied %d: %v", len(applied), err)
+	}
+
+	reverted, err := m.Down(ctx, total)
+	if err != nil || len(reverted) != total {
+		t.Fatalf("Down: reverted %d of %d: %v", len(reverted), total, err)
+	}
+	if reverted[0].Version != m.Migrations[total-1].Version {
+		t.Errorf("Down started from version %d, want %d", reverted[0].Version, m.Migrations[total-1].Version)
+	}
+	checkApplied(0)
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != total {
+		t.Fatalf("Up after Down: applied %d of %d: %v", len(applied), total, err)
+	}
+	checkApplied(total)
+}
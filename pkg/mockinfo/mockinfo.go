@@ -0,0 +1,260 @@
+// Package mockinfo - заглушка внешнего сервиса с информацией о песнях (GET /info).
+// Отвечает данными из фикстур и умеет имитировать сбои: задержку, ответы 500
+// и битый json. Песни, которых нет в фикстурах, получают 404.
+//
+// В тестах сервер поднимается через httptest:
+//
+//	srv := httptest.NewServer(mockinfo.NewServer(mockinfo.DefaultFixtures()))
+//	defer srv.Close()
+package mockinfo
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"SongLibrary/pkg/song"
+)
+
+//go:embed fixtures.json
+var defaultFixtures []byte
+
+// виды сбоев
+const (
+	FailureNone = ""
+	// FailureServerError - ответ 500
+	FailureServerError = "500"
+	// FailureMalformed - ответ 200 с битым json
+	FailureMalformed = "malformed"
+)
+
+// Fixture - песня, о которой знает заглушка
+type Fixture struct {
+	Group string `json:"group"`
+	Song  string `json:"song"`
+	song.ResponseFromExternalAPI
+}
+
+// Mode - режим сбоев. FailureRate - доля запросов, на которые отвечает Failure (0 - все)
+type Mode struct {
+	Latency     time.Duration
+	Failure     string
+	FailureRate float64
+}
+
+// modeJSON - представление Mode в управляющем api, задержка передается строкой вида "500ms"
+type modeJSON struct {
+	Latency     string  `json:"latency"`
+	Failure     string  `json:"failure"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+func (m Mode) Validate() error {
+	switch m.Failure {
+	case FailureNone, FailureServerError, FailureMalformed:
+	default:
+		return fmt.Errorf("unknown failure %q", m.Failure)
+	}
+	if m.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if m.FailureRate < 0 || m.FailureRate > 1 {
+		return fmt.Errorf("failure rate must be in [0, 1]")
+	}
+
+	return nil
+}
+
+// Server отвечает на GET /info?group=&song=, а режим сбоев меняется
+// через SetMode или запросом PUT /mode, текущий режим - GET /mode
+type Server struct {
+	Logger *slog.Logger
+
+	mu    sync.RWMutex
+	songs map[string]song.ResponseFromExternalAPI
+	mode  Mode
+	mux   *http.ServeMux
+}
+
+func NewServer(fixtures []Fixture) *Server {
+	s := &Server{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		songs:  make(map[string]song.ResponseFromExternalAPI, len(fixtures)),
+		mux:    http.NewServeMux(),
+	}
+	for _, f := range fixtures {
+		s.songs[key(f.Group, f.Song)] = f.ResponseFromExternalAPI
+	}
+
+	s.mux.HandleFunc("/info", s.info)
+	s.mux.HandleFunc("/mode", s.handleMode)
+
+	return s
+}
+
+// DefaultFixtures возвращает встроенные фикстуры
+func DefaultFixtures() []Fixture {
+	fixtures, err := parseFixtures(defaultFixtures)
+	if err != nil {
+		panic("mockinfo: bad embedded fixtures: " + err.Error())
+	}
+	return fixtures
+}
+
+// LoadFixtures читает фикстуры из json-файла: массив объектов с полями group, song, releaseDate, text, link
+func LoadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFixtures(data)
+}
+
+func parseFixtures(data []byte) ([]Fixture, error) {
+	fixtures := []Fixture{}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, err
+	}
+	for i, f := range fixtures {
+		if f.Group == "" || f.Song == "" {
+			return nil, fmt.Errorf("fixture %d: empty group or song", i)
+		}
+	}
+	return fixtures, nil
+}
+
+func (s *Server) SetMode(m Mode) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.mode = m
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Server) Mode() Mode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.mode
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) info(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, name := r.URL.Query().Get("group"), r.URL.Query().Get("song")
+	logger := s.Logger.With("group", group, "song", name)
+	mode := s.Mode()
+
+	if mode.Latency > 0 {
+		select {
+		case <-time.After(mode.Latency):
+		case <-r.Context().Done():
+			logger.Info("request canceled during latency")
+			return
+		}
+	}
+
+	if mode.Failure != FailureNone && (mode.FailureRate == 0 || rand.Float64() < mode.FailureRate) {
+		logger.Info("simulate failure", "failure", mode.Failure)
+		switch mode.Failure {
+		case FailureServerError:
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		case FailureMalformed:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"releaseDate": "16.07.2006", "text": `))
+		}
+		return
+	}
+
+	if group == "" || name == "" {
+		http.Error(w, "group and song are required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	info, ok := s.songs[key(group, name)]
+	s.mu.RUnlock()
+	if !ok {
+		logger.Info("song not found")
+		http.Error(w, "song not found", http.StatusNotFound)
+		return
+	}
+
+	// внешний сервис отдает дату в своем формате DD.MM.YYYY
+	body, err := json.Marshal(struct {
+		ReleaseDate string `json:"releaseDate"`
+		Text        string `json:"text"`
+		Link        string `json:"link"`
+	}{info.ReleaseDate.Legacy(), info.Text, info.Link})
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+	logger.Info("song info sent")
+}
+
+func (s *Server) handleMode(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		payload := modeJSON{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "cant decode body", http.StatusBadRequest)
+			return
+		}
+
+		m := Mode{Failure: payload.Failure, FailureRate: payload.FailureRate}
+		if payload.Latency != "" {
+			latency, err := time.ParseDuration(payload.Latency)
+			if err != nil {
+				http.Error(w, "bad latency: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			m.Latency = latency
+		}
+		if err := s.SetMode(m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.Logger.Info("mode changed", "latency", m.Latency, "failure", m.Failure, "failure_rate", m.FailureRate)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m := s.Mode()
+	body, _ := json.Marshal(modeJSON{
+		Latency:     m.Latency.String(),
+		Failure:     m.Failure,
+		FailureRate: m.FailureRate,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// key - ключ поиска песни: без учета регистра и пробелов по краям
+func key(group, name string) string {
+	return strings.ToLower(strings.TrimSpace(group)) + "\x00" + strings.ToLower(strings.TrimSpace(name))
+}
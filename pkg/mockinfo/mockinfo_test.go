@@ -0,0 +1,176 @@
+package mockinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultFixtures(t *testing.T) {
+	fixtures := DefaultFixtures()
+	if len(fixtures) == 0 {
+		t.Fatal("no embedded fixtures")
+	}
+	for _, f := range fixtures {
+		if f.ReleaseDate.IsZero() || f.Text == "" || f.Link == "" {
+			t.Errorf("incomplete fixture %+v", f)
+		}
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		wantErr bool
+	}{
+		{"Valid", `[{"group": "Muse", "song": "Uprising", "releaseDate": "07.09.2009", "text": "Paranoia is in bloom", "link": "https://example.com"}]`, 1, false},
+		{"Empty", `[]`, 0, false},
+		{"EmptySong", `[{"group": "Muse", "song": ""}]`, 0, true},
+		{"BadJSON", `[{"group": "Muse"`, 0, true},
+		{"BadDate", `[{"group": "Muse", "song": "Uprising", "releaseDate": "31.02.2009"}]`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fixtures.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			fixtures, err := LoadFixtures(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFixtures: got error %v, want error %v", err, tt.wantErr)
+			}
+			if len(fixtures) != tt.want {
+				t.Errorf("got %d fixtures, want %d", len(fixtures), tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadFixtures(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadFixtures of missing file: got no error")
+	}
+}
+
+func getInfo(t *testing.T, srv *httptest.Server, group, name string) *http.Response {
+	t.Helper()
+
+	params := url.Values{"group": {group}, "song": {name}}
+	resp, err := srv.Client().Get(srv.URL + "/info?" + params.Encode())
+	if err != nil {
+		t.Fatalf("GET /info: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func putMode(t *testing.T, srv *httptest.Server, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/mode", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("PUT /mode: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestInfo(t *testing.T) {
+	srv := httptest.NewServer(NewServer(DefaultFixtures()))
+	defer srv.Close()
+
+	resp := getInfo(t, srv, " muse ", "UPRISING")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	info := map[string]string{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("decode info: %v", err)
+	}
+	// дата в формате внешнего сервиса
+	if info["releaseDate"] != "07.09.2009" || info["text"] == "" || info["link"] == "" {
+		t.Errorf("unexpected info %v", info)
+	}
+
+	if resp := getInfo(t, srv, "Muse", "Unknown"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown song: status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if resp := getInfo(t, srv, "", "Uprising"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("empty group: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestPutMode(t *testing.T) {
+	server := NewServer(DefaultFixtures())
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	resp := putMode(t, srv, `{"failure": "500"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT /mode: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if m := server.Mode(); m.Failure != FailureServerError {
+		t.Errorf("mode = %+v, want failure %q", m, FailureServerError)
+	}
+	if resp := getInfo(t, srv, "Muse", "Uprising"); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("failure 500: status = %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+
+	putMode(t, srv, `{"failure": "malformed"}`)
+	resp = getInfo(t, srv, "Muse", "Uprising")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failure malformed: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&map[string]string{}); err == nil {
+		t.Error("failure malformed: body is valid json")
+	}
+
+	putMode(t, srv, `{"latency": "50ms"}`)
+	start := time.Now()
+	if resp := getInfo(t, srv, "Muse", "Uprising"); resp.StatusCode != http.StatusOK {
+		t.Errorf("latency: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("latency: response in %v, want at least 50ms", elapsed)
+	}
+
+	// режим сбрасывается пустым телом
+	putMode(t, srv, `{}`)
+	if resp := getInfo(t, srv, "Muse", "Uprising"); resp.StatusCode != http.StatusOK {
+		t.Errorf("reset mode: status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	for _, body := range []string{`{"failure": "timeout"}`, `{"latency": "soon"}`, `{"latency": "-1s"}`, `{"failure_rate": 2}`, `{`} {
+		if resp := putMode(t, srv, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("PUT /mode %s: status = %d, want %d", body, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+	if m := server.Mode(); m != (Mode{}) {
+		t.Errorf("mode changed by bad request: %+v", m)
+	}
+
+	resp, err := srv.Client().Get(srv.URL + "/mode")
+	if err != nil {
+		t.Fatalf("GET /mode: %v", err)
+	}
+	defer resp.Body.Close()
+	got := modeJSON{}
+	if err = json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode mode: %v", err)
+	}
+	if got.Failure != FailureNone || got.Latency != "0s" {
+		t.Errorf("unexpected mode %+v", got)
+	}
+}
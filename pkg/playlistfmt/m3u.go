@@ -0,0 +1,87 @@
+package playlistfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"SongLibrary/pkg/song"
+)
+
+// artistSeparator разделяет исполнителя и название в строке #EXTINF
+const artistSeparator = " - "
+
+// m3uWriter пишет расширенный M3U в UTF-8. В M3U трек - это строка с адресом,
+// поэтому треки без Location пропускаются.
+type m3uWriter struct {
+	w *bufio.Writer
+}
+
+func newM3UWriter(w io.Writer, title string) (*m3uWriter, error) {
+	m := &m3uWriter{w: bufio.NewWriter(w)}
+	_, err := m.w.WriteString("#EXTM3U\n")
+	if err == nil && title != "" {
+		_, err = fmt.Fprintf(m.w, "#PLAYLIST:%s\n", oneLine(title))
+	}
+	return m, err
+}
+
+func (m *m3uWriter) WriteTrack(t song.Track) error {
+	if t.Location == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(m.w, "#EXTINF:-1,%s%s%s\n%s\n", oneLine(t.Group), artistSeparator, oneLine(t.Song), oneLine(t.Location))
+	return err
+}
+
+func (m *m3uWriter) Close() error {
+	return m.w.Flush()
+}
+
+// oneLine заменяет переводы строк пробелами, чтобы значение не сломало строку файла
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// readM3U читает адреса треков и их подписи из #EXTINF в виде "исполнитель - название".
+// Подпись без разделителя считается названием без исполнителя.
+func readM3U(r io.Reader) ([]song.Track, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	tracks := []song.Track{}
+	next := song.Track{}
+	first := true
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if first {
+			line, first = bytes.TrimPrefix(line, bom), false
+		}
+		text := strings.TrimSpace(string(line))
+
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "#EXTINF:"):
+			// #EXTINF:<длительность> <атрибуты>,<подпись>
+			_, title, ok := strings.Cut(text, ",")
+			if !ok {
+				return nil, fmt.Errorf("%w: line %q", ErrBadFile, text)
+			}
+			next = song.Track{Song: strings.TrimSpace(title)}
+			if group, name, ok := strings.Cut(next.Song, artistSeparator); ok {
+				next.Group, next.Song = strings.TrimSpace(group), strings.TrimSpace(name)
+			}
+		case strings.HasPrefix(text, "#"):
+		default:
+			next.Location = text
+			tracks = append(tracks, next)
+			next = song.Track{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadFile, err)
+	}
+	return tracks, nil
+}
@@ -0,0 +1,87 @@
+// Package playlistfmt - файлы плейлистов для плееров: M3U8 и XSPF
+package playlistfmt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+
+	"SongLibrary/pkg/song"
+)
+
+// форматы файлов
+const (
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+)
+
+// типы содержимого форматов
+const (
+	MediaTypeM3U8 = "application/vnd.apple.mpegurl"
+	MediaTypeXSPF = "application/xspf+xml"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown playlist format")
+	ErrBadFile       = errors.New("bad playlist file")
+)
+
+// Writer пишет треки в файл по одному, Close дописывает конец файла
+type Writer interface {
+	WriteTrack(t song.Track) error
+	Close() error
+}
+
+// NewWriter начинает файл формата format с названием title
+func NewWriter(w io.Writer, format, title string) (Writer, error) {
+	switch format {
+	case FormatM3U8:
+		return newM3UWriter(w, title)
+	case FormatXSPF:
+		return newXSPFWriter(w, title)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// MediaType - тип содержимого формата
+func MediaType(format string) string {
+	if format == FormatXSPF {
+		return MediaTypeXSPF
+	}
+	return MediaTypeM3U8
+}
+
+// Read читает треки файла формата format. Ошибки разбора оборачивают ErrBadFile.
+func Read(r io.Reader, format string) ([]song.Track, error) {
+	switch format {
+	case FormatM3U8:
+		return readM3U(r)
+	case FormatXSPF:
+		return readXSPF(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Detect определяет формат файла по типу содержимого, а если тип ничего не говорит -
+// по началу файла: XSPF - это XML, все остальное читается как M3U
+func Detect(contentType string, data []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case MediaTypeXSPF, "application/xml", "text/xml":
+		return FormatXSPF
+	case MediaTypeM3U8, "application/x-mpegurl", "audio/mpegurl", "audio/x-mpegurl":
+		return FormatM3U8
+	}
+
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, bom), " \t\r\n")
+	if bytes.HasPrefix(data, []byte("<")) {
+		return FormatXSPF
+	}
+	return FormatM3U8
+}
+
+// bom - метка порядка байтов UTF-8, с которой некоторые программы начинают файлы
+var bom = []byte("\xef\xbb\xbf")
@@ -0,0 +1,85 @@
+package playlistfmt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"SongLibrary/pkg/song"
+)
+
+// xspfNamespace - пространство имен XSPF версии 1
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+type xspfTrack struct {
+	Location string `xml:"location,omitempty"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Title   string      `xml:"title"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+// xspfWriter пишет XSPF по трекам, не собирая весь документ в памяти
+type xspfWriter struct {
+	enc *xml.Encoder
+}
+
+var (
+	playlistElement  = xml.StartElement{Name: xml.Name{Local: "playlist"}, Attr: []xml.Attr{{Name: xml.Name{Local: "version"}, Value: "1"}, {Name: xml.Name{Local: "xmlns"}, Value: xspfNamespace}}}
+	trackListElement = xml.StartElement{Name: xml.Name{Local: "trackList"}}
+)
+
+func newXSPFWriter(w io.Writer, title string) (*xspfWriter, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+
+	x := &xspfWriter{enc: xml.NewEncoder(w)}
+	x.enc.Indent("", "  ")
+	if err := x.enc.EncodeToken(playlistElement); err != nil {
+		return nil, err
+	}
+	if title != "" {
+		if err := x.enc.EncodeElement(title, xml.StartElement{Name: xml.Name{Local: "title"}}); err != nil {
+			return nil, err
+		}
+	}
+	return x, x.enc.EncodeToken(trackListElement)
+}
+
+func (x *xspfWriter) WriteTrack(t song.Track) error {
+	return x.enc.EncodeElement(xspfTrack{Location: t.Location, Title: t.Song, Creator: t.Group}, xml.StartElement{Name: xml.Name{Local: "track"}})
+}
+
+func (x *xspfWriter) Close() error {
+	if err := x.enc.EncodeToken(trackListElement.End()); err != nil {
+		return err
+	}
+	if err := x.enc.EncodeToken(playlistElement.End()); err != nil {
+		return err
+	}
+	return x.enc.Close()
+}
+
+// readXSPF читает треки: creator - исполнитель, title - название, location - адрес
+func readXSPF(r io.Reader) ([]song.Track, error) {
+	doc := xspfPlaylist{}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadFile, err)
+	}
+
+	tracks := make([]song.Track, 0, len(doc.Tracks))
+	for _, t := range doc.Tracks {
+		tracks = append(tracks, song.Track{
+			Group:    strings.TrimSpace(t.Creator),
+			Song:     strings.TrimSpace(t.Title),
+			Location: strings.TrimSpace(t.Location),
+		})
+	}
+	return tracks, nil
+}
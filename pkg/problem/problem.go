@@ -0,0 +1,51 @@
+// Package problem - ответы об ошибках в формате problem details (RFC 7807)
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"SongLibrary/pkg/reqctx"
+)
+
+const ContentType = "application/problem+json"
+
+// TypePrefix - префикс URI типа ошибки, тип однозначно определяет вид ошибки для клиента
+const TypePrefix = "urn:song-library:problem:"
+
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError - ошибка валидации одного поля тела или query параметра запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Write отправляет p клиенту, дополняя его адресом и id запроса
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = reqctx.RequestID(r.Context())
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, p.Title, p.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
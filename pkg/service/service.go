@@ -3,72 +3,131 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
-	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"SongLibrary/migrations"
+	"SongLibrary/pkg/config"
+	"SongLibrary/pkg/enrichment"
 	"SongLibrary/pkg/handlers"
+	"SongLibrary/pkg/migrate"
+	"SongLibrary/pkg/storage"
+	"SongLibrary/pkg/storage/repository/memory"
 	"SongLibrary/pkg/storage/repository/postgres"
+	"SongLibrary/pkg/storage/repository/sqlite"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Service struct {
+	Config      *config.Config
+	Repo        storage.Repository
 	SongHandler *handlers.SongHandler
+	Worker      *enrichment.Worker
 	Mux         http.Handler
 }
 
-func NewService(logger *slog.Logger) (*Service, error) {
-	pool, err := postgres.NewConnPostgres(logger)
+func NewService(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*Service, error) {
+	repo, err := NewSongRepo(ctx, cfg, logger)
 	if err != nil {
-		logger.Error("error create coonect to db:",
+		logger.Error("error create song repository:",
 			"error", err,
 		)
 		return nil, err
 	}
-
-	songRepo := postgres.NewSongPostgresRepository(pool)
 	logger.Info("new db repository create success")
 
+	enricher := enrichment.NewHTTPClient(cfg.External)
+
+	var worker *enrichment.Worker
+	if cfg.Enrichment.Workers > 0 {
+		worker = enrichment.NewWorker(enricher, repo, cfg.Enrichment, logger)
+	}
+
 	songHandler := &handlers.SongHandler{
-		SongRepo: songRepo,
-		Logger:   logger,
+		SongRepo:        repo,
+		JobRepo:         repo,
+		ImportRepo:      repo,
+		ExportRepo:      repo,
+		Logger:          logger,
+		Enricher:        enricher,
+		AsyncEnrichment: cfg.Enrichment.Mode == config.EnrichmentAsync,
+		Worker:          worker,
+		MaxPageSize:     cfg.Server.MaxPageSize,
 	}
 	logger.Info("song handler create success")
 
-	mux := handlers.NewMuxServer(songHandler, logger)
+	jobHandler := &handlers.JobHandler{
+		JobRepo:     repo,
+		Logger:      logger,
+		MaxPageSize: cfg.Server.MaxPageSize,
+	}
+
+	artistHandler := &handlers.ArtistHandler{
+		ArtistRepo:  repo,
+		SongRepo:    repo,
+		Logger:      logger,
+		MaxPageSize: cfg.Server.MaxPageSize,
+	}
+
+	tagHandler := &handlers.TagHandler{
+		TagRepo:     repo,
+		Logger:      logger,
+		MaxPageSize: cfg.Server.MaxPageSize,
+	}
+
+	playlistHandler := &handlers.PlaylistHandler{
+		PlaylistRepo: repo,
+		SongRepo:     repo,
+		Logger:       logger,
+		MaxPageSize:  cfg.Server.MaxPageSize,
+	}
+
+	mux := handlers.NewMuxServer(songHandler, jobHandler, artistHandler, tagHandler, playlistHandler, logger)
 	logger.Info("create new router success")
 
 	return &Service{
+		Config:      cfg,
+		Repo:        repo,
 		SongHandler: songHandler,
+		Worker:      worker,
 		Mux:         mux,
 	}, nil
 }
 
 func (s *Service) Start(ctx context.Context, logger *slog.Logger) error {
-	host := os.Getenv("SERVER_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
-	}
-	addr := net.JoinHostPort(host, port)
+	addr := s.Config.Server.Addr()
 	logger.Debug("get result addr", "addr", addr)
 
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// контекст всех запросов: отменяется, если запросы не успели завершиться за время shutdown
+	baseCtx, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+
 	srv := &http.Server{
 		Addr:         addr,
 		Handler:      s.Mux,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  s.Config.Server.ReadTimeout,
+		WriteTimeout: s.Config.Server.WriteTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		if s.Worker != nil {
+			s.Worker.Run(ctx)
+		}
+	}()
+
 	go func() {
 		logger.Info("server start")
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -78,17 +137,65 @@ func (s *Service) Start(ctx context.Context, logger *slog.Logger) error {
 
 	<-ctx.Done()
 
-	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.Config.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error("error shutdown server:", "error", err)
+		cancelRequests()
 		return err
 	}
 	logger.Info("server stopped success")
 
-	s.SongHandler.SongRepo.Close()
+	<-workerDone
+
+	s.Repo.Close()
 	logger.Info("db pool success closed")
 
 	return nil
 }
+
+// NewSongRepo создает хранилище, выбранное в cfg.Storage.Driver
+func NewSongRepo(ctx context.Context, cfg *config.Config, logger *slog.Logger) (storage.Repository, error) {
+	logger.Info("storage driver", "driver", cfg.Storage.Driver)
+
+	switch cfg.Storage.Driver {
+	case config.DriverPostgres:
+		pool, err := postgres.NewConnPostgres(ctx, cfg.Postgres, logger)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Postgres.MigrateOnStart {
+			if err = migrateUp(ctx, pool, logger); err != nil {
+				pool.Close()
+				return nil, err
+			}
+		}
+		return postgres.NewSongPostgresRepository(pool, cfg.Storage.Timeouts), nil
+	case config.DriverMemory:
+		return memory.NewSongMemoryRepository(), nil
+	case config.DriverSQLite:
+		db, err := sqlite.NewConnSQLite(ctx, cfg.SQLite.Path, logger)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.NewSongSQLiteRepository(db, cfg.Storage.Timeouts), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+func migrateUp(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) error {
+	m, err := migrate.NewMigrator(pool, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info("db migrations applied", "count", len(applied))
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package song
+
+import "time"
+
+// Artist - исполнитель (группа). Имена исполнителей уникальны без учета регистра
+// и пробелов по краям, песня ссылается на исполнителя по ArtistID.
+type Artist struct {
+	ArtistID  int64     `json:"artist_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PayloadArtist struct {
+	Name string `json:"name"`
+}
+
+// ArtistList - страница списка исполнителей
+type ArtistList struct {
+	Items []Artist `json:"items"`
+}
+
+// Album - альбом исполнителя, название уникально у исполнителя без учета регистра
+type Album struct {
+	AlbumID     int64       `json:"album_id"`
+	ArtistID    int64       `json:"artist_id"`
+	Title       string      `json:"title"`
+	ReleaseDate ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2006-07"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+type PayloadAlbum struct {
+	ArtistID    int64       `json:"artist_id"`
+	Title       string      `json:"title"`
+	ReleaseDate ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2006-07"`
+}
+
+type AlbumForUpdate struct {
+	Title       *string      `json:"title"`
+	ReleaseDate *ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2006-07"`
+}
+
+// AlbumList - страница списка альбомов
+type AlbumList struct {
+	Items []Album `json:"items"`
+}
@@ -0,0 +1,143 @@
+package song
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// точность даты выпуска
+const (
+	PrecisionDay   = "day"
+	PrecisionMonth = "month"
+	PrecisionYear  = "year"
+)
+
+// ReleaseDate - дата выпуска с точностью до дня, месяца или года.
+// Для месяца и года Date - первый день периода. Нулевое значение - дата неизвестна.
+// В json передается строкой ISO 8601 ("2006-07-16", "2006-07", "2006"),
+// при разборе принимается и формат внешнего сервиса ("16.07.2006").
+type ReleaseDate struct {
+	Date      time.Time
+	Precision string
+}
+
+// releaseDateFormats - поддерживаемые форматы дат: регулярное выражение, layout для time.Parse и точность
+var releaseDateFormats = []struct {
+	re        *regexp.Regexp
+	layout    string
+	precision string
+}{
+	{regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`), "2006-01-02", PrecisionDay},
+	{regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T`), time.RFC3339, PrecisionDay},
+	{regexp.MustCompile(`^\d{4}-\d{2}$`), "2006-01", PrecisionMonth},
+	{regexp.MustCompile(`^\d{4}$`), "2006", PrecisionYear},
+	{regexp.MustCompile(`^\d{1,2}\.\d{1,2}\.\d{4}$`), "2.1.2006", PrecisionDay},
+	{regexp.MustCompile(`^\d{1,2}\.\d{4}$`), "1.2006", PrecisionMonth},
+}
+
+// ParseReleaseDate разбирает дату в формате ISO 8601 (YYYY-MM-DD, YYYY-MM, YYYY)
+// или в формате внешнего сервиса (DD.MM.YYYY, MM.YYYY). Пустая строка - неизвестная дата.
+func ParseReleaseDate(value string) (ReleaseDate, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ReleaseDate{}, nil
+	}
+
+	for _, f := range releaseDateFormats {
+		if !f.re.MatchString(value) {
+			continue
+		}
+		t, err := time.Parse(f.layout, value)
+		if err != nil {
+			return ReleaseDate{}, fmt.Errorf("bad release date %q: %w", value, err)
+		}
+		return NewReleaseDate(t, f.precision), nil
+	}
+
+	return ReleaseDate{}, fmt.Errorf("bad release date %q: want YYYY-MM-DD, YYYY-MM, YYYY or DD.MM.YYYY", value)
+}
+
+// NewReleaseDate обрезает t до начала периода точности precision
+func NewReleaseDate(t time.Time, precision string) ReleaseDate {
+	year, month, day := t.Date()
+	switch precision {
+	case PrecisionYear:
+		month, day = time.January, 1
+	case PrecisionMonth:
+		day = 1
+	default:
+		precision = PrecisionDay
+	}
+	return ReleaseDate{Date: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Precision: precision}
+}
+
+func (d ReleaseDate) IsZero() bool {
+	return d.Date.IsZero()
+}
+
+// End - последний день периода даты
+func (d ReleaseDate) End() time.Time {
+	switch d.Precision {
+	case PrecisionYear:
+		return d.Date.AddDate(1, 0, -1)
+	case PrecisionMonth:
+		return d.Date.AddDate(0, 1, -1)
+	default:
+		return d.Date
+	}
+}
+
+// String возвращает дату в ISO 8601 с учетом точности, пустую строку для неизвестной даты
+func (d ReleaseDate) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	switch d.Precision {
+	case PrecisionYear:
+		return d.Date.Format("2006")
+	case PrecisionMonth:
+		return d.Date.Format("2006-01")
+	default:
+		return d.Date.Format("2006-01-02")
+	}
+}
+
+// Legacy возвращает дату в формате внешнего сервиса: DD.MM.YYYY, MM.YYYY или YYYY
+func (d ReleaseDate) Legacy() string {
+	if d.IsZero() {
+		return ""
+	}
+	switch d.Precision {
+	case PrecisionYear:
+		return d.Date.Format("2006")
+	case PrecisionMonth:
+		return d.Date.Format("01.2006")
+	default:
+		return d.Date.Format("02.01.2006")
+	}
+}
+
+func (d ReleaseDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *ReleaseDate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = ReleaseDate{}
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("release date must be a string: %w", err)
+	}
+	parsed, err := ParseReleaseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
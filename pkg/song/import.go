@@ -0,0 +1,50 @@
+package song
+
+// статусы строки массового импорта песен
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ImportRow - результат одной строки файла импорта. Line - номер строки файла,
+// для CSV - строки, с которой начинается запись, для JSON - номер элемента массива. SongID - id созданной, обновленной
+// или пропущенной песни, Error - причина ошибки для ImportFailed
+type ImportRow struct {
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+	SongID int64  `json:"song_id,omitempty"`
+	Group  string `json:"group,omitempty"`
+	Song   string `json:"song,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport - итог массового импорта: число строк каждого статуса и результаты строк
+type ImportReport struct {
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// Add учитывает результат строки и добавляет его в Rows
+func (r *ImportReport) Add(row ImportRow) {
+	r.Count(row)
+	r.Rows = append(r.Rows, row)
+}
+
+// Count учитывает результат строки только в счетчиках
+func (r *ImportReport) Count(row ImportRow) {
+	switch row.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportSkipped:
+		r.Skipped++
+	default:
+		r.Failed++
+	}
+}
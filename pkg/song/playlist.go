@@ -0,0 +1,80 @@
+package song
+
+import "time"
+
+// Playlist - плейлист. Size - число песен в нем.
+// Если AllowDuplicates false, песня может быть в плейлисте только один раз.
+type Playlist struct {
+	PlaylistID      int64     `json:"playlist_id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	AllowDuplicates bool      `json:"allow_duplicates"`
+	Size            int64     `json:"size"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type PayloadPlaylist struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	AllowDuplicates bool   `json:"allow_duplicates"`
+}
+
+type PlaylistForUpdate struct {
+	Name            *string `json:"name"`
+	Description     *string `json:"description"`
+	AllowDuplicates *bool   `json:"allow_duplicates"`
+}
+
+// PlaylistList - страница списка плейлистов
+type PlaylistList struct {
+	Items []Playlist `json:"items"`
+}
+
+// PlaylistEntry - песня в плейлисте. Position - место в плейлисте, начиная с 1;
+// после удаления записи или песни следующие записи сдвигаются.
+type PlaylistEntry struct {
+	EntryID    int64     `json:"entry_id"`
+	PlaylistID int64     `json:"playlist_id"`
+	Position   int       `json:"position"`
+	Song       Song      `json:"song"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+// PayloadPlaylistEntry - песня, которая добавляется в плейлист.
+// Position - место новой записи, 0 - в конец
+type PayloadPlaylistEntry struct {
+	SongID   int64 `json:"song_id"`
+	Position int   `json:"position,omitempty"`
+}
+
+// PayloadMoveEntry - новое место записи в плейлисте
+type PayloadMoveEntry struct {
+	Position int `json:"position"`
+}
+
+// PayloadReorder - новый порядок всех записей плейлиста
+type PayloadReorder struct {
+	EntryIDs []int64 `json:"entry_ids"`
+}
+
+// PlaylistEntryList - страница записей плейлиста
+type PlaylistEntryList struct {
+	Items []PlaylistEntry `json:"items"`
+}
+
+// Track - трек файла плейлиста (M3U8, XSPF): исполнитель, название и адрес
+type Track struct {
+	Group    string `json:"group"`
+	Song     string `json:"song"`
+	Location string `json:"location,omitempty"`
+}
+
+// PlaylistImport - итог импорта файла в плейлист. Added - число добавленных песен,
+// Skipped - треки, чьи песни уже есть в плейлисте без повторов,
+// Unmatched - треки, для которых нет песни с такими группой и названием
+type PlaylistImport struct {
+	Added     int     `json:"added"`
+	Skipped   []Track `json:"skipped"`
+	Unmatched []Track `json:"unmatched"`
+}
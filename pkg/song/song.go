@@ -1,29 +1,117 @@
 package song
 
+import "time"
+
+// статусы заполнения песни данными из внешнего сервиса
+const (
+	EnrichmentPending = "pending"
+	EnrichmentDone    = "done"
+	EnrichmentFailed  = "failed"
+)
+
+// Song - песня. Group - имя исполнителя ArtistID в том виде, в котором оно сохранено у исполнителя.
+// AlbumID равен 0, если песня не привязана к альбому. Tags - теги песни по алфавиту.
 type Song struct {
-	SongID      int64  `json:"song_id"`
-	Song        string `json:"song"`
-	Group       string `json:"group"`
-	ReleaseDate string `json:"releaseDate"`
-	Text        string `json:"text"`
-	Link        string `json:"link"`
+	SongID           int64       `json:"song_id"`
+	Song             string      `json:"song"`
+	Group            string      `json:"group"`
+	ArtistID         int64       `json:"artist_id"`
+	AlbumID          int64       `json:"album_id,omitempty"`
+	ReleaseDate      ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2006-07-16"`
+	Text             string      `json:"text"`
+	Link             string      `json:"link"`
+	Tags             []string    `json:"tags"`
+	EnrichmentStatus string      `json:"enrichment_status"`
+	CreatedAt        time.Time   `json:"created_at"`
+}
+
+// SearchResult - песня, найденная полнотекстовым поиском.
+// Snippet - куплет с совпадением, найденные слова обернуты в <b></b>
+type SearchResult struct {
+	Song
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SongList - страница списка песен. NextCursor передается в параметре cursor
+// для получения следующей страницы, на последней странице он null.
+// Total - число песен под фильтром, только при include_total=true
+type SongList struct {
+	Items      []Song  `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+	Total      *int64  `json:"total,omitempty"`
 }
 
 type PayloadSong struct {
 	Song  string `json:"song"`
 	Group string `json:"group"`
+	// AlbumID - необязательный альбом того же исполнителя
+	AlbumID int64 `json:"album_id,omitempty"`
+	// Tags - необязательные теги песни
+	Tags []string `json:"tags,omitempty"`
 }
 
+// ResponseFromExternalAPI - ответ внешнего сервиса, дата выпуска приводится к ReleaseDate при разборе
 type ResponseFromExternalAPI struct {
-	ReleaseDate string `json:"releaseDate"`
-	Text        string `json:"text"`
-	Link        string `json:"link"`
+	ReleaseDate ReleaseDate `json:"releaseDate" swaggertype:"string"`
+	Text        string      `json:"text"`
+	Link        string      `json:"link"`
 }
 
 type SongForUpdate struct {
-	Song        *string `json:"song"`
-	Group       *string `json:"group"`
-	ReleaseDate *string `json:"releaseDate"`
-	Text        *string `json:"text"`
-	Link        *string `json:"link"`
+	Song        *string      `json:"song"`
+	Group       *string      `json:"group"`
+	ReleaseDate *ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2006-07-16"`
+	Text        *string      `json:"text"`
+	Link        *string      `json:"link"`
+	// AlbumID - альбом исполнителя песни, 0 отвязывает песню от альбома
+	AlbumID *int64 `json:"album_id"`
+
+	// EnrichmentStatus меняется только сервером, через API его не передать
+	EnrichmentStatus *string `json:"-"`
+}
+
+// FieldChange - изменение одного поля песни, Field совпадает с именем поля в json
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// PayloadRefresh - изменения, которые пользователь видел и подтвердил, тело POST /api/song/{id}/refresh?apply=true
+type PayloadRefresh struct {
+	Changes []FieldChange `json:"changes"`
+}
+
+// RefreshResult - сравнение сохраненной песни с актуальными данными внешнего сервиса
+type RefreshResult struct {
+	SongID  int64         `json:"song_id"`
+	Song    string        `json:"song"`
+	Group   string        `json:"group"`
+	Changes []FieldChange `json:"changes"`
+	Applied bool          `json:"applied"`
+}
+
+// статусы задачи на заполнение песни данными из внешнего сервиса
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	// JobDead - задача, которая исчерпала попытки или получила неисправимую ошибку
+	JobDead = "dead"
+)
+
+type EnrichmentJob struct {
+	JobID     int64     `json:"job_id"`
+	SongID    int64     `json:"song_id"`
+	Song      string    `json:"song"`
+	Group     string    `json:"group"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	RunAt     time.Time `json:"run_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Lease - токен воркера, который забрал задачу, с ним сохраняется ее результат
+	Lease string `json:"-"`
 }
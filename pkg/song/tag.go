@@ -0,0 +1,17 @@
+package song
+
+// Tag - тег и число песен с ним
+type Tag struct {
+	Name  string `json:"name"`
+	Songs int64  `json:"songs"`
+}
+
+// TagList - страница списка тегов
+type TagList struct {
+	Items []Tag `json:"items"`
+}
+
+// PayloadTags - теги, которые добавляются песне
+type PayloadTags struct {
+	Tags []string `json:"tags"`
+}
@@ -0,0 +1,132 @@
+// Package songexport - выгрузка песен файлами, которые снова читает songimport
+package songexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/songimport"
+)
+
+// типы содержимого форматов
+const (
+	MediaTypeJSONL = "application/x-ndjson"
+	MediaTypeCSV   = "text/csv; charset=utf-8"
+	MediaTypeJSON  = "application/json"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Writer пишет песни в файл по одной, Close дописывает конец файла
+type Writer interface {
+	WriteSong(s song.Song) error
+	Close() error
+}
+
+// NewWriter начинает файл формата songimport.FormatJSONL, FormatCSV или FormatJSON
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case songimport.FormatJSONL:
+		return &jsonWriter{w: bufio.NewWriter(w)}, nil
+	case songimport.FormatJSON:
+		j := &jsonWriter{w: bufio.NewWriter(w), array: true}
+		_, err := j.w.WriteString("[")
+		return j, err
+	case songimport.FormatCSV:
+		return newCSVWriter(w)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// MediaType - тип содержимого формата
+func MediaType(format string) string {
+	switch format {
+	case songimport.FormatCSV:
+		return MediaTypeCSV
+	case songimport.FormatJSON:
+		return MediaTypeJSON
+	default:
+		return MediaTypeJSONL
+	}
+}
+
+// jsonWriter пишет песни в том виде, в котором их отдает api: объект на строку,
+// а для массива json - через запятую между скобками
+type jsonWriter struct {
+	w     *bufio.Writer
+	array bool
+	count int
+}
+
+func (j *jsonWriter) WriteSong(s song.Song) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if j.array && j.count > 0 {
+		if err = j.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	j.count++
+	if _, err = j.w.Write(data); err != nil {
+		return err
+	}
+	return j.w.WriteByte('\n')
+}
+
+func (j *jsonWriter) Close() error {
+	if j.array {
+		if _, err := j.w.WriteString("]\n"); err != nil {
+			return err
+		}
+	}
+	return j.w.Flush()
+}
+
+// csvColumns - колонки файла CSV. Импорт читает все, кроме song_id, artist_id и created_at:
+// они нужны, чтобы файл можно было сопоставить с каталогом
+var csvColumns = []string{"song_id", "group", "song", "releaseDate", "text", "link", "tags", "artist_id", "album_id", "enrichment_status", "created_at"}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(csvColumns))}
+	return c, c.w.Write(csvColumns)
+}
+
+func (c *csvWriter) WriteSong(s song.Song) error {
+	albumID := ""
+	if s.AlbumID != 0 {
+		albumID = strconv.FormatInt(s.AlbumID, 10)
+	}
+	c.record = append(c.record[:0],
+		strconv.FormatInt(s.SongID, 10),
+		s.Group,
+		s.Song,
+		s.ReleaseDate.String(),
+		s.Text,
+		s.Link,
+		songimport.FormatTags(s.Tags),
+		strconv.FormatInt(s.ArtistID, 10),
+		albumID,
+		s.EnrichmentStatus,
+		s.CreatedAt.Format(time.RFC3339Nano),
+	)
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
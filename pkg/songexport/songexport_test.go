@@ -0,0 +1,129 @@
+package songexport_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/songexport"
+	"SongLibrary/pkg/songimport"
+	"SongLibrary/pkg/storage"
+	"SongLibrary/pkg/storage/repository/memory"
+)
+
+// newCatalog возвращает хранилище с исполнителем и альбомом, id которых совпадают в каждом новом хранилище
+func newCatalog(t *testing.T) (*memory.SongMemoryRepository, song.Album) {
+	t.Helper()
+	ctx := context.Background()
+	repo := memory.NewSongMemoryRepository()
+
+	muse, err := repo.AddArtist(ctx, "Muse")
+	if err != nil {
+		t.Fatalf("AddArtist: %v", err)
+	}
+	album, err := repo.AddAlbum(ctx, song.Album{ArtistID: muse.ArtistID, Title: "The Resistance"})
+	if err != nil {
+		t.Fatalf("AddAlbum: %v", err)
+	}
+	return repo, album
+}
+
+func releaseDate(t *testing.T, value string) song.ReleaseDate {
+	t.Helper()
+	d, err := song.ParseReleaseDate(value)
+	if err != nil {
+		t.Fatalf("ParseReleaseDate(%q): %v", value, err)
+	}
+	return d
+}
+
+func export(t *testing.T, repo storage.ExportRepo, format string) ([]byte, []song.Song) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w, err := songexport.NewWriter(buf, format)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", format, err)
+	}
+	songs := []song.Song{}
+	err = repo.ExportSongs(context.Background(), storage.ListParams{}, func(s song.Song) error {
+		songs = append(songs, s)
+		return w.WriteSong(s)
+	})
+	if err != nil {
+		t.Fatalf("ExportSongs: %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes(), songs
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source, album := newCatalog(t)
+	songs := []song.Song{
+		{
+			Group: "Muse", Song: "Uprising", AlbumID: album.AlbumID,
+			ReleaseDate: releaseDate(t, "2009-09-07"), Text: "Paranoia is in bloom,\n\"the PR\", transmissions will resume",
+			Link: "https://example.com/uprising", Tags: []string{"rock", "a;b"}, EnrichmentStatus: song.EnrichmentDone,
+		},
+		{Group: "Muse", Song: " Hysteria ", ReleaseDate: releaseDate(t, "2003"), EnrichmentStatus: song.EnrichmentPending},
+		{Group: "Queen", Song: "Innuendo", Text: "While the sun hangs in the sky", EnrichmentStatus: song.EnrichmentFailed},
+		{
+			Group: "Queen", Song: "The Show Must Go On", ReleaseDate: releaseDate(t, "1991-10"),
+			Text: strings.Repeat("The show must go on\n", 100000), Link: "https://example.com/show", EnrichmentStatus: song.EnrichmentDone,
+		},
+	}
+	for _, s := range songs {
+		if _, err := source.AddSongToDB(ctx, s); err != nil {
+			t.Fatalf("AddSongToDB(%q): %v", s.Song, err)
+		}
+	}
+
+	for _, format := range []string{songimport.FormatJSONL, songimport.FormatCSV, songimport.FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			data, want := export(t, source, format)
+
+			target, _ := newCatalog(t)
+			reader, err := songimport.NewReader(bytes.NewReader(data), format)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			err = songimport.NewImporter(target).Import(ctx, reader, storage.ImportSkip, func(row song.ImportRow) error {
+				if row.Status != song.ImportCreated {
+					t.Errorf("line %d: status %s, error %q", row.Line, row.Status, row.Error)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+
+			_, got := export(t, target, format)
+			if len(got) != len(want) {
+				t.Fatalf("got %d songs, want %d", len(got), len(want))
+			}
+			for i := range got {
+				// время добавления новые песни получают заново
+				got[i].CreatedAt = want[i].CreatedAt
+				if !reflect.DeepEqual(got[i], want[i]) {
+					got[i].Text, want[i].Text = shorten(got[i].Text), shorten(want[i].Text)
+					t.Errorf("song %d:\n got %+v\nwant %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// shorten обрезает длинный текст песни для сообщения об ошибке
+func shorten(text string) string {
+	if len(text) > 100 {
+		return fmt.Sprintf("%s... (%d bytes)", text[:100], len(text))
+	}
+	return text
+}
@@ -0,0 +1,246 @@
+package songimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxLineSize - наибольший размер строки JSON Lines. Длина текста песни в api не ограничена,
+// поэтому строка может занимать весь файл, который принимает POST /api/songs/import
+const MaxLineSize = 256 << 20
+
+// TagSeparator разделяет теги в колонке tags файла CSV
+const TagSeparator = ";"
+
+// FormatTags - значение колонки tags файла CSV. Если тег содержит TagSeparator
+// или значение начинается с "[", теги пишутся массивом json, чтобы прочитаться без потерь
+func FormatTags(tags []string) string {
+	value := strings.Join(tags, TagSeparator)
+	if strings.HasPrefix(value, "[") || strings.Count(value, TagSeparator) != max(len(tags)-1, 0) {
+		data, _ := json.Marshal(tags)
+		return string(data)
+	}
+	return value
+}
+
+// parseTags читает колонку tags, записанную FormatTags
+func parseTags(value string) ([]string, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		tags := []string{}
+		if err := json.Unmarshal([]byte(value), &tags); err != nil {
+			return nil, fmt.Errorf("bad tags: %w", err)
+		}
+		return tags, nil
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(value, TagSeparator) {
+		if strings.TrimSpace(tag) != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// Reader читает записи файла по одной
+type Reader interface {
+	// Read возвращает следующую запись и номер строки файла, с которой она начинается.
+	// Ошибка разбора одной строки оборачивает ErrBadRow, после нее можно читать дальше,
+	// остальные ошибки оборачивают ErrBadFile. В конце файла возвращается io.EOF.
+	Read() (Record, int, error)
+}
+
+// NewReader начинает чтение файла формата format. Заголовок CSV читается сразу.
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSON:
+		return newJSONReader(r), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// bom - метка порядка байтов UTF-8, с которой некоторые программы начинают файлы
+var bom = []byte("\xef\xbb\xbf")
+
+// jsonlReader читает по объекту json в строке, пустые строки пропускаются.
+// Поля, которых нет в Record, не учитываются, поэтому читаются и файлы экспорта с id песен
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, MaxLineSize)
+	return &jsonlReader{scanner: scanner}
+}
+
+func (j *jsonlReader) Read() (Record, int, error) {
+	for j.scanner.Scan() {
+		j.line++
+		data := j.scanner.Bytes()
+		if j.line == 1 {
+			data = bytes.TrimPrefix(data, bom)
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		rec := Record{}
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return Record{}, j.line, fmt.Errorf("%w: %w", ErrBadRow, err)
+		}
+		return rec, j.line, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return Record{}, j.line + 1, fmt.Errorf("%w: line %d: %w", ErrBadFile, j.line+1, err)
+	}
+	return Record{}, j.line, io.EOF
+}
+
+// csvReader читает CSV с заголовком. Колонки group и song обязательны, releaseDate (или release_date),
+// text, link, tags, album_id и enrichment_status - нет, остальные колонки не учитываются
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// csvColumns - имена колонок заголовка без учета регистра и поле Record, в которое они читаются
+var csvColumns = map[string]string{
+	"group":             "group",
+	"song":              "song",
+	"releasedate":       "releaseDate",
+	"release_date":      "releaseDate",
+	"text":              "text",
+	"link":              "link",
+	"tags":              "tags",
+	"album_id":          "album_id",
+	"enrichment_status": "enrichment_status",
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	c := &csvReader{r: csv.NewReader(r), columns: make(map[string]int)}
+	c.r.ReuseRecord = true
+
+	header, err := c.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: empty file", ErrBadFile)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrBadFile, err)
+	}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, string(bom))
+		}
+		if field, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, repeated := c.columns[field]; repeated {
+				return nil, fmt.Errorf("%w: repeated column %q", ErrBadFile, name)
+			}
+			c.columns[field] = i
+		}
+	}
+	if _, ok := c.columns["group"]; !ok {
+		return nil, fmt.Errorf("%w: header has no group column", ErrBadFile)
+	}
+	if _, ok := c.columns["song"]; !ok {
+		return nil, fmt.Errorf("%w: header has no song column", ErrBadFile)
+	}
+	return c, nil
+}
+
+func (c *csvReader) Read() (Record, int, error) {
+	fields, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return Record{}, 0, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			return Record{}, parseErr.StartLine, fmt.Errorf("%w: %w", ErrBadRow, parseErr.Err)
+		}
+		return Record{}, 0, fmt.Errorf("%w: %w", ErrBadFile, err)
+	}
+	line, _ := c.r.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok {
+			return fields[i]
+		}
+		return ""
+	}
+	rec := Record{
+		Group:            field("group"),
+		Song:             field("song"),
+		ReleaseDate:      field("releaseDate"),
+		Text:             field("text"),
+		Link:             field("link"),
+		EnrichmentStatus: field("enrichment_status"),
+	}
+	tags, err := parseTags(field("tags"))
+	if err != nil {
+		return rec, line, fmt.Errorf("%w: %w", ErrBadRow, err)
+	}
+	rec.Tags = tags
+	if albumID := strings.TrimSpace(field("album_id")); albumID != "" {
+		if rec.AlbumID, err = strconv.ParseInt(albumID, 10, 64); err != nil {
+			return rec, line, fmt.Errorf("%w: bad album_id %q", ErrBadRow, albumID)
+		}
+	}
+	return rec, line, nil
+}
+
+// jsonReader читает массив json из объектов как в JSON Lines.
+// Номер строки для него - номер элемента массива
+type jsonReader struct {
+	dec     *json.Decoder
+	started bool
+	n       int
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	buffered := bufio.NewReader(r)
+	if prefix, err := buffered.Peek(len(bom)); err == nil && bytes.Equal(prefix, bom) {
+		buffered.Discard(len(bom))
+	}
+	return &jsonReader{dec: json.NewDecoder(buffered)}
+}
+
+func (j *jsonReader) Read() (Record, int, error) {
+	if !j.started {
+		j.started = true
+		token, err := j.dec.Token()
+		if err != nil {
+			return Record{}, 0, fmt.Errorf("%w: %w", ErrBadFile, err)
+		}
+		if token != json.Delim('[') {
+			return Record{}, 0, fmt.Errorf("%w: want json array", ErrBadFile)
+		}
+	}
+	if !j.dec.More() {
+		return Record{}, j.n, io.EOF
+	}
+
+	j.n++
+	rec := Record{}
+	if err := j.dec.Decode(&rec); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Record{}, j.n, fmt.Errorf("%w: %w", ErrBadRow, err)
+		}
+		return Record{}, j.n, fmt.Errorf("%w: element %d: %w", ErrBadFile, j.n, err)
+	}
+	return rec, j.n, nil
+}
@@ -0,0 +1,192 @@
+// Package songimport - массовый импорт песен из файлов JSON Lines, CSV и JSON
+package songimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// форматы файлов
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+)
+
+// DefaultBatchSize - сколько песен по умолчанию сохраняется одной транзакцией
+const DefaultBatchSize = 500
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrBadFile       = errors.New("bad import file")
+	// ErrBadRow - строку файла не удалось разобрать, остальные строки читаются дальше
+	ErrBadRow = errors.New("bad import row")
+)
+
+// Record - строка файла импорта. Обязательны только группа и название.
+// Без статуса заполнения он определяется по дате выпуска, тексту и ссылке
+type Record struct {
+	Group            string   `json:"group"`
+	Song             string   `json:"song"`
+	ReleaseDate      string   `json:"releaseDate"`
+	Text             string   `json:"text"`
+	Link             string   `json:"link"`
+	Tags             []string `json:"tags"`
+	AlbumID          int64    `json:"album_id"`
+	EnrichmentStatus string   `json:"enrichment_status"`
+}
+
+// ToSong проверяет запись и приводит ее к песне. Группа и название сохраняются как есть, как в POST /api/songs.
+// Песня без статуса заполнения, у которой есть дата выпуска, текст и ссылка, сохраняется заполненной,
+// иначе остальное дозаполнит внешний сервис.
+func (rec Record) ToSong() (song.Song, error) {
+	s := song.Song{
+		Group:            rec.Group,
+		Song:             rec.Song,
+		Text:             rec.Text,
+		Link:             strings.TrimSpace(rec.Link),
+		AlbumID:          rec.AlbumID,
+		EnrichmentStatus: rec.EnrichmentStatus,
+	}
+
+	problems := []string{}
+	if strings.TrimSpace(s.Group) == "" || utf8.RuneCountInString(s.Group) > storage.MaxSongNameLength {
+		problems = append(problems, fmt.Sprintf("group must be from 1 to %d characters", storage.MaxSongNameLength))
+	}
+	if strings.TrimSpace(s.Song) == "" || utf8.RuneCountInString(s.Song) > storage.MaxSongNameLength {
+		problems = append(problems, fmt.Sprintf("song must be from 1 to %d characters", storage.MaxSongNameLength))
+	}
+	if utf8.RuneCountInString(s.Link) > storage.MaxLinkLength {
+		problems = append(problems, fmt.Sprintf("link must be at most %d characters", storage.MaxLinkLength))
+	}
+	if s.AlbumID < 0 {
+		problems = append(problems, "album_id must be positive")
+	}
+	switch s.EnrichmentStatus {
+	case "", song.EnrichmentPending, song.EnrichmentDone, song.EnrichmentFailed:
+	default:
+		problems = append(problems, "enrichment_status must be pending, done or failed")
+	}
+
+	var err error
+	if s.ReleaseDate, err = song.ParseReleaseDate(rec.ReleaseDate); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if s.Tags, err = storage.NormalizeTags(rec.Tags); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return song.Song{}, errors.New(strings.Join(problems, "; "))
+	}
+
+	if s.EnrichmentStatus == "" {
+		s.EnrichmentStatus = song.EnrichmentPending
+		if !s.ReleaseDate.IsZero() && s.Text != "" && s.Link != "" {
+			s.EnrichmentStatus = song.EnrichmentDone
+		}
+	}
+	return s, nil
+}
+
+// Importer сохраняет песни файла пачками по BatchSize
+type Importer struct {
+	Repo      storage.ImportRepo
+	BatchSize int
+}
+
+func NewImporter(repo storage.ImportRepo) *Importer {
+	return &Importer{
+		Repo:      repo,
+		BatchSize: DefaultBatchSize,
+	}
+}
+
+// row - строка файла, ждущая сохранения своей пачки
+type row struct {
+	result song.ImportRow
+	// batch - номер песни строки в пачке, -1 для строки с ошибкой
+	batch int
+}
+
+// Import читает файл и сохраняет песни по политике policy (storage.ImportSkip или storage.ImportUpsert).
+// Для каждой строки в порядке файла вызывается report, ошибка report останавливает импорт.
+// Если песня повторяет песню еще не сохраненной пачки, пачка сохраняется раньше, поэтому
+// повтор обрабатывается как песня, которая уже есть в библиотеке.
+// Ошибка чтения файла или хранилища останавливает импорт, уже сохраненные пачки остаются.
+func (im *Importer) Import(ctx context.Context, r Reader, policy string, report func(song.ImportRow) error) error {
+	batchSize := im.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	rows := []row{}
+	batch := []song.Song{}
+	keys := make(map[string]bool)
+
+	flush := func() error {
+		if len(batch) > 0 {
+			results, err := im.Repo.ImportSongs(ctx, batch, policy)
+			if err != nil {
+				return err
+			}
+			for i := range rows {
+				if rows[i].batch >= 0 {
+					line := rows[i].result.Line
+					rows[i].result = results[rows[i].batch]
+					rows[i].result.Line = line
+				}
+			}
+		}
+		for _, row := range rows {
+			if err := report(row.result); err != nil {
+				return err
+			}
+		}
+		rows, batch = rows[:0], batch[:0]
+		clear(keys)
+		return nil
+	}
+
+	for {
+		rec, line, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, ErrBadRow) {
+			return err
+		}
+		if len(rows) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		var s song.Song
+		if err == nil {
+			s, err = rec.ToSong()
+		}
+		if err != nil {
+			rows = append(rows, row{result: song.ImportRow{Line: line, Status: song.ImportFailed, Group: rec.Group, Song: rec.Song, Error: err.Error()}, batch: -1})
+			continue
+		}
+
+		key := storage.ImportKey(s)
+		if keys[key] {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+		keys[key] = true
+		rows = append(rows, row{result: song.ImportRow{Line: line}, batch: len(batch)})
+		batch = append(batch, s)
+	}
+
+	return flush()
+}
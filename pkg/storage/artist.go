@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"SongLibrary/pkg/song"
+)
+
+// ArtistRepo - исполнители и их альбомы.
+// AddSongToDB и UpdateSongByID находят исполнителя песни по имени группы (см. NameKey)
+// или создают нового, поэтому "Muse" и "muse " - один исполнитель.
+type ArtistRepo interface {
+	AddArtist(ctx context.Context, name string) (song.Artist, error)
+	GetArtistByID(ctx context.Context, id int64) (song.Artist, error)
+	// ListArtists возвращает исполнителей по имени, name - подстрока имени без учета регистра
+	ListArtists(ctx context.Context, name string, limit int, offset int) ([]song.Artist, error)
+	// RenameArtist меняет имя исполнителя и группу всех его песен
+	RenameArtist(ctx context.Context, id int64, name string) (song.Artist, error)
+	// DeleteArtist удаляет исполнителя вместе с альбомами, исполнителя с песнями удалить нельзя
+	DeleteArtist(ctx context.Context, id int64) error
+
+	AddAlbum(ctx context.Context, album song.Album) (song.Album, error)
+	GetAlbumByID(ctx context.Context, id int64) (song.Album, error)
+	// ListAlbums возвращает альбомы исполнителя по дате выпуска, альбомы без даты идут первыми
+	ListAlbums(ctx context.Context, artistID int64, limit int, offset int) ([]song.Album, error)
+	UpdateAlbum(ctx context.Context, id int64, album song.AlbumForUpdate) (song.Album, error)
+	// DeleteAlbum удаляет альбом, его песни остаются без альбома
+	DeleteAlbum(ctx context.Context, id int64) error
+}
+
+// NormalizeName убирает пробелы по краям имени исполнителя или названия альбома
+func NormalizeName(name string) string {
+	return strings.TrimSpace(name)
+}
+
+// NameKey - ключ уникальности имени исполнителя и названия альбома:
+// имена с одинаковым ключом считаются одинаковыми
+func NameKey(name string) string {
+	return strings.ToLower(NormalizeName(name))
+}
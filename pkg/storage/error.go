@@ -4,7 +4,28 @@ import "fmt"
 
 // кастомные ошибки
 var (
-	ErrorSongExist        = fmt.Errorf("song with this ID exist")
-	ErrorSongNotExist     = fmt.Errorf("song not exist")
-	ErrorListOfSongsEmpty = fmt.Errorf("list of songs empty")
+	ErrorSongExist          = fmt.Errorf("song with this ID exist")
+	ErrorSongNotExist       = fmt.Errorf("song not exist")
+	ErrorListOfSongsEmpty   = fmt.Errorf("list of songs empty")
+	ErrorNoFieldsToUpdate   = fmt.Errorf("no fields to update")
+	ErrorNegativePaginator  = fmt.Errorf("limit and offset must not be negative")
+	ErrorJobNotExist        = fmt.Errorf("enrichment job not exist")
+	ErrorJobNotDead         = fmt.Errorf("enrichment job is not in dead letter")
+	ErrorLeaseLost          = fmt.Errorf("enrichment job lease is lost")
+	ErrorEmptySearchQuery   = fmt.Errorf("search query is empty")
+	ErrorBadCursor          = fmt.Errorf("cursor is invalid")
+	ErrorBadSort            = fmt.Errorf("unknown or repeated sort field")
+	ErrorEmptyName          = fmt.Errorf("name or title is empty")
+	ErrorArtistExist        = fmt.Errorf("artist with this name exist")
+	ErrorArtistNotExist     = fmt.Errorf("artist not exist")
+	ErrorArtistHasSongs     = fmt.Errorf("artist has songs")
+	ErrorAlbumExist         = fmt.Errorf("album with this title exist")
+	ErrorAlbumNotExist      = fmt.Errorf("album not exist")
+	ErrorAlbumOfOtherArtist = fmt.Errorf("album belongs to another artist")
+	ErrorInvalidTag         = fmt.Errorf("tag must be from 1 to 50 characters")
+	ErrorPlaylistNotExist   = fmt.Errorf("playlist not exist")
+	ErrorEntryNotExist      = fmt.Errorf("playlist entry not exist")
+	ErrorSongInPlaylist     = fmt.Errorf("song is already in playlist")
+	ErrorPlaylistHasRepeats = fmt.Errorf("playlist has repeated songs")
+	ErrorBadReorder         = fmt.Errorf("entry ids must list every entry of the playlist once")
 )
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+
+	"SongLibrary/pkg/song"
+)
+
+// ExportBatchSize - сколько песен выгрузка читает из хранилища за раз
+const ExportBatchSize = 500
+
+// ExportRepo - выгрузка всего каталога
+type ExportRepo interface {
+	// ExportSongs вызывает fn для каждой песни под params.Filter в порядке params.Sort,
+	// не загружая в память больше ExportBatchSize песен. Limit, After, Offset и WithTotal
+	// не учитываются. Ошибка fn останавливает выгрузку и возвращается как есть.
+	ExportSongs(ctx context.Context, params ListParams, fn func(song.Song) error) error
+}
+
+// ExportPages выгружает песни страницами ListSongs по курсору. Подходит хранилищам
+// без серверных курсоров: между страницами чтение не держит соединение,
+// а песни, добавленные во время выгрузки, могут в нее попасть.
+func ExportPages(ctx context.Context, repo SongRepo, params ListParams, fn func(song.Song) error) error {
+	params = ListParams{Filter: params.Filter, Sort: params.Sort, Limit: ExportBatchSize}
+	for {
+		page, err := repo.ListSongs(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, s := range page.Items {
+			if err = fn(s); err != nil {
+				return err
+			}
+		}
+		if page.Next == nil {
+			return nil
+		}
+		params.After = page.Next
+	}
+}
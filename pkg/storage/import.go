@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"SongLibrary/pkg/song"
+)
+
+// политики для песен файла импорта, которые уже есть в библиотеке
+const (
+	ImportSkip   = "skip"
+	ImportUpsert = "upsert"
+)
+
+// ImportRepo - массовая загрузка песен
+type ImportRepo interface {
+	// ImportSongs сохраняет пачку песен одной транзакцией и возвращает результат каждой песни
+	// в порядке songs (song.ImportCreated, song.ImportUpdated или song.ImportSkipped).
+	// Песня, у исполнителя которой уже есть песня с тем же названием, пропускается (ImportSkip)
+	// или получает дату выпуска, текст, ссылку, альбом и теги из файла (ImportUpsert, см. MergeImport).
+	// Песня, альбома которой нет или он принадлежит другому исполнителю, не сохраняется
+	// и получает song.ImportFailed с причиной в Error, остальные песни пачки сохраняются.
+	// Новые песни со статусом song.EnrichmentPending получают задачу на заполнение.
+	// Песни пачки уже приведены и проверены, в пачке нет двух песен с одним ImportKey.
+	ImportSongs(ctx context.Context, songs []song.Song, policy string) ([]song.ImportRow, error)
+}
+
+// IsAlbumError - альбом песни не найден или принадлежит другому исполнителю
+func IsAlbumError(err error) bool {
+	return errors.Is(err, ErrorAlbumNotExist) || errors.Is(err, ErrorAlbumOfOtherArtist)
+}
+
+// ImportKey - ключ песни при поиске повторов: исполнитель по NameKey и точное название
+func ImportKey(s song.Song) string {
+	return NameKey(s.Group) + "\x00" + s.Song
+}
+
+// MergeImport переносит в существующую песню непустые дату выпуска, текст, ссылку и альбом песни из файла
+// и добавляет ее теги. Остальные поля, в том числе статус заполнения, не меняются.
+func MergeImport(existing, s song.Song) song.Song {
+	if !s.ReleaseDate.IsZero() {
+		existing.ReleaseDate = s.ReleaseDate
+	}
+	if s.Text != "" {
+		existing.Text = s.Text
+	}
+	if s.Link != "" {
+		existing.Link = s.Link
+	}
+	if s.AlbumID != 0 {
+		existing.AlbumID = s.AlbumID
+	}
+	if len(s.Tags) > 0 {
+		tags := append(slices.Clone(existing.Tags), s.Tags...)
+		slices.Sort(tags)
+		existing.Tags = slices.Compact(tags)
+	}
+	return existing
+}
+
+// наибольшая длина полей песни в символах, как в схеме базы
+const (
+	MaxSongNameLength = 100
+	MaxLinkLength     = 300
+)
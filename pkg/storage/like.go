@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MatchILike повторяет семантику ILIKE из PostgreSQL: '%' - любая последовательность символов,
+// '_' - ровно один символ, '\' экранирует следующий символ, регистр не учитывается.
+// Сравнение итеративное и занимает O(len(value)*len(pattern)) при любом числе '%'
+func MatchILike(value, pattern string) bool {
+	return matchLike([]rune(value), []rune(pattern))
+}
+
+func matchLike(value, pattern []rune) bool {
+	v, p := 0, 0
+	// позиция после последнего '%' в шаблоне и символ значения, с которого он начал совпадать
+	star, starValue := -1, 0
+	for v < len(value) {
+		if p < len(pattern) {
+			switch c := pattern[p]; {
+			case c == '%':
+				star, starValue = p+1, v
+				p++
+				continue
+			case c == '_':
+				v++
+				p++
+				continue
+			default:
+				next := p + 1
+				if c == '\\' && next < len(pattern) {
+					c = pattern[next]
+					next++
+				}
+				if unicode.ToLower(value[v]) == unicode.ToLower(c) {
+					v++
+					p = next
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// последний '%' забирает еще один символ значения
+		starValue++
+		v, p = starValue, star
+	}
+	for p < len(pattern) && pattern[p] == '%' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// EscapeLike экранирует '%', '_' и '\', чтобы значение совпадало в ILIKE только само с собой
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchILike(t *testing.T) {
+	cases := []struct {
+		value, pattern string
+		want           bool
+	}{
+		{"Muse", "muse", true},
+		{"Muse", "mus", false},
+		{"Muse", "%", true},
+		{"", "%", true},
+		{"", "_", false},
+		{"", "", true},
+		{"Muse", "%us%", true},
+		{"Muse", "M_se", true},
+		{"Muse", "M__se", false},
+		{"Supermassive Black Hole", "super%hole", true},
+		{"Supermassive Black Hole", "%black", false},
+		{"abcabc", "%abc", true},
+		{"abcab", "%abc", false},
+		{"aaa", "%a%a%a%", true},
+		{"aa", "%a%a%a%", false},
+		{"100%", `100\%`, true},
+		{"1000", `100\%`, false},
+		{"a_b", `a\_b`, true},
+		{"axb", `a\_b`, false},
+		{`a\b`, `a\\b`, true},
+		{"Ёлка", "ёл%", true},
+	}
+	for _, c := range cases {
+		if got := MatchILike(c.value, c.pattern); got != c.want {
+			t.Errorf("MatchILike(%q, %q) = %v, want %v", c.value, c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestMatchILikeManyWildcards(t *testing.T) {
+	value := strings.Repeat("a", 10000)
+	pattern := strings.Repeat("%a", 50) + "%b"
+
+	start := time.Now()
+	if MatchILike(value, pattern) {
+		t.Fatal("pattern must not match")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("MatchILike took %s", elapsed)
+	}
+}
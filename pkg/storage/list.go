@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/song"
+)
+
+// ListParams - параметры постраничного списка песен.
+// Песни упорядочены по Sort, при равенстве - по song_id. Следующая страница запрашивается
+// курсором After (песни после последней песни предыдущей страницы), Offset оставлен
+// для старой пагинации по номеру страницы.
+type ListParams struct {
+	Filter SongFilter
+	Sort   []SortField
+	Limit  int
+	After  *Cursor
+	Offset int
+	// WithTotal - посчитать общее число песен под фильтром
+	WithTotal bool
+}
+
+// SongPage - страница списка песен. Next - курсор следующей страницы, nil на последней.
+// Total заполняется только при ListParams.WithTotal.
+type SongPage struct {
+	Items []song.Song
+	Next  *Cursor
+	Total *int64
+}
+
+// способы сравнения текстовых полей фильтра, регистр не учитывается.
+// '%' и '_' в значении работают как в ILIKE, '\' их экранирует
+const (
+	MatchSubstring = "substring"
+	MatchExact     = "exact"
+	MatchPrefix    = "prefix"
+)
+
+// способы отбора по тегам
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// SongFilter - условия списка песен, пустые поля не учитываются.
+// Name, Groups, Text и Link сравниваются способом Match (по умолчанию MatchSubstring),
+// песня подходит, если совпала любая из Groups.
+// DateFrom и DateTo - границы даты выпуска включительно. Для дат с точностью до месяца
+// или года сравнивается первый день периода, песни без даты под них не попадают.
+// ArtistID и AlbumID, если не 0, оставляют песни исполнителя и альбома.
+// Tags - теги, приведенные NormalizeTags: при TagMatch TagMatchAll у песни должны быть
+// все теги, иначе хотя бы один.
+type SongFilter struct {
+	Name   string
+	Groups []string
+	Text   string
+	Link   string
+	Match  string
+
+	ArtistID int64
+	AlbumID  int64
+
+	Tags     []string
+	TagMatch string
+
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
+
+// NewSongFilter - фильтр подстрокой по непустым полям s, как в GetSongsFromDB.
+// Дата выпуска задает диапазон своего периода.
+func NewSongFilter(s song.Song) SongFilter {
+	f := SongFilter{
+		Name: s.Song,
+		Text: s.Text,
+		Link: s.Link,
+	}
+	if s.Group != "" {
+		f.Groups = []string{s.Group}
+	}
+	if !s.ReleaseDate.IsZero() {
+		from, to := s.ReleaseDate.Date, s.ReleaseDate.End()
+		f.DateFrom, f.DateTo = &from, &to
+	}
+	return f
+}
+
+// Pattern возвращает шаблон ILIKE для значения фильтра
+func (f SongFilter) Pattern(value string) string {
+	switch f.Match {
+	case MatchExact:
+		return value
+	case MatchPrefix:
+		return value + "%"
+	default:
+		return "%" + value + "%"
+	}
+}
+
+// поля сортировки
+const (
+	SortName        = "name"
+	SortGroup       = "group"
+	SortReleaseDate = "release_date"
+	SortCreatedAt   = "created_at"
+)
+
+// SortField - поле сортировки, строки сравниваются без учета регистра
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort разбирает сортировку вида "group,-release_date": поля через запятую,
+// минус перед полем - по убыванию. Допустимы только поля Sort*, каждое не больше одного раза.
+func ParseSort(value string) ([]SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := []SortField{}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		f := SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(f.Field, "-") {
+			f.Field, f.Desc = f.Field[1:], true
+		}
+		switch f.Field {
+		case SortName, SortGroup, SortReleaseDate, SortCreatedAt:
+		default:
+			return nil, ErrorBadSort
+		}
+		if seen[f.Field] {
+			return nil, ErrorBadSort
+		}
+		seen[f.Field] = true
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// FormatSort - обратное к ParseSort преобразование
+func FormatSort(fields []SortField) string {
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f.Desc {
+			parts = append(parts, "-"+f.Field)
+		} else {
+			parts = append(parts, f.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// SortValue - значение поля сортировки песни, которое сохраняется в курсоре
+func SortValue(s song.Song, field string) string {
+	switch field {
+	case SortName:
+		return s.Song
+	case SortGroup:
+		return s.Group
+	case SortReleaseDate:
+		if s.ReleaseDate.IsZero() {
+			return ""
+		}
+		return FormatDate(s.ReleaseDate.Date)
+	case SortCreatedAt:
+		return FormatCreatedAt(s.CreatedAt)
+	}
+	return ""
+}
+
+// createdAtFormat - время создания в курсоре: UTC с микросекундами, строки сравнимы как текст
+const createdAtFormat = "2006-01-02T15:04:05.000000Z"
+
+func FormatCreatedAt(t time.Time) string {
+	return t.UTC().Format(createdAtFormat)
+}
+
+const dateFormat = "2006-01-02"
+
+// FormatDate - дата в курсоре и в хранилищах, где она хранится строкой: YYYY-MM-DD.
+// Неизвестная дата в курсоре - пустая строка, она идет раньше любой другой.
+func FormatDate(t time.Time) string {
+	return t.Format(dateFormat)
+}
+
+// Cursor - позиция в списке песен: последняя песня предыдущей страницы.
+// Values - значения полей сортировки Sort этой песни.
+type Cursor struct {
+	ID     int64    `json:"id"`
+	Sort   string   `json:"sort,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// Encode возвращает непрозрачное для клиента представление курсора
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает курсор, полученный из Cursor.Encode
+func DecodeCursor(s string) (Cursor, error) {
+	c := Cursor{}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrorBadCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID < 0 {
+		return Cursor{}, ErrorBadCursor
+	}
+	return c, nil
+}
+
+// CheckCursor проверяет, что курсор получен для той же сортировки
+// и его значения имеют формат SortValue своих полей: хранилища приводят их к типу поля в запросе
+func (p ListParams) CheckCursor() error {
+	if p.After == nil {
+		return nil
+	}
+	if p.After.Sort != FormatSort(p.Sort) || len(p.After.Values) != len(p.Sort) {
+		return ErrorBadCursor
+	}
+	for i, f := range p.Sort {
+		if !validSortValue(f.Field, p.After.Values[i]) {
+			return ErrorBadCursor
+		}
+	}
+	return nil
+}
+
+// validSortValue проверяет, что value могло быть получено из SortValue для поля field
+func validSortValue(field, value string) bool {
+	switch field {
+	case SortReleaseDate:
+		if value == "" {
+			return true
+		}
+		_, err := time.Parse(dateFormat, value)
+		return err == nil
+	case SortCreatedAt:
+		_, err := time.Parse(createdAtFormat, value)
+		return err == nil
+	}
+	return true
+}
+
+// NewSongPage собирает страницу из items, выбранных с лимитом Limit+1:
+// лишняя песня означает, что есть следующая страница
+func (p ListParams) NewSongPage(items []song.Song) SongPage {
+	page := SongPage{Items: items}
+	if len(items) > p.Limit {
+		page.Items = items[:p.Limit]
+		if p.Limit > 0 {
+			last := items[p.Limit-1]
+			next := &Cursor{ID: last.SongID, Sort: FormatSort(p.Sort)}
+			for _, f := range p.Sort {
+				next.Values = append(next.Values, SortValue(last, f.Field))
+			}
+			page.Next = next
+		}
+	}
+	return page
+}
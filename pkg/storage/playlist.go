@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"slices"
+
+	"SongLibrary/pkg/song"
+)
+
+// MaxPlaylistNameLength - наибольшая длина названия плейлиста в символах
+const MaxPlaylistNameLength = 100
+
+// PlaylistRepo - плейлисты и их записи. Записи идут по позициям с 1, позиция за концом
+// плейлиста означает конец. Удаление песни (DeleteSongByIDFromDB) удаляет ее записи из всех плейлистов.
+type PlaylistRepo interface {
+	AddPlaylist(ctx context.Context, playlist song.Playlist) (song.Playlist, error)
+	GetPlaylistByID(ctx context.Context, id int64) (song.Playlist, error)
+	// ListPlaylists возвращает плейлисты по id, name - подстрока названия без учета регистра
+	ListPlaylists(ctx context.Context, name string, limit int, offset int) ([]song.Playlist, error)
+	// UpdatePlaylist не дает запретить повторы, если в плейлисте уже есть повторы
+	UpdatePlaylist(ctx context.Context, id int64, playlist song.PlaylistForUpdate) (song.Playlist, error)
+	// DeletePlaylist удаляет плейлист вместе с записями
+	DeletePlaylist(ctx context.Context, id int64) error
+
+	// AddPlaylistEntry добавляет песню на позицию position, 0 - в конец
+	AddPlaylistEntry(ctx context.Context, playlistID, songID int64, position int) (song.PlaylistEntry, error)
+	// ListPlaylistEntries возвращает записи плейлиста по порядку вместе с песнями
+	ListPlaylistEntries(ctx context.Context, playlistID int64, limit int, offset int) ([]song.PlaylistEntry, error)
+	// MovePlaylistEntry переносит запись на позицию position
+	MovePlaylistEntry(ctx context.Context, playlistID, entryID int64, position int) (song.PlaylistEntry, error)
+	// ReorderPlaylist задает порядок записей, entryIDs - все записи плейлиста по одному разу
+	ReorderPlaylist(ctx context.Context, playlistID int64, entryIDs []int64) error
+	DeletePlaylistEntry(ctx context.Context, playlistID, entryID int64) error
+}
+
+// MoveEntry возвращает новый порядок записей ids, в котором запись id стоит на позиции position (с 1).
+// Позиция 0 или за концом означает конец. Если записи нет, ok равен false.
+func MoveEntry(ids []int64, id int64, position int) (order []int64, ok bool) {
+	i := slices.Index(ids, id)
+	if i < 0 {
+		return nil, false
+	}
+	order = slices.Delete(slices.Clone(ids), i, i+1)
+	return InsertEntry(order, id, position), true
+}
+
+// InsertEntry вставляет запись id на позицию position (с 1), 0 или позиция за концом - в конец
+func InsertEntry(ids []int64, id int64, position int) []int64 {
+	if position <= 0 || position > len(ids) {
+		return append(ids, id)
+	}
+	return slices.Insert(ids, position-1, id)
+}
+
+// EntryPosition - место записи id в порядке order (с 1)
+func EntryPosition(order []int64, id int64) int {
+	return slices.Index(order, id) + 1
+}
+
+// CheckReorder проверяет, что order - это записи ids, каждая по одному разу
+func CheckReorder(ids, order []int64) error {
+	if len(ids) != len(order) {
+		return ErrorBadReorder
+	}
+	seen := make(map[int64]bool, len(order))
+	for _, id := range order {
+		if seen[id] {
+			return ErrorBadReorder
+		}
+		seen[id] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			return ErrorBadReorder
+		}
+	}
+	return nil
+}
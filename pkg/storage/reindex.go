@@ -0,0 +1,14 @@
+package storage
+
+import "context"
+
+// Reindexer - перестроение индексов хранилища, например после массовой загрузки.
+// Как и SearchRepo, реализуют не все хранилища, поэтому проверяется приведением типа.
+type Reindexer interface {
+	// Reindex перестраивает индексы таблиц каталога и обновляет статистику планировщика.
+	// Возвращает перестроенные таблицы
+	Reindex(ctx context.Context) ([]string, error)
+}
+
+// CatalogTables - таблицы каталога, индексы которых перестраивает Reindex
+var CatalogTables = []string{"artists", "albums", "songs", "tags", "song_tags", "playlists", "playlist_entries", "enrichment_jobs"}
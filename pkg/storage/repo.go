@@ -1,16 +1,68 @@
 package storage
 
 import (
-	"log/slog"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
 
 	"SongLibrary/pkg/song"
 )
 
+// SongRepo - хранилище песен. Все методы принимают контекст запроса,
+// из которого берутся дедлайн, id запроса и логгер (см. пакет reqctx).
+// AddSongToDB и UpdateSongByID возвращают песню в том виде, в котором она сохранена.
+// Если у песни статус song.EnrichmentPending, AddSongToDB в той же транзакции
+// ставит задачу на ее заполнение данными из внешнего сервиса.
+// ListSongs, в отличие от GetSongsFromDB, для пустой страницы возвращает пустой список без ошибки.
 type SongRepo interface {
-	AddSongToDB(*slog.Logger, song.Song) error
-	GetSongsFromDB(*slog.Logger, song.Song, int, int) ([]song.Song, error)
-	DeleteSongByIDFromDB(*slog.Logger, int) (int, error)
-	GetTextOfSongFromDB(*slog.Logger, int) (string, error)
-	UpdateSongByID(*slog.Logger, song.SongForUpdate, int) (int, error)
+	AddSongToDB(context.Context, song.Song) (song.Song, error)
+	GetSongsFromDB(context.Context, song.Song, int, int) ([]song.Song, error)
+	ListSongs(context.Context, ListParams) (SongPage, error)
+	DeleteSongByIDFromDB(context.Context, int) (int, error)
+	GetTextOfSongFromDB(context.Context, int) (string, error)
+	GetSongByID(context.Context, int) (song.Song, error)
+	UpdateSongByID(context.Context, song.SongForUpdate, int) (song.Song, error)
 	Close()
 }
+
+// JobRepo - очередь задач на заполнение песен данными из внешнего сервиса.
+// CompleteEnrichmentJob, RetryEnrichmentJob и FailEnrichmentJob сохраняют результат задачи, только пока
+// ее держит воркер с токеном lease из ClaimEnrichmentJobs и lease не истек, иначе возвращают ErrorLeaseLost
+type JobRepo interface {
+	// ClaimEnrichmentJobs забирает до limit готовых к выполнению задач на время lease
+	// и выдает каждой новый токен song.EnrichmentJob.Lease.
+	// Задачи, чей lease истек (например, упал воркер), забираются повторно.
+	ClaimEnrichmentJobs(ctx context.Context, limit int, lease time.Duration) ([]song.EnrichmentJob, error)
+	// CompleteEnrichmentJob заполняет песню данными и завершает задачу
+	CompleteEnrichmentJob(ctx context.Context, jobID int64, lease string, info song.ResponseFromExternalAPI) error
+	// RetryEnrichmentJob возвращает задачу в очередь до времени runAt
+	RetryEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string, runAt time.Time) error
+	// FailEnrichmentJob переводит задачу в dead letter, а песню в статус song.EnrichmentFailed
+	FailEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string) error
+	// GetEnrichmentJobs возвращает задачи с указанным статусом (все, если статус пустой)
+	GetEnrichmentJobs(ctx context.Context, status string, limit int, offset int) ([]song.EnrichmentJob, error)
+	// RequeueEnrichmentJob возвращает задачу из dead letter в очередь
+	RequeueEnrichmentJob(ctx context.Context, jobID int64) error
+	// RefreshSongByID сохраняет данные песни, заново полученные из внешнего сервиса, и в той же транзакции
+	// завершает ее задачи в очереди и в работе, чтобы воркер не записал поверх старый ответ.
+	// Воркер с такой задачей получит ErrorLeaseLost. Ошибки те же, что у SongRepo.UpdateSongByID
+	RefreshSongByID(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error)
+}
+
+// NewLease возвращает случайный токен, которым ClaimEnrichmentJobs помечает забранные задачи
+func NewLease() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type Repository interface {
+	SongRepo
+	JobRepo
+	ArtistRepo
+	TagRepo
+	PlaylistRepo
+	ImportRepo
+	ExportRepo
+}
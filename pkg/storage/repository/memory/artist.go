@@ -0,0 +1,364 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// resolveArtist находит исполнителя по ключу имени или создает его, вызывается под repo.mu
+func (repo *SongMemoryRepository) resolveArtist(name string) (song.Artist, error) {
+	name = storage.NormalizeName(name)
+	if name == "" {
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	for _, a := range repo.artists {
+		if storage.NameKey(a.Name) == storage.NameKey(name) {
+			return a, nil
+		}
+	}
+
+	repo.lastArtistID++
+	a := song.Artist{
+		ArtistID:  repo.lastArtistID,
+		Name:      name,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	repo.artists[a.ArtistID] = a
+	return a, nil
+}
+
+// checkAlbum проверяет, что альбом существует и принадлежит исполнителю, вызывается под repo.mu
+func (repo *SongMemoryRepository) checkAlbum(albumID, artistID int64) error {
+	album, ok := repo.albums[albumID]
+	if !ok {
+		return storage.ErrorAlbumNotExist
+	}
+	if album.ArtistID != artistID {
+		return storage.ErrorAlbumOfOtherArtist
+	}
+	return nil
+}
+
+// artistExist - есть ли другой исполнитель с таким же ключом имени, вызывается под repo.mu
+func (repo *SongMemoryRepository) artistExist(name string, exceptID int64) bool {
+	for _, a := range repo.artists {
+		if a.ArtistID != exceptID && storage.NameKey(a.Name) == storage.NameKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// albumExist - есть ли у исполнителя другой альбом с таким же ключом названия, вызывается под repo.mu
+func (repo *SongMemoryRepository) albumExist(artistID int64, title string, exceptID int64) bool {
+	for _, a := range repo.albums {
+		if a.AlbumID != exceptID && a.ArtistID == artistID && storage.NameKey(a.Title) == storage.NameKey(title) {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *SongMemoryRepository) AddArtist(ctx context.Context, name string) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Artist{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.artistExist(name, 0) {
+		logger.Error("this artist exist", "name", name)
+		return song.Artist{}, storage.ErrorArtistExist
+	}
+
+	a, err := repo.resolveArtist(name)
+	if err != nil {
+		logger.Error("error add artist", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	logger.Info("add artist success", "id", a.ArtistID)
+	return a, nil
+}
+
+func (repo *SongMemoryRepository) GetArtistByID(ctx context.Context, id int64) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Artist{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	a, ok := repo.artists[id]
+	if !ok {
+		logger.Error("artist not exist", "id", id)
+		return song.Artist{}, storage.ErrorArtistNotExist
+	}
+	return a, nil
+}
+
+func (repo *SongMemoryRepository) ListArtists(ctx context.Context, name string, limit int, offset int) ([]song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	artists := []song.Artist{}
+	for _, a := range repo.artists {
+		if name == "" || storage.MatchILike(a.Name, "%"+name+"%") {
+			artists = append(artists, a)
+		}
+	}
+	sort.Slice(artists, func(i, j int) bool {
+		ki, kj := storage.NameKey(artists[i].Name), storage.NameKey(artists[j].Name)
+		if ki != kj {
+			return ki < kj
+		}
+		return artists[i].ArtistID < artists[j].ArtistID
+	})
+
+	return page(artists, limit, offset), nil
+}
+
+func (repo *SongMemoryRepository) RenameArtist(ctx context.Context, id int64, name string) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Artist{}, err
+	}
+
+	name = storage.NormalizeName(name)
+	if name == "" {
+		logger.Error("empty artist name")
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	a, ok := repo.artists[id]
+	if !ok {
+		logger.Error("artist not exist", "id", id)
+		return song.Artist{}, storage.ErrorArtistNotExist
+	}
+	if repo.artistExist(name, id) {
+		logger.Error("this artist exist", "name", name)
+		return song.Artist{}, storage.ErrorArtistExist
+	}
+
+	a.Name = name
+	repo.artists[id] = a
+	for songID, s := range repo.songs {
+		if s.ArtistID == id {
+			s.Group = name
+			repo.songs[songID] = s
+		}
+	}
+
+	logger.Info("rename artist success", "id", id)
+	return a, nil
+}
+
+func (repo *SongMemoryRepository) DeleteArtist(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.artists[id]; !ok {
+		logger.Error("artist not exist", "id", id)
+		return storage.ErrorArtistNotExist
+	}
+	for _, s := range repo.songs {
+		if s.ArtistID == id {
+			logger.Error("artist has songs", "id", id)
+			return storage.ErrorArtistHasSongs
+		}
+	}
+
+	delete(repo.artists, id)
+	for albumID, a := range repo.albums {
+		if a.ArtistID == id {
+			delete(repo.albums, albumID)
+		}
+	}
+
+	logger.Info("delete artist success", "id", id)
+	return nil
+}
+
+func (repo *SongMemoryRepository) AddAlbum(ctx context.Context, album song.Album) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Album{}, err
+	}
+
+	album.Title = storage.NormalizeName(album.Title)
+	if album.Title == "" {
+		logger.Error("empty album title")
+		return song.Album{}, storage.ErrorEmptyName
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.artists[album.ArtistID]; !ok {
+		logger.Error("artist not exist", "id", album.ArtistID)
+		return song.Album{}, storage.ErrorArtistNotExist
+	}
+	if repo.albumExist(album.ArtistID, album.Title, 0) {
+		logger.Error("this album exist", "title", album.Title)
+		return song.Album{}, storage.ErrorAlbumExist
+	}
+
+	repo.lastAlbumID++
+	album.AlbumID = repo.lastAlbumID
+	album.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	repo.albums[album.AlbumID] = album
+
+	logger.Info("add album success", "id", album.AlbumID)
+	return album, nil
+}
+
+func (repo *SongMemoryRepository) GetAlbumByID(ctx context.Context, id int64) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Album{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	a, ok := repo.albums[id]
+	if !ok {
+		logger.Error("album not exist", "id", id)
+		return song.Album{}, storage.ErrorAlbumNotExist
+	}
+	return a, nil
+}
+
+func (repo *SongMemoryRepository) ListAlbums(ctx context.Context, artistID int64, limit int, offset int) ([]song.Album, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	albums := []song.Album{}
+	for _, a := range repo.albums {
+		if a.ArtistID == artistID {
+			albums = append(albums, a)
+		}
+	}
+	sort.Slice(albums, func(i, j int) bool {
+		di, dj := albums[i].ReleaseDate.Date, albums[j].ReleaseDate.Date
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return albums[i].AlbumID < albums[j].AlbumID
+	})
+
+	return page(albums, limit, offset), nil
+}
+
+func (repo *SongMemoryRepository) UpdateAlbum(ctx context.Context, id int64, album song.AlbumForUpdate) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Album{}, err
+	}
+
+	if album.Title == nil && album.ReleaseDate == nil {
+		logger.Error("no fields to update")
+		return song.Album{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	existing, ok := repo.albums[id]
+	if !ok {
+		logger.Error("album not exist", "id", id)
+		return song.Album{}, storage.ErrorAlbumNotExist
+	}
+
+	if album.Title != nil {
+		title := storage.NormalizeName(*album.Title)
+		if title == "" {
+			logger.Error("empty album title")
+			return song.Album{}, storage.ErrorEmptyName
+		}
+		if repo.albumExist(existing.ArtistID, title, id) {
+			logger.Error("this album exist", "title", title)
+			return song.Album{}, storage.ErrorAlbumExist
+		}
+		existing.Title = title
+	}
+	if album.ReleaseDate != nil {
+		existing.ReleaseDate = *album.ReleaseDate
+	}
+	repo.albums[id] = existing
+
+	logger.Info("update album success", "id", id)
+	return existing, nil
+}
+
+func (repo *SongMemoryRepository) DeleteAlbum(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.albums[id]; !ok {
+		logger.Error("album not exist", "id", id)
+		return storage.ErrorAlbumNotExist
+	}
+	delete(repo.albums, id)
+	for songID, s := range repo.songs {
+		if s.AlbumID == id {
+			s.AlbumID = 0
+			repo.songs[songID] = s
+		}
+	}
+
+	logger.Info("delete album success", "id", id)
+	return nil
+}
+
+// page - срез items по limit и offset
+func page[T any](items []T, limit, offset int) []T {
+	offset = min(offset, len(items))
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
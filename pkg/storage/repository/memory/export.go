@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"context"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+func (repo *SongMemoryRepository) ExportSongs(ctx context.Context, params storage.ListParams, fn func(song.Song) error) error {
+	return storage.ExportPages(ctx, repo, params, fn)
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// findSong ищет песню исполнителя с точным названием, из нескольких берется первая добавленная.
+// Вызывается под repo.mu
+func (repo *SongMemoryRepository) findSong(artistID int64, name string) (song.Song, bool) {
+	found := song.Song{}
+	for _, s := range repo.songs {
+		if s.ArtistID == artistID && s.Song == name && (found.SongID == 0 || s.SongID < found.SongID) {
+			found = s
+		}
+	}
+	return found, found.SongID != 0
+}
+
+func (repo *SongMemoryRepository) ImportSongs(ctx context.Context, songs []song.Song, policy string) ([]song.ImportRow, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	// исполнители проверяются до изменений, чтобы пачка сохранялась целиком или никак
+	for _, s := range songs {
+		if storage.NormalizeName(s.Group) == "" {
+			logger.Error("error resolve artist", "ERROR", storage.ErrorEmptyName)
+			return nil, storage.ErrorEmptyName
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	rows := make([]song.ImportRow, 0, len(songs))
+	for _, s := range songs {
+		artist, err := repo.resolveArtist(s.Group)
+		if err != nil {
+			logger.Error("error resolve artist", "ERROR", err)
+			return nil, err
+		}
+		s.Group, s.ArtistID = artist.Name, artist.ArtistID
+
+		if s.AlbumID != 0 {
+			if err = repo.checkAlbum(s.AlbumID, artist.ArtistID); err != nil {
+				rows = append(rows, song.ImportRow{Status: song.ImportFailed, Group: s.Group, Song: s.Song, Error: err.Error()})
+				continue
+			}
+		}
+
+		if existing, ok := repo.findSong(artist.ArtistID, s.Song); ok {
+			row := song.ImportRow{Status: song.ImportSkipped, SongID: existing.SongID, Group: existing.Group, Song: existing.Song}
+			if policy == storage.ImportUpsert {
+				repo.songs[existing.SongID] = storage.MergeImport(existing, s)
+				row.Status = song.ImportUpdated
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		if s.EnrichmentStatus == "" {
+			s.EnrichmentStatus = song.EnrichmentDone
+		}
+		repo.lastID++
+		s.SongID = repo.lastID
+		s.CreatedAt = now
+		repo.songs[s.SongID] = s
+		if s.EnrichmentStatus == song.EnrichmentPending {
+			repo.addJob(s.SongID)
+		}
+		rows = append(rows, song.ImportRow{Status: song.ImportCreated, SongID: s.SongID, Group: s.Group, Song: s.Song})
+	}
+
+	return rows, nil
+}
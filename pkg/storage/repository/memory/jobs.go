@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+type job struct {
+	song.EnrichmentJob
+	lockedUntil time.Time
+}
+
+// addJob вызывается под блокировкой на запись
+func (repo *SongMemoryRepository) addJob(songID int64) {
+	now := time.Now()
+
+	repo.lastJobID++
+	repo.jobs[repo.lastJobID] = &job{
+		EnrichmentJob: song.EnrichmentJob{
+			JobID:     repo.lastJobID,
+			SongID:    songID,
+			Status:    song.JobPending,
+			RunAt:     now,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+}
+
+// withSong дополняет задачу названием песни и группы, вызывается под блокировкой
+func (repo *SongMemoryRepository) withSong(j *job) song.EnrichmentJob {
+	result := j.EnrichmentJob
+	s := repo.songs[j.SongID]
+	result.Song, result.Group = s.Song, s.Group
+
+	return result
+}
+
+func (repo *SongMemoryRepository) ClaimEnrichmentJobs(ctx context.Context, limit int, lease time.Duration) ([]song.EnrichmentJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+	ready := []*job{}
+	for _, j := range repo.jobs {
+		if (j.Status == song.JobPending && !j.RunAt.After(now)) || (j.Status == song.JobRunning && j.lockedUntil.Before(now)) {
+			ready = append(ready, j)
+		}
+	}
+	sort.Slice(ready, func(i, k int) bool {
+		if ready[i].RunAt.Equal(ready[k].RunAt) {
+			return ready[i].JobID < ready[k].JobID
+		}
+		return ready[i].RunAt.Before(ready[k].RunAt)
+	})
+	if limit < len(ready) {
+		ready = ready[:limit]
+	}
+
+	token := storage.NewLease()
+	jobs := make([]song.EnrichmentJob, 0, len(ready))
+	for _, j := range ready {
+		j.Status = song.JobRunning
+		j.Attempts++
+		j.Lease = token
+		j.lockedUntil = now.Add(lease)
+		j.UpdatedAt = now
+		jobs = append(jobs, repo.withSong(j))
+	}
+
+	return jobs, nil
+}
+
+// leasedJob возвращает задачу, которую держит воркер с токеном lease, вызывается под блокировкой на запись
+func (repo *SongMemoryRepository) leasedJob(ctx context.Context, jobID int64, lease string) (*job, error) {
+	logger := reqctx.Logger(ctx)
+
+	j, ok := repo.jobs[jobID]
+	if !ok {
+		logger.Error("job not exist", "job_id", jobID)
+		return nil, storage.ErrorJobNotExist
+	}
+	if j.Status != song.JobRunning || j.Lease != lease || !j.lockedUntil.After(time.Now()) {
+		logger.Warn("enrichment job lease lost", "job_id", jobID, "status", j.Status)
+		return nil, storage.ErrorLeaseLost
+	}
+	return j, nil
+}
+
+func (repo *SongMemoryRepository) CompleteEnrichmentJob(ctx context.Context, jobID int64, lease string, info song.ResponseFromExternalAPI) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	j, err := repo.leasedJob(ctx, jobID, lease)
+	if err != nil {
+		return err
+	}
+	j.Status = song.JobDone
+	j.LastError = ""
+	j.Lease = ""
+	j.lockedUntil = time.Time{}
+	j.UpdatedAt = time.Now()
+
+	s := repo.songs[j.SongID]
+	s.ReleaseDate = info.ReleaseDate
+	s.Text = info.Text
+	s.Link = info.Link
+	s.EnrichmentStatus = song.EnrichmentDone
+	repo.songs[j.SongID] = s
+
+	logger.Info("enrichment job complete", "job_id", jobID, "song_id", j.SongID)
+	return nil
+}
+
+func (repo *SongMemoryRepository) RetryEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string, runAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	j, err := repo.leasedJob(ctx, jobID, lease)
+	if err != nil {
+		return err
+	}
+	j.Status = song.JobPending
+	j.LastError = lastError
+	j.RunAt = runAt
+	j.Lease = ""
+	j.lockedUntil = time.Time{}
+	j.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (repo *SongMemoryRepository) FailEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	j, err := repo.leasedJob(ctx, jobID, lease)
+	if err != nil {
+		return err
+	}
+	j.Status = song.JobDead
+	j.LastError = lastError
+	j.Lease = ""
+	j.lockedUntil = time.Time{}
+	j.UpdatedAt = time.Now()
+
+	s := repo.songs[j.SongID]
+	s.EnrichmentStatus = song.EnrichmentFailed
+	repo.songs[j.SongID] = s
+
+	logger.Info("enrichment job moved to dead letter", "job_id", jobID, "song_id", j.SongID)
+	return nil
+}
+
+func (repo *SongMemoryRepository) GetEnrichmentJobs(ctx context.Context, status string, limit int, offset int) ([]song.EnrichmentJob, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	jobs := []song.EnrichmentJob{}
+	for _, j := range repo.jobs {
+		if status == "" || j.Status == status {
+			jobs = append(jobs, repo.withSong(j))
+		}
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].JobID < jobs[k].JobID
+	})
+
+	if offset > len(jobs) {
+		offset = len(jobs)
+	}
+	jobs = jobs[offset:]
+	if limit < len(jobs) {
+		jobs = jobs[:limit]
+	}
+
+	return jobs, nil
+}
+
+func (repo *SongMemoryRepository) RequeueEnrichmentJob(ctx context.Context, jobID int64) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	j, ok := repo.jobs[jobID]
+	if !ok {
+		logger.Error("job not exist", "job_id", jobID)
+		return storage.ErrorJobNotExist
+	}
+	if j.Status != song.JobDead {
+		logger.Error("job is not dead", "job_id", jobID, "status", j.Status)
+		return storage.ErrorJobNotDead
+	}
+
+	now := time.Now()
+	j.Status = song.JobPending
+	j.Attempts = 0
+	j.RunAt = now
+	j.UpdatedAt = now
+
+	s := repo.songs[j.SongID]
+	s.EnrichmentStatus = song.EnrichmentPending
+	repo.songs[j.SongID] = s
+
+	logger.Info("enrichment job requeued", "job_id", jobID)
+	return nil
+}
+
+func (repo *SongMemoryRepository) RefreshSongByID(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Song{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	updated, err := repo.updateSong(ctx, s, id)
+	if err != nil {
+		return song.Song{}, err
+	}
+
+	now := time.Now()
+	for _, j := range repo.jobs {
+		if j.SongID != int64(id) || (j.Status != song.JobPending && j.Status != song.JobRunning) {
+			continue
+		}
+		j.Status = song.JobDone
+		j.LastError = ""
+		j.Lease = ""
+		j.lockedUntil = time.Time{}
+		j.UpdatedAt = now
+		logger.Info("enrichment job closed", "job_id", j.JobID, "song_id", id)
+	}
+
+	logger.Info("refresh song success", "id", id)
+	return updated, nil
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+func (repo *SongMemoryRepository) ListSongs(ctx context.Context, params storage.ListParams) (storage.SongPage, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return storage.SongPage{}, err
+	}
+
+	if params.Limit < 0 || params.Offset < 0 {
+		logger.Error("error paginator", "limit", params.Limit, "offset", params.Offset)
+		return storage.SongPage{}, storage.ErrorNegativePaginator
+	}
+	if err := params.CheckCursor(); err != nil {
+		logger.Error("error cursor", "ERROR", err)
+		return storage.SongPage{}, err
+	}
+
+	var after []string
+	if params.After != nil {
+		after = cursorKeys(params.Sort, *params.After)
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var total int64
+	songs := []song.Song{}
+	for _, existing := range repo.songs {
+		if !matchFilter(existing, params.Filter) {
+			continue
+		}
+		total++
+		if after == nil || compareKeys(songKeys(existing, params.Sort), after, params.Sort) > 0 {
+			songs = append(songs, existing)
+		}
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		return compareKeys(songKeys(songs[i], params.Sort), songKeys(songs[j], params.Sort), params.Sort) < 0
+	})
+
+	offset := min(params.Offset, len(songs))
+	songs = songs[offset:]
+	if params.Limit+1 < len(songs) {
+		songs = songs[:params.Limit+1]
+	}
+
+	page := params.NewSongPage(songs)
+	if params.WithTotal {
+		page.Total = &total
+	}
+
+	logger.Info("page of songs create success", "count", len(page.Items))
+	return page, nil
+}
+
+// songKeys - значения полей сортировки песни в том виде, в котором их сравнивают
+// postgres-репозиторий (lower для строк), и id песни последним
+func songKeys(s song.Song, fields []storage.SortField) []string {
+	keys := make([]string, 0, len(fields)+1)
+	for _, f := range fields {
+		keys = append(keys, sortKey(f.Field, storage.SortValue(s, f.Field)))
+	}
+	return append(keys, idKey(s.SongID))
+}
+
+func cursorKeys(fields []storage.SortField, c storage.Cursor) []string {
+	keys := make([]string, 0, len(fields)+1)
+	for i, f := range fields {
+		keys = append(keys, sortKey(f.Field, c.Values[i]))
+	}
+	return append(keys, idKey(c.ID))
+}
+
+func sortKey(field, value string) string {
+	if field == storage.SortName || field == storage.SortGroup {
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// idKey - id строкой фиксированной ширины, чтобы сравнивать его как остальные ключи
+func idKey(id int64) string {
+	return fmt.Sprintf("%020d", id)
+}
+
+// compareKeys сравнивает ключи с учетом направления сортировки, id всегда по возрастанию
+func compareKeys(a, b []string, fields []storage.SortField) int {
+	for i := range a {
+		c := strings.Compare(a[i], b[i])
+		if i < len(fields) && fields[i].Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// matchFilter - аналог условий songFilter из postgres-репозитория
+func matchFilter(s song.Song, f storage.SongFilter) bool {
+	fields := []struct {
+		value, filter string
+	}{
+		{s.Song, f.Name},
+		{s.Text, f.Text},
+		{s.Link, f.Link},
+	}
+	for _, field := range fields {
+		if field.filter != "" && !storage.MatchILike(field.value, f.Pattern(field.filter)) {
+			return false
+		}
+	}
+
+	if len(f.Groups) > 0 {
+		found := false
+		for _, g := range f.Groups {
+			if storage.MatchILike(s.Group, f.Pattern(g)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.ArtistID != 0 && s.ArtistID != f.ArtistID {
+		return false
+	}
+	if f.AlbumID != 0 && s.AlbumID != f.AlbumID {
+		return false
+	}
+
+	if len(f.Tags) > 0 {
+		matched := 0
+		for _, tag := range f.Tags {
+			if slices.Contains(s.Tags, tag) {
+				matched++
+			}
+		}
+		if matched == 0 || f.TagMatch == storage.TagMatchAll && matched < len(f.Tags) {
+			return false
+		}
+	}
+
+	if f.DateFrom != nil || f.DateTo != nil {
+		if s.ReleaseDate.IsZero() {
+			return false
+		}
+		date := storage.FormatDate(s.ReleaseDate.Date)
+		if f.DateFrom != nil && date < storage.FormatDate(*f.DateFrom) {
+			return false
+		}
+		if f.DateTo != nil && date > storage.FormatDate(*f.DateTo) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// SongMemoryRepository - хранилище песен в памяти процесса.
+// Повторяет поведение postgres-репозитория, используется для тестов и локальной разработки.
+type SongMemoryRepository struct {
+	mu     sync.RWMutex
+	songs  map[int64]song.Song
+	lastID int64
+
+	jobs      map[int64]*job
+	lastJobID int64
+
+	artists      map[int64]song.Artist
+	lastArtistID int64
+	albums       map[int64]song.Album
+	lastAlbumID  int64
+
+	playlists      map[int64]*playlist
+	lastPlaylistID int64
+	lastEntryID    int64
+}
+
+func NewSongMemoryRepository() *SongMemoryRepository {
+	return &SongMemoryRepository{
+		songs:     make(map[int64]song.Song),
+		jobs:      make(map[int64]*job),
+		artists:   make(map[int64]song.Artist),
+		albums:    make(map[int64]song.Album),
+		playlists: make(map[int64]*playlist),
+	}
+}
+
+func (repo *SongMemoryRepository) AddSongToDB(ctx context.Context, s song.Song) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Song{}, err
+	}
+
+	tags, err := storage.NormalizeTags(s.Tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+	s.Tags = tags
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	artist, err := repo.resolveArtist(s.Group)
+	if err != nil {
+		logger.Error("error resolve artist", "ERROR", err)
+		return song.Song{}, err
+	}
+	s.Group, s.ArtistID = artist.Name, artist.ArtistID
+
+	for _, existing := range repo.songs {
+		if existing.Song == s.Song && existing.ArtistID == s.ArtistID {
+			logger.Error("this song exist")
+			return song.Song{}, storage.ErrorSongExist
+		}
+	}
+
+	if s.AlbumID != 0 {
+		if err = repo.checkAlbum(s.AlbumID, s.ArtistID); err != nil {
+			logger.Error("error check album", "ERROR", err, "album_id", s.AlbumID)
+			return song.Song{}, err
+		}
+	}
+
+	if s.EnrichmentStatus == "" {
+		s.EnrichmentStatus = song.EnrichmentDone
+	}
+
+	repo.lastID++
+	s.SongID = repo.lastID
+	// точность как у timestamptz в postgres
+	s.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	repo.songs[s.SongID] = s
+
+	if s.EnrichmentStatus == song.EnrichmentPending {
+		repo.addJob(s.SongID)
+		logger.Info("enrichment job create success", "song_id", s.SongID)
+	}
+
+	return s, nil
+}
+
+func (repo *SongMemoryRepository) GetSongsFromDB(ctx context.Context, s song.Song, limit int, offset int) ([]song.Song, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	songs := []song.Song{}
+	for _, existing := range repo.songs {
+		if matchFilter(existing, storage.NewSongFilter(s)) {
+			songs = append(songs, existing)
+		}
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		return songs[i].SongID < songs[j].SongID
+	})
+
+	if offset > len(songs) {
+		offset = len(songs)
+	}
+	songs = songs[offset:]
+	if limit < len(songs) {
+		songs = songs[:limit]
+	}
+
+	if len(songs) == 0 {
+		logger.Error("error list of songs", "ERROR", storage.ErrorListOfSongsEmpty)
+		return nil, storage.ErrorListOfSongsEmpty
+	}
+
+	logger.Info("list of songs create success")
+
+	return songs, nil
+}
+
+func (repo *SongMemoryRepository) DeleteSongByIDFromDB(ctx context.Context, id int) (int, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.songs[int64(id)]; !ok {
+		logger.Error("song not exist", "id", id)
+		return id, storage.ErrorSongNotExist
+	}
+	delete(repo.songs, int64(id))
+	for jobID, j := range repo.jobs {
+		if j.SongID == int64(id) {
+			delete(repo.jobs, jobID)
+		}
+	}
+	repo.removeSongEntries(int64(id))
+
+	logger.Info("delete song success", "id", id)
+	return id, nil
+}
+
+func (repo *SongMemoryRepository) GetTextOfSongFromDB(ctx context.Context, id int) (string, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	s, ok := repo.songs[int64(id)]
+	if !ok {
+		logger.Error("song not exist", "id", id)
+		return "", storage.ErrorSongNotExist
+	}
+
+	logger.Info("get text of song success", "id", id)
+	return s.Text, nil
+}
+
+func (repo *SongMemoryRepository) GetSongByID(ctx context.Context, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Song{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	s, ok := repo.songs[int64(id)]
+	if !ok {
+		logger.Error("song not exist", "id", id)
+		return song.Song{}, storage.ErrorSongNotExist
+	}
+
+	logger.Info("get song success", "id", id)
+	return s, nil
+}
+
+func (repo *SongMemoryRepository) UpdateSongByID(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Song{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	updated, err := repo.updateSong(ctx, s, id)
+	if err != nil {
+		return song.Song{}, err
+	}
+
+	logger.Info("update song success", "id", id)
+	return updated, nil
+}
+
+// updateSong меняет поля песни, вызывается под блокировкой на запись
+func (repo *SongMemoryRepository) updateSong(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	existing, ok := repo.songs[int64(id)]
+	if !ok {
+		logger.Error("song not exist", "id", id)
+		return song.Song{}, storage.ErrorSongNotExist
+	}
+
+	if s.Song == nil && s.Group == nil && s.Text == nil && s.ReleaseDate == nil && s.Link == nil && s.AlbumID == nil && s.EnrichmentStatus == nil {
+		logger.Error("no fields to update")
+		return song.Song{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	// проверки до изменений: при ошибке песня и исполнители остаются как были
+	artistID := existing.ArtistID
+	var artist song.Artist
+	if s.Group != nil {
+		if storage.NormalizeName(*s.Group) == "" {
+			logger.Error("error resolve artist", "ERROR", storage.ErrorEmptyName)
+			return song.Song{}, storage.ErrorEmptyName
+		}
+		artist, _ = repo.resolveArtist(*s.Group)
+		artistID = artist.ArtistID
+	}
+	if s.AlbumID != nil && *s.AlbumID != 0 {
+		if err := repo.checkAlbum(*s.AlbumID, artistID); err != nil {
+			logger.Error("error check album", "ERROR", err, "album_id", *s.AlbumID)
+			return song.Song{}, err
+		}
+	}
+
+	if s.Song != nil {
+		existing.Song = *s.Song
+	}
+	if s.Group != nil {
+		// при смене исполнителя песня отвязывается от альбома прежнего исполнителя
+		if artist.ArtistID != existing.ArtistID {
+			existing.AlbumID = 0
+		}
+		existing.Group, existing.ArtistID = artist.Name, artist.ArtistID
+	}
+	if s.AlbumID != nil {
+		existing.AlbumID = *s.AlbumID
+	}
+	if s.Text != nil {
+		existing.Text = *s.Text
+	}
+	if s.ReleaseDate != nil {
+		existing.ReleaseDate = *s.ReleaseDate
+	}
+	if s.Link != nil {
+		existing.Link = *s.Link
+	}
+	if s.EnrichmentStatus != nil {
+		existing.EnrichmentStatus = *s.EnrichmentStatus
+	}
+	repo.songs[int64(id)] = existing
+
+	return existing, nil
+}
+
+func (repo *SongMemoryRepository) Close() {}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"testing"
+
+	"SongLibrary/pkg/storage"
+	"SongLibrary/pkg/storage/storagetest"
+)
+
+func TestSongRepo(t *testing.T) {
+	storagetest.RunSongRepoTests(t, func(t *testing.T) storage.SongRepo {
+		return NewSongMemoryRepository()
+	})
+}
+
+func TestJobRepo(t *testing.T) {
+	storagetest.RunJobRepoTests(t, func(t *testing.T) storage.Repository {
+		return NewSongMemoryRepository()
+	})
+}
+
+func TestSearchRepo(t *testing.T) {
+	storagetest.RunSearchRepoTests(t, func(t *testing.T) storagetest.SearchableRepo {
+		return NewSongMemoryRepository()
+	})
+}
+
+func TestArtistRepo(t *testing.T) {
+	storagetest.RunArtistRepoTests(t, func(t *testing.T) storage.Repository {
+		return NewSongMemoryRepository()
+	})
+}
+
+func TestTagRepo(t *testing.T) {
+	storagetest.RunTagRepoTests(t, func(t *testing.T) storage.Repository {
+		return NewSongMemoryRepository()
+	})
+}
+
+func TestPlaylistRepo(t *testing.T) {
+	storagetest.RunPlaylistRepoTests(t, func(t *testing.T) storage.Repository {
+		return NewSongMemoryRepository()
+	})
+}
+
+func TestImportRepo(t *testing.T) {
+	storagetest.RunImportRepoTests(t, func(t *testing.T) storage.Repository {
+		return NewSongMemoryRepository()
+	})
+}
+
+func TestBackup(t *testing.T) {
+	storagetest.RunBackupTests(t, func(t *testing.T) storage.Repository {
+		return NewSongMemoryRepository()
+	})
+}
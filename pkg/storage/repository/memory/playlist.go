@@ -0,0 +1,357 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// playlist - плейлист и его записи по порядку
+type playlist struct {
+	song.Playlist
+	entries []entry
+}
+
+type entry struct {
+	EntryID int64
+	SongID  int64
+	AddedAt time.Time
+}
+
+func (p *playlist) view() song.Playlist {
+	v := p.Playlist
+	v.Size = int64(len(p.entries))
+	return v
+}
+
+func (p *playlist) order() []int64 {
+	ids := make([]int64, len(p.entries))
+	for i, e := range p.entries {
+		ids[i] = e.EntryID
+	}
+	return ids
+}
+
+// reorder переставляет записи в порядке ids
+func (p *playlist) reorder(ids []int64) {
+	byID := make(map[int64]entry, len(p.entries))
+	for _, e := range p.entries {
+		byID[e.EntryID] = e
+	}
+	entries := make([]entry, len(ids))
+	for i, id := range ids {
+		entries[i] = byID[id]
+	}
+	p.entries = entries
+}
+
+func (p *playlist) touch() {
+	p.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// entryView - запись плейлиста с песней, position - ее место в плейлисте (с 1)
+func (repo *SongMemoryRepository) entryView(p *playlist, position int) song.PlaylistEntry {
+	e := p.entries[position-1]
+	return song.PlaylistEntry{
+		EntryID:    e.EntryID,
+		PlaylistID: p.PlaylistID,
+		Position:   position,
+		Song:       repo.songs[e.SongID],
+		AddedAt:    e.AddedAt,
+	}
+}
+
+func (repo *SongMemoryRepository) AddPlaylist(ctx context.Context, pl song.Playlist) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Playlist{}, err
+	}
+
+	pl.Name = storage.NormalizeName(pl.Name)
+	if pl.Name == "" {
+		logger.Error("empty playlist name")
+		return song.Playlist{}, storage.ErrorEmptyName
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.lastPlaylistID++
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	p := &playlist{Playlist: song.Playlist{
+		PlaylistID:      repo.lastPlaylistID,
+		Name:            pl.Name,
+		Description:     pl.Description,
+		AllowDuplicates: pl.AllowDuplicates,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}}
+	repo.playlists[p.PlaylistID] = p
+
+	logger.Info("add playlist success", "id", p.PlaylistID)
+	return p.view(), nil
+}
+
+func (repo *SongMemoryRepository) GetPlaylistByID(ctx context.Context, id int64) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Playlist{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	p, ok := repo.playlists[id]
+	if !ok {
+		logger.Error("playlist not exist", "id", id)
+		return song.Playlist{}, storage.ErrorPlaylistNotExist
+	}
+	return p.view(), nil
+}
+
+func (repo *SongMemoryRepository) ListPlaylists(ctx context.Context, name string, limit int, offset int) ([]song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	playlists := []song.Playlist{}
+	for _, p := range repo.playlists {
+		if name == "" || storage.MatchILike(p.Name, "%"+name+"%") {
+			playlists = append(playlists, p.view())
+		}
+	}
+	slices.SortFunc(playlists, func(a, b song.Playlist) int {
+		return int(a.PlaylistID - b.PlaylistID)
+	})
+
+	return page(playlists, limit, offset), nil
+}
+
+func (repo *SongMemoryRepository) UpdatePlaylist(ctx context.Context, id int64, pl song.PlaylistForUpdate) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Playlist{}, err
+	}
+
+	if pl.Name == nil && pl.Description == nil && pl.AllowDuplicates == nil {
+		logger.Error("no fields to update")
+		return song.Playlist{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	p, ok := repo.playlists[id]
+	if !ok {
+		logger.Error("playlist not exist", "id", id)
+		return song.Playlist{}, storage.ErrorPlaylistNotExist
+	}
+
+	updated := p.Playlist
+	if pl.Name != nil {
+		updated.Name = storage.NormalizeName(*pl.Name)
+		if updated.Name == "" {
+			logger.Error("empty playlist name")
+			return song.Playlist{}, storage.ErrorEmptyName
+		}
+	}
+	if pl.Description != nil {
+		updated.Description = *pl.Description
+	}
+	if pl.AllowDuplicates != nil {
+		if !*pl.AllowDuplicates {
+			seen := make(map[int64]bool, len(p.entries))
+			for _, e := range p.entries {
+				if seen[e.SongID] {
+					logger.Error("playlist has repeated songs", "id", id)
+					return song.Playlist{}, storage.ErrorPlaylistHasRepeats
+				}
+				seen[e.SongID] = true
+			}
+		}
+		updated.AllowDuplicates = *pl.AllowDuplicates
+	}
+	p.Playlist = updated
+	p.touch()
+
+	logger.Info("update playlist success", "id", id)
+	return p.view(), nil
+}
+
+func (repo *SongMemoryRepository) DeletePlaylist(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.playlists[id]; !ok {
+		logger.Error("playlist not exist", "id", id)
+		return storage.ErrorPlaylistNotExist
+	}
+	delete(repo.playlists, id)
+
+	logger.Info("delete playlist success", "id", id)
+	return nil
+}
+
+func (repo *SongMemoryRepository) AddPlaylistEntry(ctx context.Context, playlistID, songID int64, position int) (song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.PlaylistEntry{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	p, ok := repo.playlists[playlistID]
+	if !ok {
+		logger.Error("playlist not exist", "id", playlistID)
+		return song.PlaylistEntry{}, storage.ErrorPlaylistNotExist
+	}
+	if _, ok = repo.songs[songID]; !ok {
+		logger.Error("song not exist", "id", songID)
+		return song.PlaylistEntry{}, storage.ErrorSongNotExist
+	}
+	if !p.AllowDuplicates && slices.ContainsFunc(p.entries, func(e entry) bool { return e.SongID == songID }) {
+		logger.Error("song is already in playlist", "playlist_id", playlistID, "song_id", songID)
+		return song.PlaylistEntry{}, storage.ErrorSongInPlaylist
+	}
+
+	repo.lastEntryID++
+	e := entry{EntryID: repo.lastEntryID, SongID: songID, AddedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	p.entries = append(p.entries, e)
+	order := storage.InsertEntry(p.order()[:len(p.entries)-1], e.EntryID, position)
+	p.reorder(order)
+	p.touch()
+
+	logger.Info("add playlist entry success", "playlist_id", playlistID, "entry_id", e.EntryID)
+	return repo.entryView(p, storage.EntryPosition(order, e.EntryID)), nil
+}
+
+func (repo *SongMemoryRepository) ListPlaylistEntries(ctx context.Context, playlistID int64, limit int, offset int) ([]song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	p, ok := repo.playlists[playlistID]
+	if !ok {
+		logger.Error("playlist not exist", "id", playlistID)
+		return nil, storage.ErrorPlaylistNotExist
+	}
+
+	entries := []song.PlaylistEntry{}
+	for i := offset; i < len(p.entries) && i-offset < limit; i++ {
+		entries = append(entries, repo.entryView(p, i+1))
+	}
+	return entries, nil
+}
+
+func (repo *SongMemoryRepository) MovePlaylistEntry(ctx context.Context, playlistID, entryID int64, position int) (song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.PlaylistEntry{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	p, ok := repo.playlists[playlistID]
+	if !ok {
+		logger.Error("playlist not exist", "id", playlistID)
+		return song.PlaylistEntry{}, storage.ErrorPlaylistNotExist
+	}
+	order, ok := storage.MoveEntry(p.order(), entryID, position)
+	if !ok {
+		logger.Error("playlist entry not exist", "playlist_id", playlistID, "entry_id", entryID)
+		return song.PlaylistEntry{}, storage.ErrorEntryNotExist
+	}
+	p.reorder(order)
+	p.touch()
+
+	entry := repo.entryView(p, storage.EntryPosition(order, entryID))
+	logger.Info("move playlist entry success", "playlist_id", playlistID, "entry_id", entryID, "position", entry.Position)
+	return entry, nil
+}
+
+func (repo *SongMemoryRepository) ReorderPlaylist(ctx context.Context, playlistID int64, entryIDs []int64) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	p, ok := repo.playlists[playlistID]
+	if !ok {
+		logger.Error("playlist not exist", "id", playlistID)
+		return storage.ErrorPlaylistNotExist
+	}
+	if err := storage.CheckReorder(p.order(), entryIDs); err != nil {
+		logger.Error("error reorder playlist", "ERROR", err, "id", playlistID)
+		return err
+	}
+	p.reorder(entryIDs)
+	p.touch()
+
+	logger.Info("reorder playlist success", "id", playlistID)
+	return nil
+}
+
+func (repo *SongMemoryRepository) DeletePlaylistEntry(ctx context.Context, playlistID, entryID int64) error {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	p, ok := repo.playlists[playlistID]
+	if !ok {
+		logger.Error("playlist not exist", "id", playlistID)
+		return storage.ErrorPlaylistNotExist
+	}
+	i := slices.IndexFunc(p.entries, func(e entry) bool { return e.EntryID == entryID })
+	if i < 0 {
+		logger.Error("playlist entry not exist", "playlist_id", playlistID, "entry_id", entryID)
+		return storage.ErrorEntryNotExist
+	}
+	p.entries = slices.Delete(p.entries, i, i+1)
+	p.touch()
+
+	logger.Info("delete playlist entry success", "playlist_id", playlistID, "entry_id", entryID)
+	return nil
+}
+
+// removeSongEntries убирает песню из всех плейлистов, как ON DELETE CASCADE в базах
+func (repo *SongMemoryRepository) removeSongEntries(songID int64) {
+	for _, p := range repo.playlists {
+		p.entries = slices.DeleteFunc(p.entries, func(e entry) bool { return e.SongID == songID })
+	}
+}
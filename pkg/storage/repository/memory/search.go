@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// SearchSongs ищет песни через storage.RankSearch
+func (repo *SongMemoryRepository) SearchSongs(ctx context.Context, query string, limit int, offset int) ([]song.SearchResult, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	terms, err := storage.ParseSearchQuery(query)
+	if err != nil {
+		logger.Error("error parse search query", "ERROR", err)
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	songs := make([]song.Song, 0, len(repo.songs))
+	for _, s := range repo.songs {
+		songs = append(songs, s)
+	}
+	repo.mu.RUnlock()
+
+	results := storage.RankSearch(songs, terms, limit, offset)
+
+	logger.Info("search songs success", "found", len(results))
+	return results, nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// Теги песни хранятся в song.Song.Tags. Срез тегов не меняется на месте, а заменяется новым,
+// поэтому возвращенные песни можно отдавать без копирования.
+
+func (repo *SongMemoryRepository) AddSongTags(ctx context.Context, songID int, tags []string) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Song{}, err
+	}
+
+	tags, err := storage.NormalizeTags(tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	s, ok := repo.songs[int64(songID)]
+	if !ok {
+		logger.Error("song not exist", "id", songID)
+		return song.Song{}, storage.ErrorSongNotExist
+	}
+	// теги песни уже приведены, NormalizeTags только объединит их без повторов
+	s.Tags, _ = storage.NormalizeTags(append(slices.Clip(s.Tags), tags...))
+	repo.songs[s.SongID] = s
+
+	logger.Info("add song tags success", "id", songID, "tags", tags)
+	return s, nil
+}
+
+func (repo *SongMemoryRepository) RemoveSongTags(ctx context.Context, songID int, tags []string) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return song.Song{}, err
+	}
+
+	tags, err := storage.NormalizeTags(tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	s, ok := repo.songs[int64(songID)]
+	if !ok {
+		logger.Error("song not exist", "id", songID)
+		return song.Song{}, storage.ErrorSongNotExist
+	}
+	kept := []string{}
+	for _, tag := range s.Tags {
+		if !slices.Contains(tags, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	s.Tags = kept
+	repo.songs[s.SongID] = s
+
+	logger.Info("remove song tags success", "id", songID, "tags", tags)
+	return s, nil
+}
+
+func (repo *SongMemoryRepository) ListTags(ctx context.Context, name string, limit int, offset int) ([]song.Tag, error) {
+	logger := reqctx.Logger(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	counts := map[string]int64{}
+	for _, s := range repo.songs {
+		for _, tag := range s.Tags {
+			if name == "" || storage.MatchILike(tag, "%"+name+"%") {
+				counts[tag]++
+			}
+		}
+	}
+
+	tags := make([]song.Tag, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, song.Tag{Name: tag, Songs: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Songs != tags[j].Songs {
+			return tags[i].Songs > tags[j].Songs
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	return page(tags, limit, offset), nil
+}
@@ -0,0 +1,418 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	artistColumns = "artist_id, name, created_at"
+	albumColumns  = "album_id, artist_id, title, release_date, release_date_precision, created_at"
+)
+
+func scanArtist(row pgx.Row) (song.Artist, error) {
+	a := song.Artist{}
+	err := row.Scan(&a.ArtistID, &a.Name, &a.CreatedAt)
+	return a, err
+}
+
+func scanAlbum(row pgx.Row) (song.Album, error) {
+	a := song.Album{}
+	var date *time.Time
+	var precision string
+	if err := row.Scan(&a.AlbumID, &a.ArtistID, &a.Title, &date, &precision, &a.CreatedAt); err != nil {
+		return a, err
+	}
+	if date != nil {
+		a.ReleaseDate = song.NewReleaseDate(*date, precision)
+	}
+	return a, nil
+}
+
+// isUniqueViolation - нарушено ограничение уникальности constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// nullID - NULL вместо нулевого id
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// resolveArtist находит исполнителя по имени без учета регистра или создает его
+func resolveArtist(ctx context.Context, tx pgx.Tx, name string) (song.Artist, error) {
+	name = storage.NormalizeName(name)
+	if name == "" {
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	a, err := scanArtist(tx.QueryRow(ctx, "INSERT INTO artists (name) VALUES ($1) ON CONFLICT ((lower(name))) DO NOTHING RETURNING "+artistColumns, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		a, err = scanArtist(tx.QueryRow(ctx, "SELECT "+artistColumns+" FROM artists WHERE lower(name) = lower($1)", name))
+	}
+	return a, err
+}
+
+// checkAlbum проверяет, что альбом существует и принадлежит исполнителю
+func checkAlbum(ctx context.Context, tx pgx.Tx, albumID, artistID int64) error {
+	var owner int64
+	err := tx.QueryRow(ctx, "SELECT artist_id FROM albums WHERE album_id = $1", albumID).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrorAlbumNotExist
+	}
+	if err != nil {
+		return err
+	}
+	if owner != artistID {
+		return storage.ErrorAlbumOfOtherArtist
+	}
+	return nil
+}
+
+func (repo *SongPostgresRepository) AddArtist(ctx context.Context, name string) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	name = storage.NormalizeName(name)
+	if name == "" {
+		logger.Error("empty artist name")
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	a, err := scanArtist(repo.Pool.QueryRow(ctx, "INSERT INTO artists (name) VALUES ($1) RETURNING "+artistColumns, name))
+	if err != nil {
+		if isUniqueViolation(err, "artists_name_key") {
+			logger.Error("this artist exist", "name", name)
+			return song.Artist{}, storage.ErrorArtistExist
+		}
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	logger.Info("add artist success", "id", a.ArtistID)
+	return a, nil
+}
+
+func (repo *SongPostgresRepository) GetArtistByID(ctx context.Context, id int64) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	a, err := scanArtist(repo.Pool.QueryRow(ctx, "SELECT "+artistColumns+" FROM artists WHERE artist_id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("artist not exist", "id", id)
+			return song.Artist{}, storage.ErrorArtistNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	return a, nil
+}
+
+func (repo *SongPostgresRepository) ListArtists(ctx context.Context, name string, limit int, offset int) ([]song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	args := queryArgs{}
+	where := "1=1"
+	if name != "" {
+		where = "name ILIKE " + args.add("%"+name+"%")
+	}
+	query := fmt.Sprintf("SELECT %s FROM artists WHERE %s ORDER BY lower(name), artist_id LIMIT %s OFFSET %s",
+		artistColumns, where, args.add(limit), args.add(offset))
+
+	rows, err := repo.Pool.Query(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	artists := []song.Artist{}
+	for rows.Next() {
+		a, err := scanArtist(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		artists = append(artists, a)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return artists, nil
+}
+
+func (repo *SongPostgresRepository) RenameArtist(ctx context.Context, id int64, name string) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	name = storage.NormalizeName(name)
+	if name == "" {
+		logger.Error("empty artist name")
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Artist{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	a, err := scanArtist(tx.QueryRow(ctx, "UPDATE artists SET name = $1 WHERE artist_id = $2 RETURNING "+artistColumns, name, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("artist not exist", "id", id)
+			return song.Artist{}, storage.ErrorArtistNotExist
+		}
+		if isUniqueViolation(err, "artists_name_key") {
+			logger.Error("this artist exist", "name", name)
+			return song.Artist{}, storage.ErrorArtistExist
+		}
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE songs SET group_name = $1 WHERE artist_id = $2", a.Name, id)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	logger.Info("rename artist success", "id", id)
+	return a, nil
+}
+
+func (repo *SongPostgresRepository) DeleteArtist(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var hasSongs bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM songs WHERE artist_id = $1)", id).Scan(&hasSongs)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if hasSongs {
+		logger.Error("artist has songs", "id", id)
+		return storage.ErrorArtistHasSongs
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM artists WHERE artist_id = $1", id)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		logger.Error("artist not exist", "id", id)
+		return storage.ErrorArtistNotExist
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("delete artist success", "id", id)
+	return nil
+}
+
+func (repo *SongPostgresRepository) AddAlbum(ctx context.Context, album song.Album) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	album.Title = storage.NormalizeName(album.Title)
+	if album.Title == "" {
+		logger.Error("empty album title")
+		return song.Album{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	date, precision := releaseDate(album.ReleaseDate)
+	created, err := scanAlbum(repo.Pool.QueryRow(ctx, "INSERT INTO albums (artist_id, title, release_date, release_date_precision) VALUES ($1, $2, $3, $4) RETURNING "+albumColumns,
+		album.ArtistID, album.Title, date, precision,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			logger.Error("artist not exist", "id", album.ArtistID)
+			return song.Album{}, storage.ErrorArtistNotExist
+		}
+		if isUniqueViolation(err, "albums_title_key") {
+			logger.Error("this album exist", "title", album.Title)
+			return song.Album{}, storage.ErrorAlbumExist
+		}
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	logger.Info("add album success", "id", created.AlbumID)
+	return created, nil
+}
+
+func (repo *SongPostgresRepository) GetAlbumByID(ctx context.Context, id int64) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	a, err := scanAlbum(repo.Pool.QueryRow(ctx, "SELECT "+albumColumns+" FROM albums WHERE album_id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("album not exist", "id", id)
+			return song.Album{}, storage.ErrorAlbumNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	return a, nil
+}
+
+func (repo *SongPostgresRepository) ListAlbums(ctx context.Context, artistID int64, limit int, offset int) ([]song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	rows, err := repo.Pool.Query(ctx, "SELECT "+albumColumns+" FROM albums WHERE artist_id = $1 ORDER BY "+releaseDateKey+", album_id LIMIT $2 OFFSET $3",
+		artistID, limit, offset)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	albums := []song.Album{}
+	for rows.Next() {
+		a, err := scanAlbum(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		albums = append(albums, a)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return albums, nil
+}
+
+func (repo *SongPostgresRepository) UpdateAlbum(ctx context.Context, id int64, album song.AlbumForUpdate) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	if album.Title == nil && album.ReleaseDate == nil {
+		logger.Error("no fields to update")
+		return song.Album{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	args := queryArgs{}
+	updates := []string{}
+	if album.Title != nil {
+		title := storage.NormalizeName(*album.Title)
+		if title == "" {
+			logger.Error("empty album title")
+			return song.Album{}, storage.ErrorEmptyName
+		}
+		updates = append(updates, "title = "+args.add(title))
+	}
+	if album.ReleaseDate != nil {
+		date, precision := releaseDate(*album.ReleaseDate)
+		updates = append(updates, "release_date = "+args.add(date)+", release_date_precision = "+args.add(precision))
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	query := fmt.Sprintf("UPDATE albums SET %s WHERE album_id = %s RETURNING %s", strings.Join(updates, ", "), args.add(id), albumColumns)
+	updated, err := scanAlbum(repo.Pool.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("album not exist", "id", id)
+			return song.Album{}, storage.ErrorAlbumNotExist
+		}
+		if isUniqueViolation(err, "albums_title_key") {
+			logger.Error("this album exist", "title", *album.Title)
+			return song.Album{}, storage.ErrorAlbumExist
+		}
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	logger.Info("update album success", "id", id)
+	return updated, nil
+}
+
+func (repo *SongPostgresRepository) DeleteAlbum(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	// песни альбома отвязываются внешним ключом ON DELETE SET NULL
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM albums WHERE album_id = $1", id)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		logger.Error("album not exist", "id", id)
+		return storage.ErrorAlbumNotExist
+	}
+
+	logger.Info("delete album success", "id", id)
+	return nil
+}
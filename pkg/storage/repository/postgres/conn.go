@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"SongLibrary/pkg/backoff"
+	"SongLibrary/pkg/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func NewConnPostgres(ctx context.Context, cfg config.Postgres, logger *slog.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.ConnString())
+	if err != nil {
+		logger.Error("error parse connection string:",
+			"error", err,
+		)
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	logger.Debug("pool config",
+		"max_conns", poolConfig.MaxConns,
+		"min_conns", poolConfig.MinConns,
+		"max_conn_lifetime", poolConfig.MaxConnLifetime,
+		"max_conn_idle_time", poolConfig.MaxConnIdleTime,
+		"health_check_period", poolConfig.HealthCheckPeriod,
+	)
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		logger.Error("error create connect to db:",
+			"error", err,
+		)
+		return nil, err
+	}
+
+	if err = waitPostgres(ctx, pool, cfg, logger); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	logger.Info("connect to db create success")
+
+	return pool, nil
+}
+
+// waitPostgres пингует базу с экспоненциальной задержкой, пока она не ответит или не выйдет ConnectMaxWait
+func waitPostgres(ctx context.Context, pool *pgxpool.Pool, cfg config.Postgres, logger *slog.Logger) error {
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.ConnectMaxWait)
+	defer cancel()
+
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		err := pool.Ping(waitCtx)
+		if err == nil {
+			logger.Info("ping to db success",
+				"attempt", attempt+1,
+				"elapsed", time.Since(start),
+			)
+			return nil
+		}
+
+		delay := cfg.Retry.Duration(attempt)
+		logger.Warn("error ping to db, retry",
+			"attempt", attempt+1,
+			"elapsed", time.Since(start),
+			"next_delay", delay,
+			"ERROR", err,
+		)
+
+		if err = backoff.Sleep(waitCtx, delay); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				err = fmt.Errorf("db is not available after %v (%d attempts)", cfg.ConnectMaxWait, attempt+1)
+			}
+			logger.Error("error wait db:",
+				"error", err,
+			)
+			return err
+		}
+	}
+}
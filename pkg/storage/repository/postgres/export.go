@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExportSongs читает песни серверным курсором в одном снимке базы, по storage.ExportBatchSize за раз.
+// Выгрузка ограничена только контекстом: таймаут списка для нее слишком мал
+func (repo *SongPostgresRepository) ExportSongs(ctx context.Context, params storage.ListParams, fn func(song.Song) error) error {
+	logger := reqctx.Logger(ctx)
+
+	tx, err := repo.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	args := queryArgs{}
+	query := fmt.Sprintf("DECLARE export_songs NO SCROLL CURSOR FOR SELECT %s FROM songs WHERE %s ORDER BY %s",
+		songColumns, songFilter(params.Filter, &args), songOrder(params.Sort))
+	logger.Debug("result query to db", "query", query)
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		logger.Error("error declare cursor", "ERROR", err)
+		return err
+	}
+
+	count := 0
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf("FETCH %d FROM export_songs", storage.ExportBatchSize))
+		if err != nil {
+			logger.Error("error fetch cursor", "ERROR", err)
+			return err
+		}
+		songs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (song.Song, error) {
+			return scanSong(row)
+		})
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return err
+		}
+		if err = loadTags(ctx, tx, songRefs(songs)...); err != nil {
+			logger.Error("error load tags", "ERROR", err)
+			return err
+		}
+
+		for _, s := range songs {
+			if err = fn(s); err != nil {
+				return err
+			}
+		}
+		count += len(songs)
+		if len(songs) < storage.ExportBatchSize {
+			break
+		}
+	}
+
+	logger.Info("export songs from db success", "count", count)
+	return nil
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// importRows - временная таблица пачки импорта. n - номер песни в пачке,
+// song_id заполняется id существующей песни или новым значением последовательности
+const importRows = `CREATE TEMP TABLE import_rows (
+	n integer PRIMARY KEY,
+	song_id integer,
+	created boolean NOT NULL DEFAULT false,
+	song_name text NOT NULL,
+	group_name text NOT NULL,
+	artist_id bigint NOT NULL,
+	album_id bigint,
+	release_date date,
+	release_date_precision text NOT NULL,
+	text_of_song text NOT NULL,
+	link text NOT NULL,
+	enrichment_status text NOT NULL,
+	tags text[] NOT NULL
+) ON COMMIT DROP`
+
+var importColumns = []string{"n", "song_name", "group_name", "artist_id", "album_id", "release_date", "release_date_precision", "text_of_song", "link", "enrichment_status", "tags"}
+
+// ImportSongs копирует пачку во временную таблицу через COPY и сохраняет ее несколькими запросами на всю пачку
+func (repo *SongPostgresRepository) ImportSongs(ctx context.Context, songs []song.Song, policy string) ([]song.ImportRow, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	artists := make(map[string]song.Artist)
+	// failed - песни с чужим или несуществующим альбомом, во временную таблицу они не попадают
+	failed := make(map[int]song.ImportRow)
+	values := make([][]any, 0, len(songs))
+	for i, s := range songs {
+		key := storage.NameKey(s.Group)
+		artist, ok := artists[key]
+		if !ok {
+			if artist, err = resolveArtist(ctx, tx, s.Group); err != nil {
+				logger.Error("error resolve artist", "ERROR", err)
+				return nil, err
+			}
+			artists[key] = artist
+		}
+
+		if s.AlbumID != 0 {
+			if err = checkAlbum(ctx, tx, s.AlbumID, artist.ArtistID); err != nil {
+				if !storage.IsAlbumError(err) {
+					logger.Error("error check album", "ERROR", err, "album_id", s.AlbumID)
+					return nil, err
+				}
+				failed[i] = song.ImportRow{Status: song.ImportFailed, Error: err.Error()}
+				continue
+			}
+		}
+
+		if s.EnrichmentStatus == "" {
+			s.EnrichmentStatus = song.EnrichmentDone
+		}
+		tags := s.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		date, precision := releaseDate(s.ReleaseDate)
+		values = append(values, []any{i, s.Song, artist.Name, artist.ArtistID, nullID(s.AlbumID), date, precision, s.Text, s.Link, s.EnrichmentStatus, tags})
+	}
+
+	if _, err = tx.Exec(ctx, importRows); err != nil {
+		logger.Error("error create temp table", "ERROR", err)
+		return nil, err
+	}
+	if _, err = tx.CopyFrom(ctx, pgx.Identifier{"import_rows"}, importColumns, pgx.CopyFromRows(values)); err != nil {
+		logger.Error("error copy import rows", "ERROR", err)
+		return nil, err
+	}
+
+	statements := []string{
+		// существующая песня, из нескольких - первая добавленная
+		`UPDATE import_rows i SET song_id = (
+			SELECT min(s.song_id) FROM songs s WHERE s.song_name = i.song_name AND s.artist_id = i.artist_id
+		)`,
+		`UPDATE import_rows SET song_id = nextval(pg_get_serial_sequence('songs', 'song_id')), created = true
+		WHERE song_id IS NULL`,
+		`INSERT INTO songs (song_id, song_name, group_name, artist_id, album_id, release_date, release_date_precision, text_of_song, link, enrichment_status)
+		SELECT song_id, song_name, group_name, artist_id, album_id, release_date, release_date_precision, text_of_song, link, enrichment_status
+		FROM import_rows WHERE created ORDER BY n`,
+		`INSERT INTO enrichment_jobs (song_id)
+		SELECT song_id FROM import_rows WHERE created AND enrichment_status = 'pending' ORDER BY n`,
+	}
+	if policy == storage.ImportUpsert {
+		statements = append(statements,
+			`UPDATE songs s SET
+				release_date = coalesce(i.release_date, s.release_date),
+				release_date_precision = CASE WHEN i.release_date IS NULL THEN s.release_date_precision ELSE i.release_date_precision END,
+				text_of_song = CASE WHEN i.text_of_song = '' THEN s.text_of_song ELSE i.text_of_song END,
+				link = CASE WHEN i.link = '' THEN s.link ELSE i.link END,
+				album_id = coalesce(i.album_id, s.album_id)
+			FROM import_rows i
+			WHERE NOT i.created AND s.song_id = i.song_id`,
+		)
+	}
+	// теги получают новые песни, а при ImportUpsert и существующие
+	tagged := "WHERE created"
+	if policy == storage.ImportUpsert {
+		tagged = ""
+	}
+	statements = append(statements,
+		"INSERT INTO tags (name) SELECT DISTINCT unnest(tags) FROM import_rows "+tagged+" ON CONFLICT (name) DO NOTHING",
+		"INSERT INTO song_tags (song_id, tag_id) SELECT i.song_id, t.tag_id FROM (SELECT * FROM import_rows "+tagged+") i JOIN tags t ON t.name = ANY(i.tags) ON CONFLICT DO NOTHING",
+	)
+
+	for _, statement := range statements {
+		if _, err = tx.Exec(ctx, statement); err != nil {
+			logger.Error("error exec import query to db: ", "ERROR", err)
+			return nil, err
+		}
+	}
+
+	result, err := tx.Query(ctx, "SELECT song_id, created FROM import_rows ORDER BY n")
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	saved, err := pgx.CollectRows(result, func(row pgx.CollectableRow) (song.ImportRow, error) {
+		r := song.ImportRow{}
+		var created bool
+		if err := row.Scan(&r.SongID, &created); err != nil {
+			return r, err
+		}
+		switch {
+		case created:
+			r.Status = song.ImportCreated
+		case policy == storage.ImportUpsert:
+			r.Status = song.ImportUpdated
+		default:
+			r.Status = song.ImportSkipped
+		}
+		return r, nil
+	})
+	if err != nil {
+		logger.Error("error scan import rows", "ERROR", err)
+		return nil, err
+	}
+	rows := make([]song.ImportRow, 0, len(songs))
+	for i, s := range songs {
+		row, ok := failed[i]
+		if !ok {
+			row, saved = saved[0], saved[1:]
+		}
+		row.Group, row.Song = artists[storage.NameKey(s.Group)].Name, s.Song
+		rows = append(rows, row)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
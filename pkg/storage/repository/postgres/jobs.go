@@ -0,0 +1,296 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const jobColumns = "j.job_id, j.song_id, s.song_name, s.group_name, j.status, j.attempts, j.last_error, j.run_at, j.created_at, j.updated_at, j.leased_by"
+
+// leaseHeld - условие, что задачу $2 в статусе $3 держит воркер с токеном $4 и lease не истек
+const leaseHeld = "job_id = $2 AND status = $3 AND leased_by = $4 AND locked_until > now()"
+
+// rowQuerier - пул или транзакция
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// leaseError объясняет, почему задача не нашлась под lease: ее нет или ее держит не этот воркер
+func leaseError(ctx context.Context, q rowQuerier, jobID int64) error {
+	logger := reqctx.Logger(ctx)
+
+	var exists bool
+	if err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM enrichment_jobs WHERE job_id = $1)", jobID).Scan(&exists); err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if !exists {
+		logger.Error("job not exist", "job_id", jobID)
+		return storage.ErrorJobNotExist
+	}
+	logger.Warn("enrichment job lease lost", "job_id", jobID)
+	return storage.ErrorLeaseLost
+}
+
+func (repo *SongPostgresRepository) ClaimEnrichmentJobs(ctx context.Context, limit int, lease time.Duration) ([]song.EnrichmentJob, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	// SKIP LOCKED позволяет нескольким воркерам (и репликам) разбирать очередь, не мешая друг другу
+	rows, err := repo.Pool.Query(ctx, `WITH j AS (
+    UPDATE enrichment_jobs SET status = $1, attempts = attempts + 1, locked_until = now() + make_interval(secs => $2), leased_by = $5, updated_at = now()
+    WHERE job_id IN (
+        SELECT job_id FROM enrichment_jobs
+        WHERE (status = $3 AND run_at <= now()) OR (status = $1 AND locked_until < now())
+        ORDER BY run_at
+        LIMIT $4
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *
+)
+SELECT `+jobColumns+` FROM j JOIN songs s ON s.song_id = j.song_id ORDER BY j.run_at`,
+		song.JobRunning, lease.Seconds(), song.JobPending, limit, storage.NewLease(),
+	)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return nil, err
+	}
+
+	jobs, err := pgx.CollectRows(rows, scanJob)
+	if err != nil {
+		logger.Error("error scan row", "ERROR", err)
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (repo *SongPostgresRepository) CompleteEnrichmentJob(ctx context.Context, jobID int64, lease string, info song.ResponseFromExternalAPI) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var songID int64
+	err = tx.QueryRow(ctx, "UPDATE enrichment_jobs SET status = $1, last_error = '', locked_until = NULL, leased_by = '', updated_at = now() WHERE "+leaseHeld+" RETURNING song_id",
+		song.JobDone, jobID, song.JobRunning, lease,
+	).Scan(&songID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return leaseError(ctx, tx, jobID)
+		}
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	date, precision := releaseDate(info.ReleaseDate)
+	_, err = tx.Exec(ctx, "UPDATE songs SET release_date = $1, release_date_precision = $2, text_of_song = $3, link = $4, enrichment_status = $5 WHERE song_id = $6",
+		date, precision, info.Text, info.Link, song.EnrichmentDone, songID,
+	)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("enrichment job complete", "job_id", jobID, "song_id", songID)
+	return nil
+}
+
+func (repo *SongPostgresRepository) RetryEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string, runAt time.Time) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tag, err := repo.Pool.Exec(ctx, "UPDATE enrichment_jobs SET status = $1, last_error = $5, run_at = $6, locked_until = NULL, leased_by = '', updated_at = now() WHERE "+leaseHeld,
+		song.JobPending, jobID, song.JobRunning, lease, lastError, runAt,
+	)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return leaseError(ctx, repo.Pool, jobID)
+	}
+
+	return nil
+}
+
+func (repo *SongPostgresRepository) FailEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var songID int64
+	err = tx.QueryRow(ctx, "UPDATE enrichment_jobs SET status = $1, last_error = $5, locked_until = NULL, leased_by = '', updated_at = now() WHERE "+leaseHeld+" RETURNING song_id",
+		song.JobDead, jobID, song.JobRunning, lease, lastError,
+	).Scan(&songID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return leaseError(ctx, tx, jobID)
+		}
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE songs SET enrichment_status = $1 WHERE song_id = $2", song.EnrichmentFailed, songID)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("enrichment job moved to dead letter", "job_id", jobID, "song_id", songID)
+	return nil
+}
+
+func (repo *SongPostgresRepository) GetEnrichmentJobs(ctx context.Context, status string, limit int, offset int) ([]song.EnrichmentJob, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	rows, err := repo.Pool.Query(ctx, "SELECT "+jobColumns+" FROM enrichment_jobs j JOIN songs s ON s.song_id = j.song_id WHERE $1 = '' OR j.status = $1 ORDER BY j.job_id LIMIT $2 OFFSET $3",
+		status, limit, offset,
+	)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+
+	jobs, err := pgx.CollectRows(rows, scanJob)
+	if err != nil {
+		logger.Error("error scan row", "ERROR", err)
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (repo *SongPostgresRepository) RequeueEnrichmentJob(ctx context.Context, jobID int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	var songID int64
+	err = tx.QueryRow(ctx, "SELECT status, song_id FROM enrichment_jobs WHERE job_id = $1 FOR UPDATE", jobID).Scan(&status, &songID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("job not exist", "job_id", jobID)
+			return storage.ErrorJobNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if status != song.JobDead {
+		logger.Error("job is not dead", "job_id", jobID, "status", status)
+		return storage.ErrorJobNotDead
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE enrichment_jobs SET status = $1, attempts = 0, run_at = now(), updated_at = now() WHERE job_id = $2", song.JobPending, jobID)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE songs SET enrichment_status = $1 WHERE song_id = $2", song.EnrichmentPending, songID)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("enrichment job requeued", "job_id", jobID)
+	return nil
+}
+
+func (repo *SongPostgresRepository) RefreshSongByID(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	updated, err := updateSong(ctx, tx, s, id)
+	if err != nil {
+		return song.Song{}, err
+	}
+
+	tag, err := tx.Exec(ctx, "UPDATE enrichment_jobs SET status = $1, last_error = '', locked_until = NULL, leased_by = '', updated_at = now() WHERE song_id = $2 AND status IN ($3, $4)",
+		song.JobDone, id, song.JobPending, song.JobRunning,
+	)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("refresh song success", "id", id, "closed_jobs", tag.RowsAffected())
+	return updated, nil
+}
+
+func scanJob(row pgx.CollectableRow) (song.EnrichmentJob, error) {
+	job := song.EnrichmentJob{}
+	err := row.Scan(&job.JobID, &job.SongID, &job.Song, &job.Group, &job.Status, &job.Attempts, &job.LastError, &job.RunAt, &job.CreatedAt, &job.UpdatedAt, &job.Lease)
+	return job, err
+}
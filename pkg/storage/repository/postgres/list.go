@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// releaseDateKey - дата выпуска для сортировки: неизвестная дата идет раньше любой другой,
+// как пустая строка в курсоре (storage.SortValue)
+const releaseDateKey = "coalesce(release_date, '-infinity'::date)"
+
+// sortColumns - выражения полей сортировки и приведение значения из курсора к ним
+var sortColumns = map[string]struct {
+	expr, param string
+}{
+	storage.SortName:        {"lower(song_name)", "lower(%s)"},
+	storage.SortGroup:       {"lower(group_name)", "lower(%s)"},
+	storage.SortReleaseDate: {releaseDateKey, "coalesce(nullif(%s, '')::date, '-infinity'::date)"},
+	storage.SortCreatedAt:   {"created_at", "%s::timestamptz"},
+}
+
+// queryArgs - аргументы запроса, add возвращает плейсхолдер добавленного значения
+type queryArgs []any
+
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// songFilter строит условие WHERE по непустым полям фильтра
+func songFilter(f storage.SongFilter, args *queryArgs) string {
+	conds := []string{"1=1"}
+
+	if f.Name != "" {
+		conds = append(conds, "song_name ILIKE "+args.add(f.Pattern(f.Name)))
+	}
+	if len(f.Groups) > 0 {
+		groups := make([]string, 0, len(f.Groups))
+		for _, g := range f.Groups {
+			groups = append(groups, "group_name ILIKE "+args.add(f.Pattern(g)))
+		}
+		conds = append(conds, "("+strings.Join(groups, " OR ")+")")
+	}
+	if f.Text != "" {
+		conds = append(conds, "text_of_song ILIKE "+args.add(f.Pattern(f.Text)))
+	}
+	if f.Link != "" {
+		conds = append(conds, "link ILIKE "+args.add(f.Pattern(f.Link)))
+	}
+	if f.ArtistID != 0 {
+		conds = append(conds, "artist_id = "+args.add(f.ArtistID))
+	}
+	if f.AlbumID != 0 {
+		conds = append(conds, "album_id = "+args.add(f.AlbumID))
+	}
+	if len(f.Tags) > 0 {
+		tagged := "FROM song_tags st JOIN tags t ON t.tag_id = st.tag_id WHERE st.song_id = songs.song_id AND t.name = ANY(" + args.add(f.Tags) + ")"
+		if f.TagMatch == storage.TagMatchAll {
+			conds = append(conds, "(SELECT count(*) "+tagged+") = "+args.add(len(f.Tags)))
+		} else {
+			conds = append(conds, "EXISTS (SELECT 1 "+tagged+")")
+		}
+	}
+	if f.DateFrom != nil {
+		conds = append(conds, "release_date >= "+args.add(storage.FormatDate(*f.DateFrom))+"::date")
+	}
+	if f.DateTo != nil {
+		conds = append(conds, "release_date <= "+args.add(storage.FormatDate(*f.DateTo))+"::date")
+	}
+
+	return strings.Join(conds, " AND ")
+}
+
+// songOrder - ORDER BY по полям сортировки с song_id в конце
+func songOrder(fields []storage.SortField) string {
+	order := make([]string, 0, len(fields)+1)
+	for _, f := range fields {
+		dir := "ASC"
+		if f.Desc {
+			dir = "DESC"
+		}
+		order = append(order, sortColumns[f.Field].expr+" "+dir)
+	}
+	return strings.Join(append(order, "song_id ASC"), ", ")
+}
+
+// afterCursor - условие "строка идет после курсора" для сортировки fields:
+// (a > x) OR (a = x AND b > y) OR ... с song_id в конце
+func afterCursor(fields []storage.SortField, c storage.Cursor, args *queryArgs) string {
+	type column struct {
+		expr, value string
+		desc        bool
+	}
+	columns := make([]column, 0, len(fields)+1)
+	for i, f := range fields {
+		col := sortColumns[f.Field]
+		columns = append(columns, column{col.expr, fmt.Sprintf(col.param, args.add(c.Values[i])), f.Desc})
+	}
+	columns = append(columns, column{"song_id", args.add(c.ID), false})
+
+	ors := make([]string, 0, len(columns))
+	for i, col := range columns {
+		ands := make([]string, 0, i+1)
+		for _, prev := range columns[:i] {
+			ands = append(ands, prev.expr+" = "+prev.value)
+		}
+		op := " > "
+		if col.desc {
+			op = " < "
+		}
+		ors = append(ors, "("+strings.Join(append(ands, col.expr+op+col.value), " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
+
+func (repo *SongPostgresRepository) ListSongs(ctx context.Context, params storage.ListParams) (storage.SongPage, error) {
+	logger := reqctx.Logger(ctx)
+
+	if params.Limit < 0 || params.Offset < 0 {
+		logger.Error("error paginator", "limit", params.Limit, "offset", params.Offset)
+		return storage.SongPage{}, storage.ErrorNegativePaginator
+	}
+	if err := params.CheckCursor(); err != nil {
+		logger.Error("error cursor", "ERROR", err)
+		return storage.SongPage{}, err
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	args := queryArgs{}
+	where := songFilter(params.Filter, &args)
+
+	var total *int64
+	if params.WithTotal {
+		var n int64
+		err := repo.Pool.QueryRow(ctx, "SELECT count(*) FROM songs WHERE "+where, args...).Scan(&n)
+		if err != nil {
+			logger.Error("error exec SELECT count query to db: ", "ERROR", err)
+			return storage.SongPage{}, err
+		}
+		total = &n
+	}
+
+	if params.After != nil {
+		where += " AND " + afterCursor(params.Sort, *params.After, &args)
+	}
+	query := fmt.Sprintf("SELECT %s FROM songs WHERE %s ORDER BY %s LIMIT %s OFFSET %s",
+		songColumns, where, songOrder(params.Sort), args.add(params.Limit+1), args.add(params.Offset))
+
+	logger.Debug("result query to db", "query", query)
+
+	rows, err := repo.Pool.Query(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return storage.SongPage{}, err
+	}
+	defer rows.Close()
+
+	songs := []song.Song{}
+	for rows.Next() {
+		s, err := scanSong(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return storage.SongPage{}, err
+		}
+		songs = append(songs, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return storage.SongPage{}, err
+	}
+
+	page := params.NewSongPage(songs)
+	page.Total = total
+	if err = loadTags(ctx, repo.Pool, songRefs(page.Items)...); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return storage.SongPage{}, err
+	}
+
+	logger.Info("page of songs create success", "count", len(page.Items))
+	return page, nil
+}
@@ -0,0 +1,540 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// playlistColumns - колонки плейлиста p в порядке, который ожидает scanPlaylist
+const playlistColumns = "p.playlist_id, p.name, p.description, p.allow_duplicates, " +
+	"(SELECT count(*) FROM playlist_entries e WHERE e.playlist_id = p.playlist_id), p.created_at, p.updated_at"
+
+// entrySelect - записи плейлиста e с песнями s в порядке, который ожидает scanEntry
+var entrySelect = "SELECT " + qualified(songColumns, "s") + ", e.entry_id, e.playlist_id, e.added_at " +
+	"FROM playlist_entries e JOIN songs s ON s.song_id = e.song_id"
+
+// qualified добавляет к колонкам через запятую имя таблицы
+func qualified(columns, table string) string {
+	return table + "." + strings.ReplaceAll(columns, ", ", ", "+table+".")
+}
+
+func scanPlaylist(row pgx.Row) (song.Playlist, error) {
+	p := song.Playlist{}
+	err := row.Scan(&p.PlaylistID, &p.Name, &p.Description, &p.AllowDuplicates, &p.Size, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func scanEntry(row pgx.Row) (song.PlaylistEntry, error) {
+	e := song.PlaylistEntry{}
+	var err error
+	e.Song, err = scanSong(row, &e.EntryID, &e.PlaylistID, &e.AddedAt)
+	return e, err
+}
+
+// queryEntries возвращает записи плейлиста с песнями и их тегами, позиции не заполняются
+func queryEntries(ctx context.Context, q querier, query string, args ...any) ([]song.PlaylistEntry, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []song.PlaylistEntry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	refs := make([]*song.Song, len(entries))
+	for i := range entries {
+		refs[i] = &entries[i].Song
+	}
+	return entries, loadTags(ctx, q, refs...)
+}
+
+// lockPlaylist блокирует плейлист до конца транзакции и возвращает, разрешены ли в нем повторы
+func lockPlaylist(ctx context.Context, tx pgx.Tx, id int64) (bool, error) {
+	var allowDuplicates bool
+	err := tx.QueryRow(ctx, "SELECT allow_duplicates FROM playlists WHERE playlist_id = $1 FOR UPDATE", id).Scan(&allowDuplicates)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, storage.ErrorPlaylistNotExist
+	}
+	return allowDuplicates, err
+}
+
+// playlistOrder - записи плейлиста по порядку
+func playlistOrder(ctx context.Context, tx pgx.Tx, playlistID int64) ([]int64, error) {
+	rows, err := tx.Query(ctx, "SELECT entry_id FROM playlist_entries WHERE playlist_id = $1 ORDER BY position, entry_id", playlistID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// writeOrder нумерует записи плейлиста по порядку ids с 1
+func writeOrder(ctx context.Context, tx pgx.Tx, ids []int64) error {
+	_, err := tx.Exec(ctx, `UPDATE playlist_entries e SET position = o.n
+FROM unnest($1::bigint[]) WITH ORDINALITY AS o(entry_id, n)
+WHERE e.entry_id = o.entry_id AND e.position <> o.n`, ids)
+	return err
+}
+
+// touchPlaylist обновляет время изменения плейлиста
+func touchPlaylist(ctx context.Context, tx pgx.Tx, id int64) error {
+	_, err := tx.Exec(ctx, "UPDATE playlists SET updated_at = now() WHERE playlist_id = $1", id)
+	return err
+}
+
+// getEntry возвращает запись плейлиста с песней, position - ее место в плейлисте
+func getEntry(ctx context.Context, tx pgx.Tx, entryID int64, position int) (song.PlaylistEntry, error) {
+	entries, err := queryEntries(ctx, tx, entrySelect+" WHERE e.entry_id = $1", entryID)
+	if err != nil {
+		return song.PlaylistEntry{}, err
+	}
+	if len(entries) == 0 {
+		return song.PlaylistEntry{}, storage.ErrorEntryNotExist
+	}
+	entries[0].Position = position
+	return entries[0], nil
+}
+
+func (repo *SongPostgresRepository) AddPlaylist(ctx context.Context, playlist song.Playlist) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	playlist.Name = storage.NormalizeName(playlist.Name)
+	if playlist.Name == "" {
+		logger.Error("empty playlist name")
+		return song.Playlist{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	created, err := scanPlaylist(repo.Pool.QueryRow(ctx, "INSERT INTO playlists AS p (name, description, allow_duplicates) VALUES ($1, $2, $3) RETURNING "+playlistColumns,
+		playlist.Name, playlist.Description, playlist.AllowDuplicates))
+	if err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Playlist{}, err
+	}
+
+	logger.Info("add playlist success", "id", created.PlaylistID)
+	return created, nil
+}
+
+func (repo *SongPostgresRepository) GetPlaylistByID(ctx context.Context, id int64) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	p, err := scanPlaylist(repo.Pool.QueryRow(ctx, "SELECT "+playlistColumns+" FROM playlists p WHERE p.playlist_id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("playlist not exist", "id", id)
+			return song.Playlist{}, storage.ErrorPlaylistNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	return p, nil
+}
+
+func (repo *SongPostgresRepository) ListPlaylists(ctx context.Context, name string, limit int, offset int) ([]song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	args := queryArgs{}
+	where := "1=1"
+	if name != "" {
+		where = "p.name ILIKE " + args.add("%"+name+"%")
+	}
+	query := fmt.Sprintf("SELECT %s FROM playlists p WHERE %s ORDER BY p.playlist_id LIMIT %s OFFSET %s",
+		playlistColumns, where, args.add(limit), args.add(offset))
+
+	rows, err := repo.Pool.Query(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	playlists := []song.Playlist{}
+	for rows.Next() {
+		p, err := scanPlaylist(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		playlists = append(playlists, p)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return playlists, nil
+}
+
+func (repo *SongPostgresRepository) UpdatePlaylist(ctx context.Context, id int64, playlist song.PlaylistForUpdate) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	if playlist.Name == nil && playlist.Description == nil && playlist.AllowDuplicates == nil {
+		logger.Error("no fields to update")
+		return song.Playlist{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = lockPlaylist(ctx, tx, id); err != nil {
+		logger.Error("error lock playlist", "ERROR", err, "id", id)
+		return song.Playlist{}, err
+	}
+
+	args := queryArgs{}
+	updates := []string{"updated_at = now()"}
+	if playlist.Name != nil {
+		name := storage.NormalizeName(*playlist.Name)
+		if name == "" {
+			logger.Error("empty playlist name")
+			return song.Playlist{}, storage.ErrorEmptyName
+		}
+		updates = append(updates, "name = "+args.add(name))
+	}
+	if playlist.Description != nil {
+		updates = append(updates, "description = "+args.add(*playlist.Description))
+	}
+	if playlist.AllowDuplicates != nil {
+		if !*playlist.AllowDuplicates {
+			var repeats bool
+			err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM playlist_entries WHERE playlist_id = $1 GROUP BY song_id HAVING count(*) > 1)", id).Scan(&repeats)
+			if err != nil {
+				logger.Error("error exec SELECT query to db: ", "ERROR", err)
+				return song.Playlist{}, err
+			}
+			if repeats {
+				logger.Error("playlist has repeated songs", "id", id)
+				return song.Playlist{}, storage.ErrorPlaylistHasRepeats
+			}
+		}
+		updates = append(updates, "allow_duplicates = "+args.add(*playlist.AllowDuplicates))
+	}
+
+	query := fmt.Sprintf("UPDATE playlists p SET %s WHERE p.playlist_id = %s RETURNING %s", strings.Join(updates, ", "), args.add(id), playlistColumns)
+	updated, err := scanPlaylist(tx.QueryRow(ctx, query, args...))
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Playlist{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Playlist{}, err
+	}
+
+	logger.Info("update playlist success", "id", id)
+	return updated, nil
+}
+
+func (repo *SongPostgresRepository) DeletePlaylist(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM playlists WHERE playlist_id = $1", id)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		logger.Error("playlist not exist", "id", id)
+		return storage.ErrorPlaylistNotExist
+	}
+
+	logger.Info("delete playlist success", "id", id)
+	return nil
+}
+
+func (repo *SongPostgresRepository) AddPlaylistEntry(ctx context.Context, playlistID, songID int64, position int) (song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	allowDuplicates, err := lockPlaylist(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error lock playlist", "ERROR", err, "id", playlistID)
+		return song.PlaylistEntry{}, err
+	}
+
+	// FOR KEY SHARE не дает удалить песню до конца транзакции
+	var found bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM songs WHERE song_id = $1 FOR KEY SHARE)", songID).Scan(&found)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	if !found {
+		logger.Error("song not exist", "id", songID)
+		return song.PlaylistEntry{}, storage.ErrorSongNotExist
+	}
+
+	if !allowDuplicates {
+		err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM playlist_entries WHERE playlist_id = $1 AND song_id = $2)", playlistID, songID).Scan(&found)
+		if err != nil {
+			logger.Error("error exec SELECT query to db: ", "ERROR", err)
+			return song.PlaylistEntry{}, err
+		}
+		if found {
+			logger.Error("song is already in playlist", "playlist_id", playlistID, "song_id", songID)
+			return song.PlaylistEntry{}, storage.ErrorSongInPlaylist
+		}
+	}
+
+	ids, err := playlistOrder(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	// запись добавляется в конец, а при вставке в середину плейлист нумеруется заново
+	var entryID int64
+	err = tx.QueryRow(ctx, `INSERT INTO playlist_entries (playlist_id, song_id, position)
+VALUES ($1, $2, (SELECT coalesce(max(position), 0) + 1 FROM playlist_entries WHERE playlist_id = $1)) RETURNING entry_id`,
+		playlistID, songID).Scan(&entryID)
+	if err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	order := storage.InsertEntry(ids, entryID, position)
+	if order[len(order)-1] != entryID {
+		if err = writeOrder(ctx, tx, order); err != nil {
+			logger.Error("error exec UPDATE query to db", "ERROR", err)
+			return song.PlaylistEntry{}, err
+		}
+	}
+
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	entry, err := getEntry(ctx, tx, entryID, storage.EntryPosition(order, entryID))
+	if err != nil {
+		logger.Error("error get playlist entry", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	logger.Info("add playlist entry success", "playlist_id", playlistID, "entry_id", entryID)
+	return entry, nil
+}
+
+func (repo *SongPostgresRepository) ListPlaylistEntries(ctx context.Context, playlistID int64, limit int, offset int) ([]song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	var found bool
+	err := repo.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM playlists WHERE playlist_id = $1)", playlistID).Scan(&found)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	if !found {
+		logger.Error("playlist not exist", "id", playlistID)
+		return nil, storage.ErrorPlaylistNotExist
+	}
+
+	entries, err := queryEntries(ctx, repo.Pool, entrySelect+" WHERE e.playlist_id = $1 ORDER BY e.position, e.entry_id LIMIT $2 OFFSET $3",
+		playlistID, limit, offset)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Position = offset + i + 1
+	}
+
+	return entries, nil
+}
+
+func (repo *SongPostgresRepository) MovePlaylistEntry(ctx context.Context, playlistID, entryID int64, position int) (song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = lockPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error lock playlist", "ERROR", err, "id", playlistID)
+		return song.PlaylistEntry{}, err
+	}
+	ids, err := playlistOrder(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	order, ok := storage.MoveEntry(ids, entryID, position)
+	if !ok {
+		logger.Error("playlist entry not exist", "playlist_id", playlistID, "entry_id", entryID)
+		return song.PlaylistEntry{}, storage.ErrorEntryNotExist
+	}
+	if err = writeOrder(ctx, tx, order); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	entry, err := getEntry(ctx, tx, entryID, storage.EntryPosition(order, entryID))
+	if err != nil {
+		logger.Error("error get playlist entry", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	logger.Info("move playlist entry success", "playlist_id", playlistID, "entry_id", entryID, "position", entry.Position)
+	return entry, nil
+}
+
+func (repo *SongPostgresRepository) ReorderPlaylist(ctx context.Context, playlistID int64, entryIDs []int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = lockPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error lock playlist", "ERROR", err, "id", playlistID)
+		return err
+	}
+	ids, err := playlistOrder(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if err = storage.CheckReorder(ids, entryIDs); err != nil {
+		logger.Error("error reorder playlist", "ERROR", err, "id", playlistID)
+		return err
+	}
+	if err = writeOrder(ctx, tx, entryIDs); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("reorder playlist success", "id", playlistID)
+	return nil
+}
+
+func (repo *SongPostgresRepository) DeletePlaylistEntry(ctx context.Context, playlistID, entryID int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = lockPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error lock playlist", "ERROR", err, "id", playlistID)
+		return err
+	}
+	tag, err := tx.Exec(ctx, "DELETE FROM playlist_entries WHERE playlist_id = $1 AND entry_id = $2", playlistID, entryID)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		logger.Error("playlist entry not exist", "playlist_id", playlistID, "entry_id", entryID)
+		return storage.ErrorEntryNotExist
+	}
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("delete playlist entry success", "playlist_id", playlistID, "entry_id", entryID)
+	return nil
+}
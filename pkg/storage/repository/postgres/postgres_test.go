@@ -0,0 +1,93 @@
+package postgres_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"SongLibrary/migrations"
+	"SongLibrary/pkg/migrate"
+	"SongLibrary/pkg/storage"
+	"SongLibrary/pkg/storage/repository/postgres"
+	"SongLibrary/pkg/storage/storagetest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dsnEnv - строка подключения к отдельной тестовой базе, таблицы которой очищаются перед каждым тестом
+const dsnEnv = "POSTGRES_TEST_DSN"
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// newRepo возвращает хранилище над пустой тестовой базой, без POSTGRES_TEST_DSN тест пропускается
+func newRepo(t *testing.T) *postgres.SongPostgresRepository {
+	t.Helper()
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skip(dsnEnv + " is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrateOnce.Do(func() {
+		m, err := migrate.NewMigrator(pool, migrations.FS, slog.Default())
+		if err != nil {
+			migrateErr = err
+			return
+		}
+		_, migrateErr = m.Up(ctx)
+	})
+	if migrateErr != nil {
+		t.Fatal(migrateErr)
+	}
+
+	_, err = pool.Exec(ctx, "TRUNCATE "+strings.Join(storage.CatalogTables, ", ")+" RESTART IDENTITY CASCADE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return postgres.NewSongPostgresRepository(pool, storage.DefaultTimeouts())
+}
+
+func TestSongRepo(t *testing.T) {
+	storagetest.RunSongRepoTests(t, func(t *testing.T) storage.SongRepo { return newRepo(t) })
+}
+
+func TestJobRepo(t *testing.T) {
+	storagetest.RunJobRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestSearchRepo(t *testing.T) {
+	storagetest.RunSearchRepoTests(t, func(t *testing.T) storagetest.SearchableRepo { return newRepo(t) })
+}
+
+func TestArtistRepo(t *testing.T) {
+	storagetest.RunArtistRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestTagRepo(t *testing.T) {
+	storagetest.RunTagRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestPlaylistRepo(t *testing.T) {
+	storagetest.RunPlaylistRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestImportRepo(t *testing.T) {
+	storagetest.RunImportRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestBackup(t *testing.T) {
+	storagetest.RunBackupTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
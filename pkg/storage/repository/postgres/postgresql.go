@@ -4,11 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
-	"os"
 	"strings"
 	"time"
 
+	"SongLibrary/pkg/reqctx"
 	"SongLibrary/pkg/song"
 	"SongLibrary/pkg/storage"
 
@@ -17,126 +16,147 @@ import (
 )
 
 type SongPostgresRepository struct {
-	Pool *pgxpool.Pool
+	Pool     *pgxpool.Pool
+	Timeouts storage.Timeouts
 }
 
-func NewSongPostgresRepository(pool *pgxpool.Pool) *SongPostgresRepository {
-	return &SongPostgresRepository{
-		Pool: pool,
+// songColumns - колонки песни в порядке, который ожидает scanSong
+const songColumns = "song_id, song_name, group_name, artist_id, album_id, release_date, release_date_precision, text_of_song, link, enrichment_status, created_at"
+
+func scanSong(row pgx.Row, extra ...any) (song.Song, error) {
+	s := song.Song{}
+	var albumID *int64
+	var date *time.Time
+	var precision string
+	dest := append([]any{&s.SongID, &s.Song, &s.Group, &s.ArtistID, &albumID, &date, &precision, &s.Text, &s.Link, &s.EnrichmentStatus, &s.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return s, err
+	}
+	if albumID != nil {
+		s.AlbumID = *albumID
+	}
+	if date != nil {
+		s.ReleaseDate = song.NewReleaseDate(*date, precision)
 	}
+	return s, nil
 }
 
-func NewConnPostgres(logger *slog.Logger) (*pgxpool.Pool, error) {
-	coonString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_HOST"),
-		os.Getenv("POSTGRES_PORT"),
-		os.Getenv("POSTGRES_DB"),
-	)
-
-	slog.Info("Please wait...")
-	time.Sleep(3 * time.Second) // поднятие базы
-
-	pool, err := pgxpool.New(context.Background(), coonString)
-	if err != nil {
-		logger.Error("error create connect to db:",
-			"error", err,
-		)
-		return nil, err
+// releaseDate - значения колонок release_date и release_date_precision, NULL для неизвестной даты
+func releaseDate(d song.ReleaseDate) (any, string) {
+	if d.IsZero() {
+		return nil, ""
 	}
+	return d.Date, d.Precision
+}
 
-	err = pool.Ping(context.Background())
-	if err != nil {
-		logger.Error("error ping to db:",
-			"error", err,
-		)
-		return nil, err
+func NewSongPostgresRepository(pool *pgxpool.Pool, timeouts storage.Timeouts) *SongPostgresRepository {
+	return &SongPostgresRepository{
+		Pool:     pool,
+		Timeouts: timeouts,
 	}
+}
 
-	logger.Info("connect to db create success")
+func (repo *SongPostgresRepository) AddSongToDB(ctx context.Context, s song.Song) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
 
-	return pool, nil
-}
+	tags, err := storage.NormalizeTags(s.Tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
 
-func (repo *SongPostgresRepository) AddSongToDB(logger *slog.Logger, s song.Song) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
 	defer cancel()
 
 	tx, err := repo.Pool.Begin(ctx)
 	if err != nil {
 		logger.Error("error begin tx", "ERROR", err)
-		return err
+		return song.Song{}, err
 	}
 	defer tx.Rollback(ctx)
 
+	artist, err := resolveArtist(ctx, tx, s.Group)
+	if err != nil {
+		logger.Error("error resolve artist", "ERROR", err)
+		return song.Song{}, err
+	}
+
 	var id int
-	err = tx.QueryRow(ctx, "select song_id from songs where song_name = $1 and group_name = $2", s.Song, s.Group).Scan(&id)
+	err = tx.QueryRow(ctx, "select song_id from songs where song_name = $1 and artist_id = $2", s.Song, artist.ArtistID).Scan(&id)
 	if err == nil {
 		logger.Error("this song exist")
-		return storage.ErrorSongExist
+		return song.Song{}, storage.ErrorSongExist
 	} else if err != pgx.ErrNoRows {
 		logger.Error("error exec SELECT query to db: ", "ERROR", err)
-		return err
+		return song.Song{}, err
+	}
+
+	if s.AlbumID != 0 {
+		if err = checkAlbum(ctx, tx, s.AlbumID, artist.ArtistID); err != nil {
+			logger.Error("error check album", "ERROR", err, "album_id", s.AlbumID)
+			return song.Song{}, err
+		}
+	}
+
+	if s.EnrichmentStatus == "" {
+		s.EnrichmentStatus = song.EnrichmentDone
 	}
 
-	_, err = tx.Exec(ctx, "INSERT INTO songs (song_name, group_name, release_date, text_of_song, link) VALUES ($1, $2, $3, $4, $5)",
+	date, precision := releaseDate(s.ReleaseDate)
+	created, err := scanSong(tx.QueryRow(ctx, "INSERT INTO songs (song_name, group_name, artist_id, album_id, release_date, release_date_precision, text_of_song, link, enrichment_status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "+songColumns,
 		s.Song,
-		s.Group,
-		s.ReleaseDate,
+		artist.Name,
+		artist.ArtistID,
+		nullID(s.AlbumID),
+		date,
+		precision,
 		s.Text,
 		s.Link,
-	)
+		s.EnrichmentStatus,
+	))
 	if err != nil {
 		logger.Error("error exec INSERT query to db: ", "ERROR", err)
-		return err
+		return song.Song{}, err
+	}
+
+	if err = saveTags(ctx, tx, created.SongID, tags); err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+	created.Tags = tags
+
+	if created.EnrichmentStatus == song.EnrichmentPending {
+		_, err = tx.Exec(ctx, "INSERT INTO enrichment_jobs (song_id) VALUES ($1)", created.SongID)
+		if err != nil {
+			logger.Error("error exec INSERT query to db: ", "ERROR", err)
+			return song.Song{}, err
+		}
+		logger.Info("enrichment job create success", "song_id", created.SongID)
 	}
 
 	if err = tx.Commit(ctx); err != nil {
 		logger.Error("error in tx", "ERROR", err)
-		return err
+		return song.Song{}, err
 	}
 
-	return nil
+	return created, nil
 }
 
-func (repo *SongPostgresRepository) GetSongsFromDB(logger *slog.Logger, s song.Song, limit int, offset int) ([]song.Song, error) {
-	query := "SELECT song_id, song_name, group_name, release_date, text_of_song, link FROM songs WHERE 1=1"
-	args := []interface{}{}
-	argIndex := 1
+func (repo *SongPostgresRepository) GetSongsFromDB(ctx context.Context, s song.Song, limit int, offset int) ([]song.Song, error) {
+	logger := reqctx.Logger(ctx)
 
-	if s.Song != "" {
-		query += fmt.Sprintf(" AND song_name ILIKE $%d", argIndex)
-		args = append(args, "%"+s.Song+"%")
-		argIndex++
-	}
-	if s.Group != "" {
-		query += fmt.Sprintf(" AND group_name ILIKE $%d", argIndex)
-		args = append(args, "%"+s.Group+"%")
-		argIndex++
-	}
-	if s.Text != "" {
-		query += fmt.Sprintf(" AND text_of_song ILIKE $%d", argIndex)
-		args = append(args, "%"+s.Text+"%")
-		argIndex++
-	}
-	if s.ReleaseDate != "" {
-		query += fmt.Sprintf(" AND release_date ILIKE $%d", argIndex)
-		args = append(args, "%"+s.ReleaseDate+"%")
-		argIndex++
-	}
-	if s.Link != "" {
-		query += fmt.Sprintf(" AND group_name ILIKE $%d", argIndex)
-		args = append(args, "%"+s.Link+"%")
-		argIndex++
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
 	}
 
-	query += fmt.Sprintf(" ORDER BY song_id LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	args = append(args, limit, offset)
+	args := queryArgs{}
+	where := songFilter(storage.NewSongFilter(s), &args)
+	query := fmt.Sprintf("SELECT %s FROM songs WHERE %s ORDER BY song_id LIMIT %s OFFSET %s", songColumns, where, args.add(limit), args.add(offset))
 
 	logger.Debug("result query to db", "query", query)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
 	defer cancel()
 	rows, err := repo.Pool.Query(ctx, query, args...)
 	if err != nil {
@@ -147,13 +167,17 @@ func (repo *SongPostgresRepository) GetSongsFromDB(logger *slog.Logger, s song.S
 
 	songs := []song.Song{}
 	for rows.Next() {
-		s := song.Song{}
-		if err := rows.Scan(&s.SongID, &s.Song, &s.Group, &s.ReleaseDate, &s.Text, &s.Link); err != nil {
+		s, err := scanSong(rows)
+		if err != nil {
 			logger.Error("error scan row", "ERROR", err)
 			return nil, err
 		}
 		songs = append(songs, s)
 	}
+	if err = loadTags(ctx, repo.Pool, songRefs(songs)...); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return nil, err
+	}
 
 	if len(songs) == 0 {
 		logger.Error("error list of songs", "ERROR", storage.ErrorListOfSongsEmpty)
@@ -165,8 +189,10 @@ func (repo *SongPostgresRepository) GetSongsFromDB(logger *slog.Logger, s song.S
 	return songs, nil
 }
 
-func (repo *SongPostgresRepository) DeleteSongByIDFromDB(logger *slog.Logger, id int) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (repo *SongPostgresRepository) DeleteSongByIDFromDB(ctx context.Context, id int) (int, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
 	defer cancel()
 
 	tx, err := repo.Pool.Begin(ctx)
@@ -202,8 +228,10 @@ func (repo *SongPostgresRepository) DeleteSongByIDFromDB(logger *slog.Logger, id
 	return id, nil
 }
 
-func (repo *SongPostgresRepository) GetTextOfSongFromDB(logger *slog.Logger, id int) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (repo *SongPostgresRepository) GetTextOfSongFromDB(ctx context.Context, id int) (string, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
 	defer cancel()
 
 	var text string
@@ -221,31 +249,75 @@ func (repo *SongPostgresRepository) GetTextOfSongFromDB(logger *slog.Logger, id
 	return text, nil
 }
 
-func (repo *SongPostgresRepository) UpdateSongByID(logger *slog.Logger, s song.SongForUpdate, id int) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (repo *SongPostgresRepository) GetSongByID(ctx context.Context, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	s, err := scanSong(repo.Pool.QueryRow(ctx, "SELECT "+songColumns+" FROM songs WHERE song_id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("song not exist", "id", id)
+			return song.Song{}, storage.ErrorSongNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+	if err = loadTags(ctx, repo.Pool, &s); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("get song success", "id", id)
+	return s, nil
+}
+
+func (repo *SongPostgresRepository) UpdateSongByID(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
 	defer cancel()
 
 	tx, err := repo.Pool.Begin(ctx)
 	if err != nil {
 		logger.Error("error begin tx", "ERROR", err)
-		return 0, err
+		return song.Song{}, err
 	}
 	defer tx.Rollback(ctx)
 
-	var flagID int
-	err = tx.QueryRow(ctx, "select song_id from songs where song_id = $1", id).Scan(&flagID)
+	updated, err := updateSong(ctx, tx, s, id)
+	if err != nil {
+		return song.Song{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("update song success", "id", id)
+	return updated, nil
+}
+
+// updateSong меняет поля песни в транзакции tx и возвращает сохраненную песню
+func updateSong(ctx context.Context, tx pgx.Tx, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	var artistID int64
+	err := tx.QueryRow(ctx, "select artist_id from songs where song_id = $1", id).Scan(&artistID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			logger.Error("song not exist", "id", id)
-			return id, storage.ErrorSongNotExist
+			return song.Song{}, storage.ErrorSongNotExist
 		}
 		logger.Error("error exec SELECT query to db: ", "ERROR", err)
-		return 0, err
+		return song.Song{}, err
 	}
 
-	if s.Song == nil && s.Group == nil && s.Text == nil && s.ReleaseDate == nil && s.Link == nil {
+	if s.Song == nil && s.Group == nil && s.Text == nil && s.ReleaseDate == nil && s.Link == nil && s.AlbumID == nil && s.EnrichmentStatus == nil {
 		logger.Error("no fields to update")
-		return 0, fmt.Errorf("no fields to update")
+		return song.Song{}, storage.ErrorNoFieldsToUpdate
 	}
 
 	updates := []string{}
@@ -258,8 +330,29 @@ func (repo *SongPostgresRepository) UpdateSongByID(logger *slog.Logger, s song.S
 		argIndex++
 	}
 	if s.Group != nil {
-		updates = append(updates, fmt.Sprintf("group_name = $%d", argIndex))
-		args = append(args, *s.Group)
+		artist, err := resolveArtist(ctx, tx, *s.Group)
+		if err != nil {
+			logger.Error("error resolve artist", "ERROR", err)
+			return song.Song{}, err
+		}
+		updates = append(updates, fmt.Sprintf("group_name = $%d, artist_id = $%d", argIndex, argIndex+1))
+		args = append(args, artist.Name, artist.ArtistID)
+		argIndex += 2
+		artistID = artist.ArtistID
+		// при смене исполнителя песня отвязывается от альбома прежнего исполнителя
+		if s.AlbumID == nil {
+			updates = append(updates, fmt.Sprintf("album_id = CASE WHEN artist_id = $%d THEN album_id END", argIndex-1))
+		}
+	}
+	if s.AlbumID != nil {
+		if *s.AlbumID != 0 {
+			if err = checkAlbum(ctx, tx, *s.AlbumID, artistID); err != nil {
+				logger.Error("error check album", "ERROR", err, "album_id", *s.AlbumID)
+				return song.Song{}, err
+			}
+		}
+		updates = append(updates, fmt.Sprintf("album_id = $%d", argIndex))
+		args = append(args, nullID(*s.AlbumID))
 		argIndex++
 	}
 	if s.Text != nil {
@@ -268,34 +361,38 @@ func (repo *SongPostgresRepository) UpdateSongByID(logger *slog.Logger, s song.S
 		argIndex++
 	}
 	if s.ReleaseDate != nil {
-		updates = append(updates, fmt.Sprintf("release_date = $%d", argIndex))
-		args = append(args, *s.ReleaseDate)
-		argIndex++
+		date, precision := releaseDate(*s.ReleaseDate)
+		updates = append(updates, fmt.Sprintf("release_date = $%d, release_date_precision = $%d", argIndex, argIndex+1))
+		args = append(args, date, precision)
+		argIndex += 2
 	}
 	if s.Link != nil {
 		updates = append(updates, fmt.Sprintf("link = $%d", argIndex))
 		args = append(args, *s.Link)
 		argIndex++
 	}
+	if s.EnrichmentStatus != nil {
+		updates = append(updates, fmt.Sprintf("enrichment_status = $%d", argIndex))
+		args = append(args, *s.EnrichmentStatus)
+		argIndex++
+	}
 
 	args = append(args, id)
 
-	query := fmt.Sprintf("UPDATE songs SET %s WHERE song_id = $%d", strings.Join(updates, ", "), argIndex)
+	query := fmt.Sprintf("UPDATE songs SET %s WHERE song_id = $%d RETURNING %s", strings.Join(updates, ", "), argIndex, songColumns)
 	logger.Debug("get result query", "query", query)
 
-	_, err = tx.Exec(context.Background(), query, args...)
+	updated, err := scanSong(tx.QueryRow(ctx, query, args...))
 	if err != nil {
 		logger.Error("error exec UPDATE query to db", "ERROR", err)
-		return 0, err
+		return song.Song{}, err
 	}
-
-	if err = tx.Commit(ctx); err != nil {
-		logger.Error("error in tx", "ERROR", err)
-		return 0, err
+	if err = loadTags(ctx, tx, &updated); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return song.Song{}, err
 	}
 
-	logger.Info("update song success", "id", id)
-	return id, nil
+	return updated, nil
 }
 
 func (p *SongPostgresRepository) Close() {
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/storage"
+)
+
+// Reindex перестраивает индексы каждой таблицы, в том числе GIN-индекс поиска, CONCURRENTLY:
+// записи в таблицы во время перестроения не блокируются. Запрос не ограничен таймаутом хранилища,
+// время задается контекстом
+func (repo *SongPostgresRepository) Reindex(ctx context.Context) ([]string, error) {
+	logger := reqctx.Logger(ctx)
+
+	done := []string{}
+	for _, table := range storage.CatalogTables {
+		// REINDEX CONCURRENTLY нельзя выполнять в транзакции, поэтому каждая таблица отдельно
+		if _, err := repo.Pool.Exec(ctx, "REINDEX TABLE CONCURRENTLY "+table); err != nil {
+			logger.Error("error reindex table", "table", table, "ERROR", err)
+			return done, err
+		}
+		if _, err := repo.Pool.Exec(ctx, "ANALYZE "+table); err != nil {
+			logger.Error("error analyze table", "table", table, "ERROR", err)
+			return done, err
+		}
+		logger.Info("reindex table success", "table", table)
+		done = append(done, table)
+	}
+	return done, nil
+}
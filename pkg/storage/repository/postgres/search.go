@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// searchConfigs - конфигурации полнотекстового поиска, в которых построен songs.search_vector
+var searchConfigs = []string{"russian", "english"}
+
+// headlineOptions - подсветка совпадений в куплете целиком
+const headlineOptions = "StartSel=<b>, StopSel=</b>, HighlightAll=true"
+
+func (repo *SongPostgresRepository) SearchSongs(ctx context.Context, query string, limit int, offset int) ([]song.SearchResult, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	terms, err := storage.ParseSearchQuery(query)
+	if err != nil {
+		logger.Error("error parse search query", "ERROR", err)
+		return nil, err
+	}
+
+	tsquery, args := buildTSQuery(terms)
+	n := len(args)
+	args = append(args, limit, offset)
+
+	// сниппет - первый куплет, в котором есть совпадение; если совпало только
+	// название или группа, сниппет пустой
+	sql := fmt.Sprintf(`WITH q AS (SELECT %s AS query)
+SELECT %[5]s,
+	ts_rank_cd(s.search_vector, q.query) AS rank, coalesce(h.snippet, '') AS snippet
+FROM songs s
+CROSS JOIN q
+LEFT JOIN LATERAL (
+	SELECT CASE WHEN to_tsvector('russian', v.verse) @@ q.query
+		THEN ts_headline('russian', v.verse, q.query, '%[2]s')
+		ELSE ts_headline('english', v.verse, q.query, '%[2]s')
+	END AS snippet
+	FROM unnest(string_to_array(s.text_of_song, E'\n\n')) WITH ORDINALITY AS v(verse, n)
+	WHERE (to_tsvector('russian', v.verse) || to_tsvector('english', v.verse)) @@ q.query
+	ORDER BY v.n
+	LIMIT 1
+) h ON true
+WHERE s.search_vector @@ q.query
+ORDER BY rank DESC, s.song_id
+LIMIT $%[3]d OFFSET $%[4]d`, tsquery, headlineOptions, n+1, n+2, qualified(songColumns, "s"))
+
+	logger.Debug("result query to db", "query", sql)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	rows, err := repo.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []song.SearchResult{}
+	for rows.Next() {
+		r := song.SearchResult{}
+		r.Song, err = scanSong(rows, &r.Rank, &r.Snippet)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	refs := make([]*song.Song, len(results))
+	for i := range results {
+		refs[i] = &results[i].Song
+	}
+	if err = loadTags(ctx, repo.Pool, refs...); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return nil, err
+	}
+
+	logger.Info("search songs success", "found", len(results))
+	return results, nil
+}
+
+// buildTSQuery собирает tsquery из частей запроса: в каждой конфигурации части
+// объединяются через &&, а конфигурации между собой через ||
+func buildTSQuery(terms []storage.SearchTerm) (string, []any) {
+	args := make([]any, 0, len(terms))
+	for _, t := range terms {
+		if t.Prefix {
+			// префикс передается как лексема в кавычках с пометкой :*
+			text := strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(t.Text)
+			args = append(args, "'"+text+"':*")
+		} else {
+			args = append(args, t.Text)
+		}
+	}
+
+	byConfig := make([]string, 0, len(searchConfigs))
+	for _, cfg := range searchConfigs {
+		parts := make([]string, 0, len(terms))
+		for i, t := range terms {
+			fn := "plainto_tsquery"
+			switch {
+			case t.Phrase:
+				fn = "phraseto_tsquery"
+			case t.Prefix:
+				fn = "to_tsquery"
+			}
+			parts = append(parts, fmt.Sprintf("%s('%s', $%d)", fn, cfg, i+1))
+		}
+		byConfig = append(byConfig, "("+strings.Join(parts, " && ")+")")
+	}
+
+	return strings.Join(byConfig, " || "), args
+}
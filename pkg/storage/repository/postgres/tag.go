@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// querier - пул или транзакция
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// songRefs - указатели на песни среза для loadTags
+func songRefs(songs []song.Song) []*song.Song {
+	refs := make([]*song.Song, len(songs))
+	for i := range songs {
+		refs[i] = &songs[i]
+	}
+	return refs
+}
+
+// loadTags заполняет теги песен одним запросом
+func loadTags(ctx context.Context, q querier, songs ...*song.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(songs))
+	byID := make(map[int64]*song.Song, len(songs))
+	for _, s := range songs {
+		s.Tags = []string{}
+		ids = append(ids, s.SongID)
+		byID[s.SongID] = s
+	}
+
+	rows, err := q.Query(ctx, "SELECT st.song_id, t.name FROM song_tags st JOIN tags t ON t.tag_id = st.tag_id WHERE st.song_id = ANY($1) ORDER BY t.name", ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err = rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, name)
+	}
+	return rows.Err()
+}
+
+// saveTags добавляет песне теги, приведенные storage.NormalizeTags
+func saveTags(ctx context.Context, tx pgx.Tx, songID int64, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, "INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING", tags)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO song_tags (song_id, tag_id) SELECT $1, tag_id FROM tags WHERE name = ANY($2) ON CONFLICT DO NOTHING", songID, tags)
+	return err
+}
+
+// lockSong проверяет, что песня есть, и блокирует ее до конца транзакции
+func lockSong(ctx context.Context, tx pgx.Tx, id int) error {
+	var songID int64
+	err := tx.QueryRow(ctx, "SELECT song_id FROM songs WHERE song_id = $1 FOR UPDATE", id).Scan(&songID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrorSongNotExist
+	}
+	return err
+}
+
+func (repo *SongPostgresRepository) AddSongTags(ctx context.Context, songID int, tags []string) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	tags, err := storage.NormalizeTags(tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	updateCtx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(updateCtx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback(updateCtx)
+
+	if err = lockSong(updateCtx, tx, songID); err != nil {
+		logger.Error("error lock song", "ERROR", err, "id", songID)
+		return song.Song{}, err
+	}
+	if err = saveTags(updateCtx, tx, int64(songID), tags); err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+	if err = tx.Commit(updateCtx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("add song tags success", "id", songID, "tags", tags)
+	return repo.GetSongByID(ctx, songID)
+}
+
+func (repo *SongPostgresRepository) RemoveSongTags(ctx context.Context, songID int, tags []string) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	tags, err := storage.NormalizeTags(tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	updateCtx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.Pool.Begin(updateCtx)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback(updateCtx)
+
+	if err = lockSong(updateCtx, tx, songID); err != nil {
+		logger.Error("error lock song", "ERROR", err, "id", songID)
+		return song.Song{}, err
+	}
+	_, err = tx.Exec(updateCtx, "DELETE FROM song_tags WHERE song_id = $1 AND tag_id IN (SELECT tag_id FROM tags WHERE name = ANY($2))", songID, tags)
+	if err != nil {
+		logger.Error("error exec DELETE query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+	if err = tx.Commit(updateCtx); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("remove song tags success", "id", songID, "tags", tags)
+	return repo.GetSongByID(ctx, songID)
+}
+
+func (repo *SongPostgresRepository) ListTags(ctx context.Context, name string, limit int, offset int) ([]song.Tag, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	args := queryArgs{}
+	where := "1=1"
+	if name != "" {
+		where = "t.name ILIKE " + args.add("%"+name+"%")
+	}
+	query := fmt.Sprintf(`SELECT t.name, count(*) AS songs FROM tags t JOIN song_tags st ON st.tag_id = t.tag_id
+WHERE %s GROUP BY t.name ORDER BY songs DESC, t.name LIMIT %s OFFSET %s`, where, args.add(limit), args.add(offset))
+
+	rows, err := repo.Pool.Query(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []song.Tag{}
+	for rows.Next() {
+		t := song.Tag{}
+		if err = rows.Scan(&t.Name, &t.Songs); err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
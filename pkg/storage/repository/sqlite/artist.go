@@ -0,0 +1,503 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+const (
+	artistColumns = "artist_id, name, created_at"
+	albumColumns  = "album_id, artist_id, title, release_date, release_date_precision, created_at"
+)
+
+func scanArtist(row scanner) (song.Artist, error) {
+	a := song.Artist{}
+	var createdAt string
+	if err := row.Scan(&a.ArtistID, &a.Name, &createdAt); err != nil {
+		return a, err
+	}
+	var err error
+	a.CreatedAt, err = time.Parse(timeFormat, createdAt)
+	return a, err
+}
+
+func scanAlbum(row scanner) (song.Album, error) {
+	a := song.Album{}
+	var date, precision, createdAt string
+	if err := row.Scan(&a.AlbumID, &a.ArtistID, &a.Title, &date, &precision, &createdAt); err != nil {
+		return a, err
+	}
+	if date != "" {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return a, err
+		}
+		a.ReleaseDate = song.NewReleaseDate(t, precision)
+	}
+	var err error
+	a.CreatedAt, err = time.Parse(timeFormat, createdAt)
+	return a, err
+}
+
+// nullID - NULL вместо нулевого id
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// resolveArtist находит исполнителя по ключу имени или создает его
+func resolveArtist(ctx context.Context, tx *sql.Tx, name string) (song.Artist, error) {
+	name = storage.NormalizeName(name)
+	if name == "" {
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	a, err := scanArtist(tx.QueryRowContext(ctx, "SELECT "+artistColumns+" FROM artists WHERE name_key = ?", storage.NameKey(name)))
+	if errors.Is(err, sql.ErrNoRows) {
+		a, err = scanArtist(tx.QueryRowContext(ctx, "INSERT INTO artists (name, name_key, created_at) VALUES (?, ?, ?) RETURNING "+artistColumns,
+			name, storage.NameKey(name), formatTime(time.Now()),
+		))
+	}
+	return a, err
+}
+
+// checkAlbum проверяет, что альбом существует и принадлежит исполнителю
+func checkAlbum(ctx context.Context, tx *sql.Tx, albumID, artistID int64) error {
+	var owner int64
+	err := tx.QueryRowContext(ctx, "SELECT artist_id FROM albums WHERE album_id = ?", albumID).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrorAlbumNotExist
+	}
+	if err != nil {
+		return err
+	}
+	if owner != artistID {
+		return storage.ErrorAlbumOfOtherArtist
+	}
+	return nil
+}
+
+// exists - есть ли строка под запросом
+func exists(ctx context.Context, tx *sql.Tx, query string, args ...any) (bool, error) {
+	var found bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS ("+query+")", args...).Scan(&found)
+	return found, err
+}
+
+func (repo *SongSQLiteRepository) AddArtist(ctx context.Context, name string) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	name = storage.NormalizeName(name)
+	if name == "" {
+		logger.Error("empty artist name")
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Artist{}, err
+	}
+	defer tx.Rollback()
+
+	found, err := exists(ctx, tx, "SELECT 1 FROM artists WHERE name_key = ?", storage.NameKey(name))
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Artist{}, err
+	}
+	if found {
+		logger.Error("this artist exist", "name", name)
+		return song.Artist{}, storage.ErrorArtistExist
+	}
+
+	a, err := resolveArtist(ctx, tx, name)
+	if err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	logger.Info("add artist success", "id", a.ArtistID)
+	return a, nil
+}
+
+func (repo *SongSQLiteRepository) GetArtistByID(ctx context.Context, id int64) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	a, err := scanArtist(repo.DB.QueryRowContext(ctx, "SELECT "+artistColumns+" FROM artists WHERE artist_id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("artist not exist", "id", id)
+			return song.Artist{}, storage.ErrorArtistNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	return a, nil
+}
+
+func (repo *SongSQLiteRepository) ListArtists(ctx context.Context, name string, limit int, offset int) ([]song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	args := queryArgs{}
+	where := "1=1"
+	if name != "" {
+		where = "ilike(name, " + args.add("%"+name+"%") + ")"
+	}
+	query := "SELECT " + artistColumns + " FROM artists WHERE " + where + " ORDER BY name_key, artist_id LIMIT " + args.add(limit) + " OFFSET " + args.add(offset)
+
+	rows, err := repo.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	artists := []song.Artist{}
+	for rows.Next() {
+		a, err := scanArtist(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		artists = append(artists, a)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return artists, nil
+}
+
+func (repo *SongSQLiteRepository) RenameArtist(ctx context.Context, id int64, name string) (song.Artist, error) {
+	logger := reqctx.Logger(ctx)
+
+	name = storage.NormalizeName(name)
+	if name == "" {
+		logger.Error("empty artist name")
+		return song.Artist{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Artist{}, err
+	}
+	defer tx.Rollback()
+
+	found, err := exists(ctx, tx, "SELECT 1 FROM artists WHERE name_key = ? AND artist_id <> ?", storage.NameKey(name), id)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Artist{}, err
+	}
+	if found {
+		logger.Error("this artist exist", "name", name)
+		return song.Artist{}, storage.ErrorArtistExist
+	}
+
+	a, err := scanArtist(tx.QueryRowContext(ctx, "UPDATE artists SET name = ?, name_key = ? WHERE artist_id = ? RETURNING "+artistColumns, name, storage.NameKey(name), id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("artist not exist", "id", id)
+			return song.Artist{}, storage.ErrorArtistNotExist
+		}
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE songs SET group_name = ? WHERE artist_id = ?", a.Name, id)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Artist{}, err
+	}
+
+	logger.Info("rename artist success", "id", id)
+	return a, nil
+}
+
+func (repo *SongSQLiteRepository) DeleteArtist(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	hasSongs, err := exists(ctx, tx, "SELECT 1 FROM songs WHERE artist_id = ?", id)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if hasSongs {
+		logger.Error("artist has songs", "id", id)
+		return storage.ErrorArtistHasSongs
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM artists WHERE artist_id = ?", id)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		logger.Error("artist not exist", "id", id)
+		return storage.ErrorArtistNotExist
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("delete artist success", "id", id)
+	return nil
+}
+
+func (repo *SongSQLiteRepository) AddAlbum(ctx context.Context, album song.Album) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	album.Title = storage.NormalizeName(album.Title)
+	if album.Title == "" {
+		logger.Error("empty album title")
+		return song.Album{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Album{}, err
+	}
+	defer tx.Rollback()
+
+	found, err := exists(ctx, tx, "SELECT 1 FROM artists WHERE artist_id = ?", album.ArtistID)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Album{}, err
+	}
+	if !found {
+		logger.Error("artist not exist", "id", album.ArtistID)
+		return song.Album{}, storage.ErrorArtistNotExist
+	}
+
+	found, err = exists(ctx, tx, "SELECT 1 FROM albums WHERE artist_id = ? AND title_key = ?", album.ArtistID, storage.NameKey(album.Title))
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Album{}, err
+	}
+	if found {
+		logger.Error("this album exist", "title", album.Title)
+		return song.Album{}, storage.ErrorAlbumExist
+	}
+
+	date, precision := releaseDate(album.ReleaseDate)
+	created, err := scanAlbum(tx.QueryRowContext(ctx, "INSERT INTO albums (artist_id, title, title_key, release_date, release_date_precision, created_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING "+albumColumns,
+		album.ArtistID, album.Title, storage.NameKey(album.Title), date, precision, formatTime(time.Now()),
+	))
+	if err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	logger.Info("add album success", "id", created.AlbumID)
+	return created, nil
+}
+
+func (repo *SongSQLiteRepository) GetAlbumByID(ctx context.Context, id int64) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	a, err := scanAlbum(repo.DB.QueryRowContext(ctx, "SELECT "+albumColumns+" FROM albums WHERE album_id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("album not exist", "id", id)
+			return song.Album{}, storage.ErrorAlbumNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	return a, nil
+}
+
+func (repo *SongSQLiteRepository) ListAlbums(ctx context.Context, artistID int64, limit int, offset int) ([]song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	rows, err := repo.DB.QueryContext(ctx, "SELECT "+albumColumns+" FROM albums WHERE artist_id = ? ORDER BY release_date, album_id LIMIT ? OFFSET ?",
+		artistID, limit, offset)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	albums := []song.Album{}
+	for rows.Next() {
+		a, err := scanAlbum(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		albums = append(albums, a)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return albums, nil
+}
+
+func (repo *SongSQLiteRepository) UpdateAlbum(ctx context.Context, id int64, album song.AlbumForUpdate) (song.Album, error) {
+	logger := reqctx.Logger(ctx)
+
+	if album.Title == nil && album.ReleaseDate == nil {
+		logger.Error("no fields to update")
+		return song.Album{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Album{}, err
+	}
+	defer tx.Rollback()
+
+	var artistID int64
+	err = tx.QueryRowContext(ctx, "SELECT artist_id FROM albums WHERE album_id = ?", id).Scan(&artistID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("album not exist", "id", id)
+			return song.Album{}, storage.ErrorAlbumNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	updates := []string{}
+	args := []any{}
+	if album.Title != nil {
+		title := storage.NormalizeName(*album.Title)
+		if title == "" {
+			logger.Error("empty album title")
+			return song.Album{}, storage.ErrorEmptyName
+		}
+
+		found, err := exists(ctx, tx, "SELECT 1 FROM albums WHERE artist_id = ? AND title_key = ? AND album_id <> ?", artistID, storage.NameKey(title), id)
+		if err != nil {
+			logger.Error("error exec SELECT query to db: ", "ERROR", err)
+			return song.Album{}, err
+		}
+		if found {
+			logger.Error("this album exist", "title", title)
+			return song.Album{}, storage.ErrorAlbumExist
+		}
+
+		updates = append(updates, "title = ?, title_key = ?")
+		args = append(args, title, storage.NameKey(title))
+	}
+	if album.ReleaseDate != nil {
+		date, precision := releaseDate(*album.ReleaseDate)
+		updates = append(updates, "release_date = ?, release_date_precision = ?")
+		args = append(args, date, precision)
+	}
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE albums SET %s WHERE album_id = ? RETURNING %s", strings.Join(updates, ", "), albumColumns)
+	updated, err := scanAlbum(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Album{}, err
+	}
+
+	logger.Info("update album success", "id", id)
+	return updated, nil
+}
+
+func (repo *SongSQLiteRepository) DeleteAlbum(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	// песни альбома отвязываются внешним ключом ON DELETE SET NULL
+	res, err := repo.DB.ExecContext(ctx, "DELETE FROM albums WHERE album_id = ?", id)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		logger.Error("album not exist", "id", id)
+		return storage.ErrorAlbumNotExist
+	}
+
+	logger.Info("delete album success", "id", id)
+	return nil
+}
@@ -0,0 +1,14 @@
+package sqlite
+
+import (
+	"context"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// ExportSongs читает песни страницами: у базы одно соединение, и открытый на всю выгрузку
+// запрос остановил бы остальные запросы, пока клиент медленно читает ответ
+func (repo *SongSQLiteRepository) ExportSongs(ctx context.Context, params storage.ListParams, fn func(song.Song) error) error {
+	return storage.ExportPages(ctx, repo, params, fn)
+}
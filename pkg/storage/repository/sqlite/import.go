@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// importUpdate переносит в песню непустые поля из файла, как storage.MergeImport
+const importUpdate = `UPDATE songs SET
+	release_date = CASE WHEN ?1 = '' THEN release_date ELSE ?1 END,
+	release_date_precision = CASE WHEN ?1 = '' THEN release_date_precision ELSE ?2 END,
+	text_of_song = CASE WHEN ?3 = '' THEN text_of_song ELSE ?3 END,
+	link = CASE WHEN ?4 = '' THEN link ELSE ?4 END,
+	album_id = coalesce(?6, album_id)
+WHERE song_id = ?5`
+
+func (repo *SongSQLiteRepository) ImportSongs(ctx context.Context, songs []song.Song, policy string) ([]song.ImportRow, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.PrepareContext(ctx, "INSERT INTO songs (song_name, group_name, artist_id, album_id, release_date, release_date_precision, text_of_song, link, enrichment_status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING song_id")
+	if err != nil {
+		logger.Error("error prepare INSERT query", "ERROR", err)
+		return nil, err
+	}
+	defer insert.Close()
+
+	now := formatTime(time.Now())
+	artists := make(map[string]song.Artist)
+	rows := make([]song.ImportRow, 0, len(songs))
+	for _, s := range songs {
+		key := storage.NameKey(s.Group)
+		artist, ok := artists[key]
+		if !ok {
+			if artist, err = resolveArtist(ctx, tx, s.Group); err != nil {
+				logger.Error("error resolve artist", "ERROR", err)
+				return nil, err
+			}
+			artists[key] = artist
+		}
+
+		row := song.ImportRow{Group: artist.Name, Song: s.Song}
+		if s.AlbumID != 0 {
+			if err = checkAlbum(ctx, tx, s.AlbumID, artist.ArtistID); err != nil {
+				if !storage.IsAlbumError(err) {
+					logger.Error("error check album", "ERROR", err, "album_id", s.AlbumID)
+					return nil, err
+				}
+				row.Status, row.Error = song.ImportFailed, err.Error()
+				rows = append(rows, row)
+				continue
+			}
+		}
+
+		err = tx.QueryRowContext(ctx, "SELECT song_id FROM songs WHERE song_name = ? AND artist_id = ? ORDER BY song_id LIMIT 1", s.Song, artist.ArtistID).Scan(&row.SongID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Error("error exec SELECT query to db: ", "ERROR", err)
+			return nil, err
+		}
+
+		date, precision := releaseDate(s.ReleaseDate)
+		switch {
+		case err == nil && policy != storage.ImportUpsert:
+			row.Status = song.ImportSkipped
+		case err == nil:
+			row.Status = song.ImportUpdated
+			if _, err = tx.ExecContext(ctx, importUpdate, date, precision, s.Text, s.Link, row.SongID, nullID(s.AlbumID)); err != nil {
+				logger.Error("error exec UPDATE query to db: ", "ERROR", err)
+				return nil, err
+			}
+		default:
+			row.Status = song.ImportCreated
+			if s.EnrichmentStatus == "" {
+				s.EnrichmentStatus = song.EnrichmentDone
+			}
+			err = insert.QueryRowContext(ctx, s.Song, artist.Name, artist.ArtistID, nullID(s.AlbumID), date, precision, s.Text, s.Link, s.EnrichmentStatus, now).Scan(&row.SongID)
+			if err != nil {
+				logger.Error("error exec INSERT query to db: ", "ERROR", err)
+				return nil, err
+			}
+			if s.EnrichmentStatus == song.EnrichmentPending {
+				_, err = tx.ExecContext(ctx, "INSERT INTO enrichment_jobs (song_id, run_at, created_at, updated_at) VALUES (?, ?, ?, ?)", row.SongID, now, now, now)
+				if err != nil {
+					logger.Error("error exec INSERT query to db: ", "ERROR", err)
+					return nil, err
+				}
+			}
+		}
+
+		if row.Status != song.ImportSkipped {
+			if err = saveTags(ctx, tx, row.SongID, s.Tags); err != nil {
+				logger.Error("error exec INSERT query to db: ", "ERROR", err)
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return nil, err
+	}
+
+	return rows, nil
+}
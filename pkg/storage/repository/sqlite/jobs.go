@@ -0,0 +1,351 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+const (
+	timeFormat = "2006-01-02T15:04:05.000000Z"
+	jobColumns = "j.job_id, j.song_id, s.song_name, s.group_name, j.status, j.attempts, j.last_error, j.run_at, j.created_at, j.updated_at, j.leased_by"
+	// leaseHeld - условие, что задачу ?2 в статусе ?3 держит воркер с токеном ?4 и lease не истек к моменту ?5
+	leaseHeld = "job_id = ?2 AND status = ?3 AND leased_by = ?4 AND locked_until > ?5"
+)
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+func (repo *SongSQLiteRepository) ClaimEnrichmentJobs(ctx context.Context, limit int, lease time.Duration) ([]song.EnrichmentJob, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	rows, err := tx.QueryContext(ctx, `SELECT job_id FROM enrichment_jobs
+WHERE (status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)
+ORDER BY run_at, job_id LIMIT ?`,
+		song.JobPending, formatTime(now), song.JobRunning, formatTime(now), limit,
+	)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	ids := []any{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []song.EnrichmentJob{}, nil
+	}
+
+	in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")"
+	args := append([]any{song.JobRunning, formatTime(now.Add(lease)), storage.NewLease(), formatTime(now)}, ids...)
+	_, err = tx.ExecContext(ctx, "UPDATE enrichment_jobs SET status = ?, attempts = attempts + 1, locked_until = ?, leased_by = ?, updated_at = ? WHERE job_id IN "+in, args...)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return nil, err
+	}
+
+	jobs, err := queryJobs(ctx, tx, "SELECT "+jobColumns+" FROM enrichment_jobs j JOIN songs s ON s.song_id = j.song_id WHERE j.job_id IN "+in+" ORDER BY j.run_at, j.job_id", ids...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (repo *SongSQLiteRepository) CompleteEnrichmentJob(ctx context.Context, jobID int64, lease string, info song.ResponseFromExternalAPI) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var songID int64
+	now := formatTime(time.Now())
+	err = tx.QueryRowContext(ctx, "UPDATE enrichment_jobs SET status = ?1, last_error = '', locked_until = '', leased_by = '', updated_at = ?5 WHERE "+leaseHeld+" RETURNING song_id",
+		song.JobDone, jobID, song.JobRunning, lease, now,
+	).Scan(&songID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return leaseError(ctx, tx, jobID)
+		}
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	date, precision := releaseDate(info.ReleaseDate)
+	_, err = tx.ExecContext(ctx, "UPDATE songs SET release_date = ?, release_date_precision = ?, text_of_song = ?, link = ?, enrichment_status = ? WHERE song_id = ?",
+		date, precision, info.Text, info.Link, song.EnrichmentDone, songID,
+	)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("enrichment job complete", "job_id", jobID, "song_id", songID)
+	return nil
+}
+
+func (repo *SongSQLiteRepository) RetryEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string, runAt time.Time) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	res, err := repo.DB.ExecContext(ctx, "UPDATE enrichment_jobs SET status = ?1, last_error = ?6, run_at = ?7, locked_until = '', leased_by = '', updated_at = ?5 WHERE "+leaseHeld,
+		song.JobPending, jobID, song.JobRunning, lease, formatTime(time.Now()), lastError, formatTime(runAt),
+	)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return leaseError(ctx, repo.DB, jobID)
+	}
+
+	return nil
+}
+
+func (repo *SongSQLiteRepository) FailEnrichmentJob(ctx context.Context, jobID int64, lease string, lastError string) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var songID int64
+	err = tx.QueryRowContext(ctx, "UPDATE enrichment_jobs SET status = ?1, last_error = ?6, locked_until = '', leased_by = '', updated_at = ?5 WHERE "+leaseHeld+" RETURNING song_id",
+		song.JobDead, jobID, song.JobRunning, lease, formatTime(time.Now()), lastError,
+	).Scan(&songID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return leaseError(ctx, tx, jobID)
+		}
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE songs SET enrichment_status = ? WHERE song_id = ?", song.EnrichmentFailed, songID)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("enrichment job moved to dead letter", "job_id", jobID, "song_id", songID)
+	return nil
+}
+
+func (repo *SongSQLiteRepository) GetEnrichmentJobs(ctx context.Context, status string, limit int, offset int) ([]song.EnrichmentJob, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	jobs, err := queryJobs(ctx, repo.DB, "SELECT "+jobColumns+" FROM enrichment_jobs j JOIN songs s ON s.song_id = j.song_id WHERE ? = '' OR j.status = ? ORDER BY j.job_id LIMIT ? OFFSET ?",
+		status, status, limit, offset,
+	)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (repo *SongSQLiteRepository) RequeueEnrichmentJob(ctx context.Context, jobID int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var songID int64
+	err = tx.QueryRowContext(ctx, "SELECT status, song_id FROM enrichment_jobs WHERE job_id = ?", jobID).Scan(&status, &songID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("job not exist", "job_id", jobID)
+			return storage.ErrorJobNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if status != song.JobDead {
+		logger.Error("job is not dead", "job_id", jobID, "status", status)
+		return storage.ErrorJobNotDead
+	}
+
+	now := formatTime(time.Now())
+	_, err = tx.ExecContext(ctx, "UPDATE enrichment_jobs SET status = ?, attempts = 0, run_at = ?, updated_at = ? WHERE job_id = ?", song.JobPending, now, now, jobID)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE songs SET enrichment_status = ? WHERE song_id = ?", song.EnrichmentPending, songID)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("enrichment job requeued", "job_id", jobID)
+	return nil
+}
+
+func (repo *SongSQLiteRepository) RefreshSongByID(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback()
+
+	updated, err := updateSong(ctx, tx, s, id)
+	if err != nil {
+		return song.Song{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE enrichment_jobs SET status = ?, last_error = '', locked_until = '', leased_by = '', updated_at = ? WHERE song_id = ? AND status IN (?, ?)",
+		song.JobDone, formatTime(time.Now()), id, song.JobPending, song.JobRunning,
+	)
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Song{}, err
+	}
+	closed, _ := res.RowsAffected()
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("refresh song success", "id", id, "closed_jobs", closed)
+	return updated, nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// leaseError объясняет, почему задача не нашлась под lease: ее нет или ее держит не этот воркер
+func leaseError(ctx context.Context, q querier, jobID int64) error {
+	logger := reqctx.Logger(ctx)
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM enrichment_jobs WHERE job_id = ?)", jobID).Scan(&exists); err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if !exists {
+		logger.Error("job not exist", "job_id", jobID)
+		return storage.ErrorJobNotExist
+	}
+	logger.Warn("enrichment job lease lost", "job_id", jobID)
+	return storage.ErrorLeaseLost
+}
+
+func queryJobs(ctx context.Context, q querier, query string, args ...any) ([]song.EnrichmentJob, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []song.EnrichmentJob{}
+	for rows.Next() {
+		job := song.EnrichmentJob{}
+		var runAt, createdAt, updatedAt string
+		err := rows.Scan(&job.JobID, &job.SongID, &job.Song, &job.Group, &job.Status, &job.Attempts, &job.LastError, &runAt, &createdAt, &updatedAt, &job.Lease)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range []struct {
+			dst *time.Time
+			src string
+		}{{&job.RunAt, runAt}, {&job.CreatedAt, createdAt}, {&job.UpdatedAt, updatedAt}} {
+			if *t.dst, err = time.Parse(timeFormat, t.src); err != nil {
+				return nil, err
+			}
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
@@ -0,0 +1,608 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// playlistColumns - колонки плейлиста p в порядке, который ожидает scanPlaylist
+const playlistColumns = "p.playlist_id, p.name, p.description, p.allow_duplicates, " +
+	"(SELECT count(*) FROM playlist_entries e WHERE e.playlist_id = p.playlist_id), p.created_at, p.updated_at"
+
+// entrySelect - записи плейлиста e с песнями s в порядке, который ожидает scanEntry
+var entrySelect = "SELECT s." + strings.ReplaceAll(songColumns, ", ", ", s.") + ", e.entry_id, e.playlist_id, e.added_at " +
+	"FROM playlist_entries e JOIN songs s ON s.song_id = e.song_id"
+
+func scanPlaylist(row scanner) (song.Playlist, error) {
+	p := song.Playlist{}
+	var createdAt, updatedAt string
+	err := row.Scan(&p.PlaylistID, &p.Name, &p.Description, &p.AllowDuplicates, &p.Size, &createdAt, &updatedAt)
+	if err != nil {
+		return p, err
+	}
+	if p.CreatedAt, err = time.Parse(timeFormat, createdAt); err != nil {
+		return p, err
+	}
+	p.UpdatedAt, err = time.Parse(timeFormat, updatedAt)
+	return p, err
+}
+
+func scanEntry(row scanner) (song.PlaylistEntry, error) {
+	e := song.PlaylistEntry{}
+	var addedAt string
+	var err error
+	e.Song, err = scanSong(row, &e.EntryID, &e.PlaylistID, &addedAt)
+	if err != nil {
+		return e, err
+	}
+	e.AddedAt, err = time.Parse(timeFormat, addedAt)
+	return e, err
+}
+
+// queryEntries возвращает записи плейлиста с песнями и их тегами, позиции не заполняются.
+// Внутри транзакции q должен быть ее *sql.Tx.
+func queryEntries(ctx context.Context, q querier, query string, args ...any) ([]song.PlaylistEntry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []song.PlaylistEntry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	refs := make([]*song.Song, len(entries))
+	for i := range entries {
+		refs[i] = &entries[i].Song
+	}
+	return entries, loadTags(ctx, q, refs...)
+}
+
+// selectPlaylist читает плейлист внутри транзакции
+func selectPlaylist(ctx context.Context, tx *sql.Tx, id int64) (song.Playlist, error) {
+	p, err := scanPlaylist(tx.QueryRowContext(ctx, "SELECT "+playlistColumns+" FROM playlists p WHERE p.playlist_id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return song.Playlist{}, storage.ErrorPlaylistNotExist
+	}
+	return p, err
+}
+
+// allowDuplicates проверяет, что плейлист есть, и возвращает, разрешены ли в нем повторы.
+// Писатель у базы один, поэтому плейлист не меняется до конца транзакции.
+func allowDuplicates(ctx context.Context, tx *sql.Tx, id int64) (bool, error) {
+	var allow bool
+	err := tx.QueryRowContext(ctx, "SELECT allow_duplicates FROM playlists WHERE playlist_id = ?", id).Scan(&allow)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, storage.ErrorPlaylistNotExist
+	}
+	return allow, err
+}
+
+// playlistOrder - записи плейлиста по порядку
+func playlistOrder(ctx context.Context, tx *sql.Tx, playlistID int64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT entry_id FROM playlist_entries WHERE playlist_id = ? ORDER BY position, entry_id", playlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// writeOrder нумерует записи плейлиста по порядку ids с 1
+func writeOrder(ctx context.Context, tx *sql.Tx, ids []int64) error {
+	for i, id := range ids {
+		_, err := tx.ExecContext(ctx, "UPDATE playlist_entries SET position = ? WHERE entry_id = ? AND position <> ?", i+1, id, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touchPlaylist обновляет время изменения плейлиста
+func touchPlaylist(ctx context.Context, tx *sql.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE playlists SET updated_at = ? WHERE playlist_id = ?", formatTime(time.Now()), id)
+	return err
+}
+
+// getEntry возвращает запись плейлиста с песней, position - ее место в плейлисте
+func getEntry(ctx context.Context, tx *sql.Tx, entryID int64, position int) (song.PlaylistEntry, error) {
+	entries, err := queryEntries(ctx, tx, entrySelect+" WHERE e.entry_id = ?", entryID)
+	if err != nil {
+		return song.PlaylistEntry{}, err
+	}
+	if len(entries) == 0 {
+		return song.PlaylistEntry{}, storage.ErrorEntryNotExist
+	}
+	entries[0].Position = position
+	return entries[0], nil
+}
+
+func (repo *SongSQLiteRepository) AddPlaylist(ctx context.Context, playlist song.Playlist) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	playlist.Name = storage.NormalizeName(playlist.Name)
+	if playlist.Name == "" {
+		logger.Error("empty playlist name")
+		return song.Playlist{}, storage.ErrorEmptyName
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	defer tx.Rollback()
+
+	now := formatTime(time.Now())
+	res, err := tx.ExecContext(ctx, "INSERT INTO playlists (name, description, allow_duplicates, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		playlist.Name, playlist.Description, playlist.AllowDuplicates, now, now)
+	if err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		logger.Error("error get last insert id", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	created, err := selectPlaylist(ctx, tx, id)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Playlist{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Playlist{}, err
+	}
+
+	logger.Info("add playlist success", "id", created.PlaylistID)
+	return created, nil
+}
+
+func (repo *SongSQLiteRepository) GetPlaylistByID(ctx context.Context, id int64) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	p, err := scanPlaylist(repo.DB.QueryRowContext(ctx, "SELECT "+playlistColumns+" FROM playlists p WHERE p.playlist_id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("playlist not exist", "id", id)
+			return song.Playlist{}, storage.ErrorPlaylistNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	return p, nil
+}
+
+func (repo *SongSQLiteRepository) ListPlaylists(ctx context.Context, name string, limit int, offset int) ([]song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	args := queryArgs{}
+	where := "1=1"
+	if name != "" {
+		where = "ilike(p.name, " + args.add("%"+name+"%") + ")"
+	}
+	query := "SELECT " + playlistColumns + " FROM playlists p WHERE " + where +
+		" ORDER BY p.playlist_id LIMIT " + args.add(limit) + " OFFSET " + args.add(offset)
+
+	rows, err := repo.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	playlists := []song.Playlist{}
+	for rows.Next() {
+		p, err := scanPlaylist(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		playlists = append(playlists, p)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return playlists, nil
+}
+
+func (repo *SongSQLiteRepository) UpdatePlaylist(ctx context.Context, id int64, playlist song.PlaylistForUpdate) (song.Playlist, error) {
+	logger := reqctx.Logger(ctx)
+
+	if playlist.Name == nil && playlist.Description == nil && playlist.AllowDuplicates == nil {
+		logger.Error("no fields to update")
+		return song.Playlist{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err = allowDuplicates(ctx, tx, id); err != nil {
+		logger.Error("error check playlist", "ERROR", err, "id", id)
+		return song.Playlist{}, err
+	}
+
+	updates := []string{"updated_at = ?"}
+	args := []any{formatTime(time.Now())}
+	if playlist.Name != nil {
+		name := storage.NormalizeName(*playlist.Name)
+		if name == "" {
+			logger.Error("empty playlist name")
+			return song.Playlist{}, storage.ErrorEmptyName
+		}
+		updates = append(updates, "name = ?")
+		args = append(args, name)
+	}
+	if playlist.Description != nil {
+		updates = append(updates, "description = ?")
+		args = append(args, *playlist.Description)
+	}
+	if playlist.AllowDuplicates != nil {
+		if !*playlist.AllowDuplicates {
+			repeats, err := exists(ctx, tx, "SELECT 1 FROM playlist_entries WHERE playlist_id = ? GROUP BY song_id HAVING count(*) > 1", id)
+			if err != nil {
+				logger.Error("error exec SELECT query to db: ", "ERROR", err)
+				return song.Playlist{}, err
+			}
+			if repeats {
+				logger.Error("playlist has repeated songs", "id", id)
+				return song.Playlist{}, storage.ErrorPlaylistHasRepeats
+			}
+		}
+		updates = append(updates, "allow_duplicates = ?")
+		args = append(args, *playlist.AllowDuplicates)
+	}
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE playlists SET %s WHERE playlist_id = ?", strings.Join(updates, ", "))
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Playlist{}, err
+	}
+	updated, err := selectPlaylist(ctx, tx, id)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Playlist{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Playlist{}, err
+	}
+
+	logger.Info("update playlist success", "id", id)
+	return updated, nil
+}
+
+func (repo *SongSQLiteRepository) DeletePlaylist(ctx context.Context, id int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	res, err := repo.DB.ExecContext(ctx, "DELETE FROM playlists WHERE playlist_id = ?", id)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("error get rows affected", "ERROR", err)
+		return err
+	}
+	if n == 0 {
+		logger.Error("playlist not exist", "id", id)
+		return storage.ErrorPlaylistNotExist
+	}
+
+	logger.Info("delete playlist success", "id", id)
+	return nil
+}
+
+func (repo *SongSQLiteRepository) AddPlaylistEntry(ctx context.Context, playlistID, songID int64, position int) (song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	defer tx.Rollback()
+
+	allow, err := allowDuplicates(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error check playlist", "ERROR", err, "id", playlistID)
+		return song.PlaylistEntry{}, err
+	}
+	if err = songExist(ctx, tx, int(songID)); err != nil {
+		logger.Error("error check song", "ERROR", err, "id", songID)
+		return song.PlaylistEntry{}, err
+	}
+	if !allow {
+		found, err := exists(ctx, tx, "SELECT 1 FROM playlist_entries WHERE playlist_id = ? AND song_id = ?", playlistID, songID)
+		if err != nil {
+			logger.Error("error exec SELECT query to db: ", "ERROR", err)
+			return song.PlaylistEntry{}, err
+		}
+		if found {
+			logger.Error("song is already in playlist", "playlist_id", playlistID, "song_id", songID)
+			return song.PlaylistEntry{}, storage.ErrorSongInPlaylist
+		}
+	}
+
+	ids, err := playlistOrder(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	// запись добавляется в конец, а при вставке в середину плейлист нумеруется заново
+	res, err := tx.ExecContext(ctx, `INSERT INTO playlist_entries (playlist_id, song_id, position, added_at)
+VALUES (?, ?, (SELECT coalesce(max(position), 0) + 1 FROM playlist_entries WHERE playlist_id = ?), ?)`,
+		playlistID, songID, playlistID, formatTime(time.Now()))
+	if err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	entryID, err := res.LastInsertId()
+	if err != nil {
+		logger.Error("error get last insert id", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	order := storage.InsertEntry(ids, entryID, position)
+	if order[len(order)-1] != entryID {
+		if err = writeOrder(ctx, tx, order); err != nil {
+			logger.Error("error exec UPDATE query to db", "ERROR", err)
+			return song.PlaylistEntry{}, err
+		}
+	}
+
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	entry, err := getEntry(ctx, tx, entryID, storage.EntryPosition(order, entryID))
+	if err != nil {
+		logger.Error("error get playlist entry", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	logger.Info("add playlist entry success", "playlist_id", playlistID, "entry_id", entryID)
+	return entry, nil
+}
+
+func (repo *SongSQLiteRepository) ListPlaylistEntries(ctx context.Context, playlistID int64, limit int, offset int) ([]song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = allowDuplicates(ctx, tx, playlistID); err != nil {
+		logger.Error("error check playlist", "ERROR", err, "id", playlistID)
+		return nil, err
+	}
+	entries, err := queryEntries(ctx, tx, entrySelect+" WHERE e.playlist_id = ? ORDER BY e.position, e.entry_id LIMIT ? OFFSET ?",
+		playlistID, limit, offset)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Position = offset + i + 1
+	}
+
+	return entries, nil
+}
+
+func (repo *SongSQLiteRepository) MovePlaylistEntry(ctx context.Context, playlistID, entryID int64, position int) (song.PlaylistEntry, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err = allowDuplicates(ctx, tx, playlistID); err != nil {
+		logger.Error("error check playlist", "ERROR", err, "id", playlistID)
+		return song.PlaylistEntry{}, err
+	}
+	ids, err := playlistOrder(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	order, ok := storage.MoveEntry(ids, entryID, position)
+	if !ok {
+		logger.Error("playlist entry not exist", "playlist_id", playlistID, "entry_id", entryID)
+		return song.PlaylistEntry{}, storage.ErrorEntryNotExist
+	}
+	if err = writeOrder(ctx, tx, order); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	entry, err := getEntry(ctx, tx, entryID, storage.EntryPosition(order, entryID))
+	if err != nil {
+		logger.Error("error get playlist entry", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.PlaylistEntry{}, err
+	}
+
+	logger.Info("move playlist entry success", "playlist_id", playlistID, "entry_id", entryID, "position", entry.Position)
+	return entry, nil
+}
+
+func (repo *SongSQLiteRepository) ReorderPlaylist(ctx context.Context, playlistID int64, entryIDs []int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = allowDuplicates(ctx, tx, playlistID); err != nil {
+		logger.Error("error check playlist", "ERROR", err, "id", playlistID)
+		return err
+	}
+	ids, err := playlistOrder(ctx, tx, playlistID)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return err
+	}
+	if err = storage.CheckReorder(ids, entryIDs); err != nil {
+		logger.Error("error reorder playlist", "ERROR", err, "id", playlistID)
+		return err
+	}
+	if err = writeOrder(ctx, tx, entryIDs); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("reorder playlist success", "id", playlistID)
+	return nil
+}
+
+func (repo *SongSQLiteRepository) DeletePlaylistEntry(ctx context.Context, playlistID, entryID int64) error {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = allowDuplicates(ctx, tx, playlistID); err != nil {
+		logger.Error("error check playlist", "ERROR", err, "id", playlistID)
+		return err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM playlist_entries WHERE playlist_id = ? AND entry_id = ?", playlistID, entryID)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("error get rows affected", "ERROR", err)
+		return err
+	}
+	if n == 0 {
+		logger.Error("playlist entry not exist", "playlist_id", playlistID, "entry_id", entryID)
+		return storage.ErrorEntryNotExist
+	}
+	if err = touchPlaylist(ctx, tx, playlistID); err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return err
+	}
+
+	logger.Info("delete playlist entry success", "playlist_id", playlistID, "entry_id", entryID)
+	return nil
+}
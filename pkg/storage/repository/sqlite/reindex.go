@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"context"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/storage"
+)
+
+// Reindex перестраивает индексы таблиц каталога. SQLite блокирует базу на время REINDEX,
+// запрос не ограничен таймаутом хранилища
+func (repo *SongSQLiteRepository) Reindex(ctx context.Context) ([]string, error) {
+	logger := reqctx.Logger(ctx)
+
+	done := []string{}
+	for _, table := range storage.CatalogTables {
+		if _, err := repo.DB.ExecContext(ctx, "REINDEX "+table); err != nil {
+			logger.Error("error reindex table", "table", table, "ERROR", err)
+			return done, err
+		}
+		logger.Info("reindex table success", "table", table)
+		done = append(done, table)
+	}
+	if _, err := repo.DB.ExecContext(ctx, "ANALYZE"); err != nil {
+		logger.Error("error analyze db", "ERROR", err)
+		return done, err
+	}
+	return done, nil
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+)
+
+// schemaFS - шаги схемы, применяются по порядку имен файлов.
+// Номер последнего примененного шага хранится в PRAGMA user_version.
+//
+//go:embed schema/*.sql
+var schemaFS embed.FS
+
+func applySchema(ctx context.Context, db *sql.DB, logger *slog.Logger) error {
+	files, err := fs.Glob(schemaFS, "schema/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var version int
+	if err = db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(files); i++ {
+		step, err := schemaFS.ReadFile(files[i])
+		if err != nil {
+			return err
+		}
+
+		logger.Info("apply sqlite schema step", "file", files[i])
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, string(step)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("apply %s: %w", files[i], err)
+		}
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+-- схема повторяет migrations/0001_create_songs.up.sql, ограничения длины varchar заданы через CHECK
+CREATE TABLE IF NOT EXISTS songs (
+    song_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_name TEXT NOT NULL CHECK (length(song_name) <= 100),
+    group_name TEXT NOT NULL CHECK (length(group_name) <= 100),
+    release_date TEXT NOT NULL CHECK (length(release_date) <= 50),
+    text_of_song TEXT NOT NULL,
+    link TEXT NOT NULL CHECK (length(link) <= 300)
+);
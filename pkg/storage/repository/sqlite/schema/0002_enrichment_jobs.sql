@@ -0,0 +1,16 @@
+ALTER TABLE songs ADD COLUMN enrichment_status TEXT NOT NULL DEFAULT 'done';
+
+-- время хранится строкой в UTC фиксированной ширины, чтобы его можно было сравнивать как текст
+CREATE TABLE enrichment_jobs (
+    job_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_id INTEGER NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    run_at TEXT NOT NULL,
+    locked_until TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE INDEX enrichment_jobs_queue_idx ON enrichment_jobs (status, run_at);
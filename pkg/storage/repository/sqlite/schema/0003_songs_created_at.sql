@@ -0,0 +1,6 @@
+-- SQLite не разрешает вычисляемое значение по умолчанию в ADD COLUMN,
+-- поэтому существующим песням время создания проставляется отдельно
+ALTER TABLE songs ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+UPDATE songs SET created_at = strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.000000Z';
+
+CREATE INDEX songs_created_at_idx ON songs (created_at, song_id);
@@ -0,0 +1,32 @@
+-- дата выпуска хранится строкой YYYY-MM-DD (первый день периода) с точностью day, month или year,
+-- неизвестная дата - пустые строки. Повторяет migrations/0005_release_date.up.sql.
+ALTER TABLE songs ADD COLUMN release_date_precision TEXT NOT NULL DEFAULT '';
+
+UPDATE songs SET
+    release_date = CASE
+        WHEN release_date GLOB '[0-9][0-9].[0-9][0-9].[0-9][0-9][0-9][0-9]'
+            THEN substr(release_date, 7, 4) || '-' || substr(release_date, 4, 2) || '-' || substr(release_date, 1, 2)
+        WHEN release_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'
+            THEN release_date
+        WHEN release_date GLOB '[0-9][0-9].[0-9][0-9][0-9][0-9]'
+            THEN substr(release_date, 4, 4) || '-' || substr(release_date, 1, 2) || '-01'
+        WHEN release_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]'
+            THEN release_date || '-01'
+        WHEN release_date GLOB '[0-9][0-9][0-9][0-9]'
+            THEN release_date || '-01-01'
+        ELSE ''
+    END,
+    release_date_precision = CASE
+        WHEN release_date GLOB '[0-9][0-9].[0-9][0-9].[0-9][0-9][0-9][0-9]'
+            OR release_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]' THEN 'day'
+        WHEN release_date GLOB '[0-9][0-9].[0-9][0-9][0-9][0-9]'
+            OR release_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]' THEN 'month'
+        WHEN release_date GLOB '[0-9][0-9][0-9][0-9]' THEN 'year'
+        ELSE ''
+    END;
+
+-- несуществующие даты вроде 31.02.2000 считаются неизвестными
+UPDATE songs SET release_date = '', release_date_precision = ''
+WHERE release_date <> '' AND date(release_date) IS NOT release_date;
+
+CREATE INDEX songs_release_date_idx ON songs (release_date, song_id);
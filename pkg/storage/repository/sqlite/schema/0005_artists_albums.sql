@@ -0,0 +1,45 @@
+-- исполнители и альбомы, повторяет migrations/0006_artists_albums.up.sql.
+-- lower() в SQLite не знает кириллицу, поэтому ключ уникальности имени (storage.NameKey)
+-- хранится отдельной колонкой и считается функцией name_key.
+CREATE TABLE artists (
+    artist_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL CHECK (length(name) <= 100 AND name <> ''),
+    name_key TEXT NOT NULL UNIQUE,
+    created_at TEXT NOT NULL
+);
+
+CREATE TABLE albums (
+    album_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    artist_id INTEGER NOT NULL REFERENCES artists (artist_id) ON DELETE CASCADE,
+    title TEXT NOT NULL CHECK (length(title) <= 200 AND title <> ''),
+    title_key TEXT NOT NULL,
+    release_date TEXT NOT NULL DEFAULT '',
+    release_date_precision TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    UNIQUE (artist_id, title_key)
+);
+
+-- группы, которые отличаются только регистром и пробелами по краям, становятся одним исполнителем.
+-- Имя исполнителя берется из самой ранней песни группы.
+INSERT INTO artists (name, name_key, created_at)
+SELECT trim(group_name), name_key(group_name), strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.000000Z'
+FROM songs
+WHERE song_id IN (SELECT min(song_id) FROM songs WHERE name_key(group_name) <> '' GROUP BY name_key(group_name))
+ORDER BY song_id;
+
+INSERT OR IGNORE INTO artists (name, name_key, created_at)
+SELECT 'Unknown', 'unknown', strftime('%Y-%m-%dT%H:%M:%S', 'now') || '.000000Z'
+WHERE EXISTS (SELECT 1 FROM songs WHERE name_key(group_name) = '');
+
+-- SQLite не добавляет колонку NOT NULL без значения по умолчанию, artist_id заполняет репозиторий
+ALTER TABLE songs ADD COLUMN artist_id INTEGER REFERENCES artists (artist_id);
+ALTER TABLE songs ADD COLUMN album_id INTEGER REFERENCES albums (album_id) ON DELETE SET NULL;
+
+UPDATE songs SET artist_id = (
+    SELECT artist_id FROM artists
+    WHERE name_key = coalesce(nullif(name_key(songs.group_name), ''), 'unknown')
+);
+UPDATE songs SET group_name = (SELECT name FROM artists WHERE artists.artist_id = songs.artist_id);
+
+CREATE INDEX songs_artist_id_idx ON songs (artist_id, song_id);
+CREATE INDEX songs_album_id_idx ON songs (album_id);
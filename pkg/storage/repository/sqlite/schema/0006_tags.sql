@@ -0,0 +1,12 @@
+-- теги песен, повторяет migrations/0007_tags.up.sql
+CREATE TABLE tags (
+    tag_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE CHECK (length(name) <= 50 AND name <> '')
+);
+
+CREATE TABLE song_tags (
+    song_id INTEGER NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags (tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+CREATE INDEX song_tags_tag_id_idx ON song_tags (tag_id, song_id);
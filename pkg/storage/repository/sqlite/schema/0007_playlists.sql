@@ -0,0 +1,19 @@
+-- плейлисты, повторяет migrations/0008_playlists.up.sql
+CREATE TABLE playlists (
+    playlist_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL CHECK (length(name) <= 100 AND name <> ''),
+    description TEXT NOT NULL DEFAULT '',
+    allow_duplicates INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE playlist_entries (
+    entry_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    playlist_id INTEGER NOT NULL REFERENCES playlists (playlist_id) ON DELETE CASCADE,
+    song_id INTEGER NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    added_at TEXT NOT NULL
+);
+CREATE INDEX playlist_entries_order_idx ON playlist_entries (playlist_id, position, entry_id);
+CREATE INDEX playlist_entries_song_id_idx ON playlist_entries (song_id);
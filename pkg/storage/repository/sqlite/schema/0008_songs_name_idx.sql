@@ -0,0 +1,2 @@
+-- поиск песни исполнителя по названию, повторяет migrations/0009_songs_name_idx.up.sql
+CREATE INDEX songs_artist_name_idx ON songs (artist_id, song_name);
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// SearchSongs отбирает через ilike песни, в которых есть все слова запроса,
+// и ищет среди них storage.RankSearch: ранжирование и страница считаются в памяти
+func (repo *SongSQLiteRepository) SearchSongs(ctx context.Context, query string, limit int, offset int) ([]song.SearchResult, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	terms, err := storage.ParseSearchQuery(query)
+	if err != nil {
+		logger.Error("error parse search query", "ERROR", err)
+		return nil, err
+	}
+
+	args := queryArgs{}
+	conds := []string{"1=1"}
+	for _, w := range storage.SearchWords(terms) {
+		conds = append(conds, "ilike(song_name || ' ' || group_name || ' ' || text_of_song, "+args.add("%"+storage.EscapeLike(w)+"%")+")")
+	}
+	sql := "SELECT " + songColumns + " FROM songs WHERE " + strings.Join(conds, " AND ") + " ORDER BY song_id"
+
+	logger.Debug("result query to db", "query", sql)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	rows, err := repo.DB.QueryContext(ctx, sql, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	songs := []song.Song{}
+	for rows.Next() {
+		s, err := scanSong(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		songs = append(songs, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	results := storage.RankSearch(songs, terms, limit, offset)
+
+	refs := make([]*song.Song, len(results))
+	for i := range results {
+		refs[i] = &results[i].Song
+	}
+	if err = loadTags(ctx, repo.DB, refs...); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return nil, err
+	}
+
+	logger.Info("search songs success", "found", len(results))
+	return results, nil
+}
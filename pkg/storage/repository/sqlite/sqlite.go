@@ -0,0 +1,452 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+
+	sqlitedriver "modernc.org/sqlite"
+)
+
+func init() {
+	// в SQLite нет ILIKE, а встроенный LIKE не учитывает регистр только для ASCII
+	sqlitedriver.MustRegisterDeterministicScalarFunction("ilike", 2, func(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return storage.MatchILike(toString(args[0]), toString(args[1])), nil
+	})
+	// встроенный lower тоже меняет регистр только ASCII, а сортировка должна совпадать с остальными хранилищами
+	sqlitedriver.MustRegisterDeterministicScalarFunction("ulower", 1, func(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return strings.ToLower(toString(args[0])), nil
+	})
+	// ключ уникальности имени исполнителя для миграции схемы
+	sqlitedriver.MustRegisterDeterministicScalarFunction("name_key", 1, func(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return storage.NameKey(toString(args[0])), nil
+	})
+}
+
+func toString(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type SongSQLiteRepository struct {
+	DB       *sql.DB
+	Timeouts storage.Timeouts
+}
+
+// songColumns - колонки песни в порядке, который ожидает scanSong
+const songColumns = "song_id, song_name, group_name, artist_id, album_id, release_date, release_date_precision, text_of_song, link, enrichment_status, created_at"
+
+// scanner - общий интерфейс *sql.Row и *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSong(row scanner, extra ...any) (song.Song, error) {
+	s := song.Song{}
+	var albumID sql.NullInt64
+	var date, precision, createdAt string
+	dest := []any{&s.SongID, &s.Song, &s.Group, &s.ArtistID, &albumID, &date, &precision, &s.Text, &s.Link, &s.EnrichmentStatus, &createdAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return s, err
+	}
+	s.AlbumID = albumID.Int64
+	if date != "" {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return s, err
+		}
+		s.ReleaseDate = song.NewReleaseDate(t, precision)
+	}
+	s.CreatedAt, err = time.Parse(timeFormat, createdAt)
+	return s, err
+}
+
+// releaseDate - значения колонок release_date (YYYY-MM-DD) и release_date_precision,
+// пустые строки для неизвестной даты
+func releaseDate(d song.ReleaseDate) (string, string) {
+	if d.IsZero() {
+		return "", ""
+	}
+	return storage.FormatDate(d.Date), d.Precision
+}
+
+func NewSongSQLiteRepository(db *sql.DB, timeouts storage.Timeouts) *SongSQLiteRepository {
+	return &SongSQLiteRepository{
+		DB:       db,
+		Timeouts: timeouts,
+	}
+}
+
+// NewConnSQLite открывает файл базы (создает, если его нет) и применяет схему
+func NewConnSQLite(ctx context.Context, path string, logger *slog.Logger) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		logger.Error("error open sqlite db:",
+			"error", err,
+		)
+		return nil, err
+	}
+	// SQLite допускает только одного писателя, поэтому все запросы идут через одно соединение
+	db.SetMaxOpenConns(1)
+
+	if err = applySchema(ctx, db, logger); err != nil {
+		logger.Error("error apply sqlite schema:",
+			"error", err,
+		)
+		db.Close()
+		return nil, err
+	}
+
+	logger.Info("connect to sqlite db create success", "path", path)
+
+	return db, nil
+}
+
+func (repo *SongSQLiteRepository) AddSongToDB(ctx context.Context, s song.Song) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	tags, err := storage.NormalizeTags(s.Tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Add)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback()
+
+	artist, err := resolveArtist(ctx, tx, s.Group)
+	if err != nil {
+		logger.Error("error resolve artist", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, "select song_id from songs where song_name = ? and artist_id = ?", s.Song, artist.ArtistID).Scan(&id)
+	if err == nil {
+		logger.Error("this song exist")
+		return song.Song{}, storage.ErrorSongExist
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	if s.AlbumID != 0 {
+		if err = checkAlbum(ctx, tx, s.AlbumID, artist.ArtistID); err != nil {
+			logger.Error("error check album", "ERROR", err, "album_id", s.AlbumID)
+			return song.Song{}, err
+		}
+	}
+
+	if s.EnrichmentStatus == "" {
+		s.EnrichmentStatus = song.EnrichmentDone
+	}
+
+	date, precision := releaseDate(s.ReleaseDate)
+	created, err := scanSong(tx.QueryRowContext(ctx, "INSERT INTO songs (song_name, group_name, artist_id, album_id, release_date, release_date_precision, text_of_song, link, enrichment_status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING "+songColumns,
+		s.Song,
+		artist.Name,
+		artist.ArtistID,
+		nullID(s.AlbumID),
+		date,
+		precision,
+		s.Text,
+		s.Link,
+		s.EnrichmentStatus,
+		formatTime(time.Now()),
+	))
+	if err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	if err = saveTags(ctx, tx, created.SongID, tags); err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+	created.Tags = tags
+
+	if created.EnrichmentStatus == song.EnrichmentPending {
+		now := formatTime(time.Now())
+		_, err = tx.ExecContext(ctx, "INSERT INTO enrichment_jobs (song_id, run_at, created_at, updated_at) VALUES (?, ?, ?, ?)", created.SongID, now, now, now)
+		if err != nil {
+			logger.Error("error exec INSERT query to db: ", "ERROR", err)
+			return song.Song{}, err
+		}
+		logger.Info("enrichment job create success", "song_id", created.SongID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	return created, nil
+}
+
+func (repo *SongSQLiteRepository) GetSongsFromDB(ctx context.Context, s song.Song, limit int, offset int) ([]song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	args := queryArgs{}
+	where := songFilter(storage.NewSongFilter(s), &args)
+	query := "SELECT " + songColumns + " FROM songs WHERE " + where + " ORDER BY song_id LIMIT " + args.add(limit) + " OFFSET " + args.add(offset)
+
+	logger.Debug("result query to db", "query", query)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+	rows, err := repo.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	songs := []song.Song{}
+	for rows.Next() {
+		s, err := scanSong(rows)
+		if err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		songs = append(songs, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+	if err = loadTags(ctx, repo.DB, songRefs(songs)...); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return nil, err
+	}
+
+	if len(songs) == 0 {
+		logger.Error("error list of songs", "ERROR", storage.ErrorListOfSongsEmpty)
+		return nil, storage.ErrorListOfSongsEmpty
+	}
+
+	logger.Info("list of songs create success")
+
+	return songs, nil
+}
+
+func (repo *SongSQLiteRepository) DeleteSongByIDFromDB(ctx context.Context, id int) (int, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Delete)
+	defer cancel()
+
+	res, err := repo.DB.ExecContext(ctx, "DELETE FROM songs WHERE song_id = ?", id)
+	if err != nil {
+		logger.Error("error exec DELETE query to db", "ERROR", err)
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("error get rows affected", "ERROR", err)
+		return 0, err
+	}
+	if n == 0 {
+		logger.Error("song not exist", "id", id)
+		return id, storage.ErrorSongNotExist
+	}
+
+	logger.Info("delete song success", "id", id)
+	return id, nil
+}
+
+func (repo *SongSQLiteRepository) GetTextOfSongFromDB(ctx context.Context, id int) (string, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	var text string
+	err := repo.DB.QueryRowContext(ctx, "select text_of_song from songs where song_id = ?", id).Scan(&text)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("song not exist", "id", id)
+			return "", storage.ErrorSongNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return "", err
+	}
+
+	logger.Info("get text of song success", "id", id)
+	return text, nil
+}
+
+func (repo *SongSQLiteRepository) GetSongByID(ctx context.Context, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Get)
+	defer cancel()
+
+	s, err := scanSong(repo.DB.QueryRowContext(ctx, "SELECT "+songColumns+" FROM songs WHERE song_id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("song not exist", "id", id)
+			return song.Song{}, storage.ErrorSongNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+	if err = loadTags(ctx, repo.DB, &s); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("get song success", "id", id)
+	return s, nil
+}
+
+func (repo *SongSQLiteRepository) UpdateSongByID(ctx context.Context, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback()
+
+	updated, err := updateSong(ctx, tx, s, id)
+	if err != nil {
+		return song.Song{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("update song success", "id", id)
+	return updated, nil
+}
+
+// updateSong меняет поля песни в транзакции tx и возвращает сохраненную песню
+func updateSong(ctx context.Context, tx *sql.Tx, s song.SongForUpdate, id int) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	var artistID int64
+	err := tx.QueryRowContext(ctx, "select artist_id from songs where song_id = ?", id).Scan(&artistID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("song not exist", "id", id)
+			return song.Song{}, storage.ErrorSongNotExist
+		}
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	if s.Song == nil && s.Group == nil && s.Text == nil && s.ReleaseDate == nil && s.Link == nil && s.AlbumID == nil && s.EnrichmentStatus == nil {
+		logger.Error("no fields to update")
+		return song.Song{}, storage.ErrorNoFieldsToUpdate
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if s.Song != nil {
+		updates = append(updates, "song_name = ?")
+		args = append(args, *s.Song)
+	}
+	if s.Group != nil {
+		artist, err := resolveArtist(ctx, tx, *s.Group)
+		if err != nil {
+			logger.Error("error resolve artist", "ERROR", err)
+			return song.Song{}, err
+		}
+		updates = append(updates, "group_name = ?, artist_id = ?")
+		args = append(args, artist.Name, artist.ArtistID)
+		artistID = artist.ArtistID
+		// при смене исполнителя песня отвязывается от альбома прежнего исполнителя
+		if s.AlbumID == nil {
+			updates = append(updates, "album_id = CASE WHEN artist_id = ? THEN album_id END")
+			args = append(args, artist.ArtistID)
+		}
+	}
+	if s.AlbumID != nil {
+		if *s.AlbumID != 0 {
+			if err = checkAlbum(ctx, tx, *s.AlbumID, artistID); err != nil {
+				logger.Error("error check album", "ERROR", err, "album_id", *s.AlbumID)
+				return song.Song{}, err
+			}
+		}
+		updates = append(updates, "album_id = ?")
+		args = append(args, nullID(*s.AlbumID))
+	}
+	if s.Text != nil {
+		updates = append(updates, "text_of_song = ?")
+		args = append(args, *s.Text)
+	}
+	if s.ReleaseDate != nil {
+		date, precision := releaseDate(*s.ReleaseDate)
+		updates = append(updates, "release_date = ?, release_date_precision = ?")
+		args = append(args, date, precision)
+	}
+	if s.Link != nil {
+		updates = append(updates, "link = ?")
+		args = append(args, *s.Link)
+	}
+	if s.EnrichmentStatus != nil {
+		updates = append(updates, "enrichment_status = ?")
+		args = append(args, *s.EnrichmentStatus)
+	}
+
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE songs SET %s WHERE song_id = ? RETURNING %s", strings.Join(updates, ", "), songColumns)
+	logger.Debug("get result query", "query", query)
+
+	updated, err := scanSong(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		logger.Error("error exec UPDATE query to db", "ERROR", err)
+		return song.Song{}, err
+	}
+	if err = loadTags(ctx, tx, &updated); err != nil {
+		logger.Error("error load tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	return updated, nil
+}
+
+func (repo *SongSQLiteRepository) Close() {
+	repo.DB.Close()
+}
@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"SongLibrary/pkg/storage"
+	"SongLibrary/pkg/storage/storagetest"
+)
+
+// newRepo возвращает хранилище над новым файлом базы во временном каталоге теста
+func newRepo(t *testing.T) *SongSQLiteRepository {
+	t.Helper()
+	db, err := NewConnSQLite(context.Background(), filepath.Join(t.TempDir(), "songs.db"), slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewSongSQLiteRepository(db, storage.DefaultTimeouts())
+	t.Cleanup(repo.Close)
+	return repo
+}
+
+func TestSongRepo(t *testing.T) {
+	storagetest.RunSongRepoTests(t, func(t *testing.T) storage.SongRepo { return newRepo(t) })
+}
+
+func TestJobRepo(t *testing.T) {
+	storagetest.RunJobRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestSearchRepo(t *testing.T) {
+	storagetest.RunSearchRepoTests(t, func(t *testing.T) storagetest.SearchableRepo { return newRepo(t) })
+}
+
+func TestArtistRepo(t *testing.T) {
+	storagetest.RunArtistRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestTagRepo(t *testing.T) {
+	storagetest.RunTagRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestPlaylistRepo(t *testing.T) {
+	storagetest.RunPlaylistRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestImportRepo(t *testing.T) {
+	storagetest.RunImportRepoTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
+
+func TestBackup(t *testing.T) {
+	storagetest.RunBackupTests(t, func(t *testing.T) storage.Repository { return newRepo(t) })
+}
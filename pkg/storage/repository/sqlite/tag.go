@@ -0,0 +1,204 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"SongLibrary/pkg/reqctx"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// inList - список плейсхолдеров значений для IN
+func inList[T any](values []T, args *queryArgs) string {
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		items = append(items, args.add(v))
+	}
+	return "(" + strings.Join(items, ", ") + ")"
+}
+
+// songRefs - указатели на песни среза для loadTags
+func songRefs(songs []song.Song) []*song.Song {
+	refs := make([]*song.Song, len(songs))
+	for i := range songs {
+		refs[i] = &songs[i]
+	}
+	return refs
+}
+
+// loadTags заполняет теги песен одним запросом. Соединение с базой одно,
+// поэтому внутри транзакции q должен быть ее *sql.Tx.
+func loadTags(ctx context.Context, q querier, songs ...*song.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(songs))
+	byID := make(map[int64]*song.Song, len(songs))
+	for _, s := range songs {
+		s.Tags = []string{}
+		ids = append(ids, s.SongID)
+		byID[s.SongID] = s
+	}
+
+	args := queryArgs{}
+	rows, err := q.QueryContext(ctx, "SELECT st.song_id, t.name FROM song_tags st JOIN tags t ON t.tag_id = st.tag_id WHERE st.song_id IN "+inList(ids, &args)+" ORDER BY t.name", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err = rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, name)
+	}
+	return rows.Err()
+}
+
+// saveTags добавляет песне теги, приведенные storage.NormalizeTags
+func saveTags(ctx context.Context, tx *sql.Tx, songID int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO song_tags (song_id, tag_id) SELECT ?, tag_id FROM tags WHERE name = ?", songID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// songExist проверяет, что песня есть
+func songExist(ctx context.Context, tx *sql.Tx, id int) error {
+	found, err := exists(ctx, tx, "SELECT 1 FROM songs WHERE song_id = ?", id)
+	if err == nil && !found {
+		err = storage.ErrorSongNotExist
+	}
+	return err
+}
+
+func (repo *SongSQLiteRepository) AddSongTags(ctx context.Context, songID int, tags []string) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	tags, err := storage.NormalizeTags(tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	updateCtx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(updateCtx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback()
+
+	if err = songExist(updateCtx, tx, songID); err != nil {
+		logger.Error("error check song", "ERROR", err, "id", songID)
+		return song.Song{}, err
+	}
+	if err = saveTags(updateCtx, tx, int64(songID), tags); err != nil {
+		logger.Error("error exec INSERT query to db: ", "ERROR", err)
+		return song.Song{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("add song tags success", "id", songID, "tags", tags)
+	return repo.GetSongByID(ctx, songID)
+}
+
+func (repo *SongSQLiteRepository) RemoveSongTags(ctx context.Context, songID int, tags []string) (song.Song, error) {
+	logger := reqctx.Logger(ctx)
+
+	tags, err := storage.NormalizeTags(tags)
+	if err != nil {
+		logger.Error("error normalize tags", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	updateCtx, cancel := storage.WithTimeout(ctx, repo.Timeouts.Update)
+	defer cancel()
+
+	tx, err := repo.DB.BeginTx(updateCtx, nil)
+	if err != nil {
+		logger.Error("error begin tx", "ERROR", err)
+		return song.Song{}, err
+	}
+	defer tx.Rollback()
+
+	if err = songExist(updateCtx, tx, songID); err != nil {
+		logger.Error("error check song", "ERROR", err, "id", songID)
+		return song.Song{}, err
+	}
+	if len(tags) > 0 {
+		args := queryArgs{songID}
+		_, err = tx.ExecContext(updateCtx, "DELETE FROM song_tags WHERE song_id = ? AND tag_id IN (SELECT tag_id FROM tags WHERE name IN "+inList(tags, &args)+")", args...)
+		if err != nil {
+			logger.Error("error exec DELETE query to db: ", "ERROR", err)
+			return song.Song{}, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		logger.Error("error in tx", "ERROR", err)
+		return song.Song{}, err
+	}
+
+	logger.Info("remove song tags success", "id", songID, "tags", tags)
+	return repo.GetSongByID(ctx, songID)
+}
+
+func (repo *SongSQLiteRepository) ListTags(ctx context.Context, name string, limit int, offset int) ([]song.Tag, error) {
+	logger := reqctx.Logger(ctx)
+
+	if limit < 0 || offset < 0 {
+		logger.Error("error paginator", "limit", limit, "offset", offset)
+		return nil, storage.ErrorNegativePaginator
+	}
+
+	ctx, cancel := storage.WithTimeout(ctx, repo.Timeouts.List)
+	defer cancel()
+
+	args := queryArgs{}
+	where := "1=1"
+	if name != "" {
+		where = "ilike(t.name, " + args.add("%"+name+"%") + ")"
+	}
+	query := "SELECT t.name, count(*) AS songs FROM tags t JOIN song_tags st ON st.tag_id = t.tag_id WHERE " + where +
+		" GROUP BY t.name ORDER BY songs DESC, t.name LIMIT " + args.add(limit) + " OFFSET " + args.add(offset)
+
+	rows, err := repo.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("error exec SELECT query to db: ", "ERROR", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []song.Tag{}
+	for rows.Next() {
+		t := song.Tag{}
+		if err = rows.Scan(&t.Name, &t.Songs); err != nil {
+			logger.Error("error scan row", "ERROR", err)
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error read rows", "ERROR", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"SongLibrary/pkg/song"
+)
+
+// SearchRepo - полнотекстовый поиск песен. Реализуют не все хранилища,
+// поэтому он не входит в Repository и проверяется приведением типа.
+type SearchRepo interface {
+	// SearchSongs возвращает песни, подходящие под запрос, по убыванию релевантности.
+	// Запрос разбирается ParseSearchQuery.
+	SearchSongs(ctx context.Context, query string, limit int, offset int) ([]song.SearchResult, error)
+}
+
+// SearchTerm - часть поискового запроса: слово, префикс (слово*) или фраза в кавычках
+type SearchTerm struct {
+	Text   string
+	Phrase bool
+	Prefix bool
+}
+
+// ParseSearchQuery разбирает запрос вида `"группа крови" кин*`.
+// Все части запроса должны найтись в песне. Пустой запрос - ErrorEmptySearchQuery.
+func ParseSearchQuery(query string) ([]SearchTerm, error) {
+	terms := []SearchTerm{}
+
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			phrase := rest[1:]
+			if end >= 0 {
+				phrase, rest = rest[1:end+1], rest[end+2:]
+			} else {
+				rest = ""
+			}
+			if phrase = strings.Join(strings.Fields(phrase), " "); phrase != "" {
+				terms = append(terms, SearchTerm{Text: phrase, Phrase: true})
+			}
+		} else {
+			end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+			word := rest
+			if end >= 0 {
+				word, rest = rest[:end], rest[end:]
+			} else {
+				rest = ""
+			}
+			prefix := strings.HasSuffix(word, "*")
+			if word = strings.TrimRight(word, "*"); word != "" {
+				terms = append(terms, SearchTerm{Text: word, Prefix: prefix})
+			}
+		}
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+
+	if len(terms) == 0 {
+		return nil, ErrorEmptySearchQuery
+	}
+	return terms, nil
+}
+
+// веса полей при ранжировании, как setweight A/B/C в postgres
+const (
+	weightName  = 1.0
+	weightGroup = 0.4
+	weightText  = 0.2
+)
+
+// RankSearch - поиск для хранилищ без своего полнотекстового поиска, упрощенный аналог
+// поиска postgres: без стемминга, слова сравниваются целиком без учета регистра.
+// Возвращает страницу подходящих под terms песен из songs по убыванию релевантности.
+func RankSearch(songs []song.Song, terms []SearchTerm, limit int, offset int) []song.SearchResult {
+	terms = lowerTerms(terms)
+
+	results := []song.SearchResult{}
+	for _, s := range songs {
+		name, group, text := words(s.Song), words(s.Group), words(s.Text)
+		all := append(append(append([]word{}, name...), group...), text...)
+		if !matchAll(all, terms) {
+			continue
+		}
+
+		rank := 0.0
+		for _, t := range terms {
+			rank += weightName*float64(countMatches(name, t)) +
+				weightGroup*float64(countMatches(group, t)) +
+				weightText*float64(countMatches(text, t))
+		}
+		results = append(results, song.SearchResult{Song: s, Rank: rank, Snippet: snippet(s.Text, terms)})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].SongID < results[j].SongID
+	})
+
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if limit < len(results) {
+		results = results[:limit]
+	}
+	return results
+}
+
+// SearchWords - слова частей запроса в нижнем регистре. Каждое из них входит подстрокой
+// в название, группу или текст песни, которую найдет RankSearch.
+func SearchWords(terms []SearchTerm) []string {
+	res := []string{}
+	for _, t := range lowerTerms(terms) {
+		for _, w := range words(t.Text) {
+			res = append(res, w.text)
+		}
+	}
+	return res
+}
+
+func lowerTerms(terms []SearchTerm) []SearchTerm {
+	lower := make([]SearchTerm, len(terms))
+	for i, t := range terms {
+		t.Text = strings.ToLower(t.Text)
+		lower[i] = t
+	}
+	return lower
+}
+
+// word - слово текста в нижнем регистре и его границы в исходной строке
+type word struct {
+	text       string
+	start, end int
+}
+
+func words(s string) []word {
+	res := []word{}
+	start := -1
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			res = append(res, word{strings.ToLower(s[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		res = append(res, word{strings.ToLower(s[start:]), start, len(s)})
+	}
+	return res
+}
+
+// matchAt проверяет, совпадает ли часть запроса с текстом начиная с i-го слова,
+// и возвращает число совпавших слов
+func matchAt(ws []word, i int, t SearchTerm) int {
+	if t.Prefix {
+		if strings.HasPrefix(ws[i].text, t.Text) {
+			return 1
+		}
+		return 0
+	}
+
+	phrase := words(t.Text)
+	if len(phrase) == 0 || i+len(phrase) > len(ws) {
+		return 0
+	}
+	for j, p := range phrase {
+		if ws[i+j].text != p.text {
+			return 0
+		}
+	}
+	return len(phrase)
+}
+
+func countMatches(ws []word, t SearchTerm) int {
+	n := 0
+	for i := range ws {
+		if matchAt(ws, i, t) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func matchAll(ws []word, terms []SearchTerm) bool {
+	for _, t := range terms {
+		if countMatches(ws, t) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// snippet - первый куплет, в котором нашлись все части запроса, с подсветкой совпадений
+func snippet(text string, terms []SearchTerm) string {
+	for _, verse := range strings.Split(text, "\n\n") {
+		ws := words(verse)
+		if !matchAll(ws, terms) {
+			continue
+		}
+
+		var b strings.Builder
+		last := 0
+		for i := 0; i < len(ws); i++ {
+			n := 0
+			for _, t := range terms {
+				n = max(n, matchAt(ws, i, t))
+			}
+			if n == 0 {
+				continue
+			}
+			start, end := ws[i].start, ws[i+n-1].end
+			b.WriteString(verse[last:start])
+			b.WriteString("<b>" + verse[start:end] + "</b>")
+			last = end
+			i += n - 1
+		}
+		b.WriteString(verse[last:])
+		return b.String()
+	}
+	return ""
+}
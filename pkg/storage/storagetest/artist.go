@@ -0,0 +1,316 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// RunArtistRepoTests проверяет исполнителей, альбомы и связь песен с ними
+func RunArtistRepoTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.Repository)
+	}{
+		{"ArtistCRUD", testArtistCRUD},
+		{"SongArtist", testSongArtist},
+		{"AlbumCRUD", testAlbumCRUD},
+		{"SongAlbum", testSongAlbum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(repo.Close)
+			tt.test(t, repo)
+		})
+	}
+}
+
+func mustAddArtist(t *testing.T, repo storage.ArtistRepo, name string) song.Artist {
+	t.Helper()
+
+	a, err := repo.AddArtist(context.Background(), name)
+	if err != nil {
+		t.Fatalf("AddArtist(%q): %v", name, err)
+	}
+	return a
+}
+
+func mustAddAlbum(t *testing.T, repo storage.ArtistRepo, artistID int64, title, date string) song.Album {
+	t.Helper()
+
+	a, err := repo.AddAlbum(context.Background(), song.Album{ArtistID: artistID, Title: title, ReleaseDate: releaseDate(date)})
+	if err != nil {
+		t.Fatalf("AddAlbum(%q): %v", title, err)
+	}
+	return a
+}
+
+func artistNames(t *testing.T, repo storage.ArtistRepo, name string) []string {
+	t.Helper()
+
+	artists, err := repo.ListArtists(context.Background(), name, 10, 0)
+	if err != nil {
+		t.Fatalf("ListArtists(%q): %v", name, err)
+	}
+	names := []string{}
+	for _, a := range artists {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func assertStrings(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func testArtistCRUD(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	muse := mustAddArtist(t, repo, "  Muse ")
+	if muse.ArtistID == 0 || muse.Name != "Muse" || muse.CreatedAt.IsZero() {
+		t.Fatalf("AddArtist = %+v, want trimmed name and assigned id", muse)
+	}
+	mustAddArtist(t, repo, "Кино")
+	mustAddArtist(t, repo, "queen")
+
+	if _, err := repo.AddArtist(ctx, "MUSE"); !errors.Is(err, storage.ErrorArtistExist) {
+		t.Fatalf("add artist of other case: err = %v, want %v", err, storage.ErrorArtistExist)
+	}
+	if _, err := repo.AddArtist(ctx, "  "); !errors.Is(err, storage.ErrorEmptyName) {
+		t.Fatalf("add empty artist: err = %v, want %v", err, storage.ErrorEmptyName)
+	}
+
+	got, err := repo.GetArtistByID(ctx, muse.ArtistID)
+	if err != nil || got != muse {
+		t.Fatalf("GetArtistByID = %+v, %v, want %+v", got, err, muse)
+	}
+	if _, err = repo.GetArtistByID(ctx, muse.ArtistID+100); !errors.Is(err, storage.ErrorArtistNotExist) {
+		t.Fatalf("unknown artist: err = %v, want %v", err, storage.ErrorArtistNotExist)
+	}
+
+	assertStrings(t, artistNames(t, repo, ""), "Muse", "queen", "Кино")
+	assertStrings(t, artistNames(t, repo, "U"), "Muse", "queen")
+	assertStrings(t, artistNames(t, repo, "кИН"), "Кино")
+
+	renamed, err := repo.RenameArtist(ctx, muse.ArtistID, "MUSE")
+	if err != nil || renamed.Name != "MUSE" || renamed.ArtistID != muse.ArtistID {
+		t.Fatalf("RenameArtist to other case = %+v, %v", renamed, err)
+	}
+	if _, err = repo.RenameArtist(ctx, muse.ArtistID, "Queen"); !errors.Is(err, storage.ErrorArtistExist) {
+		t.Fatalf("rename to existing artist: err = %v, want %v", err, storage.ErrorArtistExist)
+	}
+	if _, err = repo.RenameArtist(ctx, muse.ArtistID+100, "Nirvana"); !errors.Is(err, storage.ErrorArtistNotExist) {
+		t.Fatalf("rename unknown artist: err = %v, want %v", err, storage.ErrorArtistNotExist)
+	}
+
+	if err = repo.DeleteArtist(ctx, muse.ArtistID); err != nil {
+		t.Fatalf("DeleteArtist: %v", err)
+	}
+	if err = repo.DeleteArtist(ctx, muse.ArtistID); !errors.Is(err, storage.ErrorArtistNotExist) {
+		t.Fatalf("delete twice: err = %v, want %v", err, storage.ErrorArtistNotExist)
+	}
+	assertStrings(t, artistNames(t, repo, ""), "queen", "Кино")
+}
+
+func testSongArtist(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	mustAdd(t, repo, newSong("Muse", "Uprising"), newSong(" MUSE", "Hysteria"), newSong("Queen", "Innuendo"))
+	assertStrings(t, artistNames(t, repo, ""), "Muse", "Queen")
+
+	songs := mustList(t, repo, song.Song{}, 10, 0)
+	muse, queen := songs[0].ArtistID, songs[2].ArtistID
+	if songs[1].ArtistID != muse || songs[1].Group != "Muse" || queen == muse {
+		t.Fatalf("songs are not linked to artists: %+v", songs)
+	}
+
+	page, err := repo.ListSongs(ctx, storage.ListParams{Filter: storage.SongFilter{ArtistID: muse}, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	assertNames(t, page.Items, "Uprising", "Hysteria")
+
+	// переименование исполнителя меняет группу его песен
+	if _, err = repo.RenameArtist(ctx, muse, "Muse (UK)"); err != nil {
+		t.Fatalf("RenameArtist: %v", err)
+	}
+	assertNames(t, mustList(t, repo, song.Song{Group: "Muse (UK)"}, 10, 0), "Uprising", "Hysteria")
+
+	// смена группы песни переносит ее к другому, в том числе новому, исполнителю
+	group := "queen "
+	updated, err := repo.UpdateSongByID(ctx, song.SongForUpdate{Group: &group}, int(songs[1].SongID))
+	if err != nil || updated.ArtistID != queen || updated.Group != "Queen" {
+		t.Fatalf("UpdateSongByID(group) = %+v, %v, want artist %d Queen", updated, err, queen)
+	}
+	group = "Nirvana"
+	updated, err = repo.UpdateSongByID(ctx, song.SongForUpdate{Group: &group}, int(songs[1].SongID))
+	if err != nil || updated.ArtistID == queen || updated.ArtistID == muse || updated.Group != "Nirvana" {
+		t.Fatalf("UpdateSongByID(new group) = %+v, %v", updated, err)
+	}
+	assertStrings(t, artistNames(t, repo, ""), "Muse (UK)", "Nirvana", "Queen")
+
+	if err = repo.DeleteArtist(ctx, queen); !errors.Is(err, storage.ErrorArtistHasSongs) {
+		t.Fatalf("delete artist with songs: err = %v, want %v", err, storage.ErrorArtistHasSongs)
+	}
+	if _, err = repo.DeleteSongByIDFromDB(ctx, int(songs[2].SongID)); err != nil {
+		t.Fatalf("DeleteSongByIDFromDB: %v", err)
+	}
+	if err = repo.DeleteArtist(ctx, queen); err != nil {
+		t.Fatalf("delete artist without songs: %v", err)
+	}
+}
+
+func testAlbumCRUD(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	muse := mustAddArtist(t, repo, "Muse")
+	queen := mustAddArtist(t, repo, "Queen")
+
+	if _, err := repo.AddAlbum(ctx, song.Album{ArtistID: queen.ArtistID + 100, Title: "Innuendo"}); !errors.Is(err, storage.ErrorArtistNotExist) {
+		t.Fatalf("album of unknown artist: err = %v, want %v", err, storage.ErrorArtistNotExist)
+	}
+
+	resistance := mustAddAlbum(t, repo, muse.ArtistID, " The Resistance ", "2009-09")
+	if resistance.AlbumID == 0 || resistance.Title != "The Resistance" || resistance.ArtistID != muse.ArtistID || resistance.CreatedAt.IsZero() {
+		t.Fatalf("AddAlbum = %+v", resistance)
+	}
+	if resistance.ReleaseDate != releaseDate("2009-09") {
+		t.Fatalf("album release date = %q, want 2009-09", resistance.ReleaseDate)
+	}
+	mustAddAlbum(t, repo, muse.ArtistID, "Absolution", "2003-09-15")
+	mustAddAlbum(t, repo, muse.ArtistID, "Unreleased", "")
+	mustAddAlbum(t, repo, queen.ArtistID, "The Resistance", "")
+
+	if _, err := repo.AddAlbum(ctx, song.Album{ArtistID: muse.ArtistID, Title: "the resistance"}); !errors.Is(err, storage.ErrorAlbumExist) {
+		t.Fatalf("add album of other case: err = %v, want %v", err, storage.ErrorAlbumExist)
+	}
+
+	got, err := repo.GetAlbumByID(ctx, resistance.AlbumID)
+	if err != nil || got != resistance {
+		t.Fatalf("GetAlbumByID = %+v, %v, want %+v", got, err, resistance)
+	}
+
+	albums, err := repo.ListAlbums(ctx, muse.ArtistID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListAlbums: %v", err)
+	}
+	titles := []string{}
+	for _, a := range albums {
+		titles = append(titles, a.Title)
+	}
+	assertStrings(t, titles, "Unreleased", "Absolution", "The Resistance")
+
+	title, date := "Absolution (Remastered)", releaseDate("2004")
+	updated, err := repo.UpdateAlbum(ctx, albums[1].AlbumID, song.AlbumForUpdate{Title: &title, ReleaseDate: &date})
+	if err != nil || updated.Title != title || updated.ReleaseDate != date {
+		t.Fatalf("UpdateAlbum = %+v, %v", updated, err)
+	}
+	title = "THE RESISTANCE"
+	if _, err = repo.UpdateAlbum(ctx, albums[1].AlbumID, song.AlbumForUpdate{Title: &title}); !errors.Is(err, storage.ErrorAlbumExist) {
+		t.Fatalf("rename to existing album: err = %v, want %v", err, storage.ErrorAlbumExist)
+	}
+	if _, err = repo.UpdateAlbum(ctx, albums[1].AlbumID, song.AlbumForUpdate{}); !errors.Is(err, storage.ErrorNoFieldsToUpdate) {
+		t.Fatalf("empty album update: err = %v, want %v", err, storage.ErrorNoFieldsToUpdate)
+	}
+	if _, err = repo.UpdateAlbum(ctx, resistance.AlbumID+100, song.AlbumForUpdate{Title: &title}); !errors.Is(err, storage.ErrorAlbumNotExist) {
+		t.Fatalf("update unknown album: err = %v, want %v", err, storage.ErrorAlbumNotExist)
+	}
+
+	if err = repo.DeleteAlbum(ctx, albums[0].AlbumID); err != nil {
+		t.Fatalf("DeleteAlbum: %v", err)
+	}
+	if err = repo.DeleteAlbum(ctx, albums[0].AlbumID); !errors.Is(err, storage.ErrorAlbumNotExist) {
+		t.Fatalf("delete album twice: err = %v, want %v", err, storage.ErrorAlbumNotExist)
+	}
+
+	// альбомы удаляются вместе с исполнителем
+	if err = repo.DeleteArtist(ctx, muse.ArtistID); err != nil {
+		t.Fatalf("DeleteArtist: %v", err)
+	}
+	if _, err = repo.GetAlbumByID(ctx, resistance.AlbumID); !errors.Is(err, storage.ErrorAlbumNotExist) {
+		t.Fatalf("album of deleted artist: err = %v, want %v", err, storage.ErrorAlbumNotExist)
+	}
+}
+
+func testSongAlbum(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	muse := mustAddArtist(t, repo, "Muse")
+	queen := mustAddArtist(t, repo, "Queen")
+	resistance := mustAddAlbum(t, repo, muse.ArtistID, "The Resistance", "2009-09")
+	innuendo := mustAddAlbum(t, repo, queen.ArtistID, "Innuendo", "1991")
+
+	s := newSong("muse", "Uprising")
+	s.AlbumID = innuendo.AlbumID
+	if _, err := repo.AddSongToDB(ctx, s); !errors.Is(err, storage.ErrorAlbumOfOtherArtist) {
+		t.Fatalf("song with album of other artist: err = %v, want %v", err, storage.ErrorAlbumOfOtherArtist)
+	}
+	s.AlbumID = innuendo.AlbumID + 100
+	if _, err := repo.AddSongToDB(ctx, s); !errors.Is(err, storage.ErrorAlbumNotExist) {
+		t.Fatalf("song with unknown album: err = %v, want %v", err, storage.ErrorAlbumNotExist)
+	}
+
+	s.AlbumID = resistance.AlbumID
+	uprising, err := repo.AddSongToDB(ctx, s)
+	if err != nil || uprising.AlbumID != resistance.AlbumID || uprising.ArtistID != muse.ArtistID {
+		t.Fatalf("AddSongToDB with album = %+v, %v", uprising, err)
+	}
+	mustAdd(t, repo, newSong("Muse", "Starlight"))
+
+	page, err := repo.ListSongs(ctx, storage.ListParams{Filter: storage.SongFilter{AlbumID: resistance.AlbumID}, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	assertNames(t, page.Items, "Uprising")
+
+	id := int(uprising.SongID)
+	if _, err = repo.UpdateSongByID(ctx, song.SongForUpdate{AlbumID: &innuendo.AlbumID}, id); !errors.Is(err, storage.ErrorAlbumOfOtherArtist) {
+		t.Fatalf("update to album of other artist: err = %v, want %v", err, storage.ErrorAlbumOfOtherArtist)
+	}
+
+	// смена исполнителя отвязывает песню от альбома прежнего исполнителя
+	group := "Queen"
+	updated, err := repo.UpdateSongByID(ctx, song.SongForUpdate{Group: &group}, id)
+	if err != nil || updated.ArtistID != queen.ArtistID || updated.AlbumID != 0 {
+		t.Fatalf("UpdateSongByID(group) = %+v, %v", updated, err)
+	}
+	updated, err = repo.UpdateSongByID(ctx, song.SongForUpdate{AlbumID: &innuendo.AlbumID}, id)
+	if err != nil || updated.AlbumID != innuendo.AlbumID {
+		t.Fatalf("UpdateSongByID(album) = %+v, %v", updated, err)
+	}
+	// исполнитель и альбом можно сменить одним запросом
+	group = "Muse"
+	updated, err = repo.UpdateSongByID(ctx, song.SongForUpdate{Group: &group, AlbumID: &resistance.AlbumID}, id)
+	if err != nil || updated.ArtistID != muse.ArtistID || updated.AlbumID != resistance.AlbumID {
+		t.Fatalf("UpdateSongByID(group, album) = %+v, %v", updated, err)
+	}
+
+	// удаление альбома отвязывает от него песни
+	if err = repo.DeleteAlbum(ctx, resistance.AlbumID); err != nil {
+		t.Fatalf("DeleteAlbum: %v", err)
+	}
+	got, err := repo.GetSongByID(ctx, id)
+	if err != nil || got.AlbumID != 0 {
+		t.Fatalf("song of deleted album = %+v, %v", got, err)
+	}
+
+	noAlbum := int64(0)
+	updated, err = repo.UpdateSongByID(ctx, song.SongForUpdate{AlbumID: &noAlbum}, id)
+	if err != nil || updated.AlbumID != 0 {
+		t.Fatalf("UpdateSongByID(album 0) = %+v, %v", updated, err)
+	}
+}
@@ -0,0 +1,294 @@
+package storagetest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"SongLibrary/pkg/backup"
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// RunBackupTests проверяет резервное копирование и восстановление каталога.
+// newRepo должен каждый раз возвращать пустое хранилище
+func RunBackupTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		test func(*testing.T, NewRepository)
+	}{
+		{"BackupRoundTrip", testBackupRoundTrip},
+		{"RestoreSkip", testRestoreSkip},
+		{"RestoreOverwrite", testRestoreOverwrite},
+		{"RestoreFail", testRestoreFail},
+		{"BackupCorrupted", testBackupCorrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, func(t *testing.T) storage.Repository {
+				repo := newRepo(t)
+				t.Cleanup(repo.Close)
+				return repo
+			})
+		})
+	}
+}
+
+// fillCatalog добавляет исполнителя с альбомом, песни с тегами и плейлист с повтором
+func fillCatalog(t *testing.T, repo storage.Repository) {
+	t.Helper()
+
+	muse := mustAddArtist(t, repo, "Muse")
+	album := mustAddAlbum(t, repo, muse.ArtistID, "The Resistance", "2009-09")
+	mustAddArtist(t, repo, "Radiohead")
+
+	uprising := taggedSong("Muse", "Uprising", "rock")
+	uprising.AlbumID = album.AlbumID
+	mustAdd(t, repo, uprising, taggedSong("Muse", "Hysteria", "rock", "alternative"), newSong("Queen", "Bohemian Rhapsody"))
+
+	ids := map[string]int64{}
+	for _, s := range mustExport(t, repo, storage.ListParams{}) {
+		ids[s.Song] = s.SongID
+	}
+	p := mustAddPlaylist(t, repo, "Mix", true)
+	mustAddEntry(t, repo, p.PlaylistID, ids["Hysteria"], 0)
+	mustAddEntry(t, repo, p.PlaylistID, ids["Bohemian Rhapsody"], 0)
+	mustAddEntry(t, repo, p.PlaylistID, ids["Hysteria"], 0)
+}
+
+func mustBackup(t *testing.T, repo storage.Repository) []byte {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	summary, err := backup.Write(context.Background(), repo, &buf)
+	if err != nil {
+		t.Fatalf("backup.Write: %v", err)
+	}
+	if summary.Kind != backup.Kind || summary.Version != backup.Version {
+		t.Errorf("summary header = %+v", summary.Header)
+	}
+	return buf.Bytes()
+}
+
+func restore(repo storage.Repository, data []byte, strategy string) (backup.Report, error) {
+	open := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	return backup.Restore(context.Background(), repo, open, strategy)
+}
+
+func mustRestore(t *testing.T, repo storage.Repository, data []byte, strategy string) backup.Report {
+	t.Helper()
+
+	report, err := restore(repo, data, strategy)
+	if err != nil {
+		t.Fatalf("backup.Restore(%s): %v", strategy, err)
+	}
+	return report
+}
+
+// playlistByName находит плейлист по названию
+func playlistByName(t *testing.T, repo storage.PlaylistRepo, name string) song.Playlist {
+	t.Helper()
+
+	playlists, err := repo.ListPlaylists(context.Background(), name, 10, 0)
+	if err != nil || len(playlists) != 1 {
+		t.Fatalf("ListPlaylists(%q) = %v, %v", name, playlists, err)
+	}
+	return playlists[0]
+}
+
+func testBackupRoundTrip(t *testing.T, newRepo NewRepository) {
+	src := newRepo(t)
+	fillCatalog(t, src)
+	data := mustBackup(t, src)
+
+	summary, err := backup.Verify(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if c := summary.Counts; c[backup.TypeArtist] != 3 || c[backup.TypeAlbum] != 1 || c[backup.TypeSong] != 3 || c[backup.TypePlaylist] != 1 {
+		t.Errorf("summary counts = %v", c)
+	}
+
+	dst := newRepo(t)
+	report := mustRestore(t, dst, data, backup.ConflictFail)
+	want := backup.Report{
+		Artists:   backup.Counts{Created: 3},
+		Albums:    backup.Counts{Created: 1},
+		Songs:     backup.Counts{Created: 3},
+		Playlists: backup.Counts{Created: 1},
+	}
+	if report != want {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+
+	songs := mustExport(t, dst, storage.ListParams{})
+	assertNames(t, songs, "Uprising", "Hysteria", "Bohemian Rhapsody")
+	assertStrings(t, songs[1].Tags, "alternative", "rock")
+	if songs[0].AlbumID == 0 {
+		t.Fatalf("album of restored song is lost")
+	}
+	album, err := dst.GetAlbumByID(context.Background(), songs[0].AlbumID)
+	if err != nil || album.Title != "The Resistance" || album.ReleaseDate.String() != "2009-09" {
+		t.Errorf("restored album = %+v, %v", album, err)
+	}
+	assertStrings(t, artistNames(t, dst, ""), "Muse", "Queen", "Radiohead")
+
+	p := playlistByName(t, dst, "Mix")
+	if !p.AllowDuplicates {
+		t.Errorf("restored playlist does not allow duplicates")
+	}
+	assertStrings(t, entryNames(t, dst, p.PlaylistID, 10, 0), "1:Hysteria", "2:Bohemian Rhapsody", "3:Hysteria")
+}
+
+func testRestoreSkip(t *testing.T, newRepo NewRepository) {
+	repo := newRepo(t)
+	fillCatalog(t, repo)
+	data := mustBackup(t, repo)
+
+	ctx := context.Background()
+	hysteria := mustExport(t, repo, storage.ListParams{Filter: storage.SongFilter{Name: "Hysteria"}})[0]
+	text := "changed"
+	if _, err := repo.UpdateSongByID(ctx, song.SongForUpdate{Text: &text}, int(hysteria.SongID)); err != nil {
+		t.Fatalf("UpdateSongByID: %v", err)
+	}
+
+	report := mustRestore(t, repo, data, backup.ConflictSkip)
+	want := backup.Report{
+		Artists:   backup.Counts{Skipped: 3},
+		Albums:    backup.Counts{Skipped: 1},
+		Songs:     backup.Counts{Skipped: 3},
+		Playlists: backup.Counts{Skipped: 1},
+	}
+	if report != want {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+
+	got, err := repo.GetSongByID(ctx, int(hysteria.SongID))
+	if err != nil || got.Text != text {
+		t.Errorf("skipped song = %+v, %v", got, err)
+	}
+	p := playlistByName(t, repo, "Mix")
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:Hysteria", "2:Bohemian Rhapsody", "3:Hysteria")
+}
+
+func testRestoreOverwrite(t *testing.T, newRepo NewRepository) {
+	repo := newRepo(t)
+	fillCatalog(t, repo)
+	data := mustBackup(t, repo)
+
+	ctx := context.Background()
+	hysteria := mustExport(t, repo, storage.ListParams{Filter: storage.SongFilter{Name: "Hysteria"}})[0]
+	text := "changed"
+	if _, err := repo.UpdateSongByID(ctx, song.SongForUpdate{Text: &text}, int(hysteria.SongID)); err != nil {
+		t.Fatalf("UpdateSongByID: %v", err)
+	}
+	if _, err := repo.RemoveSongTags(ctx, int(hysteria.SongID), []string{"alternative"}); err != nil {
+		t.Fatalf("RemoveSongTags: %v", err)
+	}
+	mustAddSongTags(t, repo, hysteria.SongID, "live")
+	p := playlistByName(t, repo, "Mix")
+	entries, err := repo.ListPlaylistEntries(ctx, p.PlaylistID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListPlaylistEntries: %v", err)
+	}
+	if err = repo.DeletePlaylistEntry(ctx, p.PlaylistID, entries[0].EntryID); err != nil {
+		t.Fatalf("DeletePlaylistEntry: %v", err)
+	}
+	// новая песня не из копии остается
+	mustAdd(t, repo, newSong("Muse", "Resistance"))
+
+	report := mustRestore(t, repo, data, backup.ConflictOverwrite)
+	if report.Songs != (backup.Counts{Updated: 3}) || report.Playlists != (backup.Counts{Updated: 1}) || report.Albums != (backup.Counts{Updated: 1}) {
+		t.Errorf("report = %+v", report)
+	}
+
+	got, err := repo.GetSongByID(ctx, int(hysteria.SongID))
+	if err != nil || got.Text != hysteria.Text {
+		t.Errorf("overwritten song = %+v, %v", got, err)
+	}
+	assertStrings(t, got.Tags, "alternative", "rock")
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:Hysteria", "2:Bohemian Rhapsody", "3:Hysteria")
+	assertNames(t, mustExport(t, repo, storage.ListParams{Filter: storage.SongFilter{Groups: []string{"Muse"}}}), "Uprising", "Hysteria", "Resistance")
+}
+
+func testRestoreFail(t *testing.T, newRepo NewRepository) {
+	src := newRepo(t)
+	fillCatalog(t, src)
+	data := mustBackup(t, src)
+
+	// одна совпавшая песня отменяет восстановление целиком
+	dst := newRepo(t)
+	mustAdd(t, dst, newSong("Queen", "Bohemian Rhapsody"))
+	if _, err := restore(dst, data, backup.ConflictFail); !errors.Is(err, backup.ErrConflict) {
+		t.Fatalf("Restore with conflict: err = %v, want ErrConflict", err)
+	}
+	assertNames(t, mustExport(t, dst, storage.ListParams{}), "Bohemian Rhapsody")
+	assertStrings(t, artistNames(t, dst, ""), "Queen")
+
+	if _, err := restore(dst, data, "merge"); err == nil {
+		t.Errorf("Restore with unknown strategy: want error")
+	}
+}
+
+func testBackupCorrupted(t *testing.T, newRepo NewRepository) {
+	src := newRepo(t)
+	fillCatalog(t, src)
+	data := mustBackup(t, src)
+
+	lines := gunzip(t, data)
+	dst := newRepo(t)
+
+	// измененная строка не сходится с контрольной суммой, хранилище не меняется
+	changed := bytes.Replace(lines, []byte("Hysteria"), []byte("Hysterie"), 1)
+	if _, err := restore(dst, gzipBytes(t, changed), backup.ConflictSkip); !errors.Is(err, backup.ErrChecksum) {
+		t.Errorf("Restore of changed archive: err = %v, want ErrChecksum", err)
+	}
+	assertNames(t, mustExport(t, dst, storage.ListParams{}))
+
+	// обрезанная копия без итога
+	cut := lines[:bytes.LastIndexByte(lines[:len(lines)-1], '\n')+1]
+	if _, err := backup.Verify(bytes.NewReader(gzipBytes(t, cut))); !errors.Is(err, backup.ErrBadArchive) {
+		t.Errorf("Verify of truncated archive: err = %v, want ErrBadArchive", err)
+	}
+
+	newer := bytes.Replace(lines, []byte(`"version":1`), []byte(`"version":99`), 1)
+	if _, err := backup.Verify(bytes.NewReader(gzipBytes(t, newer))); !errors.Is(err, backup.ErrUnsupportedVersion) {
+		t.Errorf("Verify of newer archive: err = %v, want ErrUnsupportedVersion", err)
+	}
+
+	if _, err := backup.Verify(bytes.NewReader(lines)); !errors.Is(err, backup.ErrBadArchive) {
+		t.Errorf("Verify of not compressed archive: err = %v, want ErrBadArchive", err)
+	}
+}
+
+func gunzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	lines, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("gunzip: %v", err)
+	}
+	return lines
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,257 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// RunImportRepoTests проверяет массовые загрузку и выгрузку песен
+func RunImportRepoTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.Repository)
+	}{
+		{"Create", testImportCreate},
+		{"Skip", testImportSkip},
+		{"Upsert", testImportUpsert},
+		{"Album", testImportAlbum},
+		{"Export", testExport},
+		{"ExportBatches", testExportBatches},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(repo.Close)
+			tt.test(t, repo)
+		})
+	}
+}
+
+func mustImport(t *testing.T, repo storage.ImportRepo, policy string, songs ...song.Song) []song.ImportRow {
+	t.Helper()
+
+	rows, err := repo.ImportSongs(context.Background(), songs, policy)
+	if err != nil {
+		t.Fatalf("ImportSongs(%s): %v", policy, err)
+	}
+	if len(rows) != len(songs) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(songs))
+	}
+	return rows
+}
+
+// importStatuses - статусы строк импорта в виде "группа - песня: статус"
+func importStatuses(rows []song.ImportRow) []string {
+	result := make([]string, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, r.Group+" - "+r.Song+": "+r.Status)
+	}
+	return result
+}
+
+func testImportCreate(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	mustAdd(t, repo, newSong("Muse", "Uprising"))
+
+	full := taggedSong("muse ", "Hysteria", "rock")
+	pending := newPendingSong("Queen", "Bohemian Rhapsody")
+	rows := mustImport(t, repo, storage.ImportSkip, full, pending)
+	assertStrings(t, importStatuses(rows), "Muse - Hysteria: created", "Queen - Bohemian Rhapsody: created")
+
+	got, err := repo.GetSongByID(ctx, int(rows[0].SongID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	// песня попадает к существующему исполнителю
+	uprising := mustList(t, repo, song.Song{Song: "Uprising"}, 10, 0)[0]
+	if got.ArtistID != uprising.ArtistID || got.Group != "Muse" || got.Text != full.Text || got.ReleaseDate != full.ReleaseDate || got.EnrichmentStatus != song.EnrichmentDone {
+		t.Errorf("unexpected imported song %+v", got)
+	}
+	assertStrings(t, got.Tags, "rock")
+
+	// задача на заполнение ставится только для незаполненной песни
+	jobs, err := repo.GetEnrichmentJobs(ctx, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].SongID != rows[1].SongID {
+		t.Errorf("unexpected jobs %+v", jobs)
+	}
+
+	// новые песни идут в списке после старых в порядке файла
+	assertNames(t, mustList(t, repo, song.Song{}, 10, 0), "Uprising", "Hysteria", "Bohemian Rhapsody")
+}
+
+func testImportSkip(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo, newSong("Muse", "Uprising"))
+	existing := mustList(t, repo, song.Song{}, 10, 0)[0]
+
+	changed := taggedSong("MUSE", "Uprising", "rock")
+	changed.Text = "They will not force us"
+	rows := mustImport(t, repo, storage.ImportSkip, changed, newSong("Muse", "Hysteria"))
+	assertStrings(t, importStatuses(rows), "Muse - Uprising: skipped", "Muse - Hysteria: created")
+	if rows[0].SongID != existing.SongID {
+		t.Errorf("skipped song id = %d, want %d", rows[0].SongID, existing.SongID)
+	}
+
+	got, err := repo.GetSongByID(context.Background(), int(existing.SongID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.Text != existing.Text || len(got.Tags) != 0 {
+		t.Errorf("skipped song was changed: %+v", got)
+	}
+}
+
+func testImportUpsert(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	mustAdd(t, repo, taggedSong("Muse", "Uprising", "alternative"))
+	existing := mustList(t, repo, song.Song{}, 10, 0)[0]
+
+	// пустые поля файла не затирают сохраненные
+	changed := song.Song{Group: "muse", Song: "Uprising", Text: "They will not force us", Tags: []string{"rock"}}
+	rows := mustImport(t, repo, storage.ImportUpsert, changed, newPendingSong("Muse", "Hysteria"))
+	assertStrings(t, importStatuses(rows), "Muse - Uprising: updated", "Muse - Hysteria: created")
+	if rows[0].SongID != existing.SongID {
+		t.Errorf("updated song id = %d, want %d", rows[0].SongID, existing.SongID)
+	}
+
+	got, err := repo.GetSongByID(ctx, int(existing.SongID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.Text != changed.Text || got.Link != existing.Link || got.ReleaseDate != existing.ReleaseDate || got.EnrichmentStatus != song.EnrichmentDone {
+		t.Errorf("unexpected updated song %+v", got)
+	}
+	assertStrings(t, got.Tags, "alternative", "rock")
+
+	// дата выпуска меняется вместе с точностью
+	changed = song.Song{Group: "Muse", Song: "Uprising", ReleaseDate: releaseDate("2009")}
+	mustImport(t, repo, storage.ImportUpsert, changed)
+	if got, err = repo.GetSongByID(ctx, int(existing.SongID)); err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.ReleaseDate != changed.ReleaseDate || got.Text != "They will not force us" {
+		t.Errorf("unexpected updated song %+v", got)
+	}
+}
+
+func testImportAlbum(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	muse := mustAddArtist(t, repo, "Muse")
+	resistance := mustAddAlbum(t, repo, muse.ArtistID, "The Resistance", "2009")
+	queen := mustAddArtist(t, repo, "Queen")
+	innuendo := mustAddAlbum(t, repo, queen.ArtistID, "Innuendo", "1991")
+
+	uprising := newSong("Muse", "Uprising")
+	uprising.AlbumID = resistance.AlbumID
+	foreign := newSong("Muse", "Hysteria")
+	foreign.AlbumID = innuendo.AlbumID
+	unknown := newSong("Queen", "Innuendo")
+	unknown.AlbumID = innuendo.AlbumID + 100
+	rows := mustImport(t, repo, storage.ImportSkip, uprising, foreign, unknown, newSong("Queen", "The Show Must Go On"))
+	assertStrings(t, importStatuses(rows),
+		"Muse - Uprising: created", "Muse - Hysteria: failed", "Queen - Innuendo: failed", "Queen - The Show Must Go On: created")
+	if rows[1].Error != storage.ErrorAlbumOfOtherArtist.Error() || rows[2].Error != storage.ErrorAlbumNotExist.Error() {
+		t.Errorf("unexpected errors %q, %q", rows[1].Error, rows[2].Error)
+	}
+
+	got, err := repo.GetSongByID(ctx, int(rows[0].SongID))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.AlbumID != resistance.AlbumID {
+		t.Errorf("album id = %d, want %d", got.AlbumID, resistance.AlbumID)
+	}
+	if songs := mustList(t, repo, song.Song{}, 10, 0); len(songs) != 2 {
+		t.Errorf("got %d songs, want 2", len(songs))
+	}
+
+	// альбом из файла переносится в существующую песню, песня без альбома в файле его не теряет
+	show := song.Song{Group: "Queen", Song: "The Show Must Go On", AlbumID: innuendo.AlbumID}
+	mustImport(t, repo, storage.ImportUpsert, show, song.Song{Group: "Muse", Song: "Uprising", Text: "They will not force us"})
+	if got, err = repo.GetSongByID(ctx, int(rows[3].SongID)); err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.AlbumID != innuendo.AlbumID {
+		t.Errorf("album id = %d, want %d", got.AlbumID, innuendo.AlbumID)
+	}
+	if got, err = repo.GetSongByID(ctx, int(rows[0].SongID)); err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.AlbumID != resistance.AlbumID {
+		t.Errorf("album id = %d, want %d", got.AlbumID, resistance.AlbumID)
+	}
+}
+
+func mustExport(t *testing.T, repo storage.ExportRepo, params storage.ListParams) []song.Song {
+	t.Helper()
+
+	songs := []song.Song{}
+	err := repo.ExportSongs(context.Background(), params, func(s song.Song) error {
+		songs = append(songs, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportSongs: %v", err)
+	}
+	return songs
+}
+
+func testExport(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo,
+		taggedSong("Muse", "Uprising", "rock"),
+		newSong("Queen", "Bohemian Rhapsody"),
+		taggedSong("Muse", "Hysteria", "rock", "alternative"),
+	)
+
+	all := mustExport(t, repo, storage.ListParams{})
+	assertNames(t, all, "Uprising", "Bohemian Rhapsody", "Hysteria")
+	assertStrings(t, all[2].Tags, "alternative", "rock")
+	if all[1].Text == "" || all[1].ReleaseDate.IsZero() {
+		t.Errorf("exported song is not full: %+v", all[1])
+	}
+
+	// фильтр и сортировка как у списка, Limit не учитывается
+	params := storage.ListParams{
+		Filter: storage.SongFilter{Tags: []string{"rock"}},
+		Sort:   []storage.SortField{{Field: storage.SortName}},
+		Limit:  1,
+	}
+	assertNames(t, mustExport(t, repo, params), "Hysteria", "Uprising")
+
+	// ошибка fn останавливает выгрузку
+	stop := errors.New("stop")
+	count := 0
+	err := repo.ExportSongs(context.Background(), storage.ListParams{}, func(song.Song) error {
+		count++
+		return stop
+	})
+	if !errors.Is(err, stop) || count != 1 {
+		t.Errorf("ExportSongs with failing fn: err = %v, calls = %d", err, count)
+	}
+}
+
+func testExportBatches(t *testing.T, repo storage.Repository) {
+	songs := make([]song.Song, 0, storage.ExportBatchSize+1)
+	for i := range cap(songs) {
+		songs = append(songs, newSong("Muse", fmt.Sprintf("Song %04d", i)))
+	}
+	mustImport(t, repo, storage.ImportSkip, songs...)
+
+	got := mustExport(t, repo, storage.ListParams{})
+	if len(got) != len(songs) {
+		t.Fatalf("exported %d songs, want %d", len(got), len(songs))
+	}
+	for i, s := range got {
+		if s.Song != songs[i].Song {
+			t.Fatalf("song %d = %q, want %q", i, s.Song, songs[i].Song)
+		}
+	}
+}
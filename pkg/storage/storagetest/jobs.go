@@ -0,0 +1,280 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// NewRepository должен возвращать пустое хранилище для каждого теста
+type NewRepository func(t *testing.T) storage.Repository
+
+// RunJobRepoTests проверяет очередь задач на заполнение песен данными внешнего сервиса
+func RunJobRepoTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.Repository)
+	}{
+		{"PendingSongCreatesJob", testPendingSongCreatesJob},
+		{"ClaimAndComplete", testClaimAndComplete},
+		{"RetryAndLease", testRetryAndLease},
+		{"FailAndRequeue", testFailAndRequeue},
+		{"DeleteSongRemovesJob", testDeleteSongRemovesJob},
+		{"RefreshClosesJobs", testRefreshClosesJobs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(repo.Close)
+			tt.test(t, repo)
+		})
+	}
+}
+
+func newPendingSong(group, name string) song.Song {
+	return song.Song{
+		Song:             name,
+		Group:            group,
+		EnrichmentStatus: song.EnrichmentPending,
+	}
+}
+
+func mustClaim(t *testing.T, repo storage.Repository, limit int, lease time.Duration) []song.EnrichmentJob {
+	t.Helper()
+
+	jobs, err := repo.ClaimEnrichmentJobs(context.Background(), limit, lease)
+	if err != nil {
+		t.Fatalf("ClaimEnrichmentJobs: %v", err)
+	}
+	return jobs
+}
+
+func testPendingSongCreatesJob(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo, newSong("Muse", "Uprising"), newPendingSong("Muse", "Hysteria"))
+
+	jobs, err := repo.GetEnrichmentJobs(context.Background(), "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJobs: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+	if jobs[0].Song != "Hysteria" || jobs[0].Group != "Muse" || jobs[0].Status != song.JobPending || jobs[0].Attempts != 0 {
+		t.Errorf("unexpected job %+v", jobs[0])
+	}
+
+	songs := mustList(t, repo, song.Song{Song: "Hysteria"}, 10, 0)
+	if songs[0].EnrichmentStatus != song.EnrichmentPending {
+		t.Errorf("enrichment status = %q, want %q", songs[0].EnrichmentStatus, song.EnrichmentPending)
+	}
+}
+
+func testClaimAndComplete(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo, newPendingSong("Muse", "Uprising"), newPendingSong("Muse", "Hysteria"))
+
+	jobs := mustClaim(t, repo, 1, time.Minute)
+	if len(jobs) != 1 || jobs[0].Song != "Uprising" || jobs[0].Status != song.JobRunning || jobs[0].Attempts != 1 {
+		t.Fatalf("unexpected claimed jobs %+v", jobs)
+	}
+
+	// задача в работе не выдается повторно
+	next := mustClaim(t, repo, 10, time.Minute)
+	if len(next) != 1 || next[0].Song != "Hysteria" {
+		t.Fatalf("unexpected claimed jobs %+v", next)
+	}
+
+	info := song.ResponseFromExternalAPI{
+		ReleaseDate: releaseDate("2009-09-03"),
+		Text:        "Paranoia is in bloom",
+		Link:        "https://www.youtube.com/watch?v=w8KQmps-Sog",
+	}
+	if err := repo.CompleteEnrichmentJob(context.Background(), jobs[0].JobID, jobs[0].Lease, info); err != nil {
+		t.Fatalf("CompleteEnrichmentJob: %v", err)
+	}
+
+	got := mustList(t, repo, song.Song{Song: "Uprising"}, 10, 0)[0]
+	if got.ReleaseDate != info.ReleaseDate || got.Text != info.Text || got.Link != info.Link || got.EnrichmentStatus != song.EnrichmentDone {
+		t.Errorf("song was not enriched: %+v", got)
+	}
+
+	done, err := repo.GetEnrichmentJobs(context.Background(), song.JobDone, 10, 0)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJobs: %v", err)
+	}
+	if len(done) != 1 || done[0].JobID != jobs[0].JobID {
+		t.Errorf("unexpected done jobs %+v", done)
+	}
+
+	err = repo.CompleteEnrichmentJob(context.Background(), 1000, jobs[0].Lease, info)
+	if !errors.Is(err, storage.ErrorJobNotExist) {
+		t.Errorf("CompleteEnrichmentJob of unknown job: got %v, want %v", err, storage.ErrorJobNotExist)
+	}
+}
+
+func testRetryAndLease(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo, newPendingSong("Muse", "Uprising"))
+
+	jobs := mustClaim(t, repo, 1, time.Minute)
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+
+	err := repo.RetryEnrichmentJob(context.Background(), jobs[0].JobID, jobs[0].Lease, "upstream error", time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatalf("RetryEnrichmentJob: %v", err)
+	}
+
+	// воркер пропал, не вернув задачу: после окончания lease ее забирает другой
+	lost := mustClaim(t, repo, 1, time.Millisecond)
+	if len(lost) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(lost))
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	again := mustClaim(t, repo, 1, time.Minute)
+	if len(again) != 1 || again[0].Attempts != 3 || again[0].LastError != "upstream error" {
+		t.Fatalf("unexpected reclaimed jobs %+v", again)
+	}
+
+	// прежний воркер вернулся и не может сохранить результат поверх нового
+	info := song.ResponseFromExternalAPI{Text: "stale"}
+	err = repo.CompleteEnrichmentJob(context.Background(), lost[0].JobID, lost[0].Lease, info)
+	if !errors.Is(err, storage.ErrorLeaseLost) {
+		t.Errorf("CompleteEnrichmentJob with lost lease: got %v, want %v", err, storage.ErrorLeaseLost)
+	}
+	err = repo.FailEnrichmentJob(context.Background(), lost[0].JobID, lost[0].Lease, "stale")
+	if !errors.Is(err, storage.ErrorLeaseLost) {
+		t.Errorf("FailEnrichmentJob with lost lease: got %v, want %v", err, storage.ErrorLeaseLost)
+	}
+	if got := mustList(t, repo, song.Song{Song: "Uprising"}, 10, 0)[0]; got.Text != "" || got.EnrichmentStatus != song.EnrichmentPending {
+		t.Errorf("song changed by lost lease: %+v", got)
+	}
+
+	err = repo.RetryEnrichmentJob(context.Background(), again[0].JobID, again[0].Lease, "upstream error", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("RetryEnrichmentJob: %v", err)
+	}
+	if jobs := mustClaim(t, repo, 1, time.Minute); len(jobs) != 0 {
+		t.Fatalf("job claimed before run_at: %+v", jobs)
+	}
+
+	// задача уже возвращена в очередь, повторно сохранить результат с тем же lease нельзя
+	err = repo.RetryEnrichmentJob(context.Background(), again[0].JobID, again[0].Lease, "upstream error", time.Now())
+	if !errors.Is(err, storage.ErrorLeaseLost) {
+		t.Errorf("RetryEnrichmentJob of pending job: got %v, want %v", err, storage.ErrorLeaseLost)
+	}
+}
+
+func testFailAndRequeue(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo, newPendingSong("Muse", "Uprising"))
+
+	jobs := mustClaim(t, repo, 1, time.Minute)
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+
+	err := repo.RequeueEnrichmentJob(context.Background(), jobs[0].JobID)
+	if !errors.Is(err, storage.ErrorJobNotDead) {
+		t.Errorf("RequeueEnrichmentJob of running job: got %v, want %v", err, storage.ErrorJobNotDead)
+	}
+
+	if err = repo.FailEnrichmentJob(context.Background(), jobs[0].JobID, jobs[0].Lease, "song not found"); err != nil {
+		t.Fatalf("FailEnrichmentJob: %v", err)
+	}
+	if got := mustList(t, repo, song.Song{Song: "Uprising"}, 10, 0)[0]; got.EnrichmentStatus != song.EnrichmentFailed {
+		t.Errorf("enrichment status = %q, want %q", got.EnrichmentStatus, song.EnrichmentFailed)
+	}
+	if jobs := mustClaim(t, repo, 1, time.Minute); len(jobs) != 0 {
+		t.Fatalf("dead job claimed: %+v", jobs)
+	}
+
+	if err = repo.RequeueEnrichmentJob(context.Background(), jobs[0].JobID); err != nil {
+		t.Fatalf("RequeueEnrichmentJob: %v", err)
+	}
+	again := mustClaim(t, repo, 1, time.Minute)
+	if len(again) != 1 || again[0].Attempts != 1 {
+		t.Errorf("unexpected requeued jobs %+v", again)
+	}
+
+	err = repo.RequeueEnrichmentJob(context.Background(), 1000)
+	if !errors.Is(err, storage.ErrorJobNotExist) {
+		t.Errorf("RequeueEnrichmentJob of unknown job: got %v, want %v", err, storage.ErrorJobNotExist)
+	}
+}
+
+func testDeleteSongRemovesJob(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo, newPendingSong("Muse", "Uprising"))
+
+	id := mustList(t, repo, song.Song{}, 10, 0)[0].SongID
+	if _, err := repo.DeleteSongByIDFromDB(context.Background(), int(id)); err != nil {
+		t.Fatalf("DeleteSongByIDFromDB: %v", err)
+	}
+
+	if jobs := mustClaim(t, repo, 10, time.Minute); len(jobs) != 0 {
+		t.Errorf("job of deleted song claimed: %+v", jobs)
+	}
+}
+
+func testRefreshClosesJobs(t *testing.T, repo storage.Repository) {
+	mustAdd(t, repo, newPendingSong("Muse", "Uprising"), newPendingSong("Muse", "Hysteria"))
+
+	jobs := mustClaim(t, repo, 1, time.Minute)
+	if len(jobs) != 1 || jobs[0].Song != "Uprising" {
+		t.Fatalf("unexpected claimed jobs %+v", jobs)
+	}
+	hysteria := mustList(t, repo, song.Song{Song: "Hysteria"}, 10, 0)[0]
+
+	// неудачное обновление не закрывает задачу
+	badAlbum := int64(1000)
+	_, err := repo.RefreshSongByID(context.Background(), song.SongForUpdate{AlbumID: &badAlbum}, int(hysteria.SongID))
+	if !errors.Is(err, storage.ErrorAlbumNotExist) {
+		t.Fatalf("RefreshSongByID with unknown album: got %v, want %v", err, storage.ErrorAlbumNotExist)
+	}
+	if pending, _ := repo.GetEnrichmentJobs(context.Background(), song.JobPending, 10, 0); len(pending) != 1 {
+		t.Fatalf("job closed by failed refresh: %+v", pending)
+	}
+
+	// задача в работе и задача в очереди
+	text, status := "Paranoia is in bloom", song.EnrichmentDone
+	update := song.SongForUpdate{Text: &text, EnrichmentStatus: &status}
+	for _, id := range []int64{jobs[0].SongID, hysteria.SongID} {
+		got, err := repo.RefreshSongByID(context.Background(), update, int(id))
+		if err != nil {
+			t.Fatalf("RefreshSongByID: %v", err)
+		}
+		if got.Text != text || got.EnrichmentStatus != status {
+			t.Errorf("song was not refreshed: %+v", got)
+		}
+	}
+
+	done, err := repo.GetEnrichmentJobs(context.Background(), song.JobDone, 10, 0)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJobs: %v", err)
+	}
+	if len(done) != 2 {
+		t.Errorf("unexpected done jobs %+v", done)
+	}
+	if jobs := mustClaim(t, repo, 10, time.Minute); len(jobs) != 0 {
+		t.Errorf("closed job claimed: %+v", jobs)
+	}
+
+	// воркер, забравший задачу до обновления, не перезаписывает песню
+	info := song.ResponseFromExternalAPI{Text: "stale"}
+	err = repo.CompleteEnrichmentJob(context.Background(), jobs[0].JobID, jobs[0].Lease, info)
+	if !errors.Is(err, storage.ErrorLeaseLost) {
+		t.Errorf("CompleteEnrichmentJob of closed job: got %v, want %v", err, storage.ErrorLeaseLost)
+	}
+	if got := mustList(t, repo, song.Song{Song: "Uprising"}, 10, 0)[0]; got.Text != text {
+		t.Errorf("song changed by closed job: %+v", got)
+	}
+
+	_, err = repo.RefreshSongByID(context.Background(), update, 1000)
+	if !errors.Is(err, storage.ErrorSongNotExist) {
+		t.Errorf("RefreshSongByID of unknown song: got %v, want %v", err, storage.ErrorSongNotExist)
+	}
+}
@@ -0,0 +1,375 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// RunPlaylistRepoTests проверяет плейлисты и порядок их записей
+func RunPlaylistRepoTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.Repository)
+	}{
+		{"PlaylistCRUD", testPlaylistCRUD},
+		{"Entries", testPlaylistEntries},
+		{"MoveAndReorder", testPlaylistMoveAndReorder},
+		{"Duplicates", testPlaylistDuplicates},
+		{"DeleteSong", testPlaylistDeleteSong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(repo.Close)
+			tt.test(t, repo)
+		})
+	}
+}
+
+func mustAddPlaylist(t *testing.T, repo storage.PlaylistRepo, name string, allowDuplicates bool) song.Playlist {
+	t.Helper()
+
+	p, err := repo.AddPlaylist(context.Background(), song.Playlist{Name: name, AllowDuplicates: allowDuplicates})
+	if err != nil {
+		t.Fatalf("AddPlaylist(%q): %v", name, err)
+	}
+	return p
+}
+
+func mustAddEntry(t *testing.T, repo storage.PlaylistRepo, playlistID, songID int64, position int) song.PlaylistEntry {
+	t.Helper()
+
+	e, err := repo.AddPlaylistEntry(context.Background(), playlistID, songID, position)
+	if err != nil {
+		t.Fatalf("AddPlaylistEntry(%d, %d, %d): %v", playlistID, songID, position, err)
+	}
+	return e
+}
+
+// mustSongIDs добавляет песни и возвращает их id по названиям
+func mustSongIDs(t *testing.T, repo storage.Repository, names ...string) map[string]int64 {
+	t.Helper()
+
+	ids := map[string]int64{}
+	for _, name := range names {
+		mustAdd(t, repo, newSong("Muse", name))
+	}
+	for _, s := range mustList(t, repo, song.Song{}, len(names)+1, 0) {
+		ids[s.Song] = s.SongID
+	}
+	return ids
+}
+
+// entryNames - "позиция:название песни" записей страницы плейлиста
+func entryNames(t *testing.T, repo storage.PlaylistRepo, playlistID int64, limit, offset int) []string {
+	t.Helper()
+
+	entries, err := repo.ListPlaylistEntries(context.Background(), playlistID, limit, offset)
+	if err != nil {
+		t.Fatalf("ListPlaylistEntries(%d): %v", playlistID, err)
+	}
+	result := []string{}
+	for _, e := range entries {
+		if e.PlaylistID != playlistID {
+			t.Fatalf("entry %d of playlist %d, want %d", e.EntryID, e.PlaylistID, playlistID)
+		}
+		result = append(result, fmt.Sprintf("%d:%s", e.Position, e.Song.Song))
+	}
+	return result
+}
+
+func testPlaylistCRUD(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	if _, err := repo.AddPlaylist(ctx, song.Playlist{Name: "  "}); !errors.Is(err, storage.ErrorEmptyName) {
+		t.Fatalf("empty name: err = %v, want %v", err, storage.ErrorEmptyName)
+	}
+
+	created, err := repo.AddPlaylist(ctx, song.Playlist{Name: " Road trip ", Description: "в машину"})
+	if err != nil {
+		t.Fatalf("AddPlaylist: %v", err)
+	}
+	if created.PlaylistID == 0 || created.Name != "Road trip" || created.Description != "в машину" || created.Size != 0 || created.AllowDuplicates {
+		t.Fatalf("AddPlaylist = %+v", created)
+	}
+	if created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("AddPlaylist times = %v, %v", created.CreatedAt, created.UpdatedAt)
+	}
+	mustAddPlaylist(t, repo, "Workout", false)
+	mustAddPlaylist(t, repo, "Road songs", false)
+
+	got, err := repo.GetPlaylistByID(ctx, created.PlaylistID)
+	if err != nil {
+		t.Fatalf("GetPlaylistByID: %v", err)
+	}
+	if got != created {
+		t.Fatalf("GetPlaylistByID = %+v, want %+v", got, created)
+	}
+	if _, err = repo.GetPlaylistByID(ctx, created.PlaylistID+100); !errors.Is(err, storage.ErrorPlaylistNotExist) {
+		t.Fatalf("unknown playlist: err = %v, want %v", err, storage.ErrorPlaylistNotExist)
+	}
+
+	list := func(name string, limit, offset int) []string {
+		t.Helper()
+		playlists, err := repo.ListPlaylists(ctx, name, limit, offset)
+		if err != nil {
+			t.Fatalf("ListPlaylists(%q): %v", name, err)
+		}
+		names := []string{}
+		for _, p := range playlists {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+	assertStrings(t, list("", 10, 0), "Road trip", "Workout", "Road songs")
+	assertStrings(t, list("", 1, 1), "Workout")
+	assertStrings(t, list("ROAD", 10, 0), "Road trip", "Road songs")
+	if _, err = repo.ListPlaylists(ctx, "", -1, 0); !errors.Is(err, storage.ErrorNegativePaginator) {
+		t.Fatalf("negative limit: err = %v, want %v", err, storage.ErrorNegativePaginator)
+	}
+
+	name, description, allow := "Long trip", "", true
+	updated, err := repo.UpdatePlaylist(ctx, created.PlaylistID, song.PlaylistForUpdate{Name: &name, Description: &description, AllowDuplicates: &allow})
+	if err != nil {
+		t.Fatalf("UpdatePlaylist: %v", err)
+	}
+	if updated.Name != name || updated.Description != "" || !updated.AllowDuplicates || updated.CreatedAt != created.CreatedAt {
+		t.Fatalf("UpdatePlaylist = %+v", updated)
+	}
+	if updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Fatalf("updated_at = %v, before %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+
+	empty := " "
+	if _, err = repo.UpdatePlaylist(ctx, created.PlaylistID, song.PlaylistForUpdate{Name: &empty}); !errors.Is(err, storage.ErrorEmptyName) {
+		t.Fatalf("update with empty name: err = %v, want %v", err, storage.ErrorEmptyName)
+	}
+	if _, err = repo.UpdatePlaylist(ctx, created.PlaylistID, song.PlaylistForUpdate{}); !errors.Is(err, storage.ErrorNoFieldsToUpdate) {
+		t.Fatalf("update without fields: err = %v, want %v", err, storage.ErrorNoFieldsToUpdate)
+	}
+	if _, err = repo.UpdatePlaylist(ctx, created.PlaylistID+100, song.PlaylistForUpdate{Name: &name}); !errors.Is(err, storage.ErrorPlaylistNotExist) {
+		t.Fatalf("update unknown playlist: err = %v, want %v", err, storage.ErrorPlaylistNotExist)
+	}
+
+	if err = repo.DeletePlaylist(ctx, created.PlaylistID); err != nil {
+		t.Fatalf("DeletePlaylist: %v", err)
+	}
+	if err = repo.DeletePlaylist(ctx, created.PlaylistID); !errors.Is(err, storage.ErrorPlaylistNotExist) {
+		t.Fatalf("delete twice: err = %v, want %v", err, storage.ErrorPlaylistNotExist)
+	}
+	assertStrings(t, list("", 10, 0), "Workout", "Road songs")
+}
+
+func testPlaylistEntries(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	ids := mustSongIDs(t, repo, "Uprising", "Hysteria", "Starlight", "Knights of Cydonia")
+	p := mustAddPlaylist(t, repo, "Muse", false)
+	other := mustAddPlaylist(t, repo, "Other", false)
+
+	e := mustAddEntry(t, repo, p.PlaylistID, ids["Uprising"], 0)
+	if e.EntryID == 0 || e.PlaylistID != p.PlaylistID || e.Position != 1 || e.Song.SongID != ids["Uprising"] || e.AddedAt.IsZero() {
+		t.Fatalf("AddPlaylistEntry = %+v", e)
+	}
+	mustAddEntry(t, repo, p.PlaylistID, ids["Hysteria"], 0)
+	// позиция за концом - в конец, позиция в середине сдвигает остальные записи
+	if e = mustAddEntry(t, repo, p.PlaylistID, ids["Starlight"], 10); e.Position != 3 {
+		t.Fatalf("add past the end: position = %d, want 3", e.Position)
+	}
+	if e = mustAddEntry(t, repo, p.PlaylistID, ids["Knights of Cydonia"], 2); e.Position != 2 {
+		t.Fatalf("add into the middle: position = %d, want 2", e.Position)
+	}
+	mustAddEntry(t, repo, other.PlaylistID, ids["Hysteria"], 0)
+
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:Uprising", "2:Knights of Cydonia", "3:Hysteria", "4:Starlight")
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 2, 1), "2:Knights of Cydonia", "3:Hysteria")
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 10))
+	assertStrings(t, entryNames(t, repo, other.PlaylistID, 10, 0), "1:Hysteria")
+
+	got, err := repo.GetPlaylistByID(ctx, p.PlaylistID)
+	if err != nil {
+		t.Fatalf("GetPlaylistByID: %v", err)
+	}
+	if got.Size != 4 || got.UpdatedAt.Before(p.UpdatedAt) {
+		t.Fatalf("playlist after adds = %+v", got)
+	}
+
+	// песня записи заполнена вместе с тегами
+	mustAddSongTags(t, repo, ids["Uprising"], "rock")
+	entries, err := repo.ListPlaylistEntries(ctx, p.PlaylistID, 1, 0)
+	if err != nil {
+		t.Fatalf("ListPlaylistEntries: %v", err)
+	}
+	if s := entries[0].Song; s.Group != "Muse" || s.Link == "" {
+		t.Fatalf("entry song = %+v", s)
+	}
+	assertStrings(t, entries[0].Song.Tags, "rock")
+
+	if _, err = repo.AddPlaylistEntry(ctx, p.PlaylistID, ids["Uprising"]+100, 0); !errors.Is(err, storage.ErrorSongNotExist) {
+		t.Fatalf("add unknown song: err = %v, want %v", err, storage.ErrorSongNotExist)
+	}
+	if _, err = repo.AddPlaylistEntry(ctx, p.PlaylistID+100, ids["Uprising"], 0); !errors.Is(err, storage.ErrorPlaylistNotExist) {
+		t.Fatalf("add to unknown playlist: err = %v, want %v", err, storage.ErrorPlaylistNotExist)
+	}
+	if _, err = repo.ListPlaylistEntries(ctx, p.PlaylistID+100, 10, 0); !errors.Is(err, storage.ErrorPlaylistNotExist) {
+		t.Fatalf("entries of unknown playlist: err = %v, want %v", err, storage.ErrorPlaylistNotExist)
+	}
+	if _, err = repo.ListPlaylistEntries(ctx, p.PlaylistID, 10, -1); !errors.Is(err, storage.ErrorNegativePaginator) {
+		t.Fatalf("negative offset: err = %v, want %v", err, storage.ErrorNegativePaginator)
+	}
+
+	// удаление записи не оставляет дыр в позициях
+	if err = repo.DeletePlaylistEntry(ctx, p.PlaylistID, entries[0].EntryID); err != nil {
+		t.Fatalf("DeletePlaylistEntry: %v", err)
+	}
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:Knights of Cydonia", "2:Hysteria", "3:Starlight")
+	if err = repo.DeletePlaylistEntry(ctx, p.PlaylistID, entries[0].EntryID); !errors.Is(err, storage.ErrorEntryNotExist) {
+		t.Fatalf("delete entry twice: err = %v, want %v", err, storage.ErrorEntryNotExist)
+	}
+	otherEntry := mustAddEntry(t, repo, other.PlaylistID, ids["Starlight"], 0)
+	if err = repo.DeletePlaylistEntry(ctx, p.PlaylistID, otherEntry.EntryID); !errors.Is(err, storage.ErrorEntryNotExist) {
+		t.Fatalf("delete entry of other playlist: err = %v, want %v", err, storage.ErrorEntryNotExist)
+	}
+
+	// удаление плейлиста удаляет его записи, но не песни
+	if err = repo.DeletePlaylist(ctx, p.PlaylistID); err != nil {
+		t.Fatalf("DeletePlaylist: %v", err)
+	}
+	if _, err = repo.GetSongByID(ctx, int(ids["Hysteria"])); err != nil {
+		t.Fatalf("song of deleted playlist: %v", err)
+	}
+	assertStrings(t, entryNames(t, repo, other.PlaylistID, 10, 0), "1:Hysteria", "2:Starlight")
+}
+
+func testPlaylistMoveAndReorder(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	ids := mustSongIDs(t, repo, "A", "B", "C", "D")
+	p := mustAddPlaylist(t, repo, "Mix", false)
+	entry := map[string]int64{}
+	for _, name := range []string{"A", "B", "C", "D"} {
+		entry[name] = mustAddEntry(t, repo, p.PlaylistID, ids[name], 0).EntryID
+	}
+
+	move := func(name string, position, want int) {
+		t.Helper()
+		e, err := repo.MovePlaylistEntry(ctx, p.PlaylistID, entry[name], position)
+		if err != nil {
+			t.Fatalf("MovePlaylistEntry(%s, %d): %v", name, position, err)
+		}
+		if e.Position != want || e.Song.Song != name {
+			t.Fatalf("MovePlaylistEntry(%s, %d) = %d:%s, want %d:%s", name, position, e.Position, e.Song.Song, want, name)
+		}
+	}
+	move("D", 1, 1)
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:D", "2:A", "3:B", "4:C")
+	move("D", 3, 3)
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:A", "2:B", "3:D", "4:C")
+	move("A", 100, 4)
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:B", "2:D", "3:C", "4:A")
+	move("C", 3, 3)
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:B", "2:D", "3:C", "4:A")
+
+	if _, err := repo.MovePlaylistEntry(ctx, p.PlaylistID, entry["A"]+100, 1); !errors.Is(err, storage.ErrorEntryNotExist) {
+		t.Fatalf("move unknown entry: err = %v, want %v", err, storage.ErrorEntryNotExist)
+	}
+	if _, err := repo.MovePlaylistEntry(ctx, p.PlaylistID+100, entry["A"], 1); !errors.Is(err, storage.ErrorPlaylistNotExist) {
+		t.Fatalf("move in unknown playlist: err = %v, want %v", err, storage.ErrorPlaylistNotExist)
+	}
+
+	err := repo.ReorderPlaylist(ctx, p.PlaylistID, []int64{entry["C"], entry["A"], entry["D"], entry["B"]})
+	if err != nil {
+		t.Fatalf("ReorderPlaylist: %v", err)
+	}
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:C", "2:A", "3:D", "4:B")
+
+	for _, order := range [][]int64{
+		{entry["C"], entry["A"], entry["D"]},
+		{entry["C"], entry["A"], entry["D"], entry["D"]},
+		{entry["C"], entry["A"], entry["D"], entry["B"], entry["B"] + 100},
+		{entry["C"], entry["A"], entry["D"], entry["B"] + 100},
+	} {
+		if err = repo.ReorderPlaylist(ctx, p.PlaylistID, order); !errors.Is(err, storage.ErrorBadReorder) {
+			t.Fatalf("ReorderPlaylist(%v): err = %v, want %v", order, err, storage.ErrorBadReorder)
+		}
+	}
+	assertStrings(t, entryNames(t, repo, p.PlaylistID, 10, 0), "1:C", "2:A", "3:D", "4:B")
+	if err = repo.ReorderPlaylist(ctx, p.PlaylistID+100, nil); !errors.Is(err, storage.ErrorPlaylistNotExist) {
+		t.Fatalf("reorder unknown playlist: err = %v, want %v", err, storage.ErrorPlaylistNotExist)
+	}
+}
+
+func testPlaylistDuplicates(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	ids := mustSongIDs(t, repo, "Uprising", "Hysteria")
+	unique := mustAddPlaylist(t, repo, "Unique", false)
+	repeats := mustAddPlaylist(t, repo, "Repeats", true)
+
+	mustAddEntry(t, repo, unique.PlaylistID, ids["Uprising"], 0)
+	if _, err := repo.AddPlaylistEntry(ctx, unique.PlaylistID, ids["Uprising"], 0); !errors.Is(err, storage.ErrorSongInPlaylist) {
+		t.Fatalf("duplicate song: err = %v, want %v", err, storage.ErrorSongInPlaylist)
+	}
+
+	mustAddEntry(t, repo, repeats.PlaylistID, ids["Uprising"], 0)
+	mustAddEntry(t, repo, repeats.PlaylistID, ids["Hysteria"], 0)
+	second := mustAddEntry(t, repo, repeats.PlaylistID, ids["Uprising"], 1)
+	assertStrings(t, entryNames(t, repo, repeats.PlaylistID, 10, 0), "1:Uprising", "2:Uprising", "3:Hysteria")
+
+	// повторы нельзя запретить, пока они есть
+	disallow := false
+	if _, err := repo.UpdatePlaylist(ctx, repeats.PlaylistID, song.PlaylistForUpdate{AllowDuplicates: &disallow}); !errors.Is(err, storage.ErrorPlaylistHasRepeats) {
+		t.Fatalf("disallow with repeats: err = %v, want %v", err, storage.ErrorPlaylistHasRepeats)
+	}
+	if err := repo.DeletePlaylistEntry(ctx, repeats.PlaylistID, second.EntryID); err != nil {
+		t.Fatalf("DeletePlaylistEntry: %v", err)
+	}
+	updated, err := repo.UpdatePlaylist(ctx, repeats.PlaylistID, song.PlaylistForUpdate{AllowDuplicates: &disallow})
+	if err != nil {
+		t.Fatalf("UpdatePlaylist: %v", err)
+	}
+	if updated.AllowDuplicates || updated.Size != 2 {
+		t.Fatalf("UpdatePlaylist = %+v", updated)
+	}
+	if _, err = repo.AddPlaylistEntry(ctx, repeats.PlaylistID, ids["Hysteria"], 0); !errors.Is(err, storage.ErrorSongInPlaylist) {
+		t.Fatalf("duplicate after disallow: err = %v, want %v", err, storage.ErrorSongInPlaylist)
+	}
+}
+
+func testPlaylistDeleteSong(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	ids := mustSongIDs(t, repo, "Uprising", "Hysteria", "Starlight")
+	first := mustAddPlaylist(t, repo, "First", true)
+	second := mustAddPlaylist(t, repo, "Second", false)
+	for _, name := range []string{"Uprising", "Hysteria", "Uprising", "Starlight"} {
+		mustAddEntry(t, repo, first.PlaylistID, ids[name], 0)
+	}
+	mustAddEntry(t, repo, second.PlaylistID, ids["Uprising"], 0)
+
+	if _, err := repo.DeleteSongByIDFromDB(ctx, int(ids["Uprising"])); err != nil {
+		t.Fatalf("DeleteSongByIDFromDB: %v", err)
+	}
+	assertStrings(t, entryNames(t, repo, first.PlaylistID, 10, 0), "1:Hysteria", "2:Starlight")
+	assertStrings(t, entryNames(t, repo, second.PlaylistID, 10, 0))
+
+	got, err := repo.GetPlaylistByID(ctx, first.PlaylistID)
+	if err != nil {
+		t.Fatalf("GetPlaylistByID: %v", err)
+	}
+	if got.Size != 2 {
+		t.Fatalf("size after song delete = %d, want 2", got.Size)
+	}
+
+	// после удаления песни новые записи встают в конец
+	e := mustAddEntry(t, repo, first.PlaylistID, ids["Hysteria"], 0)
+	if e.Position != 3 {
+		t.Fatalf("position after song delete = %d, want 3", e.Position)
+	}
+}
@@ -0,0 +1,166 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// SearchableRepo - хранилище песен с полнотекстовым поиском
+type SearchableRepo interface {
+	storage.SongRepo
+	storage.SearchRepo
+}
+
+// NewSearchRepo должен возвращать пустое хранилище для каждого теста
+type NewSearchRepo func(t *testing.T) SearchableRepo
+
+// RunSearchRepoTests - проверки для хранилищ, реализующих storage.SearchRepo
+func RunSearchRepoTests(t *testing.T, newRepo NewSearchRepo) {
+	tests := []struct {
+		name string
+		test func(*testing.T, SearchableRepo)
+	}{
+		{"WordsAndRank", testSearchWordsAndRank},
+		{"PhraseAndPrefix", testSearchPhraseAndPrefix},
+		{"Snippet", testSearchSnippet},
+		{"EmptyQuery", testSearchEmptyQuery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(repo.Close)
+			tt.test(t, repo)
+		})
+	}
+}
+
+func addSearchSongs(t *testing.T, repo storage.SongRepo) {
+	t.Helper()
+
+	songs := []song.Song{
+		{Group: "Muse", Song: "Supermassive Black Hole", Text: "Ooh baby, don't you know I suffer?\n\nGlaciers melting in the dead of night"},
+		{Group: "Black Sabbath", Song: "Paranoid", Text: "Finished with my woman\n\nCan you help me occupy my brain?"},
+		{Group: "Queen", Song: "Bohemian Rhapsody", Text: "Is this the real life?\n\nMama, just killed a man\n\nNothing really matters to me"},
+	}
+	for _, s := range songs {
+		s.EnrichmentStatus = song.EnrichmentDone
+		if _, err := repo.AddSongToDB(context.Background(), s); err != nil {
+			t.Fatalf("AddSongToDB(%q): %v", s.Song, err)
+		}
+	}
+}
+
+func searchNames(results []song.SearchResult) []string {
+	names := make([]string, 0, len(results))
+	for _, r := range results {
+		names = append(names, r.Song.Song)
+	}
+	return names
+}
+
+func testSearchWordsAndRank(t *testing.T, repo SearchableRepo) {
+	addSearchSongs(t, repo)
+
+	results, err := repo.SearchSongs(context.Background(), "black", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	// совпадение в названии выше совпадения в группе
+	names := searchNames(results)
+	if len(names) != 2 || names[0] != "Supermassive Black Hole" || names[1] != "Paranoid" {
+		t.Fatalf("SearchSongs(black) = %v, want [Supermassive Black Hole Paranoid]", names)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Errorf("ranks %v, %v are not descending", results[0].Rank, results[1].Rank)
+	}
+
+	results, err = repo.SearchSongs(context.Background(), "black brain", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if names := searchNames(results); len(names) != 1 || names[0] != "Paranoid" {
+		t.Errorf("SearchSongs(black brain) = %v, want [Paranoid]", names)
+	}
+
+	results, err = repo.SearchSongs(context.Background(), "black", 1, 1)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if names := searchNames(results); len(names) != 1 || names[0] != "Paranoid" {
+		t.Errorf("SearchSongs(black) page 2 = %v, want [Paranoid]", names)
+	}
+
+	results, err = repo.SearchSongs(context.Background(), "nonexistentword", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("SearchSongs(nonexistentword) = %v, want empty", searchNames(results))
+	}
+}
+
+func testSearchPhraseAndPrefix(t *testing.T, repo SearchableRepo) {
+	addSearchSongs(t, repo)
+
+	results, err := repo.SearchSongs(context.Background(), `"real life"`, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if names := searchNames(results); len(names) != 1 || names[0] != "Bohemian Rhapsody" {
+		t.Errorf(`SearchSongs("real life") = %v, want [Bohemian Rhapsody]`, names)
+	}
+
+	results, err = repo.SearchSongs(context.Background(), `"life real"`, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf(`SearchSongs("life real") = %v, want empty`, searchNames(results))
+	}
+
+	results, err = repo.SearchSongs(context.Background(), "glacie*", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if names := searchNames(results); len(names) != 1 || names[0] != "Supermassive Black Hole" {
+		t.Errorf("SearchSongs(glacie*) = %v, want [Supermassive Black Hole]", names)
+	}
+}
+
+func testSearchSnippet(t *testing.T, repo SearchableRepo) {
+	addSearchSongs(t, repo)
+
+	results, err := repo.SearchSongs(context.Background(), "killed", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("SearchSongs(killed) = %v, want one song", searchNames(results))
+	}
+	if snippet := results[0].Snippet; !strings.Contains(snippet, "<b>killed</b>") || strings.Contains(snippet, "real life") {
+		t.Errorf("snippet = %q, want matched verse with <b>killed</b>", snippet)
+	}
+
+	// совпадение только в группе - сниппета нет
+	results, err = repo.SearchSongs(context.Background(), "queen", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchSongs: %v", err)
+	}
+	if len(results) != 1 || results[0].Snippet != "" {
+		t.Errorf("SearchSongs(queen) = %+v, want one song with empty snippet", results)
+	}
+}
+
+func testSearchEmptyQuery(t *testing.T, repo SearchableRepo) {
+	for _, q := range []string{"", "   ", `""`, "*"} {
+		if _, err := repo.SearchSongs(context.Background(), q, 10, 0); !errors.Is(err, storage.ErrorEmptySearchQuery) {
+			t.Errorf("SearchSongs(%q) error = %v, want %v", q, err, storage.ErrorEmptySearchQuery)
+		}
+	}
+}
@@ -0,0 +1,605 @@
+// Package storagetest содержит общий набор проверок поведения storage.SongRepo.
+// Каждая реализация хранилища должна проходить его, например:
+//
+//	func TestSongRepo(t *testing.T) {
+//		storagetest.RunSongRepoTests(t, func(t *testing.T) storage.SongRepo {
+//			return memory.NewSongMemoryRepository()
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// NewRepo должен возвращать пустое хранилище для каждого теста
+type NewRepo func(t *testing.T) storage.SongRepo
+
+func RunSongRepoTests(t *testing.T, newRepo NewRepo) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.SongRepo)
+	}{
+		{"AddAndDuplicate", testAddAndDuplicate},
+		{"ListOrderAndPagination", testListOrderAndPagination},
+		{"ListFilter", testListFilter},
+		{"ListEmpty", testListEmpty},
+		{"ListNegativePaginator", testListNegativePaginator},
+		{"ListCursor", testListCursor},
+		{"ListSortAndFilter", testListSortAndFilter},
+		{"Delete", testDelete},
+		{"GetText", testGetText},
+		{"GetByID", testGetByID},
+		{"ReleaseDatePrecision", testReleaseDatePrecision},
+		{"Update", testUpdate},
+		{"ConcurrentAdd", testConcurrentAdd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(repo.Close)
+			tt.test(t, repo)
+		})
+	}
+}
+
+func newSong(group, name string) song.Song {
+	return song.Song{
+		Song:        name,
+		Group:       group,
+		ReleaseDate: releaseDate("2006-07-16"),
+		Text:        "Ooh baby, don't you know I suffer?\n\nOoh baby, can you hear me moan?",
+		Link:        "https://www.youtube.com/watch?v=Xsp3_a-PMTw",
+		Tags:        []string{},
+
+		EnrichmentStatus: song.EnrichmentDone,
+	}
+}
+
+// releaseDate разбирает дату выпуска из литерала теста
+func releaseDate(value string) song.ReleaseDate {
+	d, err := song.ParseReleaseDate(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func mustAdd(t *testing.T, repo storage.SongRepo, songs ...song.Song) {
+	t.Helper()
+
+	for _, s := range songs {
+		if _, err := repo.AddSongToDB(context.Background(), s); err != nil {
+			t.Fatalf("AddSongToDB(%q, %q): %v", s.Group, s.Song, err)
+		}
+	}
+}
+
+func mustList(t *testing.T, repo storage.SongRepo, filter song.Song, limit, offset int) []song.Song {
+	t.Helper()
+
+	songs, err := repo.GetSongsFromDB(context.Background(), filter, limit, offset)
+	if err != nil {
+		t.Fatalf("GetSongsFromDB(%+v, %d, %d): %v", filter, limit, offset, err)
+	}
+	return songs
+}
+
+func names(songs []song.Song) []string {
+	result := make([]string, 0, len(songs))
+	for _, s := range songs {
+		result = append(result, s.Song)
+	}
+	return result
+}
+
+func assertNames(t *testing.T, songs []song.Song, want ...string) {
+	t.Helper()
+
+	got := names(songs)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("songs = %v, want %v", got, want)
+	}
+}
+
+func testAddAndDuplicate(t *testing.T, repo storage.SongRepo) {
+	created, err := repo.AddSongToDB(context.Background(), newSong("Muse", "Supermassive Black Hole"))
+	if err != nil {
+		t.Fatalf("AddSongToDB: %v", err)
+	}
+
+	_, err = repo.AddSongToDB(context.Background(), newSong("Muse", "Supermassive Black Hole"))
+	if !errors.Is(err, storage.ErrorSongExist) {
+		t.Fatalf("add duplicate: err = %v, want %v", err, storage.ErrorSongExist)
+	}
+
+	// группа сравнивается с исполнителем без учета регистра и пробелов по краям,
+	// название песни - точно
+	_, err = repo.AddSongToDB(context.Background(), newSong(" muse ", "Supermassive Black Hole"))
+	if !errors.Is(err, storage.ErrorSongExist) {
+		t.Fatalf("add duplicate of other case: err = %v, want %v", err, storage.ErrorSongExist)
+	}
+	mustAdd(t, repo,
+		newSong("muse", "supermassive black hole"),
+		newSong("Muse", "Uprising"),
+	)
+
+	songs := mustList(t, repo, song.Song{}, 10, 0)
+	assertNames(t, songs, "Supermassive Black Hole", "supermassive black hole", "Uprising")
+	for _, s := range songs {
+		if s.Group != "Muse" || s.ArtistID != created.ArtistID {
+			t.Fatalf("song %q: group = %q, artist_id = %d, want Muse, %d", s.Song, s.Group, s.ArtistID, created.ArtistID)
+		}
+	}
+
+	want := newSong("Muse", "Supermassive Black Hole")
+	got := songs[0]
+	if got.SongID == 0 || got.ArtistID == 0 {
+		t.Fatalf("song_id or artist_id is not assigned: %+v", got)
+	}
+	if !reflect.DeepEqual(created, got) {
+		t.Fatalf("AddSongToDB returned %+v, stored %+v", created, got)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Fatalf("created_at is not assigned")
+	}
+	got.SongID, got.ArtistID, got.CreatedAt = 0, 0, time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("stored song = %+v, want %+v", got, want)
+	}
+}
+
+func testGetByID(t *testing.T, repo storage.SongRepo) {
+	mustAdd(t, repo, newSong("Muse", "Uprising"), newSong("Muse", "Hysteria"))
+	want := mustList(t, repo, song.Song{Song: "Hysteria"}, 10, 0)[0]
+
+	got, err := repo.GetSongByID(context.Background(), int(want.SongID))
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetSongByID(%d) = %+v, %v, want %+v, nil", want.SongID, got, err, want)
+	}
+
+	_, err = repo.GetSongByID(context.Background(), int(want.SongID)+100)
+	if !errors.Is(err, storage.ErrorSongNotExist) {
+		t.Fatalf("unknown id: err = %v, want %v", err, storage.ErrorSongNotExist)
+	}
+}
+
+func testReleaseDatePrecision(t *testing.T, repo storage.SongRepo) {
+	dates := []string{"2006-07-16", "2006-07", "2006", ""}
+	var id int
+	for i, value := range dates {
+		s := newSong("Muse", fmt.Sprintf("Song %d", i))
+		s.ReleaseDate = releaseDate(value)
+		created, err := repo.AddSongToDB(context.Background(), s)
+		if err != nil {
+			t.Fatalf("AddSongToDB(%q): %v", value, err)
+		}
+		id = int(created.SongID)
+
+		got, err := repo.GetSongByID(context.Background(), int(created.SongID))
+		if err != nil {
+			t.Fatalf("GetSongByID: %v", err)
+		}
+		if got.ReleaseDate != s.ReleaseDate || got.ReleaseDate.String() != value {
+			t.Errorf("release date = %q (%s), want %q", got.ReleaseDate, got.ReleaseDate.Precision, value)
+		}
+	}
+
+	// обновление меняет и дату, и точность
+	month := releaseDate("1975-10")
+	got, err := repo.UpdateSongByID(context.Background(), song.SongForUpdate{ReleaseDate: &month}, id)
+	if err != nil {
+		t.Fatalf("UpdateSongByID: %v", err)
+	}
+	if got.ReleaseDate != month {
+		t.Errorf("updated release date = %q, want %q", got.ReleaseDate, month)
+	}
+}
+
+func testListOrderAndPagination(t *testing.T, repo storage.SongRepo) {
+	for i := 1; i <= 5; i++ {
+		mustAdd(t, repo, newSong("Group", fmt.Sprintf("Song %d", i)))
+	}
+
+	songs := mustList(t, repo, song.Song{}, 10, 0)
+	for i := 1; i < len(songs); i++ {
+		if songs[i-1].SongID >= songs[i].SongID {
+			t.Fatalf("songs are not ordered by song_id: %v", songs)
+		}
+	}
+
+	assertNames(t, mustList(t, repo, song.Song{}, 2, 0), "Song 1", "Song 2")
+	assertNames(t, mustList(t, repo, song.Song{}, 2, 2), "Song 3", "Song 4")
+	assertNames(t, mustList(t, repo, song.Song{}, 2, 4), "Song 5")
+
+	_, err := repo.GetSongsFromDB(context.Background(), song.Song{}, 2, 6)
+	if !errors.Is(err, storage.ErrorListOfSongsEmpty) {
+		t.Fatalf("page after last: err = %v, want %v", err, storage.ErrorListOfSongsEmpty)
+	}
+}
+
+func testListFilter(t *testing.T, repo storage.SongRepo) {
+	first := newSong("Muse", "Supermassive Black Hole")
+	second := newSong("Queen", "Bohemian Rhapsody")
+	second.Text = "Is this the real life?\n\nIs this just fantasy?"
+	second.Link = "https://example.com/queen_100%"
+	second.ReleaseDate = releaseDate("1975-10-31")
+	third := newSong("Кино", "Группа крови")
+	third.Text = "Теплое место, но улицы ждут"
+	mustAdd(t, repo, first, second, third)
+
+	tests := []struct {
+		name   string
+		filter song.Song
+		want   []string
+	}{
+		{"song case insensitive", song.Song{Song: "black HOLE"}, []string{first.Song}},
+		{"group substring", song.Song{Group: "ee"}, []string{second.Song}},
+		{"text", song.Song{Text: "fantasy"}, []string{second.Song}},
+		{"release year", song.Song{ReleaseDate: releaseDate("1975")}, []string{second.Song}},
+		{"release month", song.Song{ReleaseDate: releaseDate("2006-07")}, []string{first.Song, third.Song}},
+		{"link", song.Song{Link: "queen"}, []string{second.Song}},
+		{"several fields", song.Song{Group: "u", Text: "baby"}, []string{first.Song}},
+		{"cyrillic case insensitive", song.Song{Group: "кИНо"}, []string{third.Song}},
+		{"underscore wildcard", song.Song{Group: "Q_een"}, []string{second.Song}},
+		{"percent wildcard", song.Song{Song: "Super%Hole"}, []string{first.Song}},
+		{"escaped wildcard", song.Song{Link: `100\%`}, []string{second.Song}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertNames(t, mustList(t, repo, tt.filter, 10, 0), tt.want...)
+		})
+	}
+
+	_, err := repo.GetSongsFromDB(context.Background(), song.Song{Group: "Nirvana"}, 10, 0)
+	if !errors.Is(err, storage.ErrorListOfSongsEmpty) {
+		t.Fatalf("filter without matches: err = %v, want %v", err, storage.ErrorListOfSongsEmpty)
+	}
+}
+
+func testListEmpty(t *testing.T, repo storage.SongRepo) {
+	_, err := repo.GetSongsFromDB(context.Background(), song.Song{}, 10, 0)
+	if !errors.Is(err, storage.ErrorListOfSongsEmpty) {
+		t.Fatalf("empty repo: err = %v, want %v", err, storage.ErrorListOfSongsEmpty)
+	}
+
+	mustAdd(t, repo, newSong("Muse", "Uprising"))
+
+	_, err = repo.GetSongsFromDB(context.Background(), song.Song{}, 0, 0)
+	if !errors.Is(err, storage.ErrorListOfSongsEmpty) {
+		t.Fatalf("zero limit: err = %v, want %v", err, storage.ErrorListOfSongsEmpty)
+	}
+}
+
+func testListNegativePaginator(t *testing.T, repo storage.SongRepo) {
+	mustAdd(t, repo, newSong("Muse", "Uprising"))
+
+	for _, p := range [][2]int{{-1, 0}, {10, -10}} {
+		_, err := repo.GetSongsFromDB(context.Background(), song.Song{}, p[0], p[1])
+		if !errors.Is(err, storage.ErrorNegativePaginator) {
+			t.Fatalf("limit %d, offset %d: err = %v, want %v", p[0], p[1], err, storage.ErrorNegativePaginator)
+		}
+	}
+}
+
+func testListCursor(t *testing.T, repo storage.SongRepo) {
+	page, err := repo.ListSongs(context.Background(), storage.ListParams{Limit: 2, WithTotal: true})
+	if err != nil {
+		t.Fatalf("ListSongs on empty repo: %v", err)
+	}
+	if len(page.Items) != 0 || page.Next != nil || page.Total == nil || *page.Total != 0 {
+		t.Fatalf("empty repo: page = %+v, want empty page with total 0", page)
+	}
+
+	mustAdd(t, repo,
+		newSong("Muse", "Uprising"),
+		newSong("Queen", "Bohemian Rhapsody"),
+		newSong("Muse", "Hysteria"),
+		newSong("Muse", "Madness"),
+		newSong("Muse", "Starlight"),
+	)
+
+	// проход по всем страницам курсором
+	params := storage.ListParams{Filter: storage.SongFilter{Groups: []string{"muse"}}, Limit: 2, WithTotal: true}
+	var got []song.Song
+	for i := 0; ; i++ {
+		page, err := repo.ListSongs(context.Background(), params)
+		if err != nil {
+			t.Fatalf("ListSongs(%+v): %v", params, err)
+		}
+		if page.Total == nil || *page.Total != 4 {
+			t.Fatalf("page %d: total = %v, want 4", i, page.Total)
+		}
+		got = append(got, page.Items...)
+		if page.Next == nil {
+			break
+		}
+		if i > 3 {
+			t.Fatalf("cursor does not finish")
+		}
+
+		cursor, err := storage.DecodeCursor(page.Next.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		params.After = &cursor
+	}
+	assertNames(t, got, "Uprising", "Hysteria", "Madness", "Starlight")
+
+	// последняя полная страница не дает лишнего курсора
+	page, err = repo.ListSongs(context.Background(), storage.ListParams{Filter: storage.SongFilter{Groups: []string{"muse"}}, Limit: 4})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	if page.Next != nil || page.Total != nil {
+		t.Fatalf("full page: next = %v, total = %v, want nil", page.Next, page.Total)
+	}
+
+	// старая пагинация по номеру страницы
+	page, err = repo.ListSongs(context.Background(), storage.ListParams{Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	assertNames(t, page.Items, "Starlight")
+	if page.Next != nil {
+		t.Fatalf("last page: next = %v, want nil", page.Next)
+	}
+
+	// удаление песни не сбивает курсор
+	first, err := repo.ListSongs(context.Background(), storage.ListParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	if _, err := repo.DeleteSongByIDFromDB(context.Background(), int(first.Items[0].SongID)); err != nil {
+		t.Fatalf("DeleteSongByIDFromDB: %v", err)
+	}
+	page, err = repo.ListSongs(context.Background(), storage.ListParams{Limit: 2, After: first.Next})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	assertNames(t, page.Items, "Hysteria", "Madness")
+
+	_, err = repo.ListSongs(context.Background(), storage.ListParams{Limit: -1})
+	if !errors.Is(err, storage.ErrorNegativePaginator) {
+		t.Fatalf("negative limit: err = %v, want %v", err, storage.ErrorNegativePaginator)
+	}
+
+	for _, bad := range []string{"not a cursor", "e30x", storage.Cursor{ID: -1}.Encode()} {
+		if _, err := storage.DecodeCursor(bad); !errors.Is(err, storage.ErrorBadCursor) {
+			t.Fatalf("DecodeCursor(%q): err = %v, want %v", bad, err, storage.ErrorBadCursor)
+		}
+	}
+}
+
+func testListSortAndFilter(t *testing.T, repo storage.SongRepo) {
+	songs := []struct {
+		group, name, date string
+	}{
+		{"Muse", "Uprising", "07.09.2009"},
+		{"queen", "Bohemian Rhapsody", "31.10.1975"},
+		{"Muse", "hysteria", "01.12.2003"},
+		{"Queen", "Innuendo", "14.01.1991"},
+		{"Museum", "Exhibit", ""},
+		{"Muse", "Starlight", "04.09.2006"},
+		{"Кино", "группа крови", "05.01.1988"},
+		{"Кино", "Звезда по имени Солнце", "01.08.1989"},
+	}
+	for _, s := range songs {
+		created := newSong(s.group, s.name)
+		created.ReleaseDate = releaseDate(s.date)
+		mustAdd(t, repo, created)
+	}
+
+	date := func(s string) *time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+
+	tests := []struct {
+		name   string
+		filter storage.SongFilter
+		sort   string
+		want   []string
+	}{
+		{"sort by name ignores case", storage.SongFilter{}, "name",
+			[]string{"Bohemian Rhapsody", "Exhibit", "hysteria", "Innuendo", "Starlight", "Uprising", "группа крови", "Звезда по имени Солнце"}},
+		{"sort by cyrillic name ignores case", storage.SongFilter{Groups: []string{"кино"}, Match: storage.MatchExact}, "-name",
+			[]string{"Звезда по имени Солнце", "группа крови"}},
+		{"sort by group then date desc", storage.SongFilter{}, "group,-release_date",
+			[]string{"Uprising", "Starlight", "hysteria", "Exhibit", "Innuendo", "Bohemian Rhapsody", "Звезда по имени Солнце", "группа крови"}},
+		{"sort by created", storage.SongFilter{}, "created_at",
+			[]string{"Uprising", "Bohemian Rhapsody", "hysteria", "Innuendo", "Exhibit", "Starlight", "группа крови", "Звезда по имени Солнце"}},
+		{"unknown date goes first", storage.SongFilter{}, "release_date",
+			[]string{"Exhibit", "Bohemian Rhapsody", "группа крови", "Звезда по имени Солнце", "Innuendo", "hysteria", "Starlight", "Uprising"}},
+		{"substring group", storage.SongFilter{Groups: []string{"mus"}}, "",
+			[]string{"Uprising", "hysteria", "Exhibit", "Starlight"}},
+		{"exact group", storage.SongFilter{Groups: []string{"muse"}, Match: storage.MatchExact}, "",
+			[]string{"Uprising", "hysteria", "Starlight"}},
+		{"prefix name", storage.SongFilter{Name: "in", Match: storage.MatchPrefix}, "",
+			[]string{"Innuendo"}},
+		{"any of groups", storage.SongFilter{Groups: []string{"queen", "museum"}, Match: storage.MatchExact}, "name",
+			[]string{"Bohemian Rhapsody", "Exhibit", "Innuendo"}},
+		{"date range", storage.SongFilter{DateFrom: date("1991-01-14"), DateTo: date("2006-09-04")}, "release_date",
+			[]string{"Innuendo", "hysteria", "Starlight"}},
+		{"date from", storage.SongFilter{DateFrom: date("2004-01-01")}, "",
+			[]string{"Uprising", "Starlight"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := storage.ParseSort(tt.sort)
+			if err != nil {
+				t.Fatalf("ParseSort(%q): %v", tt.sort, err)
+			}
+
+			page, err := repo.ListSongs(context.Background(), storage.ListParams{Filter: tt.filter, Sort: sort, Limit: 10})
+			if err != nil {
+				t.Fatalf("ListSongs: %v", err)
+			}
+			assertNames(t, page.Items, tt.want...)
+
+			// тот же порядок при проходе курсором по одной песне
+			var got []song.Song
+			params := storage.ListParams{Filter: tt.filter, Sort: sort, Limit: 1}
+			for i := 0; i <= len(tt.want); i++ {
+				page, err := repo.ListSongs(context.Background(), params)
+				if err != nil {
+					t.Fatalf("ListSongs(%+v): %v", params, err)
+				}
+				got = append(got, page.Items...)
+				if page.Next == nil {
+					break
+				}
+				params.After = page.Next
+			}
+			assertNames(t, got, tt.want...)
+		})
+	}
+
+	for _, bad := range []string{"id", "name,-name", "name,", "-"} {
+		if _, err := storage.ParseSort(bad); !errors.Is(err, storage.ErrorBadSort) {
+			t.Fatalf("ParseSort(%q): err = %v, want %v", bad, err, storage.ErrorBadSort)
+		}
+	}
+
+	// курсор другой сортировки не принимается
+	page, err := repo.ListSongs(context.Background(), storage.ListParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	sort, _ := storage.ParseSort("name")
+	_, err = repo.ListSongs(context.Background(), storage.ListParams{Sort: sort, Limit: 1, After: page.Next})
+	if !errors.Is(err, storage.ErrorBadCursor) {
+		t.Fatalf("cursor of other sort: err = %v, want %v", err, storage.ErrorBadCursor)
+	}
+
+	// значения курсора, которые не получить из песни, не доходят до запроса
+	for _, tt := range []struct {
+		sort  string
+		value string
+	}{
+		{"release_date", "07.09.2009"},
+		{"release_date", "2009-09-07'"},
+		{"created_at", "yesterday"},
+		{"created_at", "2009-09-07"},
+	} {
+		sort, _ := storage.ParseSort(tt.sort)
+		after := &storage.Cursor{ID: 1, Sort: tt.sort, Values: []string{tt.value}}
+		_, err = repo.ListSongs(context.Background(), storage.ListParams{Sort: sort, Limit: 1, After: after})
+		if !errors.Is(err, storage.ErrorBadCursor) {
+			t.Fatalf("cursor with %s %q: err = %v, want %v", tt.sort, tt.value, err, storage.ErrorBadCursor)
+		}
+	}
+}
+
+func testDelete(t *testing.T, repo storage.SongRepo) {
+	mustAdd(t, repo, newSong("Muse", "Uprising"), newSong("Muse", "Hysteria"))
+	songs := mustList(t, repo, song.Song{}, 10, 0)
+	id := int(songs[0].SongID)
+
+	got, err := repo.DeleteSongByIDFromDB(context.Background(), id)
+	if err != nil || got != id {
+		t.Fatalf("DeleteSongByIDFromDB(%d) = %d, %v, want %d, nil", id, got, err, id)
+	}
+	assertNames(t, mustList(t, repo, song.Song{}, 10, 0), "Hysteria")
+
+	got, err = repo.DeleteSongByIDFromDB(context.Background(), id)
+	if !errors.Is(err, storage.ErrorSongNotExist) || got != id {
+		t.Fatalf("delete twice = %d, %v, want %d, %v", got, err, id, storage.ErrorSongNotExist)
+	}
+
+	// id удаленной песни не переиспользуется
+	mustAdd(t, repo, newSong("Muse", "Uprising"))
+	songs = mustList(t, repo, song.Song{}, 10, 0)
+	if songs[1].SongID == int64(id) {
+		t.Fatalf("song_id %d reused after delete", id)
+	}
+}
+
+func testGetText(t *testing.T, repo storage.SongRepo) {
+	s := newSong("Muse", "Uprising")
+	mustAdd(t, repo, s)
+	id := int(mustList(t, repo, song.Song{}, 10, 0)[0].SongID)
+
+	text, err := repo.GetTextOfSongFromDB(context.Background(), id)
+	if err != nil || text != s.Text {
+		t.Fatalf("GetTextOfSongFromDB(%d) = %q, %v, want %q, nil", id, text, err, s.Text)
+	}
+
+	_, err = repo.GetTextOfSongFromDB(context.Background(), id+100)
+	if !errors.Is(err, storage.ErrorSongNotExist) {
+		t.Fatalf("unknown id: err = %v, want %v", err, storage.ErrorSongNotExist)
+	}
+}
+
+func testUpdate(t *testing.T, repo storage.SongRepo) {
+	mustAdd(t, repo, newSong("Muse", "Uprising"))
+	existing := mustList(t, repo, song.Song{}, 10, 0)[0]
+	id := int(existing.SongID)
+
+	name, link := "Starlight", "https://example.com/starlight"
+	want := newSong("Muse", name)
+	want.SongID = int64(id)
+	want.ArtistID = existing.ArtistID
+	want.Link = link
+	want.CreatedAt = existing.CreatedAt
+
+	got, err := repo.UpdateSongByID(context.Background(), song.SongForUpdate{Song: &name, Link: &link}, id)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("UpdateSongByID(%d) = %+v, %v, want %+v, nil", id, got, err, want)
+	}
+	if s := mustList(t, repo, song.Song{}, 10, 0)[0]; !reflect.DeepEqual(s, want) {
+		t.Fatalf("updated song = %+v, want %+v", s, want)
+	}
+
+	_, err = repo.UpdateSongByID(context.Background(), song.SongForUpdate{}, id)
+	if !errors.Is(err, storage.ErrorNoFieldsToUpdate) {
+		t.Fatalf("empty update: err = %v, want %v", err, storage.ErrorNoFieldsToUpdate)
+	}
+
+	_, err = repo.UpdateSongByID(context.Background(), song.SongForUpdate{Song: &name}, id+100)
+	if !errors.Is(err, storage.ErrorSongNotExist) {
+		t.Fatalf("unknown id: err = %v, want %v", err, storage.ErrorSongNotExist)
+	}
+}
+
+func testConcurrentAdd(t *testing.T, repo storage.SongRepo) {
+	const workers = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := repo.AddSongToDB(context.Background(), newSong("Group", fmt.Sprintf("Song %d", i)))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent AddSongToDB: %v", err)
+		}
+	}
+
+	if songs := mustList(t, repo, song.Song{}, workers*2, 0); len(songs) != workers {
+		t.Fatalf("got %d songs, want %d", len(songs), workers)
+	}
+}
@@ -0,0 +1,194 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"SongLibrary/pkg/song"
+	"SongLibrary/pkg/storage"
+)
+
+// RunTagRepoTests проверяет теги песен и отбор песен по тегам
+func RunTagRepoTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.Repository)
+	}{
+		{"AddAndRemove", testTagsAddAndRemove},
+		{"AddSongWithTags", testAddSongWithTags},
+		{"ListTags", testListTags},
+		{"FilterByTags", testFilterByTags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(repo.Close)
+			tt.test(t, repo)
+		})
+	}
+}
+
+func taggedSong(group, name string, tags ...string) song.Song {
+	s := newSong(group, name)
+	s.Tags = tags
+	return s
+}
+
+func mustAddSongTags(t *testing.T, repo storage.TagRepo, id int64, tags ...string) song.Song {
+	t.Helper()
+
+	s, err := repo.AddSongTags(context.Background(), int(id), tags)
+	if err != nil {
+		t.Fatalf("AddSongTags(%d, %q): %v", id, tags, err)
+	}
+	return s
+}
+
+func testTagsAddAndRemove(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	mustAdd(t, repo, newSong("Muse", "Uprising"))
+	id := mustList(t, repo, song.Song{}, 10, 0)[0].SongID
+
+	s := mustAddSongTags(t, repo, id, " Rock", "alternative", "ROCK", "Рок")
+	assertStrings(t, s.Tags, "alternative", "rock", "рок")
+
+	// повторное добавление ничего не меняет
+	s = mustAddSongTags(t, repo, id, "rock", "2009")
+	assertStrings(t, s.Tags, "2009", "alternative", "rock", "рок")
+
+	got, err := repo.GetSongByID(ctx, int(id))
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	assertStrings(t, got.Tags, "2009", "alternative", "rock", "рок")
+
+	for _, tags := range [][]string{{" "}, {strings.Repeat("a", storage.MaxTagLength+1)}} {
+		if _, err = repo.AddSongTags(ctx, int(id), tags); !errors.Is(err, storage.ErrorInvalidTag) {
+			t.Fatalf("AddSongTags(%q): err = %v, want %v", tags, err, storage.ErrorInvalidTag)
+		}
+	}
+	if _, err = repo.AddSongTags(ctx, int(id)+100, []string{"rock"}); !errors.Is(err, storage.ErrorSongNotExist) {
+		t.Fatalf("tags of unknown song: err = %v, want %v", err, storage.ErrorSongNotExist)
+	}
+
+	s, err = repo.RemoveSongTags(ctx, int(id), []string{"Rock", "pop"})
+	if err != nil {
+		t.Fatalf("RemoveSongTags: %v", err)
+	}
+	assertStrings(t, s.Tags, "2009", "alternative", "рок")
+	if _, err = repo.RemoveSongTags(ctx, int(id)+100, []string{"rock"}); !errors.Is(err, storage.ErrorSongNotExist) {
+		t.Fatalf("remove tags of unknown song: err = %v, want %v", err, storage.ErrorSongNotExist)
+	}
+
+	// обновление песни не трогает ее теги
+	name := "Uprising (Live)"
+	updated, err := repo.UpdateSongByID(ctx, song.SongForUpdate{Song: &name}, int(id))
+	if err != nil {
+		t.Fatalf("UpdateSongByID: %v", err)
+	}
+	assertStrings(t, updated.Tags, "2009", "alternative", "рок")
+}
+
+func testAddSongWithTags(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	created, err := repo.AddSongToDB(ctx, taggedSong("Muse", "Uprising", "Rock", "rock ", "2009"))
+	if err != nil {
+		t.Fatalf("AddSongToDB: %v", err)
+	}
+	assertStrings(t, created.Tags, "2009", "rock")
+	assertStrings(t, mustList(t, repo, song.Song{}, 10, 0)[0].Tags, "2009", "rock")
+
+	if _, err = repo.AddSongToDB(ctx, taggedSong("Muse", "Hysteria", "")); !errors.Is(err, storage.ErrorInvalidTag) {
+		t.Fatalf("song with empty tag: err = %v, want %v", err, storage.ErrorInvalidTag)
+	}
+	assertNames(t, mustList(t, repo, song.Song{}, 10, 0), "Uprising")
+}
+
+func tagCounts(t *testing.T, repo storage.TagRepo, name string, limit, offset int) []string {
+	t.Helper()
+
+	tags, err := repo.ListTags(context.Background(), name, limit, offset)
+	if err != nil {
+		t.Fatalf("ListTags(%q): %v", name, err)
+	}
+	result := []string{}
+	for _, tag := range tags {
+		result = append(result, fmt.Sprintf("%s:%d", tag.Name, tag.Songs))
+	}
+	return result
+}
+
+func testListTags(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	assertStrings(t, tagCounts(t, repo, "", 10, 0))
+
+	mustAdd(t, repo,
+		taggedSong("Muse", "Uprising", "rock", "alternative"),
+		taggedSong("Muse", "Hysteria", "rock"),
+		taggedSong("Queen", "Innuendo", "rock", "classic rock"),
+		taggedSong("Кино", "Звезда по имени Солнце", "рок"),
+	)
+
+	assertStrings(t, tagCounts(t, repo, "", 10, 0), "rock:3", "alternative:1", "classic rock:1", "рок:1")
+	assertStrings(t, tagCounts(t, repo, "", 2, 1), "alternative:1", "classic rock:1")
+	assertStrings(t, tagCounts(t, repo, "ROCK", 10, 0), "rock:3", "classic rock:1")
+
+	// теги без песен в список не попадают
+	hysteria := mustList(t, repo, song.Song{Song: "Hysteria"}, 10, 0)[0]
+	innuendo := mustList(t, repo, song.Song{Song: "Innuendo"}, 10, 0)[0]
+	if _, err := repo.DeleteSongByIDFromDB(ctx, int(hysteria.SongID)); err != nil {
+		t.Fatalf("DeleteSongByIDFromDB: %v", err)
+	}
+	if _, err := repo.RemoveSongTags(ctx, int(innuendo.SongID), []string{"classic rock"}); err != nil {
+		t.Fatalf("RemoveSongTags: %v", err)
+	}
+	assertStrings(t, tagCounts(t, repo, "", 10, 0), "rock:2", "alternative:1", "рок:1")
+
+	if _, err := repo.ListTags(ctx, "", -1, 0); !errors.Is(err, storage.ErrorNegativePaginator) {
+		t.Fatalf("negative limit: err = %v, want %v", err, storage.ErrorNegativePaginator)
+	}
+}
+
+func testFilterByTags(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	mustAdd(t, repo,
+		taggedSong("Muse", "Uprising", "rock", "alternative"),
+		taggedSong("Muse", "Hysteria", "rock"),
+		taggedSong("Queen", "Innuendo", "classic rock"),
+		newSong("Queen", "Bicycle Race"),
+	)
+
+	list := func(match string, tags ...string) []song.Song {
+		t.Helper()
+		page, err := repo.ListSongs(ctx, storage.ListParams{
+			Filter: storage.SongFilter{Tags: tags, TagMatch: match},
+			Limit:  10,
+		})
+		if err != nil {
+			t.Fatalf("ListSongs(%s %q): %v", match, tags, err)
+		}
+		return page.Items
+	}
+
+	assertNames(t, list("", "rock"), "Uprising", "Hysteria")
+	assertNames(t, list(storage.TagMatchAny, "alternative", "classic rock"), "Uprising", "Innuendo")
+	assertNames(t, list(storage.TagMatchAll, "alternative", "rock"), "Uprising")
+	assertNames(t, list(storage.TagMatchAll, "alternative", "classic rock"))
+	assertNames(t, list(storage.TagMatchAny, "pop"))
+
+	// теги песен страницы заполнены
+	songs := list("")
+	assertStrings(t, songs[0].Tags, "alternative", "rock")
+	assertStrings(t, songs[3].Tags)
+	if songs[3].Tags == nil {
+		t.Fatalf("tags of song without tags = nil, want empty list")
+	}
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"unicode/utf8"
+
+	"SongLibrary/pkg/song"
+)
+
+// MaxTagLength - наибольшая длина тега в символах
+const MaxTagLength = 50
+
+// TagRepo - теги песен. Теги хранятся в виде NormalizeTag, поэтому "Rock" и " rock" - один тег.
+// AddSongToDB сохраняет теги song.Song.Tags, все методы, возвращающие песни, заполняют их теги.
+type TagRepo interface {
+	// AddSongTags добавляет песне теги, которые у нее уже есть, пропускаются
+	AddSongTags(ctx context.Context, songID int, tags []string) (song.Song, error)
+	// RemoveSongTags убирает у песни теги, которых у нее нет, пропускаются
+	RemoveSongTags(ctx context.Context, songID int, tags []string) (song.Song, error)
+	// ListTags возвращает теги хотя бы одной песни по убыванию числа песен, name - подстрока тега
+	ListTags(ctx context.Context, name string, limit int, offset int) ([]song.Tag, error)
+}
+
+// NormalizeTag приводит тег к виду, в котором он хранится
+func NormalizeTag(tag string) string {
+	return NameKey(tag)
+}
+
+// NormalizeTags приводит теги к NormalizeTag, убирает повторы и сортирует.
+// Пустой тег или тег длиннее MaxTagLength - ErrorInvalidTag.
+func NormalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, ErrorInvalidTag
+		}
+		result = append(result, tag)
+	}
+	slices.Sort(result)
+	return slices.Compact(result), nil
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+const DefaultTimeout = 5 * time.Second
+
+// Timeouts - таймауты на каждую операцию с хранилищем
+type Timeouts struct {
+	Add    time.Duration
+	List   time.Duration
+	Get    time.Duration
+	Update time.Duration
+	Delete time.Duration
+}
+
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Add:    DefaultTimeout,
+		List:   DefaultTimeout,
+		Get:    DefaultTimeout,
+		Update: DefaultTimeout,
+		Delete: DefaultTimeout,
+	}
+}
+
+// WithTimeout ограничивает контекст запроса таймаутом операции.
+// Нулевой или отрицательный таймаут оставляет только дедлайн самого запроса.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}